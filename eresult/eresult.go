@@ -0,0 +1,88 @@
+// Package eresult defines Steam's result codes, shared by the Web API, CM
+// and community clients in the module.
+package eresult
+
+import "fmt"
+
+// EResult is a Steam result code, as carried in CM message headers, Web API
+// X-Eresult headers and response bodies.
+type EResult int32
+
+const (
+	OK                              EResult = 1
+	Fail                            EResult = 2
+	NoConnection                    EResult = 3
+	InvalidPassword                 EResult = 5
+	LoggedInElsewhere               EResult = 6
+	InvalidProtocolVer              EResult = 7
+	InvalidParam                    EResult = 8
+	FileNotFound                    EResult = 9
+	Busy                            EResult = 10
+	InvalidState                    EResult = 11
+	AccessDenied                    EResult = 15
+	Timeout                         EResult = 16
+	Banned                          EResult = 17
+	AccountNotFound                 EResult = 18
+	InvalidSteamID                  EResult = 19
+	ServiceUnavailable              EResult = 20
+	NotLoggedOn                     EResult = 21
+	Pending                         EResult = 22
+	LimitExceeded                   EResult = 25
+	Revoked                         EResult = 26
+	Expired                         EResult = 27
+	DuplicateRequest                EResult = 29
+	LogonSessionReplaced            EResult = 34
+	ConnectFailed                   EResult = 35
+	HandshakeFailed                 EResult = 36
+	IOFailure                       EResult = 37
+	RemoteDisconnect                EResult = 38
+	TryAnotherCM                    EResult = 48
+	AccountLogonDenied              EResult = 63
+	InvalidLoginAuthCode            EResult = 65
+	RateLimitExceeded               EResult = 84
+	AccountLoginDeniedNeedTwoFactor EResult = 85
+	TwoFactorCodeMismatch           EResult = 88
+)
+
+var names = map[EResult]string{
+	OK:                              "OK",
+	Fail:                            "Fail",
+	NoConnection:                    "NoConnection",
+	InvalidPassword:                 "InvalidPassword",
+	LoggedInElsewhere:               "LoggedInElsewhere",
+	InvalidProtocolVer:              "InvalidProtocolVer",
+	InvalidParam:                    "InvalidParam",
+	FileNotFound:                    "FileNotFound",
+	Busy:                            "Busy",
+	InvalidState:                    "InvalidState",
+	AccessDenied:                    "AccessDenied",
+	Timeout:                         "Timeout",
+	Banned:                          "Banned",
+	AccountNotFound:                 "AccountNotFound",
+	InvalidSteamID:                  "InvalidSteamID",
+	ServiceUnavailable:              "ServiceUnavailable",
+	NotLoggedOn:                     "NotLoggedOn",
+	Pending:                         "Pending",
+	LimitExceeded:                   "LimitExceeded",
+	Revoked:                         "Revoked",
+	Expired:                         "Expired",
+	DuplicateRequest:                "DuplicateRequest",
+	LogonSessionReplaced:            "LogonSessionReplaced",
+	ConnectFailed:                   "ConnectFailed",
+	HandshakeFailed:                 "HandshakeFailed",
+	IOFailure:                       "IOFailure",
+	RemoteDisconnect:                "RemoteDisconnect",
+	TryAnotherCM:                    "TryAnotherCM",
+	AccountLogonDenied:              "AccountLogonDenied",
+	InvalidLoginAuthCode:            "InvalidLoginAuthCode",
+	RateLimitExceeded:               "RateLimitExceeded",
+	AccountLoginDeniedNeedTwoFactor: "AccountLoginDeniedNeedTwoFactor",
+	TwoFactorCodeMismatch:           "TwoFactorCodeMismatch",
+}
+
+func (e EResult) String() string {
+	if name, ok := names[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("EResult(%d)", int32(e))
+}
@@ -0,0 +1,85 @@
+// Package market computes Steam Community Market fees: what a buyer pays
+// for a given seller amount and the reverse, with the same rounding as
+// the market's own scripts.
+//
+// Amounts are integers in hundredths of the wallet currency. Steam keeps
+// every currency that way, including those it renders without decimals
+// (12300 is "¥ 123"), so the same arithmetic covers all of them; what
+// varies per wallet are the Fees, read from its g_rgWalletInfo.
+package market
+
+import "math"
+
+// Fees are a wallet's market fee parameters.
+type Fees struct {
+	SteamPercent     float64 // wallet_fee_percent
+	SteamMinimum     int     // wallet_fee_minimum
+	SteamBase        int     // wallet_fee_base
+	PublisherPercent float64 // wallet_publisher_fee_percent_default, or the game's own
+}
+
+// DefaultFees are the fees of a wallet in any currency, unless Steam says
+// otherwise: 5% to Steam and 10% to the game's publisher, each at least
+// one hundredth.
+var DefaultFees = Fees{
+	SteamPercent:     0.05,
+	SteamMinimum:     1,
+	PublisherPercent: 0.10,
+}
+
+// Price is a market price split into what the seller receives and the
+// fees on top.
+type Price struct {
+	SellerReceives int
+	SteamFee       int
+	PublisherFee   int
+	BuyerPays      int
+}
+
+// BuyerPays prices a listing that pays the seller sellerReceives.
+func (f Fees) BuyerPays(sellerReceives int) Price {
+	steamFee := int(math.Floor(math.Max(float64(sellerReceives)*f.SteamPercent, float64(f.SteamMinimum)) + float64(f.SteamBase)))
+	var publisherFee int
+	if f.PublisherPercent > 0 {
+		publisherFee = int(math.Floor(math.Max(float64(sellerReceives)*f.PublisherPercent, 1)))
+	}
+	return Price{
+		SellerReceives: sellerReceives,
+		SteamFee:       steamFee,
+		PublisherFee:   publisherFee,
+		BuyerPays:      sellerReceives + steamFee + publisherFee,
+	}
+}
+
+// SellerReceives splits what a buyer pays into the seller's part and the
+// fees: the most the seller can receive without BuyerPays exceeding
+// buyerPays. Not every buyer price is BuyerPays of some seller amount,
+// since the fees jump in whole hundredths; like Steam, the gap goes to the
+// Steam fee. Below MinBuyerPays, SellerReceives comes out under 1.
+func (f Fees) SellerReceives(buyerPays int) Price {
+	// BuyerPays never decreases as the seller amount grows, so binary
+	// search for the largest one that fits. Unlike Steam's own estimate
+	// and walk, this holds for any fee minimum or base.
+	lo, hi := 0, max(buyerPays, 0) // f.BuyerPays(lo) fits, unless lo is 0
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		if f.BuyerPays(mid).BuyerPays <= buyerPays {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	if lo < 1 {
+		return Price{BuyerPays: buyerPays}
+	}
+	p := f.BuyerPays(lo)
+	p.SteamFee += buyerPays - p.BuyerPays
+	p.BuyerPays = buyerPays
+	return p
+}
+
+// MinBuyerPays is the lowest price a buyer can pay: one hundredth to the
+// seller plus the minimum fees.
+func (f Fees) MinBuyerPays() int {
+	return f.BuyerPays(1).BuyerPays
+}
@@ -0,0 +1,78 @@
+package market
+
+import "testing"
+
+func TestBuyerPays(t *testing.T) {
+	cases := []struct {
+		fees   Fees
+		seller int
+		want   Price
+	}{
+		{DefaultFees, 1, Price{SellerReceives: 1, SteamFee: 1, PublisherFee: 1, BuyerPays: 3}},
+		{DefaultFees, 19, Price{SellerReceives: 19, SteamFee: 1, PublisherFee: 1, BuyerPays: 21}},
+		{DefaultFees, 87, Price{SellerReceives: 87, SteamFee: 4, PublisherFee: 8, BuyerPays: 99}},
+		{DefaultFees, 100, Price{SellerReceives: 100, SteamFee: 5, PublisherFee: 10, BuyerPays: 115}},
+		{DefaultFees, 12300, Price{SellerReceives: 12300, SteamFee: 615, PublisherFee: 1230, BuyerPays: 14145}},
+		// A game without a publisher fee.
+		{Fees{SteamPercent: 0.05, SteamMinimum: 1}, 100, Price{SellerReceives: 100, SteamFee: 5, BuyerPays: 105}},
+		// A wallet with a fee base and a higher minimum.
+		{Fees{SteamPercent: 0.05, SteamMinimum: 7, SteamBase: 2, PublisherPercent: 0.10}, 50, Price{SellerReceives: 50, SteamFee: 9, PublisherFee: 5, BuyerPays: 64}},
+	}
+	for _, tc := range cases {
+		if got := tc.fees.BuyerPays(tc.seller); got != tc.want {
+			t.Errorf("%+v.BuyerPays(%d) = %+v, want %+v", tc.fees, tc.seller, got, tc.want)
+		}
+	}
+}
+
+func TestSellerReceives(t *testing.T) {
+	cases := []struct {
+		buyer int
+		want  Price
+	}{
+		{3, Price{SellerReceives: 1, SteamFee: 1, PublisherFee: 1, BuyerPays: 3}},
+		{99, Price{SellerReceives: 87, SteamFee: 4, PublisherFee: 8, BuyerPays: 99}},
+		{115, Price{SellerReceives: 100, SteamFee: 5, PublisherFee: 10, BuyerPays: 115}},
+		// No seller amount gives 22 (19 -> 21, 20 -> 23): the extra
+		// hundredth goes to Steam.
+		{22, Price{SellerReceives: 19, SteamFee: 2, PublisherFee: 1, BuyerPays: 22}},
+		// Below the minimum.
+		{2, Price{BuyerPays: 2}},
+	}
+	for _, tc := range cases {
+		if got := DefaultFees.SellerReceives(tc.buyer); got != tc.want {
+			t.Errorf("SellerReceives(%d) = %+v, want %+v", tc.buyer, got, tc.want)
+		}
+	}
+
+	// A high minimum: 102 only covers the fees and 1 for the seller.
+	fees := Fees{SteamPercent: 0.05, SteamMinimum: 100, PublisherPercent: 0.10}
+	if got, want := fees.SellerReceives(102), (Price{SellerReceives: 1, SteamFee: 100, PublisherFee: 1, BuyerPays: 102}); got != want {
+		t.Errorf("%+v.SellerReceives(102) = %+v, want %+v", fees, got, want)
+	}
+}
+
+func TestFeesRoundTrip(t *testing.T) {
+	for _, fees := range []Fees{
+		DefaultFees,
+		{SteamPercent: 0.05, SteamMinimum: 1},
+		{SteamPercent: 0.05, SteamMinimum: 7, SteamBase: 2, PublisherPercent: 0.10},
+		// Currencies with large minimums, where an estimate from the
+		// percentages alone lands far off.
+		{SteamPercent: 0.05, SteamMinimum: 100, PublisherPercent: 0.10},
+		{SteamPercent: 0.05, SteamMinimum: 1000, SteamBase: 50, PublisherPercent: 0.10},
+	} {
+		for seller := 1; seller <= 100000; seller++ {
+			buyer := fees.BuyerPays(seller).BuyerPays
+			if got := fees.SellerReceives(buyer); got.SellerReceives != seller {
+				t.Fatalf("%+v: SellerReceives(%d) = %+v, want seller %d", fees, buyer, got, seller)
+			}
+		}
+		for buyer := fees.MinBuyerPays(); buyer <= 100000; buyer++ {
+			p := fees.SellerReceives(buyer)
+			if p.BuyerPays != buyer || p.SellerReceives+p.SteamFee+p.PublisherFee != buyer || p.SellerReceives < 1 {
+				t.Fatalf("%+v: SellerReceives(%d) = %+v", fees, buyer, p)
+			}
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"reflect"
 	"strconv"
 
+	"github.com/k64z/steamstacks/eresult"
 	"github.com/k64z/steamstacks/protocol"
 	"google.golang.org/protobuf/proto"
 )
@@ -34,9 +35,9 @@ func (a *API) GetPasswordRSAPublicKey(ctx context.Context, accountName string) (
 		return nil, err
 	}
 
-	apiURL := "https://api.steampowered.com/IAuthenticationService/GetPasswordRSAPublicKey/v1"
+	apiURL := a.baseURL + "/IAuthenticationService/GetPasswordRSAPublicKey/v1"
 	params := url.Values{}
-	params.Set("origin", "https://steamcommunity.com")
+	params.Set("origin", a.communityURL)
 	params.Set("input_protobuf_encoded", payload)
 
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", apiURL+"?"+params.Encode(), nil)
@@ -127,7 +128,7 @@ func (a *API) BeginAuthSessionViaCredentials(
 		return nil, fmt.Errorf("build body: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.steampowered.com/IAuthenticationService/BeginAuthSessionViaCredentials/v1", bytes.NewReader(bodyBytes))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/IAuthenticationService/BeginAuthSessionViaCredentials/v1", bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -157,6 +158,49 @@ func (a *API) BeginAuthSessionViaCredentials(
 	return result, nil
 }
 
+// BeginAuthSessionViaQR starts an auth session that is approved by scanning
+// the returned challenge URL with the Steam mobile app.
+func (a *API) BeginAuthSessionViaQR(
+	ctx context.Context,
+	req *protocol.CAuthentication_BeginAuthSessionViaQR_Request,
+) (*protocol.CAuthentication_BeginAuthSessionViaQR_Response, error) {
+	if req == nil {
+		return nil, errors.New("invalid request")
+	}
+
+	bodyBytes, contentType, err := buildProtobufPOSTBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("build body: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/IAuthenticationService/BeginAuthSessionViaQR/v1", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+
+	resp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if result := resp.Header.Get("X-Eresult"); result != "1" {
+		code, err := strconv.Atoi(result)
+		if err != nil {
+			return nil, fmt.Errorf("invalid X-Eresult header: %s", result)
+		}
+		return nil, &EResultError{EResult: eresult.EResult(code), Message: resp.Header.Get("X-Error_message")}
+	}
+
+	result, err := decodeProtoFromHTTPResponse(resp, &protocol.CAuthentication_BeginAuthSessionViaQR_Response{})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 // UpdateAuthSessionWithSteamGuardCode approves an authentication session via steam guard code
 func (a *API) UpdateAuthSessionWithSteamGuardCode(
 	ctx context.Context,
@@ -171,7 +215,7 @@ func (a *API) UpdateAuthSessionWithSteamGuardCode(
 		return fmt.Errorf("build body: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.steampowered.com/IAuthenticationService/UpdateAuthSessionWithSteamGuardCode/v1", bytes.NewReader(bodyBytes))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/IAuthenticationService/UpdateAuthSessionWithSteamGuardCode/v1", bytes.NewReader(bodyBytes))
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
@@ -183,8 +227,12 @@ func (a *API) UpdateAuthSessionWithSteamGuardCode(
 	}
 	defer resp.Body.Close()
 
-	if resp.Header.Get("X-Eresult") != "1" {
-		return fmt.Errorf("invalid X-Eresult header: %s", resp.Header.Get("X-Eresult"))
+	if result := resp.Header.Get("X-Eresult"); result != "1" {
+		code, err := strconv.Atoi(result)
+		if err != nil {
+			return fmt.Errorf("invalid X-Eresult header: %s", result)
+		}
+		return &EResultError{EResult: eresult.EResult(code), Message: resp.Header.Get("X-Error_message")}
 	}
 
 	return nil
@@ -203,7 +251,7 @@ func (a *API) PollAuthSessionStatus(
 		return nil, fmt.Errorf("build body: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.steampowered.com/IAuthenticationService/PollAuthSessionStatus/v1", bytes.NewReader(bodyBytes))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/IAuthenticationService/PollAuthSessionStatus/v1", bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -215,8 +263,12 @@ func (a *API) PollAuthSessionStatus(
 	}
 	defer resp.Body.Close()
 
-	if resp.Header.Get("X-Eresult") != "1" {
-		return nil, fmt.Errorf("invalid X-Eresult header: %s", resp.Header.Get("X-Eresult"))
+	if result := resp.Header.Get("X-Eresult"); result != "1" {
+		code, err := strconv.Atoi(result)
+		if err != nil {
+			return nil, fmt.Errorf("invalid X-Eresult header: %s", result)
+		}
+		return nil, &EResultError{EResult: eresult.EResult(code), Message: resp.Header.Get("X-Error_message")}
 	}
 
 	result, err := decodeProtoFromHTTPResponse(resp, &protocol.CAuthentication_PollAuthSessionStatus_Response{})
@@ -255,9 +307,13 @@ func (a *API) GenerateAccessTokenForApp(
 	}
 	defer resp.Body.Close()
 
-	if resp.Header.Get("X-Eresult") != "1" {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("eresult %s: %s (body: %s)", resp.Header.Get("X-Eresult"), resp.Header.Get("X-Error_message"), body)
+	if result := resp.Header.Get("X-Eresult"); result != "1" {
+		code, err := strconv.Atoi(result)
+		if err != nil {
+			body, _ := io.ReadAll(resp.Body)
+			return nil, fmt.Errorf("invalid X-Eresult header %q (body: %s)", result, errorBodySnippet(body))
+		}
+		return nil, &EResultError{EResult: eresult.EResult(code), Message: resp.Header.Get("X-Error_message")}
 	}
 
 	result, err := decodeProtoFromHTTPResponse(resp, &protocol.CAuthentication_AccessToken_GenerateForApp_Response{})
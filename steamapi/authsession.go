@@ -0,0 +1,111 @@
+package steamapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/k64z/steamstacks/eresult"
+	"github.com/k64z/steamstacks/protocol"
+	"google.golang.org/protobuf/proto"
+)
+
+// GetAuthSessionsForAccount lists the client IDs of login attempts awaiting
+// approval on the authenticated account.
+func (a *API) GetAuthSessionsForAccount(ctx context.Context) (*protocol.CAuthentication_GetAuthSessionsForAccount_Response, error) {
+	resp := &protocol.CAuthentication_GetAuthSessionsForAccount_Response{}
+	err := a.postAuthenticationService(ctx, "GetAuthSessionsForAccount", &protocol.CAuthentication_GetAuthSessionsForAccount_Request{}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetAuthSessionInfo describes a pending login attempt (device, location,
+// requested persistence) so it can be reviewed before approval.
+func (a *API) GetAuthSessionInfo(
+	ctx context.Context,
+	req *protocol.CAuthentication_GetAuthSessionInfo_Request,
+) (*protocol.CAuthentication_GetAuthSessionInfo_Response, error) {
+	resp := &protocol.CAuthentication_GetAuthSessionInfo_Response{}
+	if err := a.postAuthenticationService(ctx, "GetAuthSessionInfo", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateAuthSessionWithMobileConfirmation approves or denies a pending login
+// on behalf of the mobile authenticator. The request must be signed with the
+// account's shared secret and sent with a MobileApp access token.
+func (a *API) UpdateAuthSessionWithMobileConfirmation(
+	ctx context.Context,
+	req *protocol.CAuthentication_UpdateAuthSessionWithMobileConfirmation_Request,
+) error {
+	return a.postAuthenticationService(ctx, "UpdateAuthSessionWithMobileConfirmation", req,
+		&protocol.CAuthentication_UpdateAuthSessionWithMobileConfirmation_Response{})
+}
+
+// EnumerateTokens lists the refresh tokens issued for the authenticated
+// account.
+func (a *API) EnumerateTokens(ctx context.Context) (*protocol.CAuthentication_RefreshToken_Enumerate_Response, error) {
+	resp := &protocol.CAuthentication_RefreshToken_Enumerate_Response{}
+	err := a.postAuthenticationService(ctx, "EnumerateTokens", &protocol.CAuthentication_RefreshToken_Enumerate_Request{}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RevokeRefreshToken revokes one of the account's refresh tokens by ID.
+func (a *API) RevokeRefreshToken(
+	ctx context.Context,
+	req *protocol.CAuthentication_RefreshToken_Revoke_Request,
+) error {
+	return a.postAuthenticationService(ctx, "RevokeRefreshToken", req,
+		&protocol.CAuthentication_RefreshToken_Revoke_Response{})
+}
+
+// postAuthenticationService calls an IAuthenticationService method that
+// requires an access token and decodes the protobuf response into resp.
+func (a *API) postAuthenticationService(ctx context.Context, method string, req, resp proto.Message) error {
+	accessToken, err := a.getAccessToken()
+	if err != nil {
+		return fmt.Errorf("get access token: %w", err)
+	}
+
+	bodyBytes, contentType, err := buildProtobufPOSTBody(req)
+	if err != nil {
+		return fmt.Errorf("build body: %w", err)
+	}
+
+	apiURL := a.baseURL + "/IAuthenticationService/" + method + "/v1?access_token=" + url.QueryEscape(accessToken)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+
+	httpResp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if result := httpResp.Header.Get("X-Eresult"); result != "1" {
+		code, err := strconv.Atoi(result)
+		if err != nil {
+			body, _ := io.ReadAll(httpResp.Body)
+			return fmt.Errorf("%s: invalid X-Eresult header %q (body: %s)", method, result, errorBodySnippet(body))
+		}
+		return fmt.Errorf("%s: %w", method, &EResultError{EResult: eresult.EResult(code), Message: httpResp.Header.Get("X-Error_message")})
+	}
+
+	if _, err := decodeProtoFromHTTPResponse(httpResp, resp); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package steamapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/k64z/steamstacks/eresult"
+)
+
+func TestPostAuthenticationServiceEResultError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Eresult", "15")
+		w.Header().Set("X-Error_message", "Access Denied")
+	}))
+	defer srv.Close()
+
+	a, err := New(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetAccessToken("token")
+
+	_, err = a.EnumerateTokens(context.Background())
+	var eresultErr *EResultError
+	if !errors.As(err, &eresultErr) {
+		t.Fatalf("err = %v, want *EResultError", err)
+	}
+	if eresultErr.EResult != eresult.AccessDenied || eresultErr.Message != "Access Denied" {
+		t.Errorf("EResultError = %+v, want AccessDenied with the header message", eresultErr)
+	}
+}
@@ -0,0 +1,21 @@
+package steamapi
+
+import (
+	"fmt"
+
+	"github.com/k64z/steamstacks/eresult"
+)
+
+// EResultError is returned when a Web API call answers with an X-Eresult
+// header other than OK.
+type EResultError struct {
+	EResult eresult.EResult
+	Message string // X-Error_message header, if any
+}
+
+func (e *EResultError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("eresult %s: %s", e.EResult, e.Message)
+	}
+	return fmt.Sprintf("eresult %s", e.EResult)
+}
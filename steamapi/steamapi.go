@@ -5,20 +5,24 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/k64z/steamstacks/steamhost"
 )
 
-const defaultBaseURL = "https://api.steampowered.com"
+const defaultBaseURL = steamhost.DefaultAPI
 
 type API struct {
-	httpClient  *http.Client
-	baseURL     string
-	accessToken string
-	apiKey      string
+	httpClient   *http.Client
+	baseURL      string
+	communityURL string
+	accessToken  string
+	apiKey       string
 }
 
 type config struct {
 	httpClient *http.Client
 	baseURL    string
+	hosts      steamhost.Hosts
 	apiKey     string
 }
 
@@ -41,6 +45,16 @@ func WithBaseURL(baseURL string) Option {
 	}
 }
 
+// WithHosts maps the Steam web hosts. The API host becomes the base URL
+// unless WithBaseURL is also given; the community host is where the
+// steamLoginSecure cookie is looked up.
+func WithHosts(hosts steamhost.Hosts) Option {
+	return func(options *config) error {
+		options.hosts = hosts
+		return nil
+	}
+}
+
 func WithAPIKey(key string) Option {
 	return func(options *config) error {
 		options.apiKey = key
@@ -57,8 +71,10 @@ func New(opts ...Option) (*API, error) {
 		}
 	}
 
+	hosts := cfg.hosts.Resolve()
 	a := &API{
-		baseURL: defaultBaseURL,
+		baseURL:      hosts.API,
+		communityURL: hosts.Community,
 	}
 
 	if cfg.httpClient != nil {
@@ -80,7 +96,7 @@ func New(opts ...Option) (*API, error) {
 // (which reflects any token refresh) and falling back to a manually-set token.
 func (a *API) getAccessToken() (string, error) {
 	if a.httpClient.Jar != nil {
-		if token, err := extractAccessToken(a.httpClient.Jar); err == nil {
+		if token, err := extractAccessToken(a.httpClient.Jar, a.communityURL); err == nil {
 			return token, nil
 		}
 	}
@@ -92,9 +108,8 @@ func (a *API) getAccessToken() (string, error) {
 
 // extractAccessToken extracts the access token from the steamLoginSecure cookie.
 // The cookie format is "{steamid}||{access_token}" (URL encoded as "%7C%7C").
-func extractAccessToken(jar http.CookieJar) (string, error) {
-	u, _ := url.Parse("https://steamcommunity.com")
-	cookies := jar.Cookies(u)
+func extractAccessToken(jar http.CookieJar, communityURL string) (string, error) {
+	cookies := jar.Cookies(steamhost.CookieURL(communityURL))
 
 	for _, cookie := range cookies {
 		if cookie.Name == "steamLoginSecure" {
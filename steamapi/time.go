@@ -18,10 +18,19 @@ func GetSteamTime(ctx context.Context) (serverTime int64, offset int64, err erro
 
 // GetSteamTimeWithClient fetches the current time from Steam servers using a custom HTTP client.
 func GetSteamTimeWithClient(ctx context.Context, client *http.Client) (serverTime int64, offset int64, err error) {
+	return querySteamTime(ctx, client, defaultBaseURL)
+}
+
+// GetSteamTime fetches the current time from the configured API host.
+func (a *API) GetSteamTime(ctx context.Context) (serverTime int64, offset int64, err error) {
+	return querySteamTime(ctx, a.httpClient, a.baseURL)
+}
+
+func querySteamTime(ctx context.Context, client *http.Client, baseURL string) (serverTime int64, offset int64, err error) {
 	localTime := time.Now().Unix()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
-		"https://api.steampowered.com/ITwoFactorService/QueryTime/v1/", nil)
+		baseURL+"/ITwoFactorService/QueryTime/v1/", nil)
 	if err != nil {
 		return 0, 0, fmt.Errorf("create request: %w", err)
 	}
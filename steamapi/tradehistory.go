@@ -0,0 +1,283 @@
+package steamapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+// ETradeStatus is the settlement state of a completed trade
+type ETradeStatus int
+
+const (
+	ETradeStatusInit                     ETradeStatus = 0
+	ETradeStatusPreCommitted             ETradeStatus = 1
+	ETradeStatusCommitted                ETradeStatus = 2
+	ETradeStatusComplete                 ETradeStatus = 3
+	ETradeStatusFailed                   ETradeStatus = 4
+	ETradeStatusPartialSupportRollback   ETradeStatus = 5
+	ETradeStatusFullSupportRollback      ETradeStatus = 6
+	ETradeStatusSupportRollbackSelective ETradeStatus = 7
+	ETradeStatusRollbackFailed           ETradeStatus = 8
+	ETradeStatusRollbackAbandoned        ETradeStatus = 9
+	ETradeStatusInEscrow                 ETradeStatus = 10
+	ETradeStatusEscrowRollback           ETradeStatus = 11
+)
+
+// Trade is the receipt of a trade (CEcon_TradeStatus). Times are unix
+// seconds; TimeSettlement is set once items can no longer be rolled back.
+type Trade struct {
+	TradeID        string              `json:"tradeid"`
+	SteamIDOther   uint64              `json:"steamid_other,string"`
+	TimeInit       int64               `json:"time_init"`
+	TimeEscrowEnd  int64               `json:"time_escrow_end"`
+	TimeSettlement int64               `json:"time_settlement"`
+	Status         ETradeStatus        `json:"status"`
+	AssetsReceived []TradeHistoryAsset `json:"assets_received"`
+	AssetsGiven    []TradeHistoryAsset `json:"assets_given"`
+}
+
+// TradeHistoryAsset is an item that changed hands in a trade. AssetID and
+// ContextID are the item's IDs before the trade; NewAssetID and
+// NewContextID are its IDs in the new owner's inventory. The Rollback
+// fields are set when a rollback returned the item under yet another ID.
+type TradeHistoryAsset struct {
+	AppID                int    `json:"appid"`
+	ContextID            string `json:"contextid"`
+	AssetID              string `json:"assetid"`
+	Amount               string `json:"amount"`
+	ClassID              string `json:"classid"`
+	InstanceID           string `json:"instanceid"`
+	CurrencyID           string `json:"currencyid,omitempty"`
+	NewAssetID           string `json:"new_assetid"`
+	NewContextID         string `json:"new_contextid"`
+	RollbackNewAssetID   string `json:"rollback_new_assetid,omitempty"`
+	RollbackNewContextID string `json:"rollback_new_contextid,omitempty"`
+}
+
+// DescriptionKey returns the lookup key for this asset's description.
+func (a TradeHistoryAsset) DescriptionKey() string {
+	return AssetDescriptionKey(a.AppID, a.ClassID, a.InstanceID)
+}
+
+// RolledBack reports whether Steam reversed the trade, in full or in part.
+func (t *Trade) RolledBack() bool {
+	switch t.Status {
+	case ETradeStatusPartialSupportRollback,
+		ETradeStatusFullSupportRollback,
+		ETradeStatusSupportRollbackSelective,
+		ETradeStatusEscrowRollback:
+		return true
+	}
+	return false
+}
+
+// ReceivedAssetIDs maps the asset ID each received item had in the
+// partner's inventory to its asset ID in ours.
+func (t *Trade) ReceivedAssetIDs() map[string]string {
+	out := make(map[string]string, len(t.AssetsReceived))
+	for _, a := range t.AssetsReceived {
+		out[a.AssetID] = a.NewAssetID
+	}
+	return out
+}
+
+// GetTradeStatusResult contains a single trade with optional descriptions.
+type GetTradeStatusResult struct {
+	Trade        *Trade
+	Descriptions map[string]AssetDescription
+}
+
+// GetTradeHistoryOptions contains options for GetTradeHistory.
+//
+// History is returned newest first. To page through it, pass the time and
+// ID of the last trade of the previous page as StartAfterTime and
+// StartAfterTradeID; TradeHistoryResponse.NextPage does that.
+type GetTradeHistoryOptions struct {
+	MaxTrades         int // defaults to 100
+	StartAfterTime    int64
+	StartAfterTradeID string
+	NavigatingBack    bool
+	GetDescriptions   bool
+	Language          string
+	IncludeFailed     bool
+	IncludeTotal      bool
+}
+
+// TradeHistoryResponse contains the response from GetTradeHistory.
+// TotalTrades is only set when IncludeTotal was requested.
+type TradeHistoryResponse struct {
+	Trades       []Trade                     `json:"trades"`
+	More         bool                        `json:"more"`
+	TotalTrades  int                         `json:"total_trades"`
+	Descriptions map[string]AssetDescription `json:"-"`
+}
+
+// NextPage returns opts advanced past the last trade in r. ok is false
+// when there are no more trades.
+func (r *TradeHistoryResponse) NextPage(opts GetTradeHistoryOptions) (next GetTradeHistoryOptions, ok bool) {
+	if !r.More || len(r.Trades) == 0 {
+		return opts, false
+	}
+	last := r.Trades[len(r.Trades)-1]
+	opts.StartAfterTime = last.TimeInit
+	opts.StartAfterTradeID = last.TradeID
+	return opts, true
+}
+
+// GetTradeStatus retrieves the receipt of a trade by its trade ID, which
+// an accepted TradeOffer carries in TradeID.
+func (a *API) GetTradeStatus(ctx context.Context, tradeID string) (*GetTradeStatusResult, error) {
+	params, err := a.getAuthParams()
+	if err != nil {
+		return nil, err
+	}
+	params.Set("tradeid", tradeID)
+	params.Set("get_descriptions", "1")
+	params.Set("language", "en")
+
+	var result struct {
+		Response struct {
+			Trades       []Trade            `json:"trades"`
+			Descriptions []AssetDescription `json:"descriptions"`
+		} `json:"response"`
+	}
+	if err := a.getEcon(ctx, "/IEconService/GetTradeStatus/v1/", params, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Response.Trades) == 0 {
+		return nil, fmt.Errorf("trade not found")
+	}
+
+	return &GetTradeStatusResult{
+		Trade:        &result.Response.Trades[0],
+		Descriptions: descriptionMap(result.Response.Descriptions),
+	}, nil
+}
+
+// GetTradeHistory retrieves one page of the account's completed trades.
+func (a *API) GetTradeHistory(ctx context.Context, opts GetTradeHistoryOptions) (*TradeHistoryResponse, error) {
+	params, err := a.getAuthParams()
+	if err != nil {
+		return nil, err
+	}
+	maxTrades := opts.MaxTrades
+	if maxTrades <= 0 {
+		maxTrades = 100
+	}
+	params.Set("max_trades", strconv.Itoa(maxTrades))
+	if opts.StartAfterTime > 0 {
+		params.Set("start_after_time", strconv.FormatInt(opts.StartAfterTime, 10))
+	}
+	if opts.StartAfterTradeID != "" {
+		params.Set("start_after_tradeid", opts.StartAfterTradeID)
+	}
+	if opts.NavigatingBack {
+		params.Set("navigating_back", "1")
+	}
+	if opts.GetDescriptions {
+		params.Set("get_descriptions", "1")
+	}
+	if opts.Language != "" {
+		params.Set("language", opts.Language)
+	}
+	if opts.IncludeFailed {
+		params.Set("include_failed", "1")
+	}
+	if opts.IncludeTotal {
+		params.Set("include_total", "1")
+	}
+
+	var result struct {
+		Response struct {
+			TradeHistoryResponse
+			Descriptions []AssetDescription `json:"descriptions"`
+		} `json:"response"`
+	}
+	if err := a.getEcon(ctx, "/IEconService/GetTradeHistory/v1/", params, &result); err != nil {
+		return nil, err
+	}
+
+	out := &result.Response.TradeHistoryResponse
+	out.Descriptions = descriptionMap(result.Response.Descriptions)
+	return out, nil
+}
+
+// FillTradeDescriptions looks up, via GetAssetClassInfo, the descriptions
+// of items in trades that descs lacks, and adds them to it. Steam often
+// omits descriptions from trade receipts, e.g. for items that have since
+// been deleted. descs may be nil; the filled map is returned.
+func (a *API) FillTradeDescriptions(ctx context.Context, trades []Trade, descs map[string]AssetDescription) (map[string]AssetDescription, error) {
+	if descs == nil {
+		descs = make(map[string]AssetDescription)
+	}
+
+	missing := make(map[int][]AssetClassKey)
+	seen := make(map[string]bool)
+	for _, t := range trades {
+		for _, asset := range slices.Concat(t.AssetsReceived, t.AssetsGiven) {
+			key := asset.DescriptionKey()
+			if _, ok := descs[key]; ok || seen[key] {
+				continue
+			}
+			seen[key] = true
+			missing[asset.AppID] = append(missing[asset.AppID], AssetClassKey{
+				ClassID:    asset.ClassID,
+				InstanceID: asset.InstanceID,
+			})
+		}
+	}
+
+	for appID, keys := range missing {
+		found, err := a.GetAssetClassInfo(ctx, appID, keys)
+		if err != nil {
+			return descs, fmt.Errorf("app %d: %w", appID, err)
+		}
+		for k, d := range found {
+			descs[k] = d
+		}
+	}
+	return descs, nil
+}
+
+// getEcon makes a GET request to an IEconService method and decodes the
+// JSON body into out.
+func (a *API) getEcon(ctx context.Context, path string, params url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkEconResponse(resp); err != nil {
+		return err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// descriptionMap keys descriptions by AssetDescriptionKey. It returns nil
+// for an empty list.
+func descriptionMap(list []AssetDescription) map[string]AssetDescription {
+	if len(list) == 0 {
+		return nil
+	}
+	out := make(map[string]AssetDescription, len(list))
+	for _, d := range list {
+		out[AssetDescriptionKey(d.AppID, d.ClassID, d.InstanceID)] = d
+	}
+	return out
+}
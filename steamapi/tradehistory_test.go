@@ -0,0 +1,89 @@
+package steamapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetTradeHistoryPagination(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/IEconService/GetTradeHistory/v1/":
+			q := r.URL.Query()
+			queries = append(queries, q.Get("start_after_time")+"/"+q.Get("start_after_tradeid"))
+			if q.Get("start_after_tradeid") == "" {
+				w.Write([]byte(`{"response":{"more":true,"trades":[
+					{"tradeid":"30","steamid_other":"76561198000000002","time_init":300,"status":3,
+					 "assets_received":[{"appid":440,"contextid":"2","assetid":"1","amount":"1","classid":"c1","instanceid":"0","new_assetid":"11","new_contextid":"2"}]},
+					{"tradeid":"20","steamid_other":"76561198000000002","time_init":200,"status":6}
+				]}}`))
+				return
+			}
+			w.Write([]byte(`{"response":{"more":false,"trades":[
+				{"tradeid":"10","steamid_other":"76561198000000003","time_init":100,"status":3,
+				 "assets_given":[{"appid":730,"contextid":"2","assetid":"5","amount":"1","classid":"c2","instanceid":"7","new_assetid":"55","new_contextid":"2"}]}
+			]}}`))
+		case "/ISteamEconomy/GetAssetClassInfo/v1/":
+			q := r.URL.Query()
+			if q.Get("appid") == "440" {
+				w.Write([]byte(`{"result":{"c1":{"classid":"c1","name":"Hat"},"success":true}}`))
+			} else {
+				w.Write([]byte(`{"result":{"c2_7":{"classid":"c2","instanceid":"7","name":"Rifle"},"success":true}}`))
+			}
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	api, err := New(WithBaseURL(srv.URL), WithAPIKey("TESTKEY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var trades []Trade
+	opts := GetTradeHistoryOptions{MaxTrades: 2}
+	for {
+		page, err := api.GetTradeHistory(ctx, opts)
+		if err != nil {
+			t.Fatalf("GetTradeHistory: %v", err)
+		}
+		trades = append(trades, page.Trades...)
+		next, ok := page.NextPage(opts)
+		if !ok {
+			break
+		}
+		opts = next
+	}
+
+	if len(queries) != 2 || queries[0] != "/" || queries[1] != "200/20" {
+		t.Errorf("pages requested after = %q; want [/ 200/20]", queries)
+	}
+	if len(trades) != 3 {
+		t.Fatalf("len(trades) = %d; want 3", len(trades))
+	}
+	if got := trades[0].ReceivedAssetIDs()["1"]; got != "11" {
+		t.Errorf("new asset ID of 1 = %q; want 11", got)
+	}
+	if trades[0].SteamIDOther != 76561198000000002 {
+		t.Errorf("SteamIDOther = %d", trades[0].SteamIDOther)
+	}
+	if trades[0].RolledBack() || !trades[1].RolledBack() {
+		t.Error("RolledBack: want only the second trade rolled back")
+	}
+
+	descs, err := api.FillTradeDescriptions(ctx, trades, nil)
+	if err != nil {
+		t.Fatalf("FillTradeDescriptions: %v", err)
+	}
+	if got := descs[trades[0].AssetsReceived[0].DescriptionKey()].Name; got != "Hat" {
+		t.Errorf("received item name = %q; want Hat", got)
+	}
+	if got := descs[trades[2].AssetsGiven[0].DescriptionKey()].Name; got != "Rifle" {
+		t.Errorf("given item name = %q; want Rifle", got)
+	}
+}
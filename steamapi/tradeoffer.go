@@ -10,8 +10,6 @@ import (
 	"strconv"
 )
 
-const econServiceURL = "https://api.steampowered.com/IEconService"
-
 // GetTradeOffer retrieves a single trade offer by ID
 func (a *API) GetTradeOffer(ctx context.Context, offerID string) (*TradeOffer, error) {
 	token, err := a.getAccessToken()
@@ -23,7 +21,7 @@ func (a *API) GetTradeOffer(ctx context.Context, offerID string) (*TradeOffer, e
 	params.Set("tradeofferid", offerID)
 	params.Set("language", "en")
 
-	reqURL := econServiceURL + "/GetTradeOffer/v1/?" + params.Encode()
+	reqURL := a.baseURL + "/IEconService/GetTradeOffer/v1/?" + params.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
@@ -91,7 +89,7 @@ func (a *API) GetTradeOffers(ctx context.Context, opts GetTradeOffersOptions) (*
 		params.Set("cursor", strconv.FormatUint(uint64(opts.Cursor), 10))
 	}
 
-	reqURL := econServiceURL + "/GetTradeOffers/v1/?" + params.Encode()
+	reqURL := a.baseURL + "/IEconService/GetTradeOffers/v1/?" + params.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
@@ -143,7 +141,7 @@ func (a *API) GetTradeOfferWithDescriptions(ctx context.Context, offerID string)
 	params.Set("language", "en")
 	params.Set("get_descriptions", "1")
 
-	reqURL := econServiceURL + "/GetTradeOffer/v1/?" + params.Encode()
+	reqURL := a.baseURL + "/IEconService/GetTradeOffer/v1/?" + params.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
@@ -43,6 +43,7 @@ type TradeOffer struct {
 	FromRealTimeTrade  bool                `json:"from_real_time_trade"`
 	EscrowEndDate      int64               `json:"escrow_end_date"`
 	ConfirmationMethod EConfirmationMethod `json:"confirmation_method"`
+	TradeID            string              `json:"tradeid"` // set once accepted; see GetTradeStatus
 }
 
 // TradeAsset represents an item in a trade (CEcon_Asset)
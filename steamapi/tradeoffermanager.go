@@ -0,0 +1,368 @@
+package steamapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+type TradeOfferEventType int
+
+const (
+	// NewOffer: a received offer became active.
+	NewOffer TradeOfferEventType = iota + 1
+	// SentOfferChanged: a known sent offer changed state.
+	SentOfferChanged
+	// ReceivedOfferChanged: a known received offer changed state.
+	ReceivedOfferChanged
+	// OfferExpired: an offer, sent or received, expired. Follows the
+	// corresponding *OfferChanged event.
+	OfferExpired
+	// ItemsEscrowed: an accepted offer went into trade hold. Follows the
+	// corresponding *OfferChanged event.
+	ItemsEscrowed
+	// PollFailed: a poll failed and will be retried on the next tick.
+	PollFailed
+)
+
+func (t TradeOfferEventType) String() string {
+	switch t {
+	case NewOffer:
+		return "NewOffer"
+	case SentOfferChanged:
+		return "SentOfferChanged"
+	case ReceivedOfferChanged:
+		return "ReceivedOfferChanged"
+	case OfferExpired:
+		return "OfferExpired"
+	case ItemsEscrowed:
+		return "ItemsEscrowed"
+	case PollFailed:
+		return "PollFailed"
+	}
+	return fmt.Sprintf("TradeOfferEventType(%d)", int(t))
+}
+
+// TradeOfferEvent reports a change seen by the TradeOfferManager.
+type TradeOfferEvent struct {
+	Type     TradeOfferEventType
+	Offer    *TradeOffer      // nil for PollFailed
+	OldState ETradeOfferState // previous state for *OfferChanged, OfferExpired and ItemsEscrowed
+	Err      error            // set for PollFailed
+
+	// Descriptions holds the descriptions of the poll the event came from,
+	// with WithOfferDescriptions.
+	Descriptions map[string]AssetDescription
+}
+
+// TradeOfferPollData is the state the TradeOfferManager diffs each poll
+// against. Persist it (see WithPollDataHandler) and hand it back through
+// WithPollData so a restarted manager neither misses changes nor reports
+// old offers as new.
+type TradeOfferPollData struct {
+	Sent     map[string]ETradeOfferState `json:"sent"`
+	Received map[string]ETradeOfferState `json:"received"`
+	Updated  map[string]int64            `json:"updated"`   // offer ID -> time_updated, for pruning
+	LastPoll int64                       `json:"last_poll"` // unix time the last successful poll started
+}
+
+// pollCutoffOverlap is subtracted from the last poll time to form
+// time_historical_cutoff, covering clock skew between us and Steam.
+const pollCutoffOverlap = 5 * time.Minute
+
+// pollDataRetention is how long finished offers are remembered. Steam stops
+// returning them through time_historical_cutoff long before this.
+const pollDataRetention = 30 * 24 * time.Hour
+
+// TradeOfferManager polls IEconService/GetTradeOffers and reports offers
+// that appear or change state.
+type TradeOfferManager struct {
+	api *API
+
+	pollInterval   time.Duration
+	onEvent        func(TradeOfferEvent)
+	onPollData     func(TradeOfferPollData)
+	getDescription bool
+
+	pollNow chan struct{}
+	pollMu  sync.Mutex // serializes polls
+
+	mu   sync.Mutex
+	data TradeOfferPollData
+}
+
+type tradeOfferManagerConfig struct {
+	pollInterval   time.Duration
+	onEvent        func(TradeOfferEvent)
+	onPollData     func(TradeOfferPollData)
+	pollData       *TradeOfferPollData
+	getDescription bool
+}
+
+type TradeOfferManagerOption func(*tradeOfferManagerConfig)
+
+// WithPollInterval sets the time between polls. Default 30 seconds.
+func WithPollInterval(d time.Duration) TradeOfferManagerOption {
+	return func(c *tradeOfferManagerConfig) {
+		c.pollInterval = d
+	}
+}
+
+// WithTradeOfferEventHandler registers a callback for offer events. It runs
+// on the polling goroutine.
+func WithTradeOfferEventHandler(fn func(TradeOfferEvent)) TradeOfferManagerOption {
+	return func(c *tradeOfferManagerConfig) {
+		c.onEvent = fn
+	}
+}
+
+// WithPollData seeds the manager with previously persisted poll data.
+func WithPollData(data TradeOfferPollData) TradeOfferManagerOption {
+	return func(c *tradeOfferManagerConfig) {
+		c.pollData = &data
+	}
+}
+
+// WithPollDataHandler registers a callback that receives the poll data
+// after every successful poll, for persisting it.
+func WithPollDataHandler(fn func(TradeOfferPollData)) TradeOfferManagerOption {
+	return func(c *tradeOfferManagerConfig) {
+		c.onPollData = fn
+	}
+}
+
+// WithOfferDescriptions requests item descriptions with each poll and
+// attaches them to the events.
+func WithOfferDescriptions() TradeOfferManagerOption {
+	return func(c *tradeOfferManagerConfig) {
+		c.getDescription = true
+	}
+}
+
+// NewTradeOfferManager creates a manager polling through api.
+func NewTradeOfferManager(api *API, opts ...TradeOfferManagerOption) (*TradeOfferManager, error) {
+	if api == nil {
+		return nil, errors.New("api should be non-nil")
+	}
+
+	cfg := tradeOfferManagerConfig{
+		pollInterval: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.pollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+
+	m := &TradeOfferManager{
+		api:            api,
+		pollInterval:   cfg.pollInterval,
+		onEvent:        cfg.onEvent,
+		onPollData:     cfg.onPollData,
+		getDescription: cfg.getDescription,
+		pollNow:        make(chan struct{}, 1),
+	}
+	if cfg.pollData != nil {
+		m.data = clonePollData(*cfg.pollData)
+	}
+	if m.data.Sent == nil {
+		m.data.Sent = make(map[string]ETradeOfferState)
+	}
+	if m.data.Received == nil {
+		m.data.Received = make(map[string]ETradeOfferState)
+	}
+	if m.data.Updated == nil {
+		m.data.Updated = make(map[string]int64)
+	}
+	return m, nil
+}
+
+// PollData returns a copy of the current poll data.
+func (m *TradeOfferManager) PollData() TradeOfferPollData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return clonePollData(m.data)
+}
+
+// PollNow makes Run poll immediately instead of waiting for the next tick.
+// It never blocks. Wire it to the CM trade notification so new offers are
+// seen as soon as Steam announces them:
+//
+//	steamclient.WithTradeNotificationHandler(func(*steamclient.TradeNotification) {
+//		manager.PollNow()
+//	})
+func (m *TradeOfferManager) PollNow() {
+	select {
+	case m.pollNow <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls until ctx is done and returns ctx.Err(). Failed polls are
+// reported as PollFailed events and retried on the next tick.
+func (m *TradeOfferManager) Run(ctx context.Context) error {
+	for {
+		if err := m.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			m.emit(TradeOfferEvent{Type: PollFailed, Err: err})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.pollNow:
+		case <-time.After(m.pollInterval):
+		}
+	}
+}
+
+// Poll fetches the offers changed since the last poll and emits events for
+// them. The first poll without poll data only looks at active offers, and
+// reports active received offers as NewOffer.
+func (m *TradeOfferManager) Poll(ctx context.Context) error {
+	m.pollMu.Lock()
+	defer m.pollMu.Unlock()
+
+	start := time.Now()
+	m.mu.Lock()
+	lastPoll := m.data.LastPoll
+	m.mu.Unlock()
+
+	cutoff := start.Unix()
+	if lastPoll > 0 {
+		cutoff = time.Unix(lastPoll, 0).Add(-pollCutoffOverlap).Unix()
+	}
+
+	var sent, received []TradeOffer
+	var descs map[string]AssetDescription
+	opts := GetTradeOffersOptions{
+		GetSentOffers:        true,
+		GetReceivedOffers:    true,
+		GetDescriptions:      m.getDescription,
+		ActiveOnly:           true,
+		TimeHistoricalCutoff: cutoff,
+	}
+	for {
+		resp, err := m.api.GetTradeOffers(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("get trade offers: %w", err)
+		}
+		sent = append(sent, resp.SentOffers...)
+		received = append(received, resp.ReceivedOffers...)
+		if resp.Descriptions != nil {
+			if descs == nil {
+				descs = make(map[string]AssetDescription, len(resp.Descriptions))
+			}
+			maps.Copy(descs, resp.Descriptions)
+		}
+		if resp.NextCursor == 0 {
+			break
+		}
+		opts.Cursor = resp.NextCursor
+	}
+
+	var events []TradeOfferEvent
+	m.mu.Lock()
+	for i := range sent {
+		events = m.diffLocked(events, &sent[i], m.data.Sent, SentOfferChanged)
+	}
+	for i := range received {
+		events = m.diffLocked(events, &received[i], m.data.Received, ReceivedOfferChanged)
+	}
+	m.data.LastPoll = start.Unix()
+	m.pruneLocked(start)
+	data := clonePollData(m.data)
+	m.mu.Unlock()
+
+	for _, evt := range events {
+		evt.Descriptions = descs
+		m.emit(evt)
+	}
+	if m.onPollData != nil {
+		m.onPollData(data)
+	}
+	return nil
+}
+
+// diffLocked records offer in known and appends the events its state change
+// warrants.
+func (m *TradeOfferManager) diffLocked(events []TradeOfferEvent, offer *TradeOffer, known map[string]ETradeOfferState, changed TradeOfferEventType) []TradeOfferEvent {
+	old, seen := known[offer.ID]
+	known[offer.ID] = offer.State
+	m.data.Updated[offer.ID] = offer.TimeUpdated
+
+	if !seen {
+		// Sent offers were made by us; only received ones are news.
+		if changed == ReceivedOfferChanged && offer.State == ETradeOfferStateActive {
+			events = append(events, TradeOfferEvent{Type: NewOffer, Offer: offer})
+		}
+		return events
+	}
+	if old == offer.State {
+		return events
+	}
+
+	if changed == ReceivedOfferChanged && old == ETradeOfferStateCreatedNeedsConfirmation && offer.State == ETradeOfferStateActive {
+		// Confirmed by the sender after we first saw it.
+		events = append(events, TradeOfferEvent{Type: NewOffer, Offer: offer, OldState: old})
+	}
+	events = append(events, TradeOfferEvent{Type: changed, Offer: offer, OldState: old})
+	switch offer.State {
+	case ETradeOfferStateExpired:
+		events = append(events, TradeOfferEvent{Type: OfferExpired, Offer: offer, OldState: old})
+	case ETradeOfferStateInEscrow:
+		events = append(events, TradeOfferEvent{Type: ItemsEscrowed, Offer: offer, OldState: old})
+	}
+	return events
+}
+
+// pruneLocked forgets finished offers not updated within pollDataRetention.
+func (m *TradeOfferManager) pruneLocked(now time.Time) {
+	horizon := now.Add(-pollDataRetention).Unix()
+	for id, updated := range m.data.Updated {
+		if updated >= horizon {
+			continue
+		}
+		if state, ok := m.data.Sent[id]; ok && isActiveOfferState(state) {
+			continue
+		}
+		if state, ok := m.data.Received[id]; ok && isActiveOfferState(state) {
+			continue
+		}
+		delete(m.data.Sent, id)
+		delete(m.data.Received, id)
+		delete(m.data.Updated, id)
+	}
+}
+
+func (m *TradeOfferManager) emit(evt TradeOfferEvent) {
+	if m.onEvent != nil {
+		m.onEvent(evt)
+	}
+}
+
+// isActiveOfferState reports whether an offer in state can still change.
+func isActiveOfferState(state ETradeOfferState) bool {
+	switch state {
+	case ETradeOfferStateActive,
+		ETradeOfferStateCreatedNeedsConfirmation,
+		ETradeOfferStateInEscrow:
+		return true
+	}
+	return false
+}
+
+func clonePollData(d TradeOfferPollData) TradeOfferPollData {
+	return TradeOfferPollData{
+		Sent:     maps.Clone(d.Sent),
+		Received: maps.Clone(d.Received),
+		Updated:  maps.Clone(d.Updated),
+		LastPoll: d.LastPoll,
+	}
+}
@@ -0,0 +1,174 @@
+package steamapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOffers serves GetTradeOffers from a mutable set of offers and records
+// the cutoffs it was asked for.
+type fakeOffers struct {
+	mu       sync.Mutex
+	sent     []TradeOffer
+	received []TradeOffer
+	cutoffs  []int64
+	polled   chan struct{}
+}
+
+func (f *fakeOffers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	cutoff, _ := strconv.ParseInt(r.URL.Query().Get("time_historical_cutoff"), 10, 64)
+	f.cutoffs = append(f.cutoffs, cutoff)
+	resp := map[string]any{"response": map[string]any{
+		"trade_offers_sent":     f.sent,
+		"trade_offers_received": f.received,
+	}}
+	f.mu.Unlock()
+
+	json.NewEncoder(w).Encode(resp)
+	if f.polled != nil {
+		select {
+		case f.polled <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func newTestManager(t *testing.T, f *fakeOffers, opts ...TradeOfferManagerOption) (*TradeOfferManager, *[]TradeOfferEvent) {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	api, err := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.accessToken = "token"
+
+	var events []TradeOfferEvent
+	opts = append(opts, WithTradeOfferEventHandler(func(evt TradeOfferEvent) {
+		events = append(events, evt)
+	}))
+	m, err := NewTradeOfferManager(api, opts...)
+	if err != nil {
+		t.Fatalf("NewTradeOfferManager: %v", err)
+	}
+	return m, &events
+}
+
+func eventTypes(events []TradeOfferEvent) []TradeOfferEventType {
+	out := make([]TradeOfferEventType, len(events))
+	for i, e := range events {
+		out[i] = e.Type
+	}
+	return out
+}
+
+func TestTradeOfferManagerPoll(t *testing.T) {
+	f := &fakeOffers{
+		sent:     []TradeOffer{{ID: "1", State: ETradeOfferStateActive}},
+		received: []TradeOffer{{ID: "2", State: ETradeOfferStateActive}},
+	}
+	var saved TradeOfferPollData
+	m, events := newTestManager(t, f, WithPollDataHandler(func(d TradeOfferPollData) { saved = d }))
+	ctx := context.Background()
+
+	if err := m.Poll(ctx); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if got := eventTypes(*events); len(got) != 1 || got[0] != NewOffer || (*events)[0].Offer.ID != "2" {
+		t.Fatalf("first poll events = %v, want NewOffer for 2", got)
+	}
+	if saved.LastPoll == 0 || saved.Sent["1"] != ETradeOfferStateActive {
+		t.Errorf("saved poll data = %+v", saved)
+	}
+
+	// Nothing changed: no events.
+	*events = nil
+	if err := m.Poll(ctx); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if len(*events) != 0 {
+		t.Fatalf("unchanged poll events = %v, want none", eventTypes(*events))
+	}
+	if want := saved.LastPoll - int64(pollCutoffOverlap/time.Second); f.cutoffs[1] != want {
+		t.Errorf("cutoff = %d, want %d", f.cutoffs[1], want)
+	}
+
+	f.mu.Lock()
+	f.sent[0].State = ETradeOfferStateExpired
+	f.received[0].State = ETradeOfferStateInEscrow
+	f.mu.Unlock()
+
+	*events = nil
+	if err := m.Poll(ctx); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	got := eventTypes(*events)
+	want := []TradeOfferEventType{SentOfferChanged, OfferExpired, ReceivedOfferChanged, ItemsEscrowed}
+	if len(got) != len(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events = %v, want %v", got, want)
+		}
+	}
+	if (*events)[0].OldState != ETradeOfferStateActive {
+		t.Errorf("OldState = %v, want Active", (*events)[0].OldState)
+	}
+}
+
+func TestTradeOfferManagerRestoresPollData(t *testing.T) {
+	f := &fakeOffers{
+		received: []TradeOffer{{ID: "2", State: ETradeOfferStateAccepted}},
+	}
+	lastPoll := time.Now().Add(-time.Hour).Unix()
+	m, events := newTestManager(t, f, WithPollData(TradeOfferPollData{
+		Received: map[string]ETradeOfferState{"2": ETradeOfferStateActive},
+		LastPoll: lastPoll,
+	}))
+
+	if err := m.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if got := eventTypes(*events); len(got) != 1 || got[0] != ReceivedOfferChanged {
+		t.Fatalf("events = %v, want ReceivedOfferChanged", got)
+	}
+	if want := lastPoll - int64(pollCutoffOverlap/time.Second); f.cutoffs[0] != want {
+		t.Errorf("cutoff = %d, want %d", f.cutoffs[0], want)
+	}
+}
+
+func TestTradeOfferManagerPollNow(t *testing.T) {
+	f := &fakeOffers{polled: make(chan struct{}, 1)}
+	m, _ := newTestManager(t, f, WithPollInterval(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+
+	wait := func() {
+		t.Helper()
+		select {
+		case <-f.polled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no poll")
+		}
+	}
+	wait() // initial poll
+	m.PollNow()
+	wait()
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run = %v, want context.Canceled", err)
+	}
+}
@@ -2,9 +2,15 @@ package steamclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/k64z/steamstacks/eresult"
 	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamid"
+	"github.com/k64z/steamstacks/steamsession"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -12,27 +18,265 @@ import (
 // rotated refresh token) via the CM service method protocol. Unlike the Web API
 // variant, this works for SteamClient platform tokens.
 func (c *Client) GenerateAccessTokenForApp(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, err error) {
+	return c.generateAccessToken(ctx, refreshToken, false)
+}
+
+// RenewRefreshToken is GenerateAccessTokenForApp with renewal allowed: Steam
+// may rotate the refresh token, returning the new one as newRefreshToken
+// (empty when it declines). If refreshToken is the one the client logged in
+// with, the new one is used for future reconnects.
+//
+// Client satisfies steamsession.TokenGenerator, so a steamsession.TokenManager
+// can keep a CM login's refresh token alive.
+func (c *Client) RenewRefreshToken(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, err error) {
+	return c.generateAccessToken(ctx, refreshToken, true)
+}
+
+func (c *Client) generateAccessToken(ctx context.Context, refreshToken string, renew bool) (accessToken, newRefreshToken string, err error) {
 	c.mu.Lock()
 	sid := c.steamID.ToSteamID64()
 	c.mu.Unlock()
 
-	body, err := proto.Marshal(&protocol.CAuthentication_AccessToken_GenerateForApp_Request{
+	req := &protocol.CAuthentication_AccessToken_GenerateForApp_Request{
 		RefreshToken: proto.String(refreshToken),
 		Steamid:      proto.Uint64(sid),
-	})
-	if err != nil {
-		return "", "", fmt.Errorf("marshal GenerateAccessTokenForApp request: %w", err)
+	}
+	if renew {
+		req.RenewalType = protocol.ETokenRenewalType_k_ETokenRenewalType_Allow.Enum()
 	}
 
-	pkt, err := c.callServiceMethod(ctx, "Authentication.GenerateAccessTokenForApp#1", body)
+	resp, err := Call[*protocol.CAuthentication_AccessToken_GenerateForApp_Request, *protocol.CAuthentication_AccessToken_GenerateForApp_Response](
+		ctx, c, "Authentication.GenerateAccessTokenForApp#1", req)
 	if err != nil {
+		if result, ok := EResultOf(err); ok && (result == eresult.Revoked || result == eresult.Expired) {
+			return "", "", fmt.Errorf("%w: %w", steamsession.ErrTokenRevoked, err)
+		}
 		return "", "", err
 	}
 
-	var resp protocol.CAuthentication_AccessToken_GenerateForApp_Response
-	if err := proto.Unmarshal(pkt.Body, &resp); err != nil {
-		return "", "", fmt.Errorf("unmarshal GenerateAccessTokenForApp response: %w", err)
+	if newRefreshToken = resp.GetRefreshToken(); newRefreshToken != "" {
+		c.mu.Lock()
+		if c.refreshToken == refreshToken {
+			c.refreshToken = newRefreshToken
+		}
+		c.mu.Unlock()
 	}
 
-	return resp.GetAccessToken(), resp.GetRefreshToken(), nil
+	return resp.GetAccessToken(), newRefreshToken, nil
+}
+
+// AuthSession is a pending credentials login started with
+// BeginAuthSessionViaCredentials. Once the required Steam Guard step is
+// satisfied, Poll yields the tokens.
+type AuthSession struct {
+	c *Client
+
+	ClientID  uint64
+	RequestID []byte
+	SteamID   steamid.SteamID
+
+	// AllowedConfirmations lists the Steam Guard methods that can approve
+	// this session, in Steam's order of preference.
+	AllowedConfirmations []steamsession.EAuthSessionGuardType
+
+	// PollInterval is the delay Steam asks for between status polls.
+	PollInterval time.Duration
+}
+
+// AuthTokens are the tokens issued for an approved auth session.
+type AuthTokens struct {
+	AccountName  string
+	AccessToken  string
+	RefreshToken string
+}
+
+// SteamGuardFunc supplies a Steam Guard code for a credentials login. It is
+// called with the allowed code types (EmailCode and/or DeviceCode) and
+// returns the code along with the type it belongs to.
+type SteamGuardFunc func(ctx context.Context, allowed []steamsession.EAuthSessionGuardType) (code string, guardType steamsession.EAuthSessionGuardType, err error)
+
+// BeginAuthSessionViaCredentials starts a SteamClient-platform auth session
+// over the open CM connection. It must be called after Connect and before
+// Login; the password is encrypted with the account's RSA key as fetched
+// from the CM.
+func (c *Client) BeginAuthSessionViaCredentials(ctx context.Context, accountName, password string) (*AuthSession, error) {
+	if accountName == "" {
+		return nil, errors.New("account name cannot be empty")
+	}
+	if password == "" {
+		return nil, errors.New("password cannot be empty")
+	}
+
+	rsaKey, err := callNonAuthed[*protocol.CAuthentication_GetPasswordRSAPublicKey_Request, *protocol.CAuthentication_GetPasswordRSAPublicKey_Response](
+		ctx, c, "Authentication.GetPasswordRSAPublicKey#1",
+		&protocol.CAuthentication_GetPasswordRSAPublicKey_Request{
+			AccountName: proto.String(accountName),
+		})
+	if err != nil {
+		return nil, fmt.Errorf("get RSA public key: %w", err)
+	}
+
+	exp, err := strconv.ParseInt(rsaKey.GetPublickeyExp(), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("parse RSA exponent: %w", err)
+	}
+	encryptedPassword, err := steamsession.EncryptPassword(password, rsaKey.GetPublickeyMod(), exp)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt password: %w", err)
+	}
+
+	platformType := protocol.EAuthTokenPlatformType_k_EAuthTokenPlatformType_SteamClient
+	persistence := protocol.ESessionPersistence_k_ESessionPersistence_Persistent
+	resp, err := callNonAuthed[*protocol.CAuthentication_BeginAuthSessionViaCredentials_Request, *protocol.CAuthentication_BeginAuthSessionViaCredentials_Response](
+		ctx, c, "Authentication.BeginAuthSessionViaCredentials#1",
+		&protocol.CAuthentication_BeginAuthSessionViaCredentials_Request{
+			AccountName:         proto.String(accountName),
+			EncryptedPassword:   proto.String(encryptedPassword),
+			EncryptionTimestamp: proto.Uint64(rsaKey.GetTimestamp()),
+			RememberLogin:       proto.Bool(true),
+			Persistence:         &persistence,
+			WebsiteId:           proto.String(steamsession.WebsiteIDClient),
+			DeviceDetails: &protocol.CAuthentication_DeviceDetails{
+				DeviceFriendlyName: proto.String(steamsession.SteamClientUA),
+				PlatformType:       &platformType,
+				OsType:             proto.Int32(20), // EOSType Windows 11, as in Login
+			},
+		})
+	if err != nil {
+		return nil, fmt.Errorf("begin auth session: %w", err)
+	}
+
+	// EAuthSessionGuardType values mirror the proto enum, so a direct cast works.
+	guardTypes := make([]steamsession.EAuthSessionGuardType, len(resp.AllowedConfirmations))
+	for i, conf := range resp.AllowedConfirmations {
+		guardTypes[i] = steamsession.EAuthSessionGuardType(conf.GetConfirmationType())
+	}
+
+	interval := time.Duration(resp.GetInterval() * float32(time.Second))
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	return &AuthSession{
+		c:                    c,
+		ClientID:             resp.GetClientId(),
+		RequestID:            resp.GetRequestId(),
+		SteamID:              steamid.FromSteamID64(resp.GetSteamid()),
+		AllowedConfirmations: guardTypes,
+		PollInterval:         interval,
+	}, nil
+}
+
+// SubmitSteamGuardCode approves the session with an email or device code.
+// If it returns no error, Poll can be started.
+func (a *AuthSession) SubmitSteamGuardCode(ctx context.Context, code string, guardType steamsession.EAuthSessionGuardType) error {
+	codeType := protocol.EAuthSessionGuardType(guardType)
+	_, err := callNonAuthed[*protocol.CAuthentication_UpdateAuthSessionWithSteamGuardCode_Request, *protocol.CAuthentication_UpdateAuthSessionWithSteamGuardCode_Response](
+		ctx, a.c, "Authentication.UpdateAuthSessionWithSteamGuardCode#1",
+		&protocol.CAuthentication_UpdateAuthSessionWithSteamGuardCode_Request{
+			ClientId: proto.Uint64(a.ClientID),
+			Steamid:  proto.Uint64(a.SteamID.ToSteamID64()),
+			Code:     proto.String(code),
+			CodeType: &codeType,
+		})
+	return err
+}
+
+// Poll polls the session status every PollInterval until Steam issues the
+// tokens, the session fails, or ctx is done.
+func (a *AuthSession) Poll(ctx context.Context) (*AuthTokens, error) {
+	for {
+		resp, err := callNonAuthed[*protocol.CAuthentication_PollAuthSessionStatus_Request, *protocol.CAuthentication_PollAuthSessionStatus_Response](
+			ctx, a.c, "Authentication.PollAuthSessionStatus#1",
+			&protocol.CAuthentication_PollAuthSessionStatus_Request{
+				ClientId:  proto.Uint64(a.ClientID),
+				RequestId: a.RequestID,
+			})
+		if err != nil {
+			return nil, fmt.Errorf("poll auth session status: %w", err)
+		}
+
+		if resp.NewClientId != nil {
+			a.ClientID = resp.GetNewClientId()
+		}
+		if resp.GetRefreshToken() != "" {
+			return &AuthTokens{
+				AccountName:  resp.GetAccountName(),
+				AccessToken:  resp.GetAccessToken(),
+				RefreshToken: resp.GetRefreshToken(),
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(a.PollInterval):
+		}
+	}
+}
+
+// LoginWithCredentials authenticates with an account name and password over
+// the open CM connection, then logs on with the issued refresh token, so a
+// single connection is enough to go from credentials to a logged-on client.
+//
+// When the session needs an email or device code, guard is asked for one.
+// Sessions that only allow a confirmation (mobile app or email link) are
+// polled until the user approves them. The returned refresh token can be
+// saved and passed to Login on later connections.
+func (c *Client) LoginWithCredentials(ctx context.Context, accountName, password string, guard SteamGuardFunc) (refreshToken string, err error) {
+	session, err := c.BeginAuthSessionViaCredentials(ctx, accountName, password)
+	if err != nil {
+		return "", err
+	}
+
+	if err := session.satisfyGuard(ctx, guard); err != nil {
+		return "", err
+	}
+
+	tokens, err := session.Poll(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if tokens.AccountName != "" {
+		accountName = tokens.AccountName
+	}
+	if err := c.Login(ctx, accountName, tokens.RefreshToken, session.SteamID); err != nil {
+		return "", err
+	}
+
+	return tokens.RefreshToken, nil
+}
+
+// satisfyGuard performs whatever Steam Guard step the session requires
+// before it can be polled.
+func (a *AuthSession) satisfyGuard(ctx context.Context, guard SteamGuardFunc) error {
+	var codeTypes []steamsession.EAuthSessionGuardType
+	canConfirm := false
+	for _, t := range a.AllowedConfirmations {
+		switch t {
+		case steamsession.EAuthSessionGuardTypeNone:
+			return nil
+		case steamsession.EAuthSessionGuardTypeEmailCode, steamsession.EAuthSessionGuardTypeDeviceCode:
+			codeTypes = append(codeTypes, t)
+		case steamsession.EAuthSessionGuardTypeDeviceConfirmation, steamsession.EAuthSessionGuardTypeEmailConfirmation:
+			canConfirm = true
+		}
+	}
+
+	if len(codeTypes) > 0 && guard != nil {
+		code, guardType, err := guard(ctx, codeTypes)
+		if err != nil {
+			return fmt.Errorf("steam guard: %w", err)
+		}
+		if err := a.SubmitSteamGuardCode(ctx, code, guardType); err != nil {
+			return fmt.Errorf("submit steam guard code: %w", err)
+		}
+		return nil
+	}
+
+	if canConfirm {
+		return nil
+	}
+	return errors.New("steam guard code required but no SteamGuardFunc given")
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/k64z/steamstacks/eresult"
 	"github.com/k64z/steamstacks/protocol"
 	"google.golang.org/protobuf/proto"
 )
@@ -119,7 +120,7 @@ func TestCallServiceMethod(t *testing.T) {
 	sentData := <-mc.writeCh
 
 	// Decode and verify the sent packet.
-	sentPkt, err := decodePacket(sentData)
+	sentPkt, err := DecodePacket(sentData)
 	if err != nil {
 		t.Fatalf("decode sent packet: %v", err)
 	}
@@ -170,7 +171,7 @@ func TestCallServiceMethodEresultError(t *testing.T) {
 	}()
 
 	sentData := <-mc.writeCh
-	sentPkt, err := decodePacket(sentData)
+	sentPkt, err := DecodePacket(sentData)
 	if err != nil {
 		t.Fatalf("decode sent packet: %v", err)
 	}
@@ -193,3 +194,38 @@ func TestCallServiceMethodEresultError(t *testing.T) {
 		t.Fatal("callServiceMethod did not return within 2s")
 	}
 }
+
+func TestRenewRefreshTokenRotatesReconnectToken(t *testing.T) {
+	mc := &mockConn{writeCh: make(chan []byte, 1)}
+	c := New()
+	c.conn = mc
+	c.done = make(chan struct{})
+	c.refreshToken = "old"
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := c.RenewRefreshToken(context.Background(), "old")
+		done <- err
+	}()
+
+	sent := respondToNextCall(t, c, mc, eresult.OK, "", &protocol.CAuthentication_AccessToken_GenerateForApp_Response{
+		AccessToken:  proto.String("access"),
+		RefreshToken: proto.String("new"),
+	})
+
+	var req protocol.CAuthentication_AccessToken_GenerateForApp_Request
+	proto.Unmarshal(sent.Body, &req)
+	if req.GetRenewalType() != protocol.ETokenRenewalType_k_ETokenRenewalType_Allow {
+		t.Errorf("RenewalType = %v, want Allow", req.GetRenewalType())
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("RenewRefreshToken: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.refreshToken != "new" {
+		t.Errorf("reconnect token = %q, want %q", c.refreshToken, "new")
+	}
+}
@@ -0,0 +1,520 @@
+package cmtest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k64z/steamstacks/eresult"
+	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamclient"
+	"github.com/k64z/steamstacks/steamsession"
+	"google.golang.org/protobuf/proto"
+)
+
+const testSteamID = 76561197960287930
+
+// connect logs a new client in to srv over the given transport.
+func connect(t *testing.T, srv *Server, transport steamclient.TransportType, opts ...steamclient.Option) (*steamclient.Client, *Session) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := steamclient.New(append(srv.ClientOptions(transport), opts...)...)
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+
+	sess, err := srv.NextSession(ctx)
+	if err != nil {
+		t.Fatalf("NextSession: %v", err)
+	}
+
+	if err := c.Login(ctx, "user", "refresh-token", testSteamID); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return c, sess
+}
+
+func TestLogonOverBothTransports(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		transport steamclient.TransportType
+	}{
+		{"websocket", steamclient.TransportWebSocket},
+		{"tcp", steamclient.TransportTCP},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer()
+			defer srv.Close()
+
+			c, sess := connect(t, srv, tt.transport)
+
+			if c.SteamID().ToSteamID64() != testSteamID {
+				t.Errorf("SteamID = %d, want %d", c.SteamID().ToSteamID64(), testSteamID)
+			}
+			if !sess.LoggedOn() {
+				t.Error("session should be logged on")
+			}
+			if got := sess.Logon().GetAccessToken(); got != "refresh-token" {
+				t.Errorf("AccessToken = %q, want %q", got, "refresh-token")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if _, err := sess.Expect(ctx, steamclient.EMsgClientHello); err != nil {
+				t.Errorf("ClientHello: %v", err)
+			}
+		})
+	}
+}
+
+func TestLogonHandlerRejects(t *testing.T) {
+	srv := NewServer(WithLogonHandler(func(*Session, *protocol.CMsgClientLogon) *protocol.CMsgClientLogonResponse {
+		return &protocol.CMsgClientLogonResponse{Eresult: proto.Int32(5)} // InvalidPassword
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := steamclient.New(srv.ClientOptions(steamclient.TransportWebSocket)...)
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Disconnect()
+
+	if err := c.Login(ctx, "user", "bad-token", testSteamID); err == nil {
+		t.Fatal("expected Login to fail")
+	}
+}
+
+func TestHeartbeats(t *testing.T) {
+	srv := NewServer(WithHeartbeatSeconds(1))
+	defer srv.Close()
+
+	_, sess := connect(t, srv, steamclient.TransportWebSocket)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := sess.Expect(ctx, steamclient.EMsgClientHeartBeat); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if sess.Heartbeats() < 1 {
+		t.Errorf("Heartbeats = %d, want >= 1", sess.Heartbeats())
+	}
+}
+
+func TestServiceMethod(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.HandleService("Authentication.GenerateAccessTokenForApp#1", func(_ *Session, body []byte) (proto.Message, eresult.EResult) {
+		var req protocol.CAuthentication_AccessToken_GenerateForApp_Request
+		if err := proto.Unmarshal(body, &req); err != nil || req.GetRefreshToken() != "refresh-token" {
+			return nil, eresult.InvalidParam
+		}
+		return &protocol.CAuthentication_AccessToken_GenerateForApp_Response{
+			AccessToken:  proto.String("access"),
+			RefreshToken: proto.String("rotated"),
+		}, eresult.OK
+	})
+
+	c, _ := connect(t, srv, steamclient.TransportTCP)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	access, refresh, err := c.GenerateAccessTokenForApp(ctx, "refresh-token")
+	if err != nil {
+		t.Fatalf("GenerateAccessTokenForApp: %v", err)
+	}
+	if access != "access" || refresh != "rotated" {
+		t.Errorf("tokens = %q, %q; want %q, %q", access, refresh, "access", "rotated")
+	}
+
+	if _, _, err := c.GenerateAccessTokenForApp(ctx, "wrong"); err == nil {
+		t.Error("expected error for rejected call")
+	}
+}
+
+func TestMultiAndGC(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		srv := NewServer()
+
+		got := make(chan *steamclient.GCMessage, 2)
+		_, sess := connect(t, srv, steamclient.TransportWebSocket,
+			steamclient.WithGCMessageHandler(func(msg *steamclient.GCMessage) { got <- msg }))
+
+		first, _ := gcPacket(440, 4004, []byte{0x08, 0x01})
+		second, _ := gcPacket(730, 4004, []byte{0x08, 0x02})
+		if err := sess.SendMulti(compress, first, second); err != nil {
+			t.Fatalf("SendMulti: %v", err)
+		}
+
+		for _, wantApp := range []uint32{440, 730} {
+			select {
+			case msg := <-got:
+				if msg.AppID != wantApp || msg.MsgType != 4004 || !msg.IsProto {
+					t.Errorf("compress=%v: got GC message %+v, want app %d type 4004", compress, msg, wantApp)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("compress=%v: GC message for app %d not delivered", compress, wantApp)
+			}
+		}
+		srv.Close()
+	}
+}
+
+func TestHandleGC(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	got := make(chan *steamclient.GCMessage, 1)
+	srv.HandleGC(440, func(s *Session, msg *steamclient.GCMessage) {
+		got <- msg
+		s.SendGC(&steamclient.GCMessage{AppID: 440, MsgType: 4004, IsProto: true})
+	})
+
+	welcome := make(chan *steamclient.GCMessage, 1)
+	c, _ := connect(t, srv, steamclient.TransportTCP,
+		steamclient.WithGCMessageHandler(func(msg *steamclient.GCMessage) { welcome <- msg }))
+
+	if err := c.SendGCMessage(context.Background(), 440, 4006, true, []byte{0x08, 0x00}); err != nil {
+		t.Fatalf("SendGCMessage: %v", err)
+	}
+
+	select {
+	case msg := <-got:
+		if msg.MsgType != 4006 || string(msg.Body) != "\x08\x00" {
+			t.Errorf("server got %+v, want type 4006", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not receive GC message")
+	}
+
+	select {
+	case msg := <-welcome:
+		if msg.MsgType != 4004 {
+			t.Errorf("client got type %d, want 4004", msg.MsgType)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not receive GC reply")
+	}
+}
+
+func TestLogOffAndDrop(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	events := make(chan *steamclient.DisconnectEvent, 1)
+	_, sess := connect(t, srv, steamclient.TransportWebSocket,
+		steamclient.WithDisconnectHandler(func(evt *steamclient.DisconnectEvent) { events <- evt }))
+
+	if err := sess.LogOff(eresult.TryAnotherCM); err != nil {
+		t.Fatalf("LogOff: %v", err)
+	}
+
+	select {
+	case evt := <-events:
+		if !evt.ServerInitiated || evt.EResult != 48 {
+			t.Errorf("event = %+v, want server-initiated EResult 48", evt)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnDisconnect was not called")
+	}
+}
+
+func TestAutoReconnectEndToEnd(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	reconnected := make(chan *steamclient.ReconnectedEvent, 1)
+	_, first := connect(t, srv, steamclient.TransportTCP,
+		steamclient.WithAutoReconnect(steamclient.ReconnectPolicy{InitialBackoff: time.Millisecond}),
+		steamclient.WithReconnectedHandler(func(evt *steamclient.ReconnectedEvent) { reconnected <- evt }))
+
+	first.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	second, err := srv.NextSession(ctx)
+	if err != nil {
+		t.Fatalf("NextSession: %v", err)
+	}
+	if _, err := second.Expect(ctx, steamclient.EMsgClientLogon); err != nil {
+		t.Fatalf("second logon: %v", err)
+	}
+
+	select {
+	case <-reconnected:
+	case <-ctx.Done():
+		t.Fatal("OnReconnected was not called")
+	}
+}
+
+// gcPacket builds a ClientFromGC packet carrying a proto GC message.
+func gcPacket(appID, msgType uint32, body []byte) (*steamclient.Packet, error) {
+	payload, err := steamclient.EncodeGCPayload(&steamclient.GCMessage{
+		AppID:   appID,
+		MsgType: msgType,
+		IsProto: true,
+		Body:    body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	gcBody, err := proto.Marshal(&protocol.CMsgGCClient{
+		Appid:   proto.Uint32(appID),
+		Msgtype: proto.Uint32(msgType | steamclient.ProtoMask),
+		Payload: payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &steamclient.Packet{
+		EMsg:    steamclient.EMsgClientFromGC,
+		IsProto: true,
+		Header:  &protocol.CMsgProtoBufHeader{},
+		Body:    gcBody,
+	}, nil
+}
+
+func TestNotify(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	c, sess := connect(t, srv, steamclient.TransportWebSocket)
+
+	const method = "Authentication.NotifyRiskQuizResults#1"
+	got := make(chan *protocol.CAuthentication_NotifyRiskQuizResults_Notification, 1)
+	steamclient.HandleNotification(c, method, func(msg *protocol.CAuthentication_NotifyRiskQuizResults_Notification) {
+		got <- msg
+	})
+
+	if err := sess.Notify(method, &protocol.CAuthentication_NotifyRiskQuizResults_Notification{
+		SelectedAction: proto.String("approve"),
+	}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	select {
+	case msg := <-got:
+		if msg.GetSelectedAction() != "approve" {
+			t.Errorf("SelectedAction = %q, want %q", msg.GetSelectedAction(), "approve")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification not delivered")
+	}
+}
+
+func TestLoginWithCredentials(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.HandleService("Authentication.GetPasswordRSAPublicKey#1", func(*Session, []byte) (proto.Message, eresult.EResult) {
+		return &protocol.CAuthentication_GetPasswordRSAPublicKey_Response{
+			PublickeyMod: proto.String(key.N.Text(16)),
+			PublickeyExp: proto.String(strconv.FormatInt(int64(key.E), 16)),
+			Timestamp:    proto.Uint64(12345),
+		}, eresult.OK
+	})
+	srv.HandleService("Authentication.BeginAuthSessionViaCredentials#1", func(_ *Session, body []byte) (proto.Message, eresult.EResult) {
+		var req protocol.CAuthentication_BeginAuthSessionViaCredentials_Request
+		if err := proto.Unmarshal(body, &req); err != nil {
+			return nil, eresult.InvalidParam
+		}
+		enc, _ := base64.StdEncoding.DecodeString(req.GetEncryptedPassword())
+		password, err := rsa.DecryptPKCS1v15(nil, key, enc)
+		if err != nil || string(password) != "hunter2" || req.GetEncryptionTimestamp() != 12345 {
+			return nil, eresult.InvalidPassword
+		}
+		if req.GetDeviceDetails().GetPlatformType() != protocol.EAuthTokenPlatformType_k_EAuthTokenPlatformType_SteamClient {
+			return nil, eresult.InvalidParam
+		}
+		return &protocol.CAuthentication_BeginAuthSessionViaCredentials_Response{
+			ClientId:  proto.Uint64(7),
+			RequestId: []byte("request"),
+			Interval:  proto.Float32(0.01),
+			Steamid:   proto.Uint64(testSteamID),
+			AllowedConfirmations: []*protocol.CAuthentication_AllowedConfirmation{
+				{ConfirmationType: protocol.EAuthSessionGuardType_k_EAuthSessionGuardType_DeviceCode.Enum()},
+			},
+		}, eresult.OK
+	})
+
+	var mu sync.Mutex
+	approved := false
+	srv.HandleService("Authentication.UpdateAuthSessionWithSteamGuardCode#1", func(_ *Session, body []byte) (proto.Message, eresult.EResult) {
+		var req protocol.CAuthentication_UpdateAuthSessionWithSteamGuardCode_Request
+		if err := proto.Unmarshal(body, &req); err != nil || req.GetCode() != "ABCDE" || req.GetClientId() != 7 {
+			return nil, eresult.InvalidLoginAuthCode
+		}
+		mu.Lock()
+		approved = true
+		mu.Unlock()
+		return &protocol.CAuthentication_UpdateAuthSessionWithSteamGuardCode_Response{}, eresult.OK
+	})
+
+	polls := 0
+	srv.HandleService("Authentication.PollAuthSessionStatus#1", func(*Session, []byte) (proto.Message, eresult.EResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls++
+		if !approved || polls < 2 {
+			return &protocol.CAuthentication_PollAuthSessionStatus_Response{}, eresult.OK
+		}
+		return &protocol.CAuthentication_PollAuthSessionStatus_Response{
+			AccountName:  proto.String("user"),
+			AccessToken:  proto.String("access"),
+			RefreshToken: proto.String("refresh"),
+		}, eresult.OK
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := steamclient.New(srv.ClientOptions(steamclient.TransportWebSocket)...)
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Disconnect()
+
+	sess, err := srv.NextSession(ctx)
+	if err != nil {
+		t.Fatalf("NextSession: %v", err)
+	}
+
+	guard := func(_ context.Context, allowed []steamsession.EAuthSessionGuardType) (string, steamsession.EAuthSessionGuardType, error) {
+		if !slices.Contains(allowed, steamsession.EAuthSessionGuardTypeDeviceCode) {
+			t.Errorf("allowed = %v, want DeviceCode", allowed)
+		}
+		return "ABCDE", steamsession.EAuthSessionGuardTypeDeviceCode, nil
+	}
+
+	refresh, err := c.LoginWithCredentials(ctx, "user", "hunter2", guard)
+	if err != nil {
+		t.Fatalf("LoginWithCredentials: %v", err)
+	}
+	if refresh != "refresh" {
+		t.Errorf("refresh token = %q, want %q", refresh, "refresh")
+	}
+	if got := sess.Logon().GetAccessToken(); got != "refresh" {
+		t.Errorf("logon AccessToken = %q, want %q", got, "refresh")
+	}
+	if c.SteamID().ToSteamID64() != testSteamID {
+		t.Errorf("SteamID = %d, want %d", c.SteamID().ToSteamID64(), testSteamID)
+	}
+
+	// Auth calls happen before logon and must use the non-authed EMsg.
+	if _, err := sess.Expect(ctx, steamclient.EMsgServiceMethodCallFromClientNonAuthed); err != nil {
+		t.Errorf("non-authed service call: %v", err)
+	}
+}
+
+func TestLoginWithCredentialsRequiresGuard(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	key, _ := rsa.GenerateKey(rand.Reader, 1024)
+	srv.HandleService("Authentication.GetPasswordRSAPublicKey#1", func(*Session, []byte) (proto.Message, eresult.EResult) {
+		return &protocol.CAuthentication_GetPasswordRSAPublicKey_Response{
+			PublickeyMod: proto.String(key.N.Text(16)),
+			PublickeyExp: proto.String(strconv.FormatInt(int64(key.E), 16)),
+		}, eresult.OK
+	})
+	srv.HandleService("Authentication.BeginAuthSessionViaCredentials#1", func(*Session, []byte) (proto.Message, eresult.EResult) {
+		return &protocol.CAuthentication_BeginAuthSessionViaCredentials_Response{
+			ClientId: proto.Uint64(7),
+			Steamid:  proto.Uint64(testSteamID),
+			AllowedConfirmations: []*protocol.CAuthentication_AllowedConfirmation{
+				{ConfirmationType: protocol.EAuthSessionGuardType_k_EAuthSessionGuardType_EmailCode.Enum()},
+			},
+		}, eresult.OK
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := steamclient.New(srv.ClientOptions(steamclient.TransportTCP)...)
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Disconnect()
+
+	if _, err := c.LoginWithCredentials(ctx, "user", "hunter2", nil); err == nil {
+		t.Fatal("expected an error when an email code is required without a SteamGuardFunc")
+	}
+}
+
+func TestTokenManagerOverCM(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	oldToken := fakeJWT(time.Now().Add(24 * time.Hour))
+	newToken := fakeJWT(time.Now().Add(200 * 24 * time.Hour))
+
+	srv.HandleService("Authentication.GenerateAccessTokenForApp#1", func(_ *Session, body []byte) (proto.Message, eresult.EResult) {
+		var req protocol.CAuthentication_AccessToken_GenerateForApp_Request
+		proto.Unmarshal(body, &req)
+		switch {
+		case req.GetRefreshToken() == "revoked":
+			return nil, eresult.Revoked
+		case req.GetRenewalType() != protocol.ETokenRenewalType_k_ETokenRenewalType_Allow:
+			return nil, eresult.InvalidParam
+		}
+		return &protocol.CAuthentication_AccessToken_GenerateForApp_Response{
+			AccessToken:  proto.String("access"),
+			RefreshToken: proto.String(newToken),
+		}, eresult.OK
+	})
+
+	c, _ := connect(t, srv, steamclient.TransportWebSocket)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var events []steamsession.TokenEvent
+	m, err := steamsession.NewTokenManager(c, oldToken, c.SteamID(),
+		steamsession.WithTokenEventHandler(func(evt steamsession.TokenEvent) { events = append(events, evt) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := m.RenewNow(ctx)
+	if err != nil || !rotated {
+		t.Fatalf("RenewNow = %v, %v; want rotated", rotated, err)
+	}
+	if m.RefreshToken() != newToken {
+		t.Error("manager did not pick up the rotated token")
+	}
+	if len(events) != 1 || events[0].Type != steamsession.TokenRenewed {
+		t.Errorf("events = %+v, want one TokenRenewed", events)
+	}
+
+	if _, _, err := c.RenewRefreshToken(ctx, "revoked"); !errors.Is(err, steamsession.ErrTokenRevoked) {
+		t.Errorf("err = %v, want ErrTokenRevoked", err)
+	}
+}
+
+// fakeJWT builds an unsigned JWT with the given expiry.
+func fakeJWT(exp time.Time) string {
+	claims, _ := json.Marshal(map[string]any{"exp": exp.Unix()})
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+}
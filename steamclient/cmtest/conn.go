@@ -0,0 +1,213 @@
+package cmtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+
+	"github.com/coder/websocket"
+	"github.com/k64z/steamstacks/steamclient"
+)
+
+// frameConn is the server side of a CM transport: it moves whole CM
+// messages, hiding WebSocket framing or VT01 framing and encryption.
+type frameConn interface {
+	readFrame() ([]byte, error)
+	writeFrame(data []byte) error
+	close() error
+}
+
+// wsFrameConn carries one CM message per binary WebSocket message.
+type wsFrameConn struct {
+	conn *websocket.Conn
+}
+
+func (w *wsFrameConn) readFrame() ([]byte, error) {
+	_, data, err := w.conn.Read(context.Background())
+	return data, err
+}
+
+func (w *wsFrameConn) writeFrame(data []byte) error {
+	return w.conn.Write(context.Background(), websocket.MessageBinary, data)
+}
+
+func (w *wsFrameConn) close() error {
+	return w.conn.CloseNow()
+}
+
+const tcpMagic = 0x31305456 // "VT01"
+
+// tcpFrameConn carries CM messages with VT01 framing, encrypted once the
+// handshake has completed.
+type tcpFrameConn struct {
+	conn       net.Conn
+	block      cipher.Block
+	hmacSecret []byte
+}
+
+func (t *tcpFrameConn) readFrame() ([]byte, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(t.conn, hdr[:]); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(hdr[4:8]) != tcpMagic {
+		return nil, fmt.Errorf("invalid magic: 0x%08X", binary.LittleEndian.Uint32(hdr[4:8]))
+	}
+
+	payload := make([]byte, binary.LittleEndian.Uint32(hdr[0:4]))
+	if _, err := io.ReadFull(t.conn, payload); err != nil {
+		return nil, err
+	}
+
+	if t.block == nil {
+		return payload, nil
+	}
+	return t.decrypt(payload)
+}
+
+func (t *tcpFrameConn) writeFrame(data []byte) error {
+	if t.block != nil {
+		var err error
+		if data, err = t.encrypt(data); err != nil {
+			return err
+		}
+	}
+
+	frame := binary.LittleEndian.AppendUint32(nil, uint32(len(data)))
+	frame = binary.LittleEndian.AppendUint32(frame, tcpMagic)
+	frame = append(frame, data...)
+	_, err := t.conn.Write(frame)
+	return err
+}
+
+func (t *tcpFrameConn) close() error {
+	return t.conn.Close()
+}
+
+// msgHdrLen is the size of the plain MsgHdr used by the encryption
+// handshake: EMsg(4) + TargetJobID(8) + SourceJobID(8).
+const msgHdrLen = 20
+
+func appendMsgHdr(b []byte, emsg steamclient.EMsg) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(emsg))
+	b = binary.LittleEndian.AppendUint64(b, 0xFFFFFFFFFFFFFFFF) // target job id
+	b = binary.LittleEndian.AppendUint64(b, 0xFFFFFFFFFFFFFFFF) // source job id
+	return b
+}
+
+// handshake runs the server side of the channel encryption handshake:
+// ChannelEncryptRequest with a challenge, ChannelEncryptResponse carrying
+// the RSA-encrypted session key, ChannelEncryptResult. Afterwards every frame
+// is encrypted in HMAC mode.
+func (t *tcpFrameConn) handshake(key *rsa.PrivateKey) error {
+	challenge := make([]byte, 16)
+	if _, err := rand.Read(challenge); err != nil {
+		return err
+	}
+
+	req := appendMsgHdr(nil, steamclient.EMsgChannelEncryptRequest)
+	req = binary.LittleEndian.AppendUint32(req, 1) // protocol version
+	req = binary.LittleEndian.AppendUint32(req, 1) // universe: Public
+	req = append(req, challenge...)
+	if err := t.writeFrame(req); err != nil {
+		return fmt.Errorf("send encrypt request: %w", err)
+	}
+
+	resp, err := t.readFrame()
+	if err != nil {
+		return fmt.Errorf("read encrypt response: %w", err)
+	}
+	if len(resp) < msgHdrLen+8 || steamclient.EMsg(binary.LittleEndian.Uint32(resp)) != steamclient.EMsgChannelEncryptResponse {
+		return fmt.Errorf("unexpected encrypt response")
+	}
+
+	body := resp[msgHdrLen:]
+	keySize := binary.LittleEndian.Uint32(body[4:8])
+	if uint32(len(body)) < 8+keySize+4 {
+		return fmt.Errorf("encrypt response truncated")
+	}
+	blob := body[8 : 8+keySize]
+	if crc32.ChecksumIEEE(blob) != binary.LittleEndian.Uint32(body[8+keySize:]) {
+		return fmt.Errorf("encrypt response CRC mismatch")
+	}
+
+	plain, err := rsa.DecryptOAEP(sha1.New(), nil, key, blob, nil)
+	if err != nil {
+		return fmt.Errorf("decrypt session key: %w", err)
+	}
+	if len(plain) != 32+len(challenge) || !bytes.Equal(plain[32:], challenge) {
+		return fmt.Errorf("challenge mismatch")
+	}
+
+	result := appendMsgHdr(nil, steamclient.EMsgChannelEncryptResult)
+	result = binary.LittleEndian.AppendUint32(result, 1) // EResult OK
+	if err := t.writeFrame(result); err != nil {
+		return fmt.Errorf("send encrypt result: %w", err)
+	}
+
+	sessionKey := plain[:32]
+	t.block, err = aes.NewCipher(sessionKey)
+	if err != nil {
+		return err
+	}
+	t.hmacSecret = append([]byte(nil), sessionKey[:16]...)
+	return nil
+}
+
+// encrypt mirrors the client's NetFilterEncryptionWithHMAC: the IV is the
+// first 13 bytes of HMAC-SHA1(secret, random3 + plaintext) followed by
+// random3, sent AES-ECB encrypted ahead of the AES-CBC ciphertext.
+func (t *tcpFrameConn) encrypt(plaintext []byte) ([]byte, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv[13:]); err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, t.hmacSecret)
+	mac.Write(iv[13:])
+	mac.Write(plaintext)
+	copy(iv[:13], mac.Sum(nil))
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte(nil), plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	out := make([]byte, aes.BlockSize+len(padded))
+	t.block.Encrypt(out[:aes.BlockSize], iv)
+	cipher.NewCBCEncrypter(t.block, iv).CryptBlocks(out[aes.BlockSize:], padded)
+	return out, nil
+}
+
+func (t *tcpFrameConn) decrypt(data []byte) ([]byte, error) {
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("bad ciphertext length: %d", len(data))
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	t.block.Decrypt(iv, data[:aes.BlockSize])
+
+	plain := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(t.block, iv).CryptBlocks(plain, data[aes.BlockSize:])
+
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, fmt.Errorf("bad padding")
+	}
+	plain = plain[:len(plain)-padding]
+
+	mac := hmac.New(sha1.New, t.hmacSecret)
+	mac.Write(iv[13:])
+	mac.Write(plain)
+	if !hmac.Equal(iv[:13], mac.Sum(nil)[:13]) {
+		return nil, fmt.Errorf("HMAC verification failed")
+	}
+	return plain, nil
+}
@@ -0,0 +1,297 @@
+package cmtest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/coder/websocket"
+	"github.com/k64z/steamstacks/eresult"
+	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamclient"
+	"google.golang.org/protobuf/proto"
+)
+
+// ServiceHandler answers a unified service method call such as
+// "Player.GetNickname#1". body is the serialized request. The returned
+// message (which may be nil) is sent back with the given EResult.
+type ServiceHandler func(s *Session, body []byte) (resp proto.Message, result eresult.EResult)
+
+// MessageHandler handles a packet sent by the client. Handlers registered
+// for an EMsg replace the server's built-in behaviour for that EMsg.
+type MessageHandler func(s *Session, pkt *steamclient.Packet)
+
+// GCHandler handles a message the client sent to a Game Coordinator.
+type GCHandler func(s *Session, msg *steamclient.GCMessage)
+
+// LogonHandler decides the response to a ClientLogon. The default accepts
+// every logon with EResult OK.
+type LogonHandler func(s *Session, logon *protocol.CMsgClientLogon) *protocol.CMsgClientLogonResponse
+
+// Server is an in-process Steam CM server for tests. It listens for
+// WebSocket connections (TLS, like the real CMs) and for TCP connections
+// (VT01 framing with the ChannelEncryptRequest handshake), answers
+// ClientHello/ClientLogon and heartbeats, and routes service method jobs and
+// GC messages to scriptable handlers.
+//
+// Use ClientOptions to point a steamclient.Client at the server.
+type Server struct {
+	ws  *httptest.Server
+	tcp net.Listener
+	key *rsa.PrivateKey
+
+	logon            LogonHandler
+	heartbeatSeconds int32
+
+	mu            sync.Mutex
+	services      map[string]ServiceHandler
+	handlers      map[steamclient.EMsg]MessageHandler
+	gcHandlers    map[uint32]GCHandler
+	sessions      []*Session
+	nextSessionID int32
+	closed        bool
+	newSessions   chan *Session
+	wg            sync.WaitGroup
+}
+
+type config struct {
+	logon            LogonHandler
+	heartbeatSeconds int32
+}
+
+// Option configures a Server.
+type Option func(*config)
+
+// WithLogonHandler sets the function that answers ClientLogon.
+func WithLogonHandler(fn LogonHandler) Option {
+	return func(c *config) { c.logon = fn }
+}
+
+// WithHeartbeatSeconds sets the heartbeat interval announced in the logon
+// response (default 9).
+func WithHeartbeatSeconds(n int32) Option {
+	return func(c *config) { c.heartbeatSeconds = n }
+}
+
+// NewServer starts a server listening on loopback. Like httptest.NewServer,
+// it panics if it cannot listen. Call Close when done.
+func NewServer(opts ...Option) *Server {
+	cfg := config{
+		heartbeatSeconds: 9,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// The client's handshake announces a 128-byte key, so this must be RSA-1024.
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		panic(fmt.Sprintf("cmtest: generate key: %v", err))
+	}
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("cmtest: listen: %v", err))
+	}
+
+	s := &Server{
+		tcp:              tcp,
+		key:              key,
+		logon:            cfg.logon,
+		heartbeatSeconds: cfg.heartbeatSeconds,
+		services:         make(map[string]ServiceHandler),
+		handlers:         make(map[steamclient.EMsg]MessageHandler),
+		gcHandlers:       make(map[uint32]GCHandler),
+		newSessions:      make(chan *Session, 64),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cmsocket/", s.serveWebSocket)
+	s.ws = httptest.NewTLSServer(mux)
+
+	s.wg.Add(1)
+	go s.acceptTCP()
+
+	return s
+}
+
+// WebSocketAddr returns the "host:port" of the WebSocket listener.
+func (s *Server) WebSocketAddr() string {
+	return s.ws.Listener.Addr().String()
+}
+
+// TCPAddr returns the "host:port" of the TCP listener.
+func (s *Server) TCPAddr() string {
+	return s.tcp.Addr().String()
+}
+
+// ClientOptions returns the steamclient options that make a Client connect
+// to this server over the given transport: a pinned server list, an HTTP
+// client that trusts the server's certificate and the server's channel key.
+func (s *Server) ClientOptions(transport steamclient.TransportType) []steamclient.Option {
+	httpClient := s.ws.Client()
+	pool := steamclient.NewServerPool(httpClient, steamclient.WithServerList(
+		steamclient.CMServer{Addr: s.WebSocketAddr(), Type: "websockets"},
+		steamclient.CMServer{Addr: s.TCPAddr(), Type: "netfilter"},
+	))
+
+	return []steamclient.Option{
+		steamclient.WithTransport(transport),
+		steamclient.WithHTTPClient(httpClient),
+		steamclient.WithServerPool(pool),
+		steamclient.WithChannelPublicKey(&s.key.PublicKey),
+	}
+}
+
+// HandleService registers the handler for a service method such as
+// "Player.GetNickname#1". Calls to methods without a handler fail with
+// eresult.Fail.
+func (s *Server) HandleService(method string, fn ServiceHandler) {
+	s.mu.Lock()
+	s.services[method] = fn
+	s.mu.Unlock()
+}
+
+// HandleEMsg registers a handler for packets of the given EMsg, replacing
+// any built-in behaviour.
+func (s *Server) HandleEMsg(emsg steamclient.EMsg, fn MessageHandler) {
+	s.mu.Lock()
+	s.handlers[emsg] = fn
+	s.mu.Unlock()
+}
+
+// HandleGC registers a handler for messages sent to the Game Coordinator of appID.
+func (s *Server) HandleGC(appID uint32, fn GCHandler) {
+	s.mu.Lock()
+	s.gcHandlers[appID] = fn
+	s.mu.Unlock()
+}
+
+// NextSession waits for the next client connection.
+func (s *Server) NextSession(ctx context.Context) (*Session, error) {
+	select {
+	case sess := <-s.newSessions:
+		return sess, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sessions returns every session the server has accepted so far.
+func (s *Server) Sessions() []*Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Session(nil), s.sessions...)
+}
+
+// Close drops all connections and stops the listeners.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	sessions := append([]*Session(nil), s.sessions...)
+	s.mu.Unlock()
+
+	for _, sess := range sessions {
+		sess.Close()
+	}
+	s.tcp.Close()
+	s.ws.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn.SetReadLimit(1 << 24)
+
+	sess := s.newSession(&wsFrameConn{conn: conn})
+	if sess == nil {
+		conn.CloseNow()
+		return
+	}
+	sess.serve()
+}
+
+func (s *Server) acceptTCP() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			fc := &tcpFrameConn{conn: conn}
+			if err := fc.handshake(s.key); err != nil {
+				conn.Close()
+				return
+			}
+
+			sess := s.newSession(fc)
+			if sess == nil {
+				conn.Close()
+				return
+			}
+			sess.serve()
+		}()
+	}
+}
+
+// newSession registers a connection, or returns nil once the server is closed.
+func (s *Server) newSession(conn frameConn) *Session {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.nextSessionID++
+	sess := &Session{
+		srv:       s,
+		conn:      conn,
+		sessionID: s.nextSessionID,
+		received:  make(map[steamclient.EMsg][]*steamclient.Packet),
+		notify:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	s.sessions = append(s.sessions, sess)
+	s.mu.Unlock()
+
+	select {
+	case s.newSessions <- sess:
+	default:
+	}
+	return sess
+}
+
+func (s *Server) serviceHandler(method string) ServiceHandler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.services[method]
+}
+
+func (s *Server) messageHandler(emsg steamclient.EMsg) MessageHandler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handlers[emsg]
+}
+
+func (s *Server) gcHandler(appID uint32) GCHandler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gcHandlers[appID]
+}
+
+// errClosed is returned by Session methods after the connection is gone.
+var errClosed = errors.New("cmtest: session closed")
@@ -0,0 +1,389 @@
+package cmtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/k64z/steamstacks/eresult"
+	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamclient"
+	"google.golang.org/protobuf/proto"
+)
+
+// Session is one client connection to the Server.
+type Session struct {
+	srv       *Server
+	conn      frameConn
+	sessionID int32
+
+	writeMu sync.Mutex // serializes frame writes
+
+	mu         sync.Mutex
+	steamID    uint64
+	loggedOn   bool
+	logon      *protocol.CMsgClientLogon
+	heartbeats int
+	received   map[steamclient.EMsg][]*steamclient.Packet // not yet returned by Expect
+	notify     chan struct{}                              // closed and replaced on every packet
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// SteamID returns the SteamID assigned at logon, or 0 before logon.
+func (s *Session) SteamID() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.steamID
+}
+
+// SessionID returns the client session ID assigned to this connection.
+func (s *Session) SessionID() int32 {
+	return s.sessionID
+}
+
+// Logon returns the ClientLogon the client sent, or nil before logon.
+func (s *Session) Logon() *protocol.CMsgClientLogon {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logon
+}
+
+// LoggedOn reports whether the client has been sent a successful logon response.
+func (s *Session) LoggedOn() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loggedOn
+}
+
+// Heartbeats returns the number of heartbeats received.
+func (s *Session) Heartbeats() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.heartbeats
+}
+
+// Done is closed when the connection ends.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Expect waits for a packet with the given EMsg from the client. Each packet
+// is returned at most once, in arrival order. Packets nested in a Multi are
+// delivered individually.
+func (s *Session) Expect(ctx context.Context, emsg steamclient.EMsg) (*steamclient.Packet, error) {
+	for {
+		s.mu.Lock()
+		if q := s.received[emsg]; len(q) > 0 {
+			pkt := q[0]
+			s.received[emsg] = q[1:]
+			s.mu.Unlock()
+			return pkt, nil
+		}
+		notify := s.notify
+		s.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("expect %s: %w", emsg, ctx.Err())
+		case <-s.done:
+			return nil, fmt.Errorf("expect %s: %w", emsg, errClosed)
+		}
+	}
+}
+
+// Send sends a protobuf message to the client.
+func (s *Session) Send(emsg steamclient.EMsg, msg proto.Message) error {
+	return s.SendWithHeader(emsg, &protocol.CMsgProtoBufHeader{}, msg)
+}
+
+// SendWithHeader sends a protobuf message with a caller-supplied header.
+// The SteamID and session ID are filled in when unset.
+func (s *Session) SendWithHeader(emsg steamclient.EMsg, hdr *protocol.CMsgProtoBufHeader, msg proto.Message) error {
+	var body []byte
+	if msg != nil {
+		var err error
+		body, err = proto.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("marshal %s: %w", emsg, err)
+		}
+	}
+
+	return s.SendPacket(&steamclient.Packet{
+		EMsg:    emsg,
+		IsProto: true,
+		Header:  hdr,
+		Body:    body,
+	})
+}
+
+// SendPacket sends a raw packet to the client.
+func (s *Session) SendPacket(pkt *steamclient.Packet) error {
+	data, err := s.encode(pkt)
+	if err != nil {
+		return err
+	}
+	return s.writeFrame(data)
+}
+
+// SendMulti bundles packets into a single EMsgMulti, gzip-compressed when
+// compress is true.
+func (s *Session) SendMulti(compress bool, pkts ...*steamclient.Packet) error {
+	var payload bytes.Buffer
+	for _, pkt := range pkts {
+		data, err := s.encode(pkt)
+		if err != nil {
+			return err
+		}
+		payload.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(data))))
+		payload.Write(data)
+	}
+
+	multi := &protocol.CMsgMulti{MessageBody: payload.Bytes()}
+	if compress {
+		var gz bytes.Buffer
+		w := gzip.NewWriter(&gz)
+		w.Write(payload.Bytes())
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("gzip Multi: %w", err)
+		}
+		multi.MessageBody = gz.Bytes()
+		multi.SizeUnzipped = proto.Uint32(uint32(payload.Len()))
+	}
+
+	return s.Send(steamclient.EMsgMulti, multi)
+}
+
+// Reply answers a client request, targeting the request's source job ID.
+func (s *Session) Reply(req *steamclient.Packet, emsg steamclient.EMsg, result eresult.EResult, msg proto.Message) error {
+	return s.SendWithHeader(emsg, &protocol.CMsgProtoBufHeader{
+		JobidTarget: proto.Uint64(req.Header.GetJobidSource()),
+		Eresult:     proto.Int32(int32(result)),
+	}, msg)
+}
+
+// Notify pushes a server-initiated service method notification such as
+// "FriendMessagesClient.IncomingMessage#1".
+func (s *Session) Notify(method string, msg proto.Message) error {
+	return s.SendWithHeader(steamclient.EMsgServiceMethodSendToClient, &protocol.CMsgProtoBufHeader{
+		TargetJobName: proto.String(method),
+	}, msg)
+}
+
+// SendGC sends a Game Coordinator message to the client.
+func (s *Session) SendGC(msg *steamclient.GCMessage) error {
+	payload, err := steamclient.EncodeGCPayload(msg)
+	if err != nil {
+		return fmt.Errorf("encode GC payload: %w", err)
+	}
+
+	msgType := msg.MsgType
+	if msg.IsProto {
+		msgType |= steamclient.ProtoMask
+	}
+
+	return s.SendWithHeader(steamclient.EMsgClientFromGC, &protocol.CMsgProtoBufHeader{
+		RoutingAppid: proto.Uint32(msg.AppID),
+	}, &protocol.CMsgGCClient{
+		Appid:   proto.Uint32(msg.AppID),
+		Msgtype: proto.Uint32(msgType),
+		Payload: payload,
+	})
+}
+
+// LogOff sends ClientLoggedOff with the given EResult, as Steam does before
+// dropping a session.
+func (s *Session) LogOff(result eresult.EResult) error {
+	s.mu.Lock()
+	s.loggedOn = false
+	s.mu.Unlock()
+	return s.Send(steamclient.EMsgClientLoggedOff, &protocol.CMsgClientLoggedOff{
+		Eresult: proto.Int32(int32(result)),
+	})
+}
+
+// Close drops the connection without a logoff, simulating a network failure.
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.conn.close()
+	})
+}
+
+// encode fills in the session's SteamID and session ID and serializes pkt.
+func (s *Session) encode(pkt *steamclient.Packet) ([]byte, error) {
+	if pkt.Header == nil {
+		pkt.Header = &protocol.CMsgProtoBufHeader{}
+	}
+	s.mu.Lock()
+	if pkt.Header.Steamid == nil && s.steamID != 0 {
+		pkt.Header.Steamid = proto.Uint64(s.steamID)
+	}
+	if pkt.Header.ClientSessionid == nil && s.loggedOn {
+		pkt.Header.ClientSessionid = proto.Int32(s.sessionID)
+	}
+	s.mu.Unlock()
+
+	data, err := steamclient.EncodePacket(pkt)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", pkt.EMsg, err)
+	}
+	return data, nil
+}
+
+func (s *Session) writeFrame(data []byte) error {
+	select {
+	case <-s.done:
+		return errClosed
+	default:
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.writeFrame(data)
+}
+
+func (s *Session) serve() {
+	defer s.Close()
+
+	for {
+		data, err := s.conn.readFrame()
+		if err != nil {
+			return
+		}
+
+		pkt, err := steamclient.DecodePacket(data)
+		if err != nil {
+			continue
+		}
+		s.handle(pkt)
+	}
+}
+
+func (s *Session) handle(pkt *steamclient.Packet) {
+	if pkt.EMsg == steamclient.EMsgMulti {
+		s.handleMulti(pkt)
+		return
+	}
+
+	s.mu.Lock()
+	s.received[pkt.EMsg] = append(s.received[pkt.EMsg], pkt)
+	close(s.notify)
+	s.notify = make(chan struct{})
+	s.mu.Unlock()
+
+	if fn := s.srv.messageHandler(pkt.EMsg); fn != nil {
+		fn(s, pkt)
+		return
+	}
+
+	switch pkt.EMsg {
+	case steamclient.EMsgClientLogon:
+		s.handleLogon(pkt)
+
+	case steamclient.EMsgClientHeartBeat:
+		s.mu.Lock()
+		s.heartbeats++
+		s.mu.Unlock()
+
+	case steamclient.EMsgServiceMethodCallFromClient, steamclient.EMsgServiceMethodCallFromClientNonAuthed:
+		s.handleServiceMethod(pkt)
+
+	case steamclient.EMsgClientToGC:
+		s.handleToGC(pkt)
+	}
+}
+
+func (s *Session) handleLogon(pkt *steamclient.Packet) {
+	var logon protocol.CMsgClientLogon
+	if err := proto.Unmarshal(pkt.Body, &logon); err != nil {
+		return
+	}
+
+	resp := &protocol.CMsgClientLogonResponse{
+		Eresult:          proto.Int32(1),
+		HeartbeatSeconds: proto.Int32(s.srv.heartbeatSeconds),
+	}
+	if s.srv.logon != nil {
+		resp = s.srv.logon(s, &logon)
+	}
+
+	s.mu.Lock()
+	s.logon = &logon
+	if resp.GetEresult() == 1 {
+		s.steamID = pkt.Header.GetSteamid()
+		s.loggedOn = true
+	}
+	s.mu.Unlock()
+
+	s.Send(steamclient.EMsgClientLogOnResponse, resp)
+}
+
+func (s *Session) handleServiceMethod(pkt *steamclient.Packet) {
+	method := pkt.Header.GetTargetJobName()
+
+	fn := s.srv.serviceHandler(method)
+	if fn == nil {
+		s.Reply(pkt, steamclient.EMsgServiceMethodResponse, eresult.Fail, nil)
+		return
+	}
+
+	resp, result := fn(s, pkt.Body)
+	s.Reply(pkt, steamclient.EMsgServiceMethodResponse, result, resp)
+}
+
+func (s *Session) handleToGC(pkt *steamclient.Packet) {
+	var msg protocol.CMsgGCClient
+	if err := proto.Unmarshal(pkt.Body, &msg); err != nil {
+		return
+	}
+
+	fn := s.srv.gcHandler(msg.GetAppid())
+	if fn == nil {
+		return
+	}
+
+	gcMsg, err := steamclient.DecodeGCPayload(msg.GetAppid(), msg.GetMsgtype(), msg.GetPayload())
+	if err != nil {
+		return
+	}
+	fn(s, gcMsg)
+}
+
+func (s *Session) handleMulti(pkt *steamclient.Packet) {
+	var multi protocol.CMsgMulti
+	if err := proto.Unmarshal(pkt.Body, &multi); err != nil {
+		return
+	}
+
+	body := multi.GetMessageBody()
+	if multi.GetSizeUnzipped() > 0 {
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(r); err != nil {
+			return
+		}
+		body = buf.Bytes()
+	}
+
+	for len(body) >= 4 {
+		size := binary.LittleEndian.Uint32(body[:4])
+		body = body[4:]
+		if uint32(len(body)) < size {
+			return
+		}
+		sub, err := steamclient.DecodePacket(body[:size])
+		body = body[size:]
+		if err != nil {
+			continue
+		}
+		s.handle(sub)
+	}
+}
@@ -153,16 +153,20 @@ func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
 }
 
 // rsaEncryptSessionKey encrypts the session key (and optional challenge) with
-// Steam's RSA public key using OAEP-SHA1.
-func rsaEncryptSessionKey(sessionKey, challenge []byte) ([]byte, error) {
-	pub, err := x509.ParsePKIXPublicKey(steamPublicKey)
-	if err != nil {
-		return nil, fmt.Errorf("parse public key: %w", err)
-	}
+// the given RSA public key using OAEP-SHA1. A nil key means Steam's public
+// universe key.
+func rsaEncryptSessionKey(rsaPub *rsa.PublicKey, sessionKey, challenge []byte) ([]byte, error) {
+	if rsaPub == nil {
+		pub, err := x509.ParsePKIXPublicKey(steamPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
 
-	rsaPub, ok := pub.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("not an RSA public key")
+		var ok bool
+		rsaPub, ok = pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("not an RSA public key")
+		}
 	}
 
 	blob := sessionKey
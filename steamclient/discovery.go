@@ -6,19 +6,31 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+
+	"github.com/k64z/steamstacks/steamhost"
 )
 
 // CMServer represents a Steam CM server endpoint.
 type CMServer struct {
-	Addr string // "host:port" for TCP, "host" for WebSocket
-	Type string // "websockets" or "netfilter"
+	Addr string `json:"addr"` // "host:port" for TCP, "host" for WebSocket
+	Type string `json:"type"` // "websockets" or "netfilter"
 }
 
-const cmListURL = "https://api.steampowered.com/ISteamDirectory/GetCMListForConnect/v1/?cellid=0"
+const cmListPath = "/ISteamDirectory/GetCMListForConnect/v1/?cellid=%d"
 
 // DiscoverServers fetches the CM server list from the Steam Web API.
 func DiscoverServers(ctx context.Context, httpClient *http.Client) ([]CMServer, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cmListURL, nil)
+	return DiscoverServersForCell(ctx, httpClient, 0)
+}
+
+// DiscoverServersForCell fetches the CM server list for the given cell ID.
+// Steam orders the list by proximity to the cell.
+func DiscoverServersForCell(ctx context.Context, httpClient *http.Client, cellID uint32) ([]CMServer, error) {
+	return discoverServers(ctx, httpClient, steamhost.DefaultAPI, cellID)
+}
+
+func discoverServers(ctx context.Context, httpClient *http.Client, apiURL string, cellID uint32) ([]CMServer, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+fmt.Sprintf(cmListPath, cellID), nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -6,76 +6,80 @@ import "fmt"
 type EMsg uint32
 
 const (
-	EMsgMulti                          EMsg = 1
-	EMsgClientHeartBeat                EMsg = 703
-	EMsgClientLogOff                   EMsg = 706
-	EMsgClientRemoveFriend             EMsg = 714
-	EMsgClientChangeStatus             EMsg = 716
-	EMsgClientFriendMsg                EMsg = 718
-	EMsgClientGamesPlayed              EMsg = 742
-	EMsgClientLogOnResponse            EMsg = 751
-	EMsgClientLoggedOff                EMsg = 757
-	EMsgClientPersonaState             EMsg = 766
-	EMsgClientFriendsList              EMsg = 767
-	EMsgClientAddFriend                EMsg = 791
-	EMsgClientAddFriendResponse        EMsg = 792
-	EMsgClientRequestFriendData        EMsg = 815
-	EMsgClientSessionToken             EMsg = 850
-	EMsgClientSetIgnoreFriend          EMsg = 855
-	EMsgClientSetIgnoreFriendResponse  EMsg = 856
-	EMsgChannelEncryptRequest          EMsg = 1303
-	EMsgChannelEncryptResponse         EMsg = 1304
-	EMsgChannelEncryptResult           EMsg = 1305
-	EMsgClientFriendMsgIncoming        EMsg = 5427
-	EMsgClientLogon                    EMsg = 5514
-	EMsgClientItemAnnouncements        EMsg = 5576
-	EMsgClientRequestItemAnnouncements EMsg = 5577
-	EMsgClientFriendMsgEchoToSender    EMsg = 5578
-	EMsgClientPersonaChangeResponse    EMsg = 5584
-	EMsgClientUserNotifications        EMsg = 5599
-	EMsgClientToGC                     EMsg = 5452
-	EMsgClientFromGC                   EMsg = 5453
-	EMsgServiceMethodCallFromClient    EMsg = 151
-	EMsgServiceMethodSendToClient      EMsg = 152
-	EMsgClientHello                    EMsg = 9805
+	EMsgMulti                                EMsg = 1
+	EMsgClientHeartBeat                      EMsg = 703
+	EMsgClientLogOff                         EMsg = 706
+	EMsgClientRemoveFriend                   EMsg = 714
+	EMsgClientChangeStatus                   EMsg = 716
+	EMsgClientFriendMsg                      EMsg = 718
+	EMsgClientGamesPlayed                    EMsg = 742
+	EMsgClientLogOnResponse                  EMsg = 751
+	EMsgClientLoggedOff                      EMsg = 757
+	EMsgClientPersonaState                   EMsg = 766
+	EMsgClientFriendsList                    EMsg = 767
+	EMsgClientAddFriend                      EMsg = 791
+	EMsgClientAddFriendResponse              EMsg = 792
+	EMsgClientRequestFriendData              EMsg = 815
+	EMsgClientSessionToken                   EMsg = 850
+	EMsgClientSetIgnoreFriend                EMsg = 855
+	EMsgClientSetIgnoreFriendResponse        EMsg = 856
+	EMsgChannelEncryptRequest                EMsg = 1303
+	EMsgChannelEncryptResponse               EMsg = 1304
+	EMsgChannelEncryptResult                 EMsg = 1305
+	EMsgClientFriendMsgIncoming              EMsg = 5427
+	EMsgClientLogon                          EMsg = 5514
+	EMsgClientItemAnnouncements              EMsg = 5576
+	EMsgClientRequestItemAnnouncements       EMsg = 5577
+	EMsgClientFriendMsgEchoToSender          EMsg = 5578
+	EMsgClientPersonaChangeResponse          EMsg = 5584
+	EMsgClientUserNotifications              EMsg = 5599
+	EMsgClientToGC                           EMsg = 5452
+	EMsgClientFromGC                         EMsg = 5453
+	EMsgServiceMethodResponse                EMsg = 147
+	EMsgServiceMethodCallFromClient          EMsg = 151
+	EMsgServiceMethodSendToClient            EMsg = 152
+	EMsgServiceMethodCallFromClientNonAuthed EMsg = 9804
+	EMsgClientHello                          EMsg = 9805
 )
 
 const ProtoMask uint32 = 0x80000000
 const ProtoVersion uint32 = 65581
 
 var emsgNames = map[EMsg]string{
-	EMsgMulti:                          "Multi",
-	EMsgClientHeartBeat:                "ClientHeartBeat",
-	EMsgClientLogOff:                   "ClientLogOff",
-	EMsgClientRemoveFriend:             "ClientRemoveFriend",
-	EMsgClientChangeStatus:             "ClientChangeStatus",
-	EMsgClientFriendMsg:                "ClientFriendMsg",
-	EMsgClientGamesPlayed:              "ClientGamesPlayed",
-	EMsgClientLogOnResponse:            "ClientLogOnResponse",
-	EMsgClientLoggedOff:                "ClientLoggedOff",
-	EMsgClientPersonaState:             "ClientPersonaState",
-	EMsgClientFriendsList:              "ClientFriendsList",
-	EMsgClientAddFriend:                "ClientAddFriend",
-	EMsgClientAddFriendResponse:        "ClientAddFriendResponse",
-	EMsgClientRequestFriendData:        "ClientRequestFriendData",
-	EMsgClientSessionToken:             "ClientSessionToken",
-	EMsgClientSetIgnoreFriend:          "ClientSetIgnoreFriend",
-	EMsgClientSetIgnoreFriendResponse:  "ClientSetIgnoreFriendResponse",
-	EMsgChannelEncryptRequest:          "ChannelEncryptRequest",
-	EMsgChannelEncryptResponse:         "ChannelEncryptResponse",
-	EMsgChannelEncryptResult:           "ChannelEncryptResult",
-	EMsgClientFriendMsgIncoming:        "ClientFriendMsgIncoming",
-	EMsgClientLogon:                    "ClientLogon",
-	EMsgClientItemAnnouncements:        "ClientItemAnnouncements",
-	EMsgClientRequestItemAnnouncements: "ClientRequestItemAnnouncements",
-	EMsgClientFriendMsgEchoToSender:    "ClientFriendMsgEchoToSender",
-	EMsgClientPersonaChangeResponse:    "ClientPersonaChangeResponse",
-	EMsgClientUserNotifications:        "ClientUserNotifications",
-	EMsgClientToGC:                     "ClientToGC",
-	EMsgClientFromGC:                   "ClientFromGC",
-	EMsgServiceMethodCallFromClient:    "ServiceMethodCallFromClient",
-	EMsgServiceMethodSendToClient:      "ServiceMethodSendToClient",
-	EMsgClientHello:                    "ClientHello",
+	EMsgMulti:                                "Multi",
+	EMsgClientHeartBeat:                      "ClientHeartBeat",
+	EMsgClientLogOff:                         "ClientLogOff",
+	EMsgClientRemoveFriend:                   "ClientRemoveFriend",
+	EMsgClientChangeStatus:                   "ClientChangeStatus",
+	EMsgClientFriendMsg:                      "ClientFriendMsg",
+	EMsgClientGamesPlayed:                    "ClientGamesPlayed",
+	EMsgClientLogOnResponse:                  "ClientLogOnResponse",
+	EMsgClientLoggedOff:                      "ClientLoggedOff",
+	EMsgClientPersonaState:                   "ClientPersonaState",
+	EMsgClientFriendsList:                    "ClientFriendsList",
+	EMsgClientAddFriend:                      "ClientAddFriend",
+	EMsgClientAddFriendResponse:              "ClientAddFriendResponse",
+	EMsgClientRequestFriendData:              "ClientRequestFriendData",
+	EMsgClientSessionToken:                   "ClientSessionToken",
+	EMsgClientSetIgnoreFriend:                "ClientSetIgnoreFriend",
+	EMsgClientSetIgnoreFriendResponse:        "ClientSetIgnoreFriendResponse",
+	EMsgChannelEncryptRequest:                "ChannelEncryptRequest",
+	EMsgChannelEncryptResponse:               "ChannelEncryptResponse",
+	EMsgChannelEncryptResult:                 "ChannelEncryptResult",
+	EMsgClientFriendMsgIncoming:              "ClientFriendMsgIncoming",
+	EMsgClientLogon:                          "ClientLogon",
+	EMsgClientItemAnnouncements:              "ClientItemAnnouncements",
+	EMsgClientRequestItemAnnouncements:       "ClientRequestItemAnnouncements",
+	EMsgClientFriendMsgEchoToSender:          "ClientFriendMsgEchoToSender",
+	EMsgClientPersonaChangeResponse:          "ClientPersonaChangeResponse",
+	EMsgClientUserNotifications:              "ClientUserNotifications",
+	EMsgClientToGC:                           "ClientToGC",
+	EMsgClientFromGC:                         "ClientFromGC",
+	EMsgServiceMethodResponse:                "ServiceMethodResponse",
+	EMsgServiceMethodCallFromClient:          "ServiceMethodCallFromClient",
+	EMsgServiceMethodSendToClient:            "ServiceMethodSendToClient",
+	EMsgServiceMethodCallFromClientNonAuthed: "ServiceMethodCallFromClientNonAuthed",
+	EMsgClientHello:                          "ClientHello",
 }
 
 func (e EMsg) String() string {
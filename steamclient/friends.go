@@ -52,7 +52,8 @@ type RelationshipEvent struct {
 // AddFriend sends a friend request to the given Steam user.
 // It returns the server's response containing the result and persona name.
 func (c *Client) AddFriend(ctx context.Context, target steamid.SteamID) (*protocol.CMsgClientAddFriendResponse, error) {
-	responseCh := c.expectEMsg(EMsgClientAddFriendResponse)
+	waiter := c.Expect(EMsgClientAddFriendResponse)
+	defer waiter.Cancel()
 
 	sid := target.ToSteamID64()
 	body, err := proto.Marshal(&protocol.CMsgClientAddFriend{
@@ -66,7 +67,7 @@ func (c *Client) AddFriend(ctx context.Context, target steamid.SteamID) (*protoc
 		return nil, fmt.Errorf("send AddFriend: %w", err)
 	}
 
-	pkt, err := c.awaitPacket(ctx, responseCh)
+	pkt, err := waiter.Wait(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("wait for AddFriend response: %w", err)
 	}
@@ -104,7 +105,8 @@ func (c *Client) RemoveFriend(ctx context.Context, target steamid.SteamID) error
 // IgnoreFriend blocks or unblocks a Steam user. This uses the legacy non-protobuf
 // wire format (MsgClientSetIgnoreFriend).
 func (c *Client) IgnoreFriend(ctx context.Context, target steamid.SteamID, ignore bool) error {
-	responseCh := c.expectEMsg(EMsgClientSetIgnoreFriendResponse)
+	waiter := c.Expect(EMsgClientSetIgnoreFriendResponse)
+	defer waiter.Cancel()
 
 	body := encodeIgnoreFriendBody(c.steamID, target, ignore)
 
@@ -112,7 +114,7 @@ func (c *Client) IgnoreFriend(ctx context.Context, target steamid.SteamID, ignor
 		return fmt.Errorf("send SetIgnoreFriend: %w", err)
 	}
 
-	pkt, err := c.awaitPacket(ctx, responseCh)
+	pkt, err := waiter.Wait(ctx)
 	if err != nil {
 		return fmt.Errorf("wait for SetIgnoreFriend response: %w", err)
 	}
@@ -168,12 +170,13 @@ func (c *Client) sendNonProtoPacket(ctx context.Context, emsg EMsg, body []byte)
 		Body: body,
 	}
 
-	data, err := encodePacket(pkt)
+	data, err := EncodePacket(pkt)
 	if err != nil {
 		return fmt.Errorf("encode %s: %w", emsg, err)
 	}
 
-	return c.conn.Write(ctx, data)
+	conn, _ := c.connection()
+	return conn.Write(ctx, data)
 }
 
 // DecodeFriendsList unmarshals a CMsgClientFriendsList from an EMsgClientFriendsList packet.
@@ -29,5 +29,9 @@ func (c *Client) SetGamesPlayed(ctx context.Context, appIDs []uint32) error {
 		return fmt.Errorf("send GamesPlayed: %w", err)
 	}
 
+	c.mu.Lock()
+	c.gamesPlayed = append([]uint32{}, appIDs...)
+	c.mu.Unlock()
+
 	return nil
 }
@@ -24,7 +24,7 @@ func WithGCMessageHandler(fn func(*GCMessage)) Option {
 
 // SendGCMessage sends a message to a Game Coordinator.
 func (c *Client) SendGCMessage(ctx context.Context, appID, msgType uint32, isProto bool, body []byte) error {
-	payload, err := encodeGCPayload(&GCMessage{
+	payload, err := EncodeGCPayload(&GCMessage{
 		AppID:   appID,
 		MsgType: msgType,
 		IsProto: isProto,
@@ -68,7 +68,7 @@ func (c *Client) handleGCMessage(pkt *Packet) {
 		return
 	}
 
-	gcMsg, err := decodeGCPayload(msg.GetAppid(), msg.GetMsgtype(), msg.GetPayload())
+	gcMsg, err := DecodeGCPayload(msg.GetAppid(), msg.GetMsgtype(), msg.GetPayload())
 	if err != nil {
 		c.logger.Error("decode GC payload", "err", err, "appid", msg.GetAppid())
 		return
@@ -77,7 +77,7 @@ func (c *Client) handleGCMessage(pkt *Packet) {
 	c.OnGCMessage(gcMsg)
 }
 
-// encodeGCPayload encodes a GC message body with the appropriate GC header.
+// EncodeGCPayload encodes a GC message body with the appropriate GC header.
 //
 // Protobuf format:
 //
@@ -86,7 +86,7 @@ func (c *Client) handleGCMessage(pkt *Packet) {
 // Binary format:
 //
 //	[version=1 : u16 LE][targetJob : u64 LE][sourceJob : u64 LE][body]
-func encodeGCPayload(msg *GCMessage) ([]byte, error) {
+func EncodeGCPayload(msg *GCMessage) ([]byte, error) {
 	if msg.IsProto {
 		return encodeGCProtoPayload(msg)
 	}
@@ -120,11 +120,11 @@ func encodeGCBinaryPayload(msg *GCMessage) []byte {
 	return buf
 }
 
-// decodeGCPayload parses a GC payload, strips the GC header, and returns the body.
+// DecodeGCPayload parses a GC payload, strips the GC header, and returns the body.
 // The rawMsgType from CMsgGCClient.Msgtype determines whether the inner payload
 // uses proto or binary framing: proto payloads include the msgType in the inner
 // header, while binary payloads do not.
-func decodeGCPayload(appID, rawMsgType uint32, payload []byte) (*GCMessage, error) {
+func DecodeGCPayload(appID, rawMsgType uint32, payload []byte) (*GCMessage, error) {
 	isProto := rawMsgType&ProtoMask != 0
 	msgType := rawMsgType &^ ProtoMask
 
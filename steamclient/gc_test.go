@@ -13,14 +13,14 @@ import (
 func makeGCPacket(t *testing.T, appID, msgType uint32, isProto bool, body []byte) *Packet {
 	t.Helper()
 
-	payload, err := encodeGCPayload(&GCMessage{
+	payload, err := EncodeGCPayload(&GCMessage{
 		AppID:   appID,
 		MsgType: msgType,
 		IsProto: isProto,
 		Body:    body,
 	})
 	if err != nil {
-		t.Fatalf("encodeGCPayload: %v", err)
+		t.Fatalf("EncodeGCPayload: %v", err)
 	}
 
 	gcBody, err := proto.Marshal(&protocol.CMsgGCClient{
@@ -48,12 +48,12 @@ func TestGCEncodeDecodeProtoPayload(t *testing.T) {
 		Body:    []byte{0x08, 0x01}, // some proto bytes
 	}
 
-	payload, err := encodeGCPayload(original)
+	payload, err := EncodeGCPayload(original)
 	if err != nil {
 		t.Fatalf("encode: %v", err)
 	}
 
-	decoded, err := decodeGCPayload(original.AppID, original.MsgType|ProtoMask, payload)
+	decoded, err := DecodeGCPayload(original.AppID, original.MsgType|ProtoMask, payload)
 	if err != nil {
 		t.Fatalf("decode: %v", err)
 	}
@@ -80,12 +80,12 @@ func TestGCEncodeDecodeBinaryPayload(t *testing.T) {
 		Body:    []byte{0xDE, 0xAD, 0xBE, 0xEF},
 	}
 
-	payload, err := encodeGCPayload(original)
+	payload, err := EncodeGCPayload(original)
 	if err != nil {
 		t.Fatalf("encode: %v", err)
 	}
 
-	decoded, err := decodeGCPayload(original.AppID, original.MsgType, payload)
+	decoded, err := DecodeGCPayload(original.AppID, original.MsgType, payload)
 	if err != nil {
 		t.Fatalf("decode: %v", err)
 	}
@@ -105,7 +105,7 @@ func TestGCEncodeDecodeBinaryPayload(t *testing.T) {
 }
 
 func TestGCDecodePayloadTooShort(t *testing.T) {
-	_, err := decodeGCPayload(440, 4004, []byte{0x01, 0x02})
+	_, err := DecodeGCPayload(440, 4004, []byte{0x01, 0x02})
 	if err == nil {
 		t.Fatal("expected error for payload under 4 bytes")
 	}
@@ -181,7 +181,7 @@ func TestGCSendGCMessage(t *testing.T) {
 	sentData := <-mc.writeCh
 
 	// Decode the outer CM packet.
-	sentPkt, err := decodePacket(sentData)
+	sentPkt, err := DecodePacket(sentData)
 	if err != nil {
 		t.Fatalf("decode sent packet: %v", err)
 	}
@@ -206,7 +206,7 @@ func TestGCSendGCMessage(t *testing.T) {
 	}
 
 	// Decode the inner GC payload and verify the body.
-	gcMsg, err := decodeGCPayload(gcClient.GetAppid(), gcClient.GetMsgtype(), gcClient.GetPayload())
+	gcMsg, err := DecodeGCPayload(gcClient.GetAppid(), gcClient.GetMsgtype(), gcClient.GetPayload())
 	if err != nil {
 		t.Fatalf("decode inner GC payload: %v", err)
 	}
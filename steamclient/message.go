@@ -19,7 +19,7 @@ type Packet struct {
 	Body    []byte // raw serialized protobuf body
 }
 
-// encodePacket serializes a Packet to the CM wire format.
+// EncodePacket serializes a Packet to the CM wire format.
 //
 // Protobuf wire format:
 //
@@ -30,7 +30,7 @@ type Packet struct {
 //	[EMsg : uint32 LE][header_size=36 : byte][header_version=2 : uint16 LE]
 //	[target_job_id : uint64 LE][source_job_id : uint64 LE]
 //	[canary=0xEF : byte][steam_id : uint64 LE][session_id : int32 LE][body]
-func encodePacket(p *Packet) ([]byte, error) {
+func EncodePacket(p *Packet) ([]byte, error) {
 	if p.IsProto {
 		return encodeProtoPacket(p)
 	}
@@ -80,8 +80,8 @@ func encodeNonProtoPacket(p *Packet) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// decodePacket deserializes raw CM wire bytes into a Packet.
-func decodePacket(data []byte) (*Packet, error) {
+// DecodePacket deserializes raw CM wire bytes into a Packet.
+func DecodePacket(data []byte) (*Packet, error) {
 	if len(data) < 4 {
 		return nil, fmt.Errorf("packet too short: %d bytes", len(data))
 	}
@@ -173,7 +173,7 @@ func decodeMulti(body []byte, sizeUnzipped uint32) ([]*Packet, error) {
 			return nil, fmt.Errorf("read sub-message body: %w", err)
 		}
 
-		pkt, err := decodePacket(subData)
+		pkt, err := DecodePacket(subData)
 		if err != nil {
 			return nil, fmt.Errorf("decode sub-message: %w", err)
 		}
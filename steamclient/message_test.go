@@ -28,7 +28,7 @@ func TestEncodeDecodeProtoPacket(t *testing.T) {
 		Body:    body,
 	}
 
-	encoded, err := encodePacket(original)
+	encoded, err := EncodePacket(original)
 	if err != nil {
 		t.Fatalf("encode: %v", err)
 	}
@@ -42,7 +42,7 @@ func TestEncodeDecodeProtoPacket(t *testing.T) {
 		t.Errorf("EMsg mismatch: got %d, want %d", rawEMsg&^ProtoMask, EMsgClientHeartBeat)
 	}
 
-	decoded, err := decodePacket(encoded)
+	decoded, err := DecodePacket(encoded)
 	if err != nil {
 		t.Fatalf("decode: %v", err)
 	}
@@ -68,7 +68,7 @@ func TestEncodeDecodeNonProtoPacket(t *testing.T) {
 		Body:    []byte{0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
 	}
 
-	encoded, err := encodePacket(original)
+	encoded, err := EncodePacket(original)
 	if err != nil {
 		t.Fatalf("encode: %v", err)
 	}
@@ -79,7 +79,7 @@ func TestEncodeDecodeNonProtoPacket(t *testing.T) {
 		t.Error("ProtoMask unexpectedly set for non-proto packet")
 	}
 
-	decoded, err := decodePacket(encoded)
+	decoded, err := DecodePacket(encoded)
 	if err != nil {
 		t.Fatalf("decode: %v", err)
 	}
@@ -150,7 +150,7 @@ func buildProtoPacket(t *testing.T, emsg EMsg, hdr *protocol.CMsgProtoBufHeader)
 		Header:  hdr,
 		Body:    nil,
 	}
-	data, err := encodePacket(pkt)
+	data, err := EncodePacket(pkt)
 	if err != nil {
 		t.Fatalf("buildProtoPacket: %v", err)
 	}
@@ -93,6 +93,10 @@ func (c *Client) SetPersonaState(ctx context.Context, state PersonaState) error
 		return fmt.Errorf("send ChangeStatus: %w", err)
 	}
 
+	c.mu.Lock()
+	c.personaState = &state
+	c.mu.Unlock()
+
 	return nil
 }
 
@@ -3,7 +3,12 @@ package steamclient
 import (
 	"context"
 	"errors"
-	"sync"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/k64z/steamstacks/eresult"
+	"github.com/k64z/steamstacks/steamid"
 )
 
 // ErrDisconnected is returned by awaitPacket when the connection is closed.
@@ -16,7 +21,7 @@ type DisconnectEvent struct {
 	// ServerInitiated is true when the server sent EMsgClientLoggedOff.
 	ServerInitiated bool
 	// EResult is the server's reason code (only meaningful when ServerInitiated is true).
-	EResult int32
+	EResult eresult.EResult
 }
 
 // WithDisconnectHandler sets a callback that fires when the connection drops.
@@ -26,39 +31,234 @@ func WithDisconnectHandler(fn func(*DisconnectEvent)) Option {
 
 // fireDisconnect invokes the OnDisconnect callback at most once per connection lifecycle.
 // The callback runs in a new goroutine so the caller can safely call Reconnect.
+// When automatic reconnects are enabled and the drop is retryable, the
+// reconnect supervisor is started as well.
 func (c *Client) fireDisconnect(evt *DisconnectEvent) {
-	c.disconnectOnce.Do(func() {
+	var ctx context.Context
+	c.mu.Lock()
+	if c.disconnected {
+		c.mu.Unlock()
+		return
+	}
+	c.disconnected = true
+	c.loggedIn = false
+	if c.reconnect != nil && !c.reconnecting && evt.Retryable() {
+		c.reconnecting = true
+		ctx, c.stopReconnect = context.WithCancel(context.Background())
+	}
+	c.mu.Unlock()
+
+	if c.OnDisconnect != nil {
+		go c.OnDisconnect(evt)
+	}
+	if ctx != nil {
+		go c.superviseReconnect(ctx, evt)
+	}
+}
+
+// retryableLogOffResults lists the ClientLoggedOff reasons after which the
+// reconnect supervisor logs in again. Anything else (LoggedInElsewhere,
+// Revoked, AccessDenied, ...) needs the caller's attention.
+var retryableLogOffResults = map[eresult.EResult]bool{
+	eresult.Fail:               true,
+	eresult.NoConnection:       true,
+	eresult.Busy:               true,
+	eresult.Timeout:            true,
+	eresult.ServiceUnavailable: true,
+	eresult.ConnectFailed:      true,
+	eresult.HandshakeFailed:    true,
+	eresult.IOFailure:          true,
+	eresult.RemoteDisconnect:   true,
+	eresult.TryAnotherCM:       true,
+}
+
+// Retryable reports whether the reconnect supervisor should try to restore
+// the session after this disconnect. Transport errors are always retryable;
+// server-initiated logoffs only for transient EResults.
+func (e *DisconnectEvent) Retryable() bool {
+	if !e.ServerInitiated {
+		return true
+	}
+	return retryableLogOffResults[e.EResult]
+}
+
+// ReconnectPolicy configures the automatic reconnect supervisor.
+// Zero fields fall back to the defaults documented on each field.
+type ReconnectPolicy struct {
+	// InitialBackoff is the delay before the first attempt (default 1s).
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts (default 2m).
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after each failed attempt (default 2).
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction, in [0, 1] (default 0.2).
+	Jitter float64
+	// MaxAttempts stops the supervisor after this many failures (0 = unlimited).
+	MaxAttempts int
+}
+
+// backoff returns the jittered delay before the given attempt (1-based).
+func (p ReconnectPolicy) backoff(attempt int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = time.Second
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 2 * time.Minute
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+	jitter := p.Jitter
+	if jitter <= 0 || jitter > 1 {
+		jitter = 0.2
+	}
+
+	d := float64(initial)
+	for i := 1; i < attempt && d < float64(maxBackoff); i++ {
+		d *= mult
+	}
+	d = min(d, float64(maxBackoff))
+	d += d * jitter * (2*rand.Float64() - 1)
+	return time.Duration(d)
+}
+
+// ReconnectedEvent is fired when the supervisor has restored the session.
+type ReconnectedEvent struct {
+	Attempts int             // number of attempts it took, starting at 1
+	Downtime time.Duration   // time since the disconnect
+	SteamID  steamid.SteamID // SteamID after the new logon
+}
+
+// ReconnectFailedEvent is fired when the supervisor gives up.
+type ReconnectFailedEvent struct {
+	Attempts int
+	Err      error // error from the last attempt
+}
+
+// WithAutoReconnect enables the reconnect supervisor. After a transport error
+// or a retryable ClientLoggedOff it reconnects with jittered exponential
+// backoff, logs in again with the stored refresh token and restores the
+// persona state and games played before firing OnReconnected.
+func WithAutoReconnect(p ReconnectPolicy) Option {
+	return func(c *config) { c.reconnect = &p }
+}
+
+// WithReconnectedHandler sets a callback for successful automatic reconnects.
+func WithReconnectedHandler(fn func(*ReconnectedEvent)) Option {
+	return func(c *config) { c.onReconnected = fn }
+}
+
+// WithReconnectFailedHandler sets a callback for when the reconnect supervisor gives up.
+func WithReconnectFailedHandler(fn func(*ReconnectFailedEvent)) Option {
+	return func(c *config) { c.onReconnectFailed = fn }
+}
+
+// superviseReconnect retries restoreSession until it succeeds, the policy's
+// attempt limit is hit, or ctx is cancelled by Disconnect.
+func (c *Client) superviseReconnect(ctx context.Context, evt *DisconnectEvent) {
+	defer func() {
 		c.mu.Lock()
-		c.loggedIn = false
+		c.reconnecting = false
+		if c.stopReconnect != nil {
+			c.stopReconnect()
+			c.stopReconnect = nil
+		}
 		c.mu.Unlock()
-		if c.OnDisconnect != nil {
-			go c.OnDisconnect(evt)
+	}()
+
+	start := time.Now()
+	var lastErr error
+	for attempt := 1; c.reconnect.MaxAttempts == 0 || attempt <= c.reconnect.MaxAttempts; attempt++ {
+		delay := c.reconnect.backoff(attempt)
+		c.logger.Info("reconnecting", "attempt", attempt, "delay", delay, "reason", evt.Err, "eresult", evt.EResult)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		lastErr = c.restoreSession(ctx)
+		if lastErr == nil {
+			c.logger.Info("reconnected", "attempt", attempt, "steamid", c.SteamID().String())
+			if c.OnReconnected != nil {
+				c.OnReconnected(&ReconnectedEvent{
+					Attempts: attempt,
+					Downtime: time.Since(start),
+					SteamID:  c.SteamID(),
+				})
+			}
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		c.logger.Warn("reconnect attempt failed", "attempt", attempt, "err", lastErr)
+	}
+
+	c.logger.Error("giving up reconnecting", "attempts", c.reconnect.MaxAttempts, "err", lastErr)
+	if c.OnReconnectFailed != nil {
+		c.OnReconnectFailed(&ReconnectFailedEvent{
+			Attempts: c.reconnect.MaxAttempts,
+			Err:      lastErr,
+		})
+	}
+}
+
+// restoreSession reconnects, logs in with the stored refresh token and
+// replays the last persona state and games played.
+func (c *Client) restoreSession(ctx context.Context) error {
+	if err := c.Reconnect(ctx); err != nil {
+		return fmt.Errorf("reconnect: %w", err)
+	}
+
+	c.mu.Lock()
+	accountName, refreshToken, sid := c.accountName, c.refreshToken, c.loginSteamID
+	persona, games := c.personaState, c.gamesPlayed
+	c.mu.Unlock()
+
+	if refreshToken == "" {
+		return nil // never logged in; the transport is all there is to restore
+	}
+
+	if err := c.Login(ctx, accountName, refreshToken, sid); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+
+	if persona != nil {
+		if err := c.SetPersonaState(ctx, *persona); err != nil {
+			return fmt.Errorf("restore persona state: %w", err)
+		}
+	}
+	if games != nil {
+		if err := c.SetGamesPlayed(ctx, games); err != nil {
+			return fmt.Errorf("restore games played: %w", err)
 		}
-	})
+	}
+
+	return nil
 }
 
 // Reconnect tears down the existing connection and establishes a new one.
 // After Reconnect returns successfully the caller should call Login again.
 func (c *Client) Reconnect(ctx context.Context) error {
-	// Signal goroutines to stop (safe if already closed).
-	c.closeOnce.Do(func() { close(c.done) })
-
-	// Close transport to unblock pending I/O.
-	if c.conn != nil {
-		c.conn.Close()
-	}
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
 
-	// Wait for readLoop + heartbeatLoop to finish.
+	// Signal goroutines to stop and close the transport to unblock
+	// pending I/O, then wait for readLoop + heartbeatLoop to finish.
+	c.closeConn()
 	c.wg.Wait()
 
-	// Reset sync primitives for new connection cycle.
-	c.closeOnce = sync.Once{}
-	c.disconnectOnce = sync.Once{}
-	c.done = make(chan struct{})
 	c.mu.Lock()
 	c.loggedIn = false
 	c.mu.Unlock()
 
-	// Establish new connection (overwrites c.done, starts new readLoop).
-	return c.Connect(ctx)
+	// Establish new connection (replaces c.done, starts new readLoop).
+	return c.connect(ctx)
 }
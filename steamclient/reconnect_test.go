@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -164,3 +165,280 @@ func TestAwaitPacketReturnsOnDone(t *testing.T) {
 		t.Errorf("err = %v, want %v", err, ErrDisconnected)
 	}
 }
+
+// fakeCM is a scriptable Connection that answers ClientLogon with a
+// successful ClientLogOnResponse and records every other packet it receives.
+type fakeCM struct {
+	readCh chan []byte
+	sent   chan *Packet
+	once   sync.Once
+	closed chan struct{}
+}
+
+func newFakeCM() *fakeCM {
+	return &fakeCM{
+		readCh: make(chan []byte, 8),
+		sent:   make(chan *Packet, 16),
+		closed: make(chan struct{}),
+	}
+}
+
+func (f *fakeCM) Write(_ context.Context, data []byte) error {
+	pkt, err := DecodePacket(data)
+	if err != nil {
+		return err
+	}
+	if pkt.EMsg == EMsgClientLogon {
+		body, _ := proto.Marshal(&protocol.CMsgClientLogonResponse{
+			Eresult:          proto.Int32(1),
+			HeartbeatSeconds: proto.Int32(60),
+		})
+		resp, _ := EncodePacket(&Packet{
+			EMsg:    EMsgClientLogOnResponse,
+			IsProto: true,
+			Header: &protocol.CMsgProtoBufHeader{
+				Steamid:         proto.Uint64(76561197960287930),
+				ClientSessionid: proto.Int32(1234),
+			},
+			Body: body,
+		})
+		f.readCh <- resp
+	}
+	f.sent <- pkt
+	return nil
+}
+
+func (f *fakeCM) Read(ctx context.Context) ([]byte, error) {
+	select {
+	case data := <-f.readCh:
+		return data, nil
+	case <-f.closed:
+		return nil, errors.New("connection reset")
+	}
+}
+
+func (f *fakeCM) Close() error {
+	f.once.Do(func() { close(f.closed) })
+	return nil
+}
+
+func (f *fakeCM) RemoteAddr() string { return "fake" }
+
+// waitSent returns the first packet with the given EMsg written to f.
+func (f *fakeCM) waitSent(t *testing.T, emsg EMsg) *Packet {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case pkt := <-f.sent:
+			if pkt.EMsg == emsg {
+				return pkt
+			}
+		case <-timeout:
+			t.Fatalf("%s was not sent within 2s", emsg)
+			return nil
+		}
+	}
+}
+
+func TestAutoReconnectRestoresSession(t *testing.T) {
+	conns := make(chan *fakeCM, 2)
+	reconnected := make(chan *ReconnectedEvent, 1)
+
+	c := New(
+		WithDialer(func(context.Context) (Connection, error) {
+			f := newFakeCM()
+			conns <- f
+			return f, nil
+		}),
+		WithAutoReconnect(ReconnectPolicy{InitialBackoff: time.Millisecond}),
+		WithReconnectedHandler(func(evt *ReconnectedEvent) { reconnected <- evt }),
+	)
+
+	ctx := context.Background()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	first := <-conns
+	if err := c.Login(ctx, "user", "refresh-token", 76561197960287930); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if err := c.SetPersonaState(ctx, PersonaStateLookingToTrade); err != nil {
+		t.Fatalf("SetPersonaState: %v", err)
+	}
+	if err := c.SetGamesPlayed(ctx, []uint32{440}); err != nil {
+		t.Fatalf("SetGamesPlayed: %v", err)
+	}
+
+	// Drop the connection.
+	first.Close()
+
+	var second *fakeCM
+	select {
+	case second = <-conns:
+	case <-time.After(2 * time.Second):
+		t.Fatal("supervisor did not redial within 2s")
+	}
+
+	logon := second.waitSent(t, EMsgClientLogon)
+	var logonMsg protocol.CMsgClientLogon
+	if err := proto.Unmarshal(logon.Body, &logonMsg); err != nil {
+		t.Fatalf("unmarshal logon: %v", err)
+	}
+	if logonMsg.GetAccessToken() != "refresh-token" {
+		t.Errorf("AccessToken = %q, want %q", logonMsg.GetAccessToken(), "refresh-token")
+	}
+
+	status := second.waitSent(t, EMsgClientChangeStatus)
+	var statusMsg protocol.CMsgClientChangeStatus
+	if err := proto.Unmarshal(status.Body, &statusMsg); err != nil {
+		t.Fatalf("unmarshal status: %v", err)
+	}
+	if PersonaState(statusMsg.GetPersonaState()) != PersonaStateLookingToTrade {
+		t.Errorf("PersonaState = %d, want %d", statusMsg.GetPersonaState(), PersonaStateLookingToTrade)
+	}
+
+	games := second.waitSent(t, EMsgClientGamesPlayed)
+	var gamesMsg protocol.CMsgClientGamesPlayed
+	if err := proto.Unmarshal(games.Body, &gamesMsg); err != nil {
+		t.Fatalf("unmarshal games: %v", err)
+	}
+	if len(gamesMsg.GetGamesPlayed()) != 1 || gamesMsg.GetGamesPlayed()[0].GetGameId() != 440 {
+		t.Errorf("GamesPlayed = %v, want [440]", gamesMsg.GetGamesPlayed())
+	}
+
+	select {
+	case evt := <-reconnected:
+		if evt.Attempts != 1 {
+			t.Errorf("Attempts = %d, want 1", evt.Attempts)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnReconnected was not called within 2s")
+	}
+
+	c.Disconnect()
+}
+
+func TestDisconnectDuringAutoReconnect(t *testing.T) {
+	dials := make(chan struct{}, 2)
+	var dialed atomic.Int32
+	first := newFakeCM()
+	c := New(
+		WithDialer(func(ctx context.Context) (Connection, error) {
+			dials <- struct{}{}
+			if dialed.Add(1) == 1 {
+				return first, nil
+			}
+			// The redial hangs until Disconnect stops the supervisor.
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+		WithAutoReconnect(ReconnectPolicy{InitialBackoff: time.Millisecond}),
+	)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	<-dials
+
+	first.Close()
+	select {
+	case <-dials:
+	case <-time.After(2 * time.Second):
+		t.Fatal("supervisor did not redial within 2s")
+	}
+
+	disconnected := make(chan struct{})
+	go func() {
+		c.Disconnect()
+		close(disconnected)
+	}()
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Disconnect did not return within 2s")
+	}
+}
+
+func TestAutoReconnectSkipsNonRetryableLogOff(t *testing.T) {
+	dials := make(chan struct{}, 2)
+	c := New(
+		WithDialer(func(context.Context) (Connection, error) {
+			dials <- struct{}{}
+			return newFakeCM(), nil
+		}),
+		WithAutoReconnect(ReconnectPolicy{InitialBackoff: time.Millisecond}),
+	)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	<-dials
+
+	body, _ := proto.Marshal(&protocol.CMsgClientLoggedOff{Eresult: proto.Int32(6)}) // LoggedInElsewhere
+	c.handlePacket(&Packet{
+		EMsg:    EMsgClientLoggedOff,
+		IsProto: true,
+		Header:  &protocol.CMsgProtoBufHeader{},
+		Body:    body,
+	})
+
+	select {
+	case <-dials:
+		t.Fatal("supervisor redialed after non-retryable logoff")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAutoReconnectGivesUp(t *testing.T) {
+	failed := make(chan *ReconnectFailedEvent, 1)
+	dialErr := errors.New("dial refused")
+	c := New(
+		WithDialer(func(context.Context) (Connection, error) { return nil, dialErr }),
+		WithAutoReconnect(ReconnectPolicy{InitialBackoff: time.Millisecond, MaxAttempts: 3}),
+		WithReconnectFailedHandler(func(evt *ReconnectFailedEvent) { failed <- evt }),
+	)
+	c.conn = newFakeCM()
+	c.done = make(chan struct{})
+
+	c.fireDisconnect(&DisconnectEvent{Err: errors.New("connection reset")})
+
+	select {
+	case evt := <-failed:
+		if evt.Attempts != 3 {
+			t.Errorf("Attempts = %d, want 3", evt.Attempts)
+		}
+		if !errors.Is(evt.Err, dialErr) {
+			t.Errorf("Err = %v, want %v", evt.Err, dialErr)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnReconnectFailed was not called within 2s")
+	}
+}
+
+func TestReconnectPolicyBackoff(t *testing.T) {
+	p := ReconnectPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0.1,
+	}
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	}
+
+	for _, tt := range tests {
+		got := p.backoff(tt.attempt)
+		lo := time.Duration(float64(tt.base) * 0.9)
+		hi := time.Duration(float64(tt.base) * 1.1)
+		if got < lo || got > hi {
+			t.Errorf("backoff(%d) = %v, want within [%v, %v]", tt.attempt, got, lo, hi)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package steamclient
+
+import (
+	"context"
+	"sync"
+)
+
+// subscriptionBuffer is the channel capacity of a Subscribe channel. Packets
+// arriving while the buffer is full are dropped so that a slow subscriber
+// cannot stall the read loop.
+const subscriptionBuffer = 64
+
+// router dispatches decoded packets to any number of handlers per key
+// (an EMsg, or a service method name for notifications).
+type router[K comparable] struct {
+	mu       sync.Mutex
+	nextID   uint64
+	handlers map[K][]route
+}
+
+type route struct {
+	id uint64
+	fn func(*Packet)
+}
+
+// add registers fn for key and returns a function that removes it.
+// Removing is idempotent.
+func (r *router[K]) add(key K, fn func(*Packet)) (remove func()) {
+	r.mu.Lock()
+	if r.handlers == nil {
+		r.handlers = make(map[K][]route)
+	}
+	r.nextID++
+	id := r.nextID
+	r.handlers[key] = append(r.handlers[key], route{id: id, fn: fn})
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		routes := r.handlers[key]
+		for i, rt := range routes {
+			if rt.id == id {
+				r.handlers[key] = append(routes[:i:i], routes[i+1:]...)
+				break
+			}
+		}
+		if len(r.handlers[key]) == 0 {
+			delete(r.handlers, key)
+		}
+	}
+}
+
+// dispatch calls every handler registered for key, in registration order.
+// Handlers run outside the lock so they may add or remove routes.
+func (r *router[K]) dispatch(key K, pkt *Packet) {
+	r.mu.Lock()
+	routes := r.handlers[key]
+	r.mu.Unlock()
+
+	for _, rt := range routes {
+		rt.fn(pkt)
+	}
+}
+
+// route registers an internal handler for emsg.
+func (c *Client) route(emsg EMsg, fn func(*Packet)) {
+	c.routes.add(emsg, fn)
+}
+
+// Subscribe returns a channel that receives every packet with the given EMsg
+// until cancel is called, which also closes the channel. The channel is
+// buffered; packets that arrive while it is full are dropped and logged.
+func (c *Client) Subscribe(emsg EMsg) (<-chan *Packet, func()) {
+	ch := make(chan *Packet, subscriptionBuffer)
+
+	var mu sync.Mutex
+	closed := false
+
+	remove := c.routes.add(emsg, func(pkt *Packet) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- pkt:
+		default:
+			c.logger.Warn("subscriber too slow, dropping packet", "emsg", emsg)
+		}
+	})
+
+	cancel := func() {
+		remove()
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+
+	return ch, cancel
+}
+
+// Waiter is a one-shot listener for a single packet, created by Expect.
+type Waiter struct {
+	c      *Client
+	ch     chan *Packet
+	remove func()
+}
+
+// Expect registers a one-shot waiter for the next packet with the given EMsg.
+// Call it BEFORE sending the request the packet answers, to avoid racing
+// with the read loop, then call Wait. Any number of waiters may be pending
+// for the same EMsg; a packet is delivered to all of them, so each gets the
+// first matching packet that arrives after it was registered.
+func (c *Client) Expect(emsg EMsg) *Waiter {
+	w := &Waiter{c: c, ch: make(chan *Packet, 1)}
+
+	var once sync.Once
+	w.remove = c.routes.add(emsg, func(pkt *Packet) {
+		once.Do(func() { w.ch <- pkt })
+	})
+
+	return w
+}
+
+// Wait blocks until the packet arrives, ctx expires, or the connection
+// closes. The waiter is unregistered when Wait returns.
+func (w *Waiter) Wait(ctx context.Context) (*Packet, error) {
+	defer w.remove()
+	return w.c.awaitPacket(ctx, w.ch)
+}
+
+// Cancel unregisters the waiter without waiting.
+func (w *Waiter) Cancel() {
+	w.remove()
+}
@@ -0,0 +1,151 @@
+package steamclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k64z/steamstacks/protocol"
+)
+
+func testPacket(emsg EMsg) *Packet {
+	return &Packet{EMsg: emsg, IsProto: true, Header: &protocol.CMsgProtoBufHeader{}}
+}
+
+func TestSubscribeMultipleSubscribers(t *testing.T) {
+	c := New()
+	c.done = make(chan struct{})
+
+	ch1, cancel1 := c.Subscribe(EMsgClientAddFriendResponse)
+	defer cancel1()
+	ch2, cancel2 := c.Subscribe(EMsgClientAddFriendResponse)
+	defer cancel2()
+
+	pkt := testPacket(EMsgClientAddFriendResponse)
+	c.handlePacket(pkt)
+	c.handlePacket(testPacket(EMsgClientSessionToken)) // different EMsg, ignored
+
+	for i, ch := range []<-chan *Packet{ch1, ch2} {
+		select {
+		case got := <-ch:
+			if got != pkt {
+				t.Errorf("subscriber %d got wrong packet", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("subscriber %d did not receive packet", i)
+		}
+		select {
+		case got := <-ch:
+			t.Errorf("subscriber %d got unexpected packet %s", i, got.EMsg)
+		default:
+		}
+	}
+}
+
+func TestSubscribeCancelClosesChannel(t *testing.T) {
+	c := New()
+	c.done = make(chan struct{})
+
+	ch, cancel := c.Subscribe(EMsgClientAddFriendResponse)
+	cancel()
+	cancel() // idempotent
+
+	if _, ok := <-ch; ok {
+		t.Error("channel should be closed after cancel")
+	}
+
+	// Dispatching after cancel must not panic.
+	c.handlePacket(testPacket(EMsgClientAddFriendResponse))
+
+	if n := len(c.routes.handlers[EMsgClientAddFriendResponse]); n != 0 {
+		t.Errorf("%d routes left after cancel, want 0", n)
+	}
+}
+
+func TestExpectConcurrentWaiters(t *testing.T) {
+	c := New()
+	c.done = make(chan struct{})
+
+	const n = 5
+	waiters := make([]*Waiter, n)
+	for i := range waiters {
+		waiters[i] = c.Expect(EMsgClientAddFriendResponse)
+	}
+
+	pkt := testPacket(EMsgClientAddFriendResponse)
+	c.handlePacket(pkt)
+
+	var wg sync.WaitGroup
+	for i, w := range waiters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			got, err := w.Wait(ctx)
+			if err != nil {
+				t.Errorf("waiter %d: %v", i, err)
+				return
+			}
+			if got != pkt {
+				t.Errorf("waiter %d got wrong packet", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := len(c.routes.handlers[EMsgClientAddFriendResponse]); n != 0 {
+		t.Errorf("%d routes left after Wait, want 0", n)
+	}
+}
+
+func TestExpectContextCancelUnregisters(t *testing.T) {
+	c := New()
+	c.done = make(chan struct{})
+
+	w := c.Expect(EMsgClientAddFriendResponse)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := w.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+
+	if n := len(c.routes.handlers[EMsgClientAddFriendResponse]); n != 0 {
+		t.Errorf("%d routes left after cancelled Wait, want 0", n)
+	}
+}
+
+func TestExpectDoesNotReplaceOnPacket(t *testing.T) {
+	var mu sync.Mutex
+	var seen []EMsg
+	c := New(WithPacketHandler(func(pkt *Packet) {
+		mu.Lock()
+		seen = append(seen, pkt.EMsg)
+		mu.Unlock()
+	}))
+	c.done = make(chan struct{})
+
+	userHandler := c.OnPacket
+	w := c.Expect(EMsgClientAddFriendResponse)
+	defer w.Cancel()
+
+	c.handlePacket(testPacket(EMsgClientSessionToken))
+	c.handlePacket(testPacket(EMsgClientAddFriendResponse))
+
+	if _, err := w.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	// OnPacket must still be the user's handler, and must have seen both packets.
+	c.OnPacket(testPacket(EMsgClientHeartBeat))
+	userHandler(testPacket(EMsgClientHeartBeat))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 4 {
+		t.Errorf("OnPacket saw %d packets, want 4", len(seen))
+	}
+}
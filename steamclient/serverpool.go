@@ -0,0 +1,319 @@
+package steamclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k64z/steamstacks/steamhost"
+)
+
+// ServerPool caches the CM server list and tracks the health of each
+// endpoint so Connect can prefer servers that worked recently and skip
+// servers that keep failing. A pool is safe for concurrent use and can be
+// shared between clients via WithServerPool.
+type ServerPool struct {
+	httpClient  *http.Client
+	apiURL      string
+	cellID      uint32
+	ttl         time.Duration
+	cacheFile   string
+	penalty     time.Duration
+	maxPenalty  time.Duration
+	maxFailover int
+	static      bool
+
+	mu        sync.Mutex
+	servers   []CMServer
+	fetchedAt time.Time
+	fetch     *serverFetch // discovery in flight, if any
+	health    map[string]*serverHealth
+}
+
+// serverFetch is a discovery request shared by the callers waiting on it.
+type serverFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// serverHealth is the dial history of a single CM endpoint.
+type serverHealth struct {
+	failures     int           // consecutive dial failures
+	latency      time.Duration // smoothed dial latency, 0 if unknown
+	penaltyUntil time.Time     // not tried again before this time
+}
+
+// ServerPoolOption configures a ServerPool.
+type ServerPoolOption func(*ServerPool)
+
+// WithServerListTTL sets how long a fetched server list is reused (default 30m).
+func WithServerListTTL(d time.Duration) ServerPoolOption {
+	return func(p *ServerPool) { p.ttl = d }
+}
+
+// WithServerListFile persists the fetched server list to path so that a
+// restarted process can skip discovery while the list is still fresh.
+func WithServerListFile(path string) ServerPoolOption {
+	return func(p *ServerPool) { p.cacheFile = path }
+}
+
+// WithServerList seeds the pool with a fixed server list and disables
+// discovery. This is useful for pinning servers or pointing at a test server.
+func WithServerList(servers ...CMServer) ServerPoolOption {
+	return func(p *ServerPool) {
+		p.servers = servers
+		p.static = true
+	}
+}
+
+// WithServerCellID sets the cell ID sent to GetCMListForConnect.
+func WithServerCellID(cellID uint32) ServerPoolOption {
+	return func(p *ServerPool) { p.cellID = cellID }
+}
+
+// WithServerDirectory sets the Web API base URL that GetCMListForConnect is
+// fetched from (default https://api.steampowered.com).
+func WithServerDirectory(apiURL string) ServerPoolOption {
+	return func(p *ServerPool) { p.apiURL = strings.TrimRight(apiURL, "/") }
+}
+
+// WithServerPenalty sets the base and maximum time a failing server is kept
+// out of rotation. The penalty doubles with each consecutive failure
+// (defaults 30s and 30m).
+func WithServerPenalty(base, maxPenalty time.Duration) ServerPoolOption {
+	return func(p *ServerPool) {
+		p.penalty = base
+		p.maxPenalty = maxPenalty
+	}
+}
+
+// WithServerFailover sets how many candidates Connect tries before giving up (default 5).
+func WithServerFailover(n int) ServerPoolOption {
+	return func(p *ServerPool) { p.maxFailover = n }
+}
+
+// NewServerPool creates a server pool that discovers servers with httpClient.
+func NewServerPool(httpClient *http.Client, opts ...ServerPoolOption) *ServerPool {
+	p := &ServerPool{
+		httpClient:  httpClient,
+		apiURL:      steamhost.DefaultAPI,
+		ttl:         30 * time.Minute,
+		penalty:     30 * time.Second,
+		maxPenalty:  30 * time.Minute,
+		maxFailover: 5,
+		health:      make(map[string]*serverHealth),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// serverListFile is the on-disk format written by WithServerListFile.
+type serverListFile struct {
+	CellID    uint32     `json:"cell_id"`
+	FetchedAt time.Time  `json:"fetched_at"`
+	Servers   []CMServer `json:"servers"`
+}
+
+// Servers returns the cached server list, refreshing it from the Steam Web
+// API when it is older than the TTL. If the refresh fails and a stale list is
+// available, the stale list is returned. Concurrent callers share a single
+// refresh.
+func (p *ServerPool) Servers(ctx context.Context) ([]CMServer, error) {
+	p.mu.Lock()
+	if p.servers == nil && p.cacheFile != "" {
+		p.loadFile()
+	}
+	if p.static || p.servers != nil && time.Since(p.fetchedAt) < p.ttl {
+		defer p.mu.Unlock()
+		return p.servers, nil
+	}
+
+	// The request runs without the lock so that health reports and
+	// Candidates on a fresh list aren't held up by it.
+	f := p.fetch
+	if f == nil {
+		f = &serverFetch{done: make(chan struct{})}
+		p.fetch = f
+		p.mu.Unlock()
+		p.refresh(ctx, f)
+	} else {
+		p.mu.Unlock()
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return p.staleOr(ctx.Err())
+		}
+	}
+	return p.staleOr(f.err)
+}
+
+// refresh runs discovery for f and stores the result.
+func (p *ServerPool) refresh(ctx context.Context, f *serverFetch) {
+	servers, err := discoverServers(ctx, p.httpClient, p.apiURL, p.cellID)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err == nil {
+		p.servers = servers
+		p.fetchedAt = time.Now()
+		if p.cacheFile != "" {
+			p.saveFile()
+		}
+	}
+	f.err = err
+	p.fetch = nil
+	close(f.done)
+}
+
+// staleOr returns the cached list, fresh or stale, or err when there is none.
+func (p *ServerPool) staleOr(err error) ([]CMServer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.servers != nil {
+		return p.servers, nil
+	}
+	return nil, err
+}
+
+func (p *ServerPool) loadFile() {
+	data, err := os.ReadFile(p.cacheFile)
+	if err != nil {
+		return
+	}
+
+	var f serverListFile
+	if err := json.Unmarshal(data, &f); err != nil || f.CellID != p.cellID || len(f.Servers) == 0 {
+		return
+	}
+
+	p.servers = f.Servers
+	p.fetchedAt = f.FetchedAt
+}
+
+func (p *ServerPool) saveFile() {
+	data, err := json.Marshal(serverListFile{
+		CellID:    p.cellID,
+		FetchedAt: p.fetchedAt,
+		Servers:   p.servers,
+	})
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(p.cacheFile, data, 0o644)
+}
+
+// Invalidate drops the cached server list so the next call refetches it.
+func (p *ServerPool) Invalidate() {
+	p.mu.Lock()
+	p.fetchedAt = time.Time{}
+	p.mu.Unlock()
+}
+
+// Candidates returns up to the failover limit of servers of the given type
+// ("websockets" or "netfilter"), best first. Servers in the penalty box are
+// only returned when no healthy server is left, soonest-released first.
+func (p *ServerPool) Candidates(ctx context.Context, serverType string) ([]CMServer, error) {
+	servers, err := p.Servers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var healthy, penalized []CMServer
+	for _, s := range servers {
+		if s.Type != serverType {
+			continue
+		}
+		if h := p.health[s.Addr]; h != nil && now.Before(h.penaltyUntil) {
+			penalized = append(penalized, s)
+		} else {
+			healthy = append(healthy, s)
+		}
+	}
+
+	if len(healthy) == 0 && len(penalized) == 0 {
+		return nil, fmt.Errorf("no %s servers found", serverType)
+	}
+
+	// Shuffle first so servers with equal scores share the load.
+	rand.Shuffle(len(healthy), func(i, j int) { healthy[i], healthy[j] = healthy[j], healthy[i] })
+	slices.SortStableFunc(healthy, func(a, b CMServer) int {
+		return p.score(a.Addr) - p.score(b.Addr)
+	})
+	slices.SortStableFunc(penalized, func(a, b CMServer) int {
+		return p.health[a.Addr].penaltyUntil.Compare(p.health[b.Addr].penaltyUntil)
+	})
+
+	candidates := append(healthy, penalized...)
+	if p.maxFailover > 0 && len(candidates) > p.maxFailover {
+		candidates = candidates[:p.maxFailover]
+	}
+	return candidates, nil
+}
+
+// score ranks a server; lower is better. Consecutive failures dominate,
+// then known latency in milliseconds. Unknown servers rank after fast
+// known-good ones but ahead of slow ones.
+func (p *ServerPool) score(addr string) int {
+	h := p.health[addr]
+	if h == nil {
+		return 250
+	}
+	latency := 250
+	if h.latency > 0 {
+		latency = int(h.latency.Milliseconds())
+	}
+	return h.failures*10000 + latency
+}
+
+// ReportSuccess records a successful dial to addr that took latency.
+func (p *ServerPool) ReportSuccess(addr string, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := p.healthFor(addr)
+	h.failures = 0
+	h.penaltyUntil = time.Time{}
+	if h.latency == 0 {
+		h.latency = latency
+	} else {
+		h.latency = (h.latency*7 + latency) / 8
+	}
+}
+
+// ReportFailure records a failed dial to addr and puts it in the penalty box.
+func (p *ServerPool) ReportFailure(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := p.healthFor(addr)
+	h.failures++
+
+	penalty := p.penalty
+	for i := 1; i < h.failures && penalty < p.maxPenalty; i++ {
+		penalty *= 2
+	}
+	penalty = min(penalty, p.maxPenalty)
+	h.penaltyUntil = time.Now().Add(penalty)
+}
+
+func (p *ServerPool) healthFor(addr string) *serverHealth {
+	h := p.health[addr]
+	if h == nil {
+		h = &serverHealth{}
+		p.health[addr] = h
+	}
+	return h
+}
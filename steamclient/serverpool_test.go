@@ -0,0 +1,241 @@
+package steamclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const poolFixture = `{
+	"response": {
+		"serverlist": [
+			{"endpoint": "a.steamserver.net:443", "type": "websockets"},
+			{"endpoint": "b.steamserver.net:443", "type": "websockets"},
+			{"endpoint": "c.steamserver.net:443", "type": "websockets"},
+			{"endpoint": "d.steamserver.net:27017", "type": "netfilter"}
+		]
+	}
+}`
+
+// countingDirectory serves poolFixture and counts requests.
+func countingDirectory(calls *atomic.Int32, gotURL *atomic.Value) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		if gotURL != nil {
+			gotURL.Store(req.URL.String())
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(poolFixture)),
+		}, nil
+	})}
+}
+
+func TestServerPoolCachesWithinTTL(t *testing.T) {
+	var calls atomic.Int32
+	var gotURL atomic.Value
+	p := NewServerPool(countingDirectory(&calls, &gotURL), WithServerCellID(25))
+
+	for range 3 {
+		if _, err := p.Servers(context.Background()); err != nil {
+			t.Fatalf("Servers: %v", err)
+		}
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("directory fetched %d times, want 1", calls.Load())
+	}
+	if u := gotURL.Load().(string); !strings.Contains(u, "cellid=25") {
+		t.Errorf("URL = %q, want cellid=25", u)
+	}
+
+	p.Invalidate()
+	if _, err := p.Servers(context.Background()); err != nil {
+		t.Fatalf("Servers: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("directory fetched %d times after Invalidate, want 2", calls.Load())
+	}
+}
+
+func TestServerPoolSharesRefresh(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	p := NewServerPool(&http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		calls.Add(1)
+		started <- struct{}{}
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(poolFixture)),
+		}, nil
+	})})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			servers, err := p.Servers(context.Background())
+			if err == nil && len(servers) != 4 {
+				err = fmt.Errorf("got %d servers, want 4", len(servers))
+			}
+			errs <- err
+		}()
+	}
+
+	<-started
+	// The pool isn't locked while discovery is in flight.
+	p.ReportSuccess("a.steamserver.net:443", time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Servers: %v", err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("directory fetched %d times, want 1", calls.Load())
+	}
+}
+
+func TestServerPoolStaleFallback(t *testing.T) {
+	var fail atomic.Bool
+	p := NewServerPool(&http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		if fail.Load() {
+			return nil, errors.New("directory down")
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(poolFixture)),
+		}, nil
+	})}, WithServerListTTL(time.Nanosecond))
+
+	if _, err := p.Servers(context.Background()); err != nil {
+		t.Fatalf("Servers: %v", err)
+	}
+
+	fail.Store(true)
+	servers, err := p.Servers(context.Background())
+	if err != nil {
+		t.Fatalf("Servers with stale cache: %v", err)
+	}
+	if len(servers) != 4 {
+		t.Errorf("got %d servers, want 4", len(servers))
+	}
+}
+
+func TestServerPoolFileCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cmlist.json")
+
+	var calls atomic.Int32
+	first := NewServerPool(countingDirectory(&calls, nil), WithServerListFile(path))
+	if _, err := first.Servers(context.Background()); err != nil {
+		t.Fatalf("Servers: %v", err)
+	}
+
+	second := NewServerPool(countingDirectory(&calls, nil), WithServerListFile(path))
+	servers, err := second.Servers(context.Background())
+	if err != nil {
+		t.Fatalf("Servers: %v", err)
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("directory fetched %d times, want 1", calls.Load())
+	}
+	if len(servers) != 4 {
+		t.Errorf("got %d servers, want 4", len(servers))
+	}
+
+	// A different cell must not reuse the file.
+	third := NewServerPool(countingDirectory(&calls, nil), WithServerListFile(path), WithServerCellID(7))
+	if _, err := third.Servers(context.Background()); err != nil {
+		t.Fatalf("Servers: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("directory fetched %d times, want 2", calls.Load())
+	}
+}
+
+func TestServerPoolCandidatesFiltersType(t *testing.T) {
+	var calls atomic.Int32
+	p := NewServerPool(countingDirectory(&calls, nil))
+
+	got, err := p.Candidates(context.Background(), "netfilter")
+	if err != nil {
+		t.Fatalf("Candidates: %v", err)
+	}
+	if len(got) != 1 || got[0].Addr != "d.steamserver.net:27017" {
+		t.Errorf("Candidates = %v, want only d.steamserver.net:27017", got)
+	}
+}
+
+func TestServerPoolPenaltyAndLatency(t *testing.T) {
+	var calls atomic.Int32
+	p := NewServerPool(countingDirectory(&calls, nil), WithServerPenalty(time.Hour, time.Hour))
+
+	p.ReportFailure("a.steamserver.net:443")
+	p.ReportSuccess("b.steamserver.net:443", 300*time.Millisecond)
+	p.ReportSuccess("c.steamserver.net:443", 20*time.Millisecond)
+
+	got, err := p.Candidates(context.Background(), "websockets")
+	if err != nil {
+		t.Fatalf("Candidates: %v", err)
+	}
+
+	want := []string{"c.steamserver.net:443", "b.steamserver.net:443", "a.steamserver.net:443"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d candidates, want %d", len(got), len(want))
+	}
+	for i, s := range got {
+		if s.Addr != want[i] {
+			t.Errorf("candidate[%d] = %s, want %s", i, s.Addr, want[i])
+		}
+	}
+
+	// A success releases the server from the penalty box.
+	p.ReportSuccess("a.steamserver.net:443", 10*time.Millisecond)
+	got, _ = p.Candidates(context.Background(), "websockets")
+	if got[0].Addr != "a.steamserver.net:443" {
+		t.Errorf("candidate[0] = %s, want a.steamserver.net:443", got[0].Addr)
+	}
+}
+
+func TestServerPoolFailoverLimit(t *testing.T) {
+	var calls atomic.Int32
+	p := NewServerPool(countingDirectory(&calls, nil), WithServerFailover(2))
+
+	got, err := p.Candidates(context.Background(), "websockets")
+	if err != nil {
+		t.Fatalf("Candidates: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("got %d candidates, want 2", len(got))
+	}
+}
+
+func TestServerPoolPenaltyGrows(t *testing.T) {
+	p := NewServerPool(http.DefaultClient, WithServerPenalty(time.Second, 3*time.Second))
+
+	p.ReportFailure("x:443")
+	first := time.Until(p.health["x:443"].penaltyUntil)
+	p.ReportFailure("x:443")
+	second := time.Until(p.health["x:443"].penaltyUntil)
+	p.ReportFailure("x:443")
+	third := time.Until(p.health["x:443"].penaltyUntil)
+
+	if first > time.Second || second <= first || third > 3*time.Second {
+		t.Errorf("penalties = %v, %v, %v; want growing and capped at 3s", first, second, third)
+	}
+}
@@ -0,0 +1,88 @@
+package steamclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/k64z/steamstacks/eresult"
+	"google.golang.org/protobuf/proto"
+)
+
+// ServiceError is returned when a unified service method answers with an
+// EResult other than OK.
+type ServiceError struct {
+	Method  string
+	EResult eresult.EResult
+	Message string // error_message from the response header, if any
+}
+
+func (e *ServiceError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("service method %s: %s (%s)", e.Method, e.EResult, e.Message)
+	}
+	return fmt.Sprintf("service method %s: %s", e.Method, e.EResult)
+}
+
+// EResultOf returns the EResult carried by err if it wraps a ServiceError,
+// or eresult.OK and false otherwise.
+func EResultOf(err error) (eresult.EResult, bool) {
+	var se *ServiceError
+	if errors.As(err, &se) {
+		return se.EResult, true
+	}
+	return eresult.OK, false
+}
+
+// Call invokes a unified service method such as "Player.GetNickname#1" over
+// the CM connection and decodes the response into a new Resp. A non-OK
+// EResult is returned as a *ServiceError.
+//
+//	resp, err := steamclient.Call[*protocol.CAuthentication_AccessToken_GenerateForApp_Request,
+//		*protocol.CAuthentication_AccessToken_GenerateForApp_Response](ctx, client,
+//		"Authentication.GenerateAccessTokenForApp#1", req)
+func Call[Req, Resp proto.Message](ctx context.Context, c *Client, method string, req Req) (Resp, error) {
+	return call[Req, Resp](ctx, c, EMsgServiceMethodCallFromClient, method, req)
+}
+
+// callNonAuthed is Call for the methods Steam accepts before logon, such as
+// those used to obtain a refresh token in the first place.
+func callNonAuthed[Req, Resp proto.Message](ctx context.Context, c *Client, method string, req Req) (Resp, error) {
+	return call[Req, Resp](ctx, c, EMsgServiceMethodCallFromClientNonAuthed, method, req)
+}
+
+func call[Req, Resp proto.Message](ctx context.Context, c *Client, emsg EMsg, method string, req Req) (Resp, error) {
+	var zero Resp
+
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return zero, fmt.Errorf("marshal %s request: %w", method, err)
+	}
+
+	pkt, err := c.callService(ctx, emsg, method, body)
+	if err != nil {
+		return zero, err
+	}
+
+	resp := zero.ProtoReflect().New().Interface().(Resp)
+	if err := proto.Unmarshal(pkt.Body, resp); err != nil {
+		return zero, fmt.Errorf("unmarshal %s response: %w", method, err)
+	}
+	return resp, nil
+}
+
+// HandleNotification registers fn for server-pushed service notifications
+// (ServiceMethodSendToClient without a job) with the given method name, such
+// as "FriendMessagesClient.IncomingMessage#1". The returned function
+// unregisters the handler. Handlers run on the read loop and must not block.
+func HandleNotification[Msg proto.Message](c *Client, method string, fn func(Msg)) (remove func()) {
+	return c.services.add(method, func(pkt *Packet) {
+		var zero Msg
+		msg := zero.ProtoReflect().New().Interface().(Msg)
+		if err := proto.Unmarshal(pkt.Body, msg); err != nil {
+			c.logger.Error("unmarshal notification", "method", method, "err", err)
+			return
+		}
+		fn(msg)
+	})
+}
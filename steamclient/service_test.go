@@ -0,0 +1,188 @@
+package steamclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/k64z/steamstacks/eresult"
+	"github.com/k64z/steamstacks/protocol"
+	"google.golang.org/protobuf/proto"
+)
+
+// respondToNextCall reads the next service method call written to mc and
+// answers it with the given EResult and body.
+func respondToNextCall(t *testing.T, c *Client, mc *mockConn, result eresult.EResult, errMsg string, body proto.Message) *Packet {
+	t.Helper()
+
+	sent, err := DecodePacket(<-mc.writeCh)
+	if err != nil {
+		t.Fatalf("decode sent packet: %v", err)
+	}
+
+	var respBody []byte
+	if body != nil {
+		respBody, _ = proto.Marshal(body)
+	}
+	hdr := &protocol.CMsgProtoBufHeader{
+		JobidTarget: proto.Uint64(sent.Header.GetJobidSource()),
+		Eresult:     proto.Int32(int32(result)),
+	}
+	if errMsg != "" {
+		hdr.ErrorMessage = proto.String(errMsg)
+	}
+	c.handlePacket(&Packet{EMsg: EMsgServiceMethodResponse, IsProto: true, Header: hdr, Body: respBody})
+	return sent
+}
+
+func TestCallTyped(t *testing.T) {
+	mc := &mockConn{writeCh: make(chan []byte, 1)}
+	c := New()
+	c.conn = mc
+	c.done = make(chan struct{})
+
+	type result struct {
+		resp *protocol.CAuthentication_AccessToken_GenerateForApp_Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := Call[*protocol.CAuthentication_AccessToken_GenerateForApp_Request, *protocol.CAuthentication_AccessToken_GenerateForApp_Response](
+			context.Background(), c, "Authentication.GenerateAccessTokenForApp#1",
+			&protocol.CAuthentication_AccessToken_GenerateForApp_Request{RefreshToken: proto.String("rt")})
+		done <- result{resp, err}
+	}()
+
+	sent := respondToNextCall(t, c, mc, eresult.OK, "", &protocol.CAuthentication_AccessToken_GenerateForApp_Response{
+		AccessToken: proto.String("at"),
+	})
+
+	var req protocol.CAuthentication_AccessToken_GenerateForApp_Request
+	if err := proto.Unmarshal(sent.Body, &req); err != nil {
+		t.Fatalf("unmarshal request: %v", err)
+	}
+	if req.GetRefreshToken() != "rt" {
+		t.Errorf("RefreshToken = %q, want %q", req.GetRefreshToken(), "rt")
+	}
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("Call: %v", r.err)
+		}
+		if r.resp.GetAccessToken() != "at" {
+			t.Errorf("AccessToken = %q, want %q", r.resp.GetAccessToken(), "at")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Call did not return within 2s")
+	}
+}
+
+func TestCallServiceError(t *testing.T) {
+	mc := &mockConn{writeCh: make(chan []byte, 1)}
+	c := New()
+	c.conn = mc
+	c.done = make(chan struct{})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Call[*protocol.CAuthentication_AccessToken_GenerateForApp_Request, *protocol.CAuthentication_AccessToken_GenerateForApp_Response](
+			context.Background(), c, "Authentication.GenerateAccessTokenForApp#1",
+			&protocol.CAuthentication_AccessToken_GenerateForApp_Request{})
+		done <- err
+	}()
+
+	respondToNextCall(t, c, mc, eresult.AccessDenied, "token revoked", nil)
+
+	select {
+	case err := <-done:
+		var se *ServiceError
+		if !errors.As(err, &se) {
+			t.Fatalf("err = %v, want *ServiceError", err)
+		}
+		if se.EResult != eresult.AccessDenied || se.Message != "token revoked" {
+			t.Errorf("ServiceError = %+v", se)
+		}
+		if got, ok := EResultOf(err); !ok || got != eresult.AccessDenied {
+			t.Errorf("EResultOf = %v, %v; want AccessDenied, true", got, ok)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Call did not return within 2s")
+	}
+}
+
+func TestHandleNotification(t *testing.T) {
+	c := New()
+	c.done = make(chan struct{})
+
+	const method = "Authentication.NotifyRiskQuizResults#1"
+	got := make(chan *protocol.CAuthentication_NotifyRiskQuizResults_Notification, 2)
+	remove := HandleNotification(c, method, func(msg *protocol.CAuthentication_NotifyRiskQuizResults_Notification) {
+		got <- msg
+	})
+
+	body, _ := proto.Marshal(&protocol.CAuthentication_NotifyRiskQuizResults_Notification{
+		Results: &protocol.CAuthentication_NotifyRiskQuizResults_Notification_RiskQuizResults{
+			Platform: proto.Bool(true),
+		},
+	})
+	notify := func(name string) {
+		c.handlePacket(&Packet{
+			EMsg:    EMsgServiceMethodSendToClient,
+			IsProto: true,
+			Header:  &protocol.CMsgProtoBufHeader{TargetJobName: proto.String(name)},
+			Body:    body,
+		})
+	}
+
+	notify(method)
+	notify("Other.Method#1")
+
+	select {
+	case msg := <-got:
+		if !msg.GetResults().GetPlatform() {
+			t.Error("notification body not decoded")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("notification handler not called")
+	}
+	select {
+	case <-got:
+		t.Error("handler called for a different method")
+	default:
+	}
+
+	remove()
+	notify(method)
+	select {
+	case <-got:
+		t.Error("handler called after remove")
+	default:
+	}
+}
+
+func TestNotificationSkipsJobResponses(t *testing.T) {
+	c := New()
+	c.done = make(chan struct{})
+
+	called := false
+	HandleNotification(c, "Some.Method#1", func(*protocol.CAuthentication_NotifyRiskQuizResults_Notification) {
+		called = true
+	})
+
+	ch := c.expectJobID(5)
+	c.handlePacket(&Packet{
+		EMsg:    EMsgServiceMethodSendToClient,
+		IsProto: true,
+		Header: &protocol.CMsgProtoBufHeader{
+			TargetJobName: proto.String("Some.Method#1"),
+			JobidTarget:   proto.Uint64(5),
+		},
+	})
+	<-ch
+
+	if called {
+		t.Error("job response was dispatched as a notification")
+	}
+}
@@ -2,16 +2,18 @@ package steamclient
 
 import (
 	"context"
+	"crypto/rsa"
 	"errors"
 	"fmt"
 	"log/slog"
-	"math/rand/v2"
 	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/k64z/steamstacks/eresult"
 	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamhost"
 	"github.com/k64z/steamstacks/steamid"
 	"google.golang.org/protobuf/proto"
 )
@@ -26,12 +28,14 @@ const (
 
 // Client manages a connection to a Steam CM server.
 type Client struct {
-	conn      Connection
+	conn      Connection // protected by mu
 	steamID   steamid.SteamID
 	sessionID int32
 
 	transport  TransportType
 	httpClient *http.Client
+	pool       *ServerPool
+	channelKey *rsa.PublicKey
 	logger     *slog.Logger
 
 	// OnPacket is called for every decoded packet not handled internally.
@@ -58,20 +62,47 @@ type Client struct {
 	// OnDisconnect is called when the connection drops unexpectedly.
 	OnDisconnect func(*DisconnectEvent)
 
+	// OnReconnected is called after the reconnect supervisor has restored the session.
+	OnReconnected func(*ReconnectedEvent)
+
+	// OnReconnectFailed is called when the reconnect supervisor gives up.
+	OnReconnectFailed func(*ReconnectFailedEvent)
+
+	dial      func(context.Context) (Connection, error)
+	reconnect *ReconnectPolicy
+
+	// Session state replayed by the reconnect supervisor; protected by mu.
+	accountName   string
+	refreshToken  string
+	loginSteamID  steamid.SteamID
+	personaState  *PersonaState
+	gamesPlayed   []uint32
+	reconnecting  bool
+	stopReconnect context.CancelFunc
+
+	routes      router[EMsg]
+	services    router[string]
 	nextJobID   atomic.Uint64
 	pendingJobs map[uint64]chan<- *Packet // protected by mu
 
-	mu             sync.Mutex
-	done           chan struct{} // closed on Disconnect
-	wg             sync.WaitGroup
-	loggedIn       bool
-	closeOnce      sync.Once
-	disconnectOnce sync.Once
+	lifecycle sync.Mutex // serialises Connect, Reconnect and Disconnect
+
+	// done and disconnected belong to the current connection, like conn,
+	// and are replaced by Connect; protected by mu.
+	mu           sync.Mutex
+	done         chan struct{} // closed on Disconnect
+	disconnected bool          // OnDisconnect fired for this connection
+	wg           sync.WaitGroup
+	loggedIn     bool
 }
 
 type config struct {
 	transport           TransportType
 	httpClient          *http.Client
+	pool                *ServerPool
+	cellID              uint32
+	hosts               steamhost.Hosts
+	channelKey          *rsa.PublicKey
 	logger              *slog.Logger
 	onPacket            func(*Packet)
 	onFriendMsg         func(*FriendMessage)
@@ -81,6 +112,10 @@ type config struct {
 	onItemNotification  func(*ItemNotification)
 	onGCMessage         func(*GCMessage)
 	onDisconnect        func(*DisconnectEvent)
+	onReconnected       func(*ReconnectedEvent)
+	onReconnectFailed   func(*ReconnectFailedEvent)
+	dial                func(context.Context) (Connection, error)
+	reconnect           *ReconnectPolicy
 }
 
 // Option configures a Client.
@@ -91,11 +126,43 @@ func WithTransport(t TransportType) Option {
 	return func(c *config) { c.transport = t }
 }
 
-// WithHTTPClient sets the HTTP client used for server discovery.
+// WithHTTPClient sets the HTTP client used for server discovery and the
+// WebSocket handshake.
 func WithHTTPClient(h *http.Client) Option {
 	return func(c *config) { c.httpClient = h }
 }
 
+// WithServerPool shares a server pool between clients, so that server
+// health and the cached server list survive across clients.
+func WithServerPool(p *ServerPool) Option {
+	return func(c *config) { c.pool = p }
+}
+
+// WithCellID sets the cell ID used for server discovery when no server pool
+// is given. Steam returns the servers closest to that cell first.
+func WithCellID(cellID uint32) Option {
+	return func(c *config) { c.cellID = cellID }
+}
+
+// WithHosts maps the Steam web hosts. The API host is used for server
+// discovery when no server pool is given.
+func WithHosts(h steamhost.Hosts) Option {
+	return func(c *config) { c.hosts = h }
+}
+
+// WithChannelPublicKey sets the RSA key used to encrypt the session key in
+// the TCP encryption handshake, in place of Steam's public universe key.
+// This is only useful against test servers such as cmtest.
+func WithChannelPublicKey(pub *rsa.PublicKey) Option {
+	return func(c *config) { c.channelKey = pub }
+}
+
+// WithDialer replaces server discovery and dialing in Connect with fn.
+// This is useful for testing against fake transports.
+func WithDialer(fn func(ctx context.Context) (Connection, error)) Option {
+	return func(c *config) { c.dial = fn }
+}
+
 // WithLogger sets the structured logger.
 func WithLogger(l *slog.Logger) Option {
 	return func(c *config) { c.logger = l }
@@ -131,10 +198,17 @@ func New(opts ...Option) *Client {
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+	if cfg.pool == nil {
+		cfg.pool = NewServerPool(cfg.httpClient,
+			WithServerCellID(cfg.cellID),
+			WithServerDirectory(cfg.hosts.Resolve().API))
+	}
 
-	return &Client{
+	c := &Client{
 		transport:           cfg.transport,
 		httpClient:          cfg.httpClient,
+		pool:                cfg.pool,
+		channelKey:          cfg.channelKey,
 		logger:              cfg.logger,
 		OnPacket:            cfg.onPacket,
 		OnFriendMessage:     cfg.onFriendMsg,
@@ -144,68 +218,122 @@ func New(opts ...Option) *Client {
 		OnTradeNotification: cfg.onTradeNotification,
 		OnItemNotification:  cfg.onItemNotification,
 		OnDisconnect:        cfg.onDisconnect,
-	}
+		OnReconnected:       cfg.onReconnected,
+		OnReconnectFailed:   cfg.onReconnectFailed,
+		dial:                cfg.dial,
+		reconnect:           cfg.reconnect,
+	}
+
+	c.route(EMsgClientLoggedOff, c.handleLoggedOff)
+	c.route(EMsgClientFriendsList, c.handleFriendsList)
+	c.route(EMsgClientPersonaState, c.handlePersonaState)
+	c.route(EMsgClientFriendMsgIncoming, c.handleFriendMsgIncoming)
+	c.route(EMsgClientFriendMsgEchoToSender, c.handleFriendMsgIncoming)
+	c.route(EMsgClientUserNotifications, c.handleUserNotifications)
+	c.route(EMsgClientItemAnnouncements, c.handleItemAnnouncements)
+	c.route(EMsgClientFromGC, c.handleGCMessage)
+
+	return c
 }
 
 // SetConn sets the underlying connection. This is useful for testing with
 // mock connections from external packages.
 func (c *Client) SetConn(conn Connection) {
+	c.mu.Lock()
 	c.conn = conn
+	c.mu.Unlock()
 }
 
 // Connect discovers CM servers, dials one, and prepares the connection.
 // For TCP, this includes the encryption handshake.
 func (c *Client) Connect(ctx context.Context) error {
-	servers, err := DiscoverServers(ctx, c.httpClient)
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	return c.connect(ctx)
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	dial := c.dial
+	if dial == nil {
+		dial = c.dialServer
+	}
+
+	conn, err := dial(ctx)
 	if err != nil {
-		return fmt.Errorf("discover servers: %w", err)
+		return err
 	}
+	done := make(chan struct{})
+	c.mu.Lock()
+	c.conn = conn
+	c.done = done
+	c.disconnected = false
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go c.readLoop(conn, done)
+
+	c.logger.Info("connected", "addr", conn.RemoteAddr())
+	return nil
+}
 
+// dialServer dials the best candidates from the server pool in turn until
+// one succeeds, reporting each outcome back to the pool.
+func (c *Client) dialServer(ctx context.Context) (Connection, error) {
 	targetType := "websockets"
 	if c.transport == TransportTCP {
 		targetType = "netfilter"
 	}
 
-	var candidates []CMServer
-	for _, s := range servers {
-		if s.Type == targetType {
-			candidates = append(candidates, s)
-		}
+	candidates, err := c.pool.Candidates(ctx, targetType)
+	if err != nil {
+		return nil, fmt.Errorf("discover servers: %w", err)
 	}
 
-	if len(candidates) == 0 {
-		return fmt.Errorf("no %s servers found", targetType)
+	var errs []error
+	for _, server := range candidates {
+		c.logger.Info("connecting to CM server", "addr", server.Addr, "type", server.Type)
+
+		start := time.Now()
+		conn, err := c.dialAddr(ctx, server.Addr)
+		if err == nil {
+			c.pool.ReportSuccess(server.Addr, time.Since(start))
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		c.logger.Warn("CM server dial failed", "addr", server.Addr, "err", err)
+		c.pool.ReportFailure(server.Addr)
+		errs = append(errs, err)
 	}
 
-	server := candidates[rand.IntN(len(candidates))]
-	c.logger.Info("connecting to CM server", "addr", server.Addr, "type", server.Type)
+	// Every candidate failed; the list itself may be stale.
+	c.pool.Invalidate()
+	return nil, fmt.Errorf("all %d %s servers failed: %w", len(candidates), targetType, errors.Join(errs...))
+}
 
+// dialAddr dials a single CM server with the configured transport.
+func (c *Client) dialAddr(ctx context.Context, addr string) (Connection, error) {
 	switch c.transport {
-	case TransportWebSocket:
-		ws, err := dialWebSocket(ctx, server.Addr)
-		if err != nil {
-			return err
-		}
-		c.conn = ws
-
 	case TransportTCP:
-		tcp, err := dialTCP(ctx, server.Addr)
+		tcp, err := dialTCP(ctx, addr)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if err := tcp.performEncryptionHandshake(ctx); err != nil {
+		if err := tcp.performEncryptionHandshake(ctx, c.channelKey); err != nil {
 			tcp.Close()
-			return fmt.Errorf("encryption handshake: %w", err)
+			return nil, fmt.Errorf("encryption handshake: %w", err)
 		}
-		c.conn = tcp
-	}
+		return tcp, nil
 
-	c.done = make(chan struct{})
-	c.wg.Add(1)
-	go c.readLoop()
-
-	c.logger.Info("connected", "addr", c.conn.RemoteAddr())
-	return nil
+	default:
+		ws, err := dialWebSocket(ctx, addr, c.httpClient)
+		if err != nil {
+			return nil, err
+		}
+		return ws, nil
+	}
 }
 
 // Login authenticates with the CM server using an account name and refresh token.
@@ -230,7 +358,8 @@ func (c *Client) Login(ctx context.Context, accountName, refreshToken string, si
 	}
 
 	// Install response handler BEFORE sending logon to avoid race with readLoop
-	responseCh := c.expectEMsg(EMsgClientLogOnResponse)
+	waiter := c.Expect(EMsgClientLogOnResponse)
+	defer waiter.Cancel()
 
 	osType := uint32(20) // EOSType Windows 11
 	lang := "english"
@@ -254,7 +383,7 @@ func (c *Client) Login(ctx context.Context, accountName, refreshToken string, si
 		return fmt.Errorf("send ClientLogon: %w", err)
 	}
 
-	pkt, err := c.awaitPacket(ctx, responseCh)
+	pkt, err := waiter.Wait(ctx)
 	if err != nil {
 		return fmt.Errorf("wait for logon response: %w", err)
 	}
@@ -272,6 +401,9 @@ func (c *Client) Login(ctx context.Context, accountName, refreshToken string, si
 	c.steamID = steamid.FromSteamID64(pkt.Header.GetSteamid())
 	c.sessionID = pkt.Header.GetClientSessionid()
 	c.loggedIn = true
+	c.accountName = accountName
+	c.refreshToken = refreshToken
+	c.loginSteamID = sid
 	c.mu.Unlock()
 
 	heartbeatSec := resp.GetHeartbeatSeconds()
@@ -279,8 +411,9 @@ func (c *Client) Login(ctx context.Context, accountName, refreshToken string, si
 		heartbeatSec = 30 // fallback
 	}
 
+	conn, done := c.connection()
 	c.wg.Add(1)
-	go c.heartbeatLoop(time.Duration(heartbeatSec) * time.Second)
+	go c.heartbeatLoop(conn, done, time.Duration(heartbeatSec)*time.Second)
 
 	c.logger.Info("logged in",
 		"steamid", c.steamID.String(),
@@ -298,6 +431,18 @@ func (c *Client) SteamID() steamid.SteamID {
 
 // Disconnect cleanly disconnects from the CM server.
 func (c *Client) Disconnect() error {
+	// Stop the supervisor first, so that a Reconnect it has in flight
+	// gives up and releases the lifecycle lock.
+	c.mu.Lock()
+	if c.stopReconnect != nil {
+		c.stopReconnect()
+		c.stopReconnect = nil
+	}
+	c.mu.Unlock()
+
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
 	c.mu.Lock()
 	wasLoggedIn := c.loggedIn
 	var sidU64 uint64
@@ -319,17 +464,9 @@ func (c *Client) Disconnect() error {
 		}, body)
 	}
 
-	c.closeOnce.Do(func() { close(c.done) })
-
-	if c.conn != nil {
-		c.conn.Close()
-	}
+	c.closeConn()
 	c.wg.Wait()
 
-	// Reset sync primitives for potential reuse via Reconnect.
-	c.closeOnce = sync.Once{}
-	c.disconnectOnce = sync.Once{}
-
 	c.logger.Info("disconnected")
 	return nil
 }
@@ -345,6 +482,7 @@ func (c *Client) sendPacket(ctx context.Context, emsg EMsg, hdr *protocol.CMsgPr
 		hdr.Steamid = &sid
 		hdr.ClientSessionid = &c.sessionID
 	}
+	conn := c.conn
 	c.mu.Unlock()
 
 	pkt := &Packet{
@@ -354,22 +492,48 @@ func (c *Client) sendPacket(ctx context.Context, emsg EMsg, hdr *protocol.CMsgPr
 		Body:    body,
 	}
 
-	data, err := encodePacket(pkt)
+	data, err := EncodePacket(pkt)
 	if err != nil {
 		return fmt.Errorf("encode %s: %w", emsg, err)
 	}
 
-	return c.conn.Write(ctx, data)
+	return conn.Write(ctx, data)
+}
+
+// connection returns the current connection and its done channel.
+func (c *Client) connection() (Connection, chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn, c.done
+}
+
+// closeConn closes the current connection's done channel, if still open,
+// and its transport, which stops its read and heartbeat loops.
+func (c *Client) closeConn() {
+	c.mu.Lock()
+	conn, done := c.conn, c.done
+	if done != nil {
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+	}
+	c.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
 }
 
-func (c *Client) readLoop() {
+func (c *Client) readLoop(conn Connection, done chan struct{}) {
 	defer c.wg.Done()
 
 	for {
-		data, err := c.conn.Read(context.Background())
+		data, err := conn.Read(context.Background())
 		if err != nil {
 			select {
-			case <-c.done:
+			case <-done:
 				return // expected disconnect
 			default:
 				if !errors.Is(err, context.Canceled) {
@@ -380,7 +544,7 @@ func (c *Client) readLoop() {
 			}
 		}
 
-		pkt, err := decodePacket(data)
+		pkt, err := DecodePacket(data)
 		if err != nil {
 			c.logger.Error("decode error", "err", err)
 			continue
@@ -426,78 +590,42 @@ func (c *Client) handlePacket(pkt *Packet) {
 		}
 	}
 
-	// Dispatch to type-specific handlers.
-	switch pkt.EMsg {
-	case EMsgClientLoggedOff:
-		var logoff protocol.CMsgClientLoggedOff
-		eresult := int32(2)
-		if err := proto.Unmarshal(pkt.Body, &logoff); err == nil {
-			eresult = logoff.GetEresult()
-		}
-		c.logger.Warn("logged off by server", "eresult", eresult)
-		c.fireDisconnect(&DisconnectEvent{ServerInitiated: true, EResult: eresult})
-		// Close connection — readLoop will exit cleanly on next Read().
-		c.closeOnce.Do(func() { close(c.done) })
-		if c.conn != nil {
-			c.conn.Close()
-		}
-
-	case EMsgClientFriendsList:
-		c.handleFriendsList(pkt)
-
-	case EMsgClientPersonaState:
-		c.handlePersonaState(pkt)
-
-	case EMsgClientFriendMsgIncoming, EMsgClientFriendMsgEchoToSender:
-		c.handleFriendMsgIncoming(pkt)
-
-	case EMsgClientUserNotifications:
-		c.handleUserNotifications(pkt)
-
-	case EMsgClientItemAnnouncements:
-		c.handleItemAnnouncements(pkt)
-
-	case EMsgClientFromGC:
-		c.handleGCMessage(pkt)
+	// Server-pushed service notifications carry no job; route them by method name.
+	if !ok && pkt.EMsg == EMsgServiceMethodSendToClient {
+		c.services.dispatch(pkt.Header.GetTargetJobName(), pkt)
 	}
 
+	// Dispatch to internal handlers and subscribers.
+	c.routes.dispatch(pkt.EMsg, pkt)
+
 	// Forward all non-Multi packets to the generic handler.
 	if c.OnPacket != nil {
 		c.OnPacket(pkt)
 	}
 }
 
-// expectEMsg installs a one-shot packet listener for the given EMsg.
-// Call this BEFORE sending the request to avoid a race with readLoop.
-// Use awaitPacket to block until the response arrives.
-func (c *Client) expectEMsg(target EMsg) <-chan *Packet {
-	ch := make(chan *Packet, 1)
-
-	prev := c.OnPacket
-	c.OnPacket = func(pkt *Packet) {
-		if pkt.EMsg == target {
-			select {
-			case ch <- pkt:
-			default:
-			}
-			c.OnPacket = prev
-		}
-		if prev != nil {
-			prev(pkt)
-		}
-	}
-
-	return ch
+// handleLoggedOff processes EMsgClientLoggedOff: the server ended the session.
+func (c *Client) handleLoggedOff(pkt *Packet) {
+	var logoff protocol.CMsgClientLoggedOff
+	result := eresult.Fail
+	if err := proto.Unmarshal(pkt.Body, &logoff); err == nil {
+		result = eresult.EResult(logoff.GetEresult())
+	}
+	c.logger.Warn("logged off by server", "eresult", result)
+	c.fireDisconnect(&DisconnectEvent{ServerInitiated: true, EResult: result})
+	// Close connection — readLoop will exit cleanly on next Read().
+	c.closeConn()
 }
 
 // awaitPacket blocks until a packet arrives on ch, ctx expires, or the connection closes.
 func (c *Client) awaitPacket(ctx context.Context, ch <-chan *Packet) (*Packet, error) {
+	_, done := c.connection()
 	select {
 	case pkt := <-ch:
 		return pkt, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
-	case <-c.done:
+	case <-done:
 		return nil, ErrDisconnected
 	}
 }
@@ -519,6 +647,12 @@ func (c *Client) expectJobID(jobID uint64) <-chan *Packet {
 // callServiceMethod sends a unified service method request and awaits the
 // matching response, correlated by job ID.
 func (c *Client) callServiceMethod(ctx context.Context, method string, body []byte) (*Packet, error) {
+	return c.callService(ctx, EMsgServiceMethodCallFromClient, method, body)
+}
+
+// callService sends a unified service method request with the given EMsg,
+// which is ServiceMethodCallFromClientNonAuthed for calls made before logon.
+func (c *Client) callService(ctx context.Context, emsg EMsg, method string, body []byte) (*Packet, error) {
 	jobID := c.nextJobID.Add(1)
 	responseCh := c.expectJobID(jobID)
 	defer func() {
@@ -531,7 +665,7 @@ func (c *Client) callServiceMethod(ctx context.Context, method string, body []by
 		TargetJobName: proto.String(method),
 		JobidSource:   proto.Uint64(jobID),
 	}
-	if err := c.sendPacket(ctx, EMsgServiceMethodCallFromClient, hdr, body); err != nil {
+	if err := c.sendPacket(ctx, emsg, hdr, body); err != nil {
 		return nil, fmt.Errorf("send %s: %w", method, err)
 	}
 
@@ -540,12 +674,16 @@ func (c *Client) callServiceMethod(ctx context.Context, method string, body []by
 		return nil, fmt.Errorf("wait for %s response: %w", method, err)
 	}
 	if pkt.Header.GetEresult() != 1 {
-		return pkt, fmt.Errorf("service method %s: eresult=%d", method, pkt.Header.GetEresult())
+		return pkt, &ServiceError{
+			Method:  method,
+			EResult: eresult.EResult(pkt.Header.GetEresult()),
+			Message: pkt.Header.GetErrorMessage(),
+		}
 	}
 	return pkt, nil
 }
 
-func (c *Client) heartbeatLoop(interval time.Duration) {
+func (c *Client) heartbeatLoop(conn Connection, done chan struct{}, interval time.Duration) {
 	defer c.wg.Done()
 
 	ticker := time.NewTicker(interval)
@@ -553,15 +691,13 @@ func (c *Client) heartbeatLoop(interval time.Duration) {
 
 	for {
 		select {
-		case <-c.done:
+		case <-done:
 			return
 		case <-ticker.C:
 			body, _ := proto.Marshal(&protocol.CMsgClientHeartBeat{})
 			if err := c.sendPacket(context.Background(), EMsgClientHeartBeat, nil, body); err != nil {
 				c.logger.Error("heartbeat failed, closing connection", "err", err)
-				if c.conn != nil {
-					c.conn.Close()
-				}
+				conn.Close()
 				return
 			}
 			c.logger.Debug("heartbeat sent")
@@ -3,6 +3,7 @@ package steamclient
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/coder/websocket"
 )
@@ -21,10 +22,10 @@ type wsConn struct {
 	addr string
 }
 
-func dialWebSocket(ctx context.Context, host string) (*wsConn, error) {
+func dialWebSocket(ctx context.Context, host string, httpClient *http.Client) (*wsConn, error) {
 	url := fmt.Sprintf("wss://%s/cmsocket/", host)
 
-	conn, _, err := websocket.Dial(ctx, url, nil)
+	conn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{HTTPClient: httpClient})
 	if err != nil {
 		return nil, fmt.Errorf("websocket dial %s: %w", url, err)
 	}
@@ -3,6 +3,7 @@ package steamclient
 import (
 	"context"
 	"crypto/rand"
+	"crypto/rsa"
 	"encoding/binary"
 	"fmt"
 	"hash/crc32"
@@ -106,7 +107,9 @@ func (t *tcpConn) RemoteAddr() string {
 // 3. RSA-encrypt (sessionKey + challenge) with Steam's public key
 // 4. Send ChannelEncryptResponse (1304) — protocol_version + key_size + encrypted blob + CRC32
 // 5. Receive ChannelEncryptResult (1305) — verify eresult == 1
-func (t *tcpConn) performEncryptionHandshake(ctx context.Context) error {
+//
+// A nil pub encrypts the session key with Steam's public universe key.
+func (t *tcpConn) performEncryptionHandshake(ctx context.Context, pub *rsa.PublicKey) error {
 	const msgHdrLen = 20 // EMsg(4) + TargetJobID(8) + SourceJobID(8)
 
 	data, err := t.Read(ctx)
@@ -135,7 +138,7 @@ func (t *tcpConn) performEncryptionHandshake(ctx context.Context) error {
 		return fmt.Errorf("generate session key: %w", err)
 	}
 
-	encryptedBlob, err := rsaEncryptSessionKey(sessionKey, challenge)
+	encryptedBlob, err := rsaEncryptSessionKey(pub, sessionKey, challenge)
 	if err != nil {
 		return fmt.Errorf("rsa encrypt: %w", err)
 	}
@@ -0,0 +1,294 @@
+package steamcommunity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BulkSellItem is one item for BulkSell. PriceCents is what the seller
+// receives, or what the buyer pays if BuyerPrice is set, split with the
+// game's PublisherFeePercent as in SellAtBuyerPrice: 0 for the wallet
+// default, or NoPublisherFee.
+type BulkSellItem struct {
+	AppID               int
+	ContextID           uint64
+	AssetID             uint64
+	Amount              int // defaults to 1
+	PriceCents          int
+	BuyerPrice          bool
+	PublisherFeePercent float64 // only used with BuyerPrice
+}
+
+// BulkSellResult is the outcome for one BulkSellItem. ListingID is set
+// for listings found awaiting confirmation; Confirmed once their
+// confirmation was accepted. Listings Steam put live without one have
+// neither.
+type BulkSellResult struct {
+	Item      BulkSellItem
+	ListingID string
+	Confirmed bool
+	Attempts  int
+	Err       error
+}
+
+type bulkSellConfig struct {
+	concurrency    int
+	interval       time.Duration
+	retries        int
+	retryDelay     time.Duration
+	identitySecret []byte
+	onResult       func(BulkSellResult)
+}
+
+type BulkSellOption func(*bulkSellConfig)
+
+// WithBulkSellConcurrency sets how many listings are in flight at once.
+// Default 1: Steam reports ErrMarketPreviousActionPending for listings
+// made too close together anyway.
+func WithBulkSellConcurrency(n int) BulkSellOption {
+	return func(c *bulkSellConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithBulkSellInterval sets the minimum time between listing requests,
+// across all workers. Default 1 second.
+func WithBulkSellInterval(d time.Duration) BulkSellOption {
+	return func(c *bulkSellConfig) {
+		c.interval = d
+	}
+}
+
+// WithBulkSellRetries sets how often an item is retried after a
+// transient error, waiting the retry delay, doubled each time, in
+// between. Defaults 3 and 5 seconds.
+func WithBulkSellRetries(n int, delay time.Duration) BulkSellOption {
+	return func(c *bulkSellConfig) {
+		c.retries = n
+		c.retryDelay = delay
+	}
+}
+
+// WithBulkSellConfirmations makes BulkSell accept the mobile
+// confirmations of the listings it created. identitySecret is the
+// base64-decoded identity_secret. Without it the listings stay pending.
+func WithBulkSellConfirmations(identitySecret []byte) BulkSellOption {
+	return func(c *bulkSellConfig) {
+		c.identitySecret = identitySecret
+	}
+}
+
+// WithBulkSellProgress registers a callback for each item's listing
+// outcome, before confirmations. It may run on several goroutines at
+// once.
+func WithBulkSellProgress(fn func(BulkSellResult)) BulkSellOption {
+	return func(c *bulkSellConfig) {
+		c.onResult = fn
+	}
+}
+
+// BulkSell lists items on the market, paced and retried, then looks up
+// the pending listings and accepts their confirmations in one batch.
+// Results are in the order of items. The error is for the run as a
+// whole, such as a failed confirmation step or ctx ending; per-item
+// failures are in the results.
+func (c *Community) BulkSell(ctx context.Context, items []BulkSellItem, opts ...BulkSellOption) ([]BulkSellResult, error) {
+	cfg := bulkSellConfig{
+		concurrency: 1,
+		interval:    time.Second,
+		retries:     3,
+		retryDelay:  5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.concurrency < 1 {
+		return nil, errors.New("concurrency must be positive")
+	}
+	// Before the workers start: ensureInit isn't safe to race.
+	if err := c.ensureInit(); err != nil {
+		return nil, err
+	}
+
+	results := make([]BulkSellResult, len(items))
+	pace := newBulkSellPacer(cfg.interval)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(cfg.concurrency, max(len(items), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = c.bulkSellOne(ctx, items[i], &cfg, pace)
+				if cfg.onResult != nil {
+					cfg.onResult(results[i])
+				}
+			}
+		}()
+	}
+feed:
+	for i := range items {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			for j := i; j < len(items); j++ {
+				results[j] = BulkSellResult{Item: items[j], Err: ctx.Err()}
+			}
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+
+	if err := c.bulkSellCollect(ctx, results, cfg.identitySecret); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// bulkSellOne lists one item, retrying transient errors.
+func (c *Community) bulkSellOne(ctx context.Context, item BulkSellItem, cfg *bulkSellConfig, pace *bulkSellPacer) BulkSellResult {
+	res := BulkSellResult{Item: item}
+	amount := item.Amount
+	if amount <= 0 {
+		amount = 1
+	}
+	var sellOpts []SellMarketItemOption
+	if item.BuyerPrice {
+		sellOpts = append(sellOpts, SellAtBuyerPrice(item.PublisherFeePercent))
+	}
+
+	delay := cfg.retryDelay
+	for {
+		if err := pace.wait(ctx); err != nil {
+			res.Err = err
+			return res
+		}
+		res.Attempts++
+		_, err := c.SellMarketItem(ctx, item.AppID, item.ContextID, item.AssetID, amount, item.PriceCents, sellOpts...)
+		// A listing pending confirmation already exists; the collect
+		// step will find it like the ones made here.
+		if err == nil || errors.Is(err, ErrMarketPendingConfirmation) {
+			res.Err = nil
+			return res
+		}
+		res.Err = err
+		if !bulkSellTransient(err) || res.Attempts > cfg.retries {
+			return res
+		}
+		select {
+		case <-ctx.Done():
+			res.Err = ctx.Err()
+			return res
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// bulkSellTransient reports whether a failed listing is worth retrying.
+func bulkSellTransient(err error) bool {
+	return errors.Is(err, ErrMarketPreviousActionPending) ||
+		errors.Is(err, ErrMarketListingProblem) ||
+		errors.Is(err, ErrMarketItemServerDown) ||
+		errors.Is(err, errRateLimited)
+}
+
+// bulkSellCollect fills in the listing IDs of the listed items from the
+// pending listings, then accepts their confirmations together.
+func (c *Community) bulkSellCollect(ctx context.Context, results []BulkSellResult, identitySecret []byte) error {
+	listed := 0
+	for _, r := range results {
+		if r.Err == nil {
+			listed++
+		}
+	}
+	if listed == 0 {
+		return nil
+	}
+
+	pending, err := c.GetMyPendingMarketListings(ctx)
+	if err != nil {
+		return fmt.Errorf("get pending listings: %w", err)
+	}
+	byAsset := make(map[string]string, len(pending))
+	for _, p := range pending {
+		byAsset[p.AssetID] = p.ListingID
+	}
+	byListing := make(map[string]int)
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		if id, ok := byAsset[strconv.FormatUint(results[i].Item.AssetID, 10)]; ok {
+			results[i].ListingID = id
+			byListing[id] = i
+		}
+	}
+	if len(identitySecret) == 0 || len(byListing) == 0 {
+		return nil
+	}
+
+	confs, err := c.GetConfirmations(ctx, identitySecret)
+	if err != nil {
+		return fmt.Errorf("get confirmations: %w", err)
+	}
+	var accept []Confirmation
+	for _, conf := range confs {
+		if _, ok := byListing[conf.CreatorID]; ok && conf.Type == ConfirmationTypeMarketListing {
+			accept = append(accept, conf)
+		}
+	}
+	if len(accept) == 0 {
+		return nil
+	}
+	if err := c.AcceptConfirmations(ctx, accept, identitySecret); err != nil {
+		return fmt.Errorf("accept confirmations: %w", err)
+	}
+	for _, conf := range accept {
+		results[byListing[conf.CreatorID]].Confirmed = true
+	}
+	return nil
+}
+
+// bulkSellPacer spaces requests at least interval apart.
+type bulkSellPacer struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+func newBulkSellPacer(interval time.Duration) *bulkSellPacer {
+	return &bulkSellPacer{interval: interval}
+}
+
+// wait blocks until the caller's turn.
+func (p *bulkSellPacer) wait(ctx context.Context) error {
+	if p.interval <= 0 {
+		return ctx.Err()
+	}
+	p.mu.Lock()
+	now := time.Now()
+	at := now
+	if p.next.After(now) {
+		at = p.next
+	}
+	p.next = at.Add(p.interval)
+	p.mu.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package steamcommunity
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k64z/steamstacks/steamtest"
+)
+
+func TestBulkSell(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	secret := []byte("identity-secret-for-tests")
+	alice := srv.AddAccount(steamtest.Account{IdentitySecret: secret, SellCooldown: 30 * time.Millisecond})
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+
+	var items []BulkSellItem
+	for range 5 {
+		item := srv.AddItem(alice.SteamID, steamtest.Item{AppID: 440, Name: "Earbuds", Marketable: true})
+		items = append(items, BulkSellItem{AppID: 440, ContextID: 2, AssetID: mustParseUint(t, item.AssetID), PriceCents: 115, BuyerPrice: true})
+	}
+	items = append(items, BulkSellItem{AppID: 440, ContextID: 2, AssetID: 1, PriceCents: 100})
+
+	var mu sync.Mutex
+	progress := 0
+	results, err := alicec.BulkSell(ctx, items,
+		WithBulkSellConcurrency(3),
+		WithBulkSellInterval(0),
+		WithBulkSellRetries(10, 10*time.Millisecond),
+		WithBulkSellConfirmations(secret),
+		WithBulkSellProgress(func(BulkSellResult) {
+			mu.Lock()
+			progress++
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("BulkSell: %v", err)
+	}
+	if progress != len(items) {
+		t.Errorf("progress reported %d times, want %d", progress, len(items))
+	}
+
+	retried := false
+	for i, r := range results[:5] {
+		if r.Err != nil || r.ListingID == "" || !r.Confirmed {
+			t.Errorf("result %d = %+v, want a confirmed listing", i, r)
+		}
+		if r.Attempts > 1 {
+			retried = true
+		}
+	}
+	if !retried {
+		t.Error("no item was retried despite the sell cooldown")
+	}
+	if r := results[5]; !errors.Is(r.Err, ErrMarketItemNotInInventory) || r.ListingID != "" {
+		t.Errorf("missing item: result = %+v, want ErrMarketItemNotInInventory", r)
+	}
+
+	listings := srv.Listings(alice.SteamID)
+	if len(listings) != 5 {
+		t.Fatalf("len(listings) = %d, want 5", len(listings))
+	}
+	for _, l := range listings {
+		if l.Pending || l.Price != 100 {
+			t.Errorf("listing %+v, want active at 100", l)
+		}
+	}
+	if confs := srv.Confirmations(alice.SteamID); len(confs) != 0 {
+		t.Errorf("confirmations left pending: %+v", confs)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/k64z/steamstacks/steamapi"
@@ -47,9 +48,30 @@ type Confirmation struct {
 	Icon      string           `json:"icon"`
 }
 
+// steamTimeResync is how long a fetched Steam time offset is trusted
+// before it is fetched again.
+const steamTimeResync = time.Hour
+
+// steamTime returns the current Steam server time. The offset to the
+// local clock is fetched once and then reused for steamTimeResync.
+func (c *Community) steamTime(ctx context.Context) (int64, error) {
+	c.timeMu.Lock()
+	defer c.timeMu.Unlock()
+
+	if c.timeSynced.IsZero() || time.Since(c.timeSynced) > steamTimeResync {
+		_, offset, err := c.api.GetSteamTime(ctx)
+		if err != nil {
+			return 0, err
+		}
+		c.timeOffset = offset
+		c.timeSynced = time.Now()
+	}
+	return time.Now().Unix() + c.timeOffset, nil
+}
+
 // buildConfirmationParams builds the common query parameters for confirmation requests.
-func (c *Community) buildConfirmationParams(identitySecret []byte, tag string) (url.Values, error) {
-	serverTime, _, err := steamapi.GetSteamTimeWithClient(context.Background(), c.httpClient)
+func (c *Community) buildConfirmationParams(ctx context.Context, identitySecret []byte, tag string) (url.Values, error) {
+	serverTime, err := c.steamTime(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get steam time: %w", err)
 	}
@@ -75,12 +97,12 @@ func (c *Community) GetConfirmations(ctx context.Context, identitySecret []byte)
 	if err := c.ensureInit(); err != nil {
 		return nil, err
 	}
-	params, err := c.buildConfirmationParams(identitySecret, "list")
+	params, err := c.buildConfirmationParams(ctx, identitySecret, "list")
 	if err != nil {
 		return nil, err
 	}
 
-	reqURL := "https://steamcommunity.com/mobileconf/getlist?" + params.Encode()
+	reqURL := c.baseURL + "/mobileconf/getlist?" + params.Encode()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
@@ -97,7 +119,11 @@ func (c *Community) GetConfirmations(ctx context.Context, identitySecret []byte)
 		return nil, fmt.Errorf("read body: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return nil, errRateLimited
+	default:
 		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
 	}
 
@@ -151,8 +177,12 @@ func (c *Community) GetConfirmations(ctx context.Context, identitySecret []byte)
 	return confirmations, nil
 }
 
-// respondToConfirmation sends an accept or reject response for a confirmation.
-func (c *Community) respondToConfirmation(ctx context.Context, conf Confirmation, identitySecret []byte, accept bool) error {
+// respondToConfirmations accepts or rejects several confirmations in one
+// multiajaxop request.
+func (c *Community) respondToConfirmations(ctx context.Context, confs []Confirmation, identitySecret []byte, accept bool) error {
+	if len(confs) == 0 {
+		return nil
+	}
 	tag := "reject"
 	op := "cancel"
 	if accept {
@@ -160,20 +190,22 @@ func (c *Community) respondToConfirmation(ctx context.Context, conf Confirmation
 		op = "allow"
 	}
 
-	params, err := c.buildConfirmationParams(identitySecret, tag)
+	formData, err := c.buildConfirmationParams(ctx, identitySecret, tag)
 	if err != nil {
 		return err
 	}
 
-	params.Set("op", op)
-	params.Set("cid", conf.ID)
-	params.Set("ck", conf.Key)
+	formData.Set("op", op)
+	for _, conf := range confs {
+		formData.Add("cid[]", conf.ID)
+		formData.Add("ck[]", conf.Key)
+	}
 
-	reqURL := "https://steamcommunity.com/mobileconf/ajaxop?" + params.Encode()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/mobileconf/multiajaxop", strings.NewReader(formData.Encode()))
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -186,7 +218,11 @@ func (c *Community) respondToConfirmation(ctx context.Context, conf Confirmation
 		return fmt.Errorf("read body: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return errRateLimited
+	default:
 		return steamapi.HTTPStatusError(resp.StatusCode, body)
 	}
 
@@ -214,7 +250,7 @@ func (c *Community) AcceptConfirmation(ctx context.Context, conf Confirmation, i
 	if err := c.ensureInit(); err != nil {
 		return err
 	}
-	return c.respondToConfirmation(ctx, conf, identitySecret, true)
+	return c.respondToConfirmations(ctx, []Confirmation{conf}, identitySecret, true)
 }
 
 // RejectConfirmation rejects a pending confirmation.
@@ -222,7 +258,23 @@ func (c *Community) RejectConfirmation(ctx context.Context, conf Confirmation, i
 	if err := c.ensureInit(); err != nil {
 		return err
 	}
-	return c.respondToConfirmation(ctx, conf, identitySecret, false)
+	return c.respondToConfirmations(ctx, []Confirmation{conf}, identitySecret, false)
+}
+
+// AcceptConfirmations accepts several pending confirmations in one request.
+func (c *Community) AcceptConfirmations(ctx context.Context, confs []Confirmation, identitySecret []byte) error {
+	if err := c.ensureInit(); err != nil {
+		return err
+	}
+	return c.respondToConfirmations(ctx, confs, identitySecret, true)
+}
+
+// RejectConfirmations rejects several pending confirmations in one request.
+func (c *Community) RejectConfirmations(ctx context.Context, confs []Confirmation, identitySecret []byte) error {
+	if err := c.ensureInit(); err != nil {
+		return err
+	}
+	return c.respondToConfirmations(ctx, confs, identitySecret, false)
 }
 
 // AcceptConfirmationByCreatorID finds and accepts a confirmation by its creator ID.
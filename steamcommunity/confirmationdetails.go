@@ -0,0 +1,140 @@
+package steamcommunity
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// ConfirmationDetails is what the confirmation's details page shows.
+// For a trade, items are split by side and Partner is the other party;
+// for a market listing, ItemsToGive holds the listed item.
+type ConfirmationDetails struct {
+	OfferID        string          // trade confirmations only
+	Partner        steamid.SteamID // trade confirmations only
+	ItemsToGive    []ConfirmationItem
+	ItemsToReceive []ConfirmationItem
+	HTML           string // the page, for anything not parsed here
+}
+
+// ConfirmationItem is an item on a details page. Steam identifies it
+// either by class (ClassID, InstanceID) or by asset (ContextID, AssetID).
+type ConfirmationItem struct {
+	AppID      int
+	ContextID  string
+	AssetID    string
+	ClassID    string
+	InstanceID string
+}
+
+var (
+	reConfOfferID     = regexp.MustCompile(`id="tradeofferid_(\d+)"`)
+	reConfMiniprofile = regexp.MustCompile(`data-miniprofile="(\d+)"`)
+	reConfEconomyItem = regexp.MustCompile(`data-economy-item="(?:classinfo/(\d+)/(\d+)(?:/(\d+))?|(\d+)/(\d+)/(\d+))`)
+)
+
+// GetConfirmationDetails loads the details page of a confirmation and
+// parses the offer, partner and items off it, so a trade can be checked
+// before it is accepted.
+func (c *Community) GetConfirmationDetails(ctx context.Context, conf Confirmation, identitySecret []byte) (*ConfirmationDetails, error) {
+	if err := c.ensureInit(); err != nil {
+		return nil, err
+	}
+	params, err := c.buildConfirmationParams(ctx, identitySecret, "details")
+	if err != nil {
+		return nil, err
+	}
+
+	reqURL := c.baseURL + "/mobileconf/detailspage/" + conf.ID + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return nil, errRateLimited
+	default:
+		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+	}
+
+	return parseConfirmationDetails(string(body), c.SteamID), nil
+}
+
+// parseConfirmationDetails reads a details page. A trade page has a
+// "tradeoffer_items primary" block for the offer's sender and a
+// "secondary" one for its recipient, each led by that side's avatar;
+// the avatar tells which side is ours.
+func parseConfirmationDetails(page string, self steamid.SteamID) *ConfirmationDetails {
+	details := &ConfirmationDetails{HTML: page}
+	if m := reConfOfferID.FindStringSubmatch(page); m != nil {
+		details.OfferID = m[1]
+	}
+
+	primary := strings.Index(page, `class="tradeoffer_items primary"`)
+	secondary := strings.Index(page, `class="tradeoffer_items secondary"`)
+	if primary < 0 || secondary < 0 {
+		details.ItemsToGive = parseConfirmationItems(page)
+		return details
+	}
+
+	var blocks [2]string
+	if primary < secondary {
+		blocks = [2]string{page[primary:secondary], page[secondary:]}
+	} else {
+		blocks = [2]string{page[primary:], page[secondary:primary]}
+	}
+	for _, block := range blocks {
+		var accountID uint64
+		if m := reConfMiniprofile.FindStringSubmatch(block); m != nil {
+			accountID, _ = strconv.ParseUint(m[1], 10, 32)
+		}
+		items := parseConfirmationItems(block)
+		if uint32(accountID) == self.AccountID() {
+			details.ItemsToGive = items
+			continue
+		}
+		details.ItemsToReceive = items
+		if accountID != 0 {
+			details.Partner = steamid.SteamID(0).SetUniverse(1).SetType(1).SetInstance(1).SetAccountID(uint32(accountID))
+		}
+	}
+	return details
+}
+
+func parseConfirmationItems(html string) []ConfirmationItem {
+	var items []ConfirmationItem
+	for _, m := range reConfEconomyItem.FindAllStringSubmatch(html, -1) {
+		var item ConfirmationItem
+		if m[1] != "" {
+			item.AppID, _ = strconv.Atoi(m[1])
+			item.ClassID = m[2]
+			item.InstanceID = m[3]
+		} else {
+			item.AppID, _ = strconv.Atoi(m[4])
+			item.ContextID = m[5]
+			item.AssetID = m[6]
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,128 @@
+package steamcommunity
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamid"
+	"github.com/k64z/steamstacks/steamtest"
+)
+
+func TestParseConfirmationDetails(t *testing.T) {
+	// Accepting a received offer: the partner sent it, so their side is
+	// primary.
+	const page = `<div class="mobileconf_trade_area">
+<div class="tradeoffer" id="tradeofferid_6543210">
+	<div class="tradeoffer_items primary">
+		<div class="tradeoffer_items_avatar_ctn">
+			<a class="tradeoffer_avatar playerAvatar offline" href="https://steamcommunity.com/profiles/76561198000000002" data-miniprofile="39734274"></a>
+		</div>
+		<div class="tradeoffer_item_list">
+			<div class="trade_item " style="" data-economy-item="classinfo/440/101/0"></div>
+			<div class="trade_item " style="" data-economy-item="440/2/9876543"></div>
+		</div>
+	</div>
+	<div class="tradeoffer_items secondary">
+		<div class="tradeoffer_items_avatar_ctn">
+			<a class="tradeoffer_avatar playerAvatar online" href="https://steamcommunity.com/profiles/76561198000000001" data-miniprofile="39734273"></a>
+		</div>
+		<div class="tradeoffer_item_list">
+			<div class="trade_item " style="" data-economy-item="classinfo/730/200/55"></div>
+		</div>
+	</div>
+</div>
+</div>`
+
+	self := steamid.SteamID(76561198000000001)
+	details := parseConfirmationDetails(page, self)
+	if details.OfferID != "6543210" {
+		t.Errorf("OfferID = %q, want 6543210", details.OfferID)
+	}
+	if details.Partner != steamid.SteamID(76561198000000002) {
+		t.Errorf("Partner = %d, want 76561198000000002", details.Partner)
+	}
+	wantGive := []ConfirmationItem{{AppID: 730, ClassID: "200", InstanceID: "55"}}
+	wantReceive := []ConfirmationItem{
+		{AppID: 440, ClassID: "101", InstanceID: "0"},
+		{AppID: 440, ContextID: "2", AssetID: "9876543"},
+	}
+	if !slices.Equal(details.ItemsToGive, wantGive) {
+		t.Errorf("ItemsToGive = %+v, want %+v", details.ItemsToGive, wantGive)
+	}
+	if !slices.Equal(details.ItemsToReceive, wantReceive) {
+		t.Errorf("ItemsToReceive = %+v, want %+v", details.ItemsToReceive, wantReceive)
+	}
+
+	listing := parseConfirmationDetails(`<div id="mobileconf_listing_item" data-economy-item="classinfo/753/300/0"></div>`, self)
+	if listing.OfferID != "" || listing.Partner != 0 || len(listing.ItemsToGive) != 1 || listing.ItemsToGive[0].ClassID != "300" {
+		t.Errorf("listing details = %+v", listing)
+	}
+}
+
+func TestBatchConfirmations(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	secret := []byte("identity-secret-for-tests")
+	alice := srv.AddAccount(steamtest.Account{IdentitySecret: secret})
+	bob := srv.AddAccount(steamtest.Account{})
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+
+	key := srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Mann Co. Supply Crate Key", Tradable: true})
+	var offerIDs []string
+	for range 3 {
+		hat := srv.AddItem(alice.SteamID, steamtest.Item{AppID: 440, Name: "Team Captain", Tradable: true})
+		sent, err := alicec.SendTradeOffer(ctx, SendTradeOfferOptions{
+			Partner:        bob.SteamID,
+			ItemsToGive:    []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: hat.AssetID}},
+			ItemsToReceive: []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: key.AssetID}},
+		})
+		if err != nil {
+			t.Fatalf("SendTradeOffer: %v", err)
+		}
+		offerIDs = append(offerIDs, sent.TradeOfferID)
+	}
+
+	confs, err := alicec.GetConfirmations(ctx, secret)
+	if err != nil {
+		t.Fatalf("GetConfirmations: %v", err)
+	}
+	if len(confs) != 3 {
+		t.Fatalf("len(confs) = %d, want 3", len(confs))
+	}
+
+	details, err := alicec.GetConfirmationDetails(ctx, confs[0], secret)
+	if err != nil {
+		t.Fatalf("GetConfirmationDetails: %v", err)
+	}
+	if details.OfferID != confs[0].CreatorID || details.Partner != bob.SteamID {
+		t.Errorf("details = offer %s partner %d, want offer %s partner %d",
+			details.OfferID, details.Partner, confs[0].CreatorID, bob.SteamID)
+	}
+	if len(details.ItemsToGive) != 1 || len(details.ItemsToReceive) != 1 || details.ItemsToReceive[0].ClassID != key.ClassID {
+		t.Errorf("details items = give %+v receive %+v", details.ItemsToGive, details.ItemsToReceive)
+	}
+
+	if err := alicec.AcceptConfirmations(ctx, confs[:2], secret); err != nil {
+		t.Fatalf("AcceptConfirmations: %v", err)
+	}
+	if err := alicec.RejectConfirmations(ctx, confs[2:], secret); err != nil {
+		t.Fatalf("RejectConfirmations: %v", err)
+	}
+	wantStates := []steamapi.ETradeOfferState{
+		steamapi.ETradeOfferStateActive,
+		steamapi.ETradeOfferStateActive,
+		steamapi.ETradeOfferStateCanceledBySecondFactor,
+	}
+	for i, conf := range confs {
+		if offer, _ := srv.TradeOffer(conf.CreatorID); offer.State != wantStates[i] {
+			t.Errorf("offer %s state = %v, want %v", conf.CreatorID, offer.State, wantStates[i])
+		}
+	}
+	if err := alicec.AcceptConfirmations(ctx, confs[:1], secret); err == nil {
+		t.Error("accepting a confirmation twice: want error")
+	}
+}
@@ -0,0 +1,315 @@
+package steamcommunity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+type ConfirmationEventType int
+
+const (
+	// ConfirmationAccepted: a confirmation was accepted, because it was
+	// expected or the decider chose to.
+	ConfirmationAccepted ConfirmationEventType = iota + 1
+	// ConfirmationRejected: the decider rejected a confirmation.
+	ConfirmationRejected
+	// ConfirmationUnmatched: a confirmation that nothing claimed is
+	// pending. Reported once per confirmation.
+	ConfirmationUnmatched
+	// ConfirmationPollFailed: a poll failed and will be retried, after
+	// RetryIn.
+	ConfirmationPollFailed
+)
+
+func (t ConfirmationEventType) String() string {
+	switch t {
+	case ConfirmationAccepted:
+		return "ConfirmationAccepted"
+	case ConfirmationRejected:
+		return "ConfirmationRejected"
+	case ConfirmationUnmatched:
+		return "ConfirmationUnmatched"
+	case ConfirmationPollFailed:
+		return "ConfirmationPollFailed"
+	}
+	return fmt.Sprintf("ConfirmationEventType(%d)", int(t))
+}
+
+// ConfirmationEvent reports what the ConfirmationWatcher did or saw.
+type ConfirmationEvent struct {
+	Type         ConfirmationEventType
+	Confirmation *Confirmation // nil for ConfirmationPollFailed
+	Err          error         // set for ConfirmationPollFailed
+	RetryIn      time.Duration // set for ConfirmationPollFailed
+}
+
+// ConfirmationDecision is a decider's verdict on an unexpected confirmation.
+type ConfirmationDecision int
+
+const (
+	// ConfirmationLeave leaves the confirmation pending and reports it as
+	// ConfirmationUnmatched.
+	ConfirmationLeave ConfirmationDecision = iota
+	ConfirmationAccept
+	ConfirmationReject
+)
+
+// confirmationExpectTTL is how long an expected creator ID waits for its
+// confirmation to show up.
+const confirmationExpectTTL = 24 * time.Hour
+
+// ConfirmationWatcher polls the mobile confirmation list and accepts the
+// confirmations for trade offers and listings registered with Expect.
+// Accepts and rejects are batched into one request per poll.
+type ConfirmationWatcher struct {
+	c              *Community
+	identitySecret []byte
+
+	pollInterval time.Duration
+	maxBackoff   time.Duration
+	onEvent      func(ConfirmationEvent)
+	decide       func(Confirmation) ConfirmationDecision
+
+	pollNow chan struct{}
+	pollMu  sync.Mutex // serializes polls
+
+	mu       sync.Mutex
+	expected map[string]time.Time // creator ID -> when it was expected
+	reported map[string]bool      // confirmation IDs reported as unmatched
+}
+
+type confirmationWatcherConfig struct {
+	pollInterval time.Duration
+	maxBackoff   time.Duration
+	onEvent      func(ConfirmationEvent)
+	decide       func(Confirmation) ConfirmationDecision
+}
+
+type ConfirmationWatcherOption func(*confirmationWatcherConfig)
+
+// WithConfirmationPollInterval sets the time between polls. Default 30
+// seconds.
+func WithConfirmationPollInterval(d time.Duration) ConfirmationWatcherOption {
+	return func(c *confirmationWatcherConfig) {
+		c.pollInterval = d
+	}
+}
+
+// WithConfirmationMaxBackoff caps the delay between polls while Steam is
+// rate limiting. Default 10 minutes.
+func WithConfirmationMaxBackoff(d time.Duration) ConfirmationWatcherOption {
+	return func(c *confirmationWatcherConfig) {
+		c.maxBackoff = d
+	}
+}
+
+// WithConfirmationEventHandler registers a callback for confirmation
+// events. It runs on the polling goroutine.
+func WithConfirmationEventHandler(fn func(ConfirmationEvent)) ConfirmationWatcherOption {
+	return func(c *confirmationWatcherConfig) {
+		c.onEvent = fn
+	}
+}
+
+// WithConfirmationDecider registers a callback that decides what to do
+// with confirmations nobody expected. Without one they are left pending.
+// It runs on the polling goroutine and may call Expect or Forget.
+func WithConfirmationDecider(fn func(Confirmation) ConfirmationDecision) ConfirmationWatcherOption {
+	return func(c *confirmationWatcherConfig) {
+		c.decide = fn
+	}
+}
+
+// NewConfirmationWatcher creates a watcher for c's confirmations.
+// identitySecret is the base64-decoded identity_secret.
+func NewConfirmationWatcher(c *Community, identitySecret []byte, opts ...ConfirmationWatcherOption) (*ConfirmationWatcher, error) {
+	if c == nil {
+		return nil, errors.New("community should be non-nil")
+	}
+	if len(identitySecret) == 0 {
+		return nil, errors.New("identity secret is required")
+	}
+
+	cfg := confirmationWatcherConfig{
+		pollInterval: 30 * time.Second,
+		maxBackoff:   10 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.pollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+	if cfg.maxBackoff < cfg.pollInterval {
+		cfg.maxBackoff = cfg.pollInterval
+	}
+
+	return &ConfirmationWatcher{
+		c:              c,
+		identitySecret: identitySecret,
+		pollInterval:   cfg.pollInterval,
+		maxBackoff:     cfg.maxBackoff,
+		onEvent:        cfg.onEvent,
+		decide:         cfg.decide,
+		pollNow:        make(chan struct{}, 1),
+		expected:       make(map[string]time.Time),
+		reported:       make(map[string]bool),
+	}, nil
+}
+
+// Expect registers the IDs of trade offers or market listings we created,
+// so their confirmations are accepted when they show up. Call it when
+// SendTradeOffer, AcceptTradeOffer or SellMarketItem report that a
+// confirmation is needed, then PollNow.
+func (w *ConfirmationWatcher) Expect(creatorIDs ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	for _, id := range creatorIDs {
+		w.expected[id] = now
+	}
+}
+
+// Forget unregisters a creator ID passed to Expect.
+func (w *ConfirmationWatcher) Forget(creatorID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.expected, creatorID)
+}
+
+// PollNow makes Run poll immediately instead of waiting for the next tick,
+// unless it is backing off. It never blocks.
+func (w *ConfirmationWatcher) PollNow() {
+	select {
+	case w.pollNow <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls until ctx is done and returns ctx.Err(). Failed polls are
+// reported as ConfirmationPollFailed events and retried on the next tick;
+// while Steam is rate limiting, the delay doubles up to the max backoff.
+func (w *ConfirmationWatcher) Run(ctx context.Context) error {
+	var backoff time.Duration
+	for {
+		delay := w.pollInterval
+		if err := w.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, errRateLimited) {
+				backoff = nextConfirmationBackoff(backoff, w.pollInterval, w.maxBackoff)
+				delay = backoff
+			}
+			w.emit(ConfirmationEvent{Type: ConfirmationPollFailed, Err: err, RetryIn: delay})
+		} else {
+			backoff = 0
+		}
+
+		pollNow := w.pollNow
+		if backoff > 0 {
+			pollNow = nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-pollNow:
+		case <-time.After(delay):
+		}
+	}
+}
+
+// nextConfirmationBackoff doubles the backoff, starting from twice the
+// poll interval, up to limit.
+func nextConfirmationBackoff(cur, interval, limit time.Duration) time.Duration {
+	if cur == 0 {
+		cur = interval
+	}
+	return min(2*cur, limit)
+}
+
+// Poll fetches the pending confirmations, accepts the expected ones,
+// applies the decider to the rest and reports those left pending.
+func (w *ConfirmationWatcher) Poll(ctx context.Context) error {
+	w.pollMu.Lock()
+	defer w.pollMu.Unlock()
+
+	confs, err := w.c.GetConfirmations(ctx, w.identitySecret)
+	if err != nil {
+		return fmt.Errorf("get confirmations: %w", err)
+	}
+
+	w.mu.Lock()
+	for id, at := range w.expected {
+		if time.Since(at) > confirmationExpectTTL {
+			delete(w.expected, id)
+		}
+	}
+	expected := maps.Clone(w.expected)
+	prevReported := w.reported
+	w.mu.Unlock()
+
+	// The decider runs without w.mu held, so it may call Expect or Forget
+	// and take its time.
+	var accept, reject, unmatched []Confirmation
+	reported := make(map[string]bool, len(prevReported))
+	for _, conf := range confs {
+		if _, ok := expected[conf.CreatorID]; ok {
+			accept = append(accept, conf)
+			continue
+		}
+		decision := ConfirmationLeave
+		if w.decide != nil {
+			decision = w.decide(conf)
+		}
+		switch decision {
+		case ConfirmationAccept:
+			accept = append(accept, conf)
+		case ConfirmationReject:
+			reject = append(reject, conf)
+		default:
+			if !prevReported[conf.ID] {
+				unmatched = append(unmatched, conf)
+			}
+			reported[conf.ID] = true
+		}
+	}
+	// Confirmations that are gone no longer need remembering.
+	w.mu.Lock()
+	w.reported = reported
+	w.mu.Unlock()
+
+	for i := range unmatched {
+		w.emit(ConfirmationEvent{Type: ConfirmationUnmatched, Confirmation: &unmatched[i]})
+	}
+
+	if err := w.c.respondToConfirmations(ctx, accept, w.identitySecret, true); err != nil {
+		return fmt.Errorf("accept confirmations: %w", err)
+	}
+	w.mu.Lock()
+	for _, conf := range accept {
+		delete(w.expected, conf.CreatorID)
+	}
+	w.mu.Unlock()
+	for i := range accept {
+		w.emit(ConfirmationEvent{Type: ConfirmationAccepted, Confirmation: &accept[i]})
+	}
+
+	if err := w.c.respondToConfirmations(ctx, reject, w.identitySecret, false); err != nil {
+		return fmt.Errorf("reject confirmations: %w", err)
+	}
+	for i := range reject {
+		w.emit(ConfirmationEvent{Type: ConfirmationRejected, Confirmation: &reject[i]})
+	}
+	return nil
+}
+
+func (w *ConfirmationWatcher) emit(evt ConfirmationEvent) {
+	if w.onEvent != nil {
+		w.onEvent(evt)
+	}
+}
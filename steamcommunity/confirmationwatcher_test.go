@@ -0,0 +1,157 @@
+package steamcommunity
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamtest"
+)
+
+func TestConfirmationWatcher(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	secret := []byte("identity-secret-for-tests")
+	alice := srv.AddAccount(steamtest.Account{IdentitySecret: secret})
+	bob := srv.AddAccount(steamtest.Account{})
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+
+	var offerIDs []string
+	for _, name := range []string{"Team Captain", "Bill's Hat", "Earbuds"} {
+		item := srv.AddItem(alice.SteamID, steamtest.Item{AppID: 440, Name: name, Tradable: true})
+		sent, err := alicec.SendTradeOffer(ctx, SendTradeOfferOptions{
+			Partner:     bob.SteamID,
+			ItemsToGive: []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: item.AssetID}},
+		})
+		if err != nil {
+			t.Fatalf("SendTradeOffer: %v", err)
+		}
+		offerIDs = append(offerIDs, sent.TradeOfferID)
+	}
+	ours, unknown, unwanted := offerIDs[0], offerIDs[1], offerIDs[2]
+
+	var events []ConfirmationEvent
+	w, err := NewConfirmationWatcher(alicec, secret,
+		WithConfirmationEventHandler(func(evt ConfirmationEvent) { events = append(events, evt) }),
+		WithConfirmationDecider(func(conf Confirmation) ConfirmationDecision {
+			if conf.CreatorID == unwanted {
+				return ConfirmationReject
+			}
+			return ConfirmationLeave
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewConfirmationWatcher: %v", err)
+	}
+	w.Expect(ours)
+
+	for range 2 {
+		if err := w.Poll(ctx); err != nil {
+			t.Fatalf("Poll: %v", err)
+		}
+	}
+
+	got := make(map[string]ConfirmationEventType)
+	for _, evt := range events {
+		if _, dup := got[evt.Confirmation.CreatorID]; dup {
+			t.Errorf("second %v event for %s", evt.Type, evt.Confirmation.CreatorID)
+		}
+		got[evt.Confirmation.CreatorID] = evt.Type
+	}
+	want := map[string]ConfirmationEventType{
+		ours:     ConfirmationAccepted,
+		unknown:  ConfirmationUnmatched,
+		unwanted: ConfirmationRejected,
+	}
+	for id, typ := range want {
+		if got[id] != typ {
+			t.Errorf("event for %s = %v, want %v", id, got[id], typ)
+		}
+	}
+
+	wantStates := map[string]steamapi.ETradeOfferState{
+		ours:     steamapi.ETradeOfferStateActive,
+		unknown:  steamapi.ETradeOfferStateCreatedNeedsConfirmation,
+		unwanted: steamapi.ETradeOfferStateCanceledBySecondFactor,
+	}
+	for id, state := range wantStates {
+		if offer, _ := srv.TradeOffer(id); offer.State != state {
+			t.Errorf("offer %s state = %v, want %v", id, offer.State, state)
+		}
+	}
+	if confs := srv.Confirmations(alice.SteamID); len(confs) != 1 || confs[0].CreatorID != unknown {
+		t.Errorf("pending confirmations = %+v, want only %s", confs, unknown)
+	}
+}
+
+func TestConfirmationWatcherDeciderCallsExpect(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	secret := []byte("identity-secret-for-tests")
+	alice := srv.AddAccount(steamtest.Account{IdentitySecret: secret})
+	bob := srv.AddAccount(steamtest.Account{})
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+
+	item := srv.AddItem(alice.SteamID, steamtest.Item{AppID: 440, Name: "Team Captain", Tradable: true})
+	sent, err := alicec.SendTradeOffer(ctx, SendTradeOfferOptions{
+		Partner:     bob.SteamID,
+		ItemsToGive: []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: item.AssetID}},
+	})
+	if err != nil {
+		t.Fatalf("SendTradeOffer: %v", err)
+	}
+
+	var w *ConfirmationWatcher
+	w, err = NewConfirmationWatcher(alicec, secret,
+		WithConfirmationDecider(func(conf Confirmation) ConfirmationDecision {
+			// Claim it for the next poll instead of deciding now.
+			w.Expect(conf.CreatorID)
+			return ConfirmationLeave
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewConfirmationWatcher: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		for range 2 {
+			if err := w.Poll(ctx); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Poll: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Poll deadlocked on a decider calling Expect")
+	}
+
+	if offer, _ := srv.TradeOffer(sent.TradeOfferID); offer.State != steamapi.ETradeOfferStateActive {
+		t.Errorf("offer state = %v, want Active", offer.State)
+	}
+}
+
+func TestNextConfirmationBackoff(t *testing.T) {
+	var got []time.Duration
+	var backoff time.Duration
+	for range 5 {
+		backoff = nextConfirmationBackoff(backoff, 30*time.Second, 3*time.Minute)
+		got = append(got, backoff)
+	}
+	want := []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute, 3 * time.Minute}
+	if !slices.Equal(got, want) {
+		t.Errorf("backoffs = %v, want %v", got, want)
+	}
+}
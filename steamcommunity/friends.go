@@ -32,7 +32,7 @@ func (c *Community) GetFriendsList(ctx context.Context) (map[steamid.SteamID]EFr
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://steamcommunity.com/textfilter/ajaxgetfriendslist", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/textfilter/ajaxgetfriendslist", nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -87,7 +87,7 @@ func (c *Community) AddFriend(ctx context.Context, target steamid.SteamID) error
 	extra.Set("steamid", strconv.FormatUint(target.ToSteamID64(), 10))
 	extra.Set("accept_invite", "0")
 
-	resp, err := c.postAction(ctx, "https://steamcommunity.com/actions/AddFriendAjax", extra)
+	resp, err := c.postAction(ctx, c.baseURL+"/actions/AddFriendAjax", extra)
 	if err != nil {
 		return err
 	}
@@ -111,7 +111,7 @@ func (c *Community) AcceptFriendRequest(ctx context.Context, target steamid.Stea
 	extra.Set("steamid", strconv.FormatUint(target.ToSteamID64(), 10))
 	extra.Set("accept_invite", "1")
 
-	resp, err := c.postAction(ctx, "https://steamcommunity.com/actions/AddFriendAjax", extra)
+	resp, err := c.postAction(ctx, c.baseURL+"/actions/AddFriendAjax", extra)
 	if err != nil {
 		return err
 	}
@@ -123,7 +123,7 @@ func (c *Community) RemoveFriend(ctx context.Context, target steamid.SteamID) er
 	extra := url.Values{}
 	extra.Set("steamid", strconv.FormatUint(target.ToSteamID64(), 10))
 
-	resp, err := c.postAction(ctx, "https://steamcommunity.com/actions/RemoveFriendAjax", extra)
+	resp, err := c.postAction(ctx, c.baseURL+"/actions/RemoveFriendAjax", extra)
 	if err != nil {
 		return err
 	}
@@ -136,7 +136,7 @@ func (c *Community) BlockUser(ctx context.Context, target steamid.SteamID) error
 	extra := url.Values{}
 	extra.Set("steamid", strconv.FormatUint(target.ToSteamID64(), 10))
 
-	resp, err := c.postAction(ctx, "https://steamcommunity.com/actions/BlockUserAjax", extra)
+	resp, err := c.postAction(ctx, c.baseURL+"/actions/BlockUserAjax", extra)
 	if err != nil {
 		return err
 	}
@@ -149,7 +149,7 @@ func (c *Community) UnblockUser(ctx context.Context, target steamid.SteamID) err
 	extra.Set("steamid", strconv.FormatUint(target.ToSteamID64(), 10))
 	extra.Set("block", "0")
 
-	resp, err := c.postAction(ctx, "https://steamcommunity.com/actions/BlockUserAjax", extra)
+	resp, err := c.postAction(ctx, c.baseURL+"/actions/BlockUserAjax", extra)
 	if err != nil {
 		return err
 	}
@@ -145,15 +145,15 @@ var (
 
 func (c *Community) GetInventory(ctx context.Context, steamID steamid.SteamID, appID int, contextID string) ([]InventoryItem, error) {
 	steamID64 := strconv.FormatUint(steamID.ToSteamID64(), 10)
-	referer := fmt.Sprintf("https://steamcommunity.com/profiles/%s/inventory", steamID64)
+	referer := fmt.Sprintf("%s/profiles/%s/inventory", c.baseURL, steamID64)
 
 	var allItems []InventoryItem
 	var startAssetID string
 
 	for {
 		reqURL := fmt.Sprintf(
-			"https://steamcommunity.com/inventory/%s/%d/%s?l=english&count=1000",
-			steamID64, appID, contextID,
+			"%s/inventory/%s/%d/%s?l=english&count=1000",
+			c.baseURL, steamID64, appID, contextID,
 		)
 		if startAssetID != "" {
 			reqURL += "&start_assetid=" + startAssetID
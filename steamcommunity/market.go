@@ -16,12 +16,17 @@ import (
 )
 
 // MarketPriceOverview is the Steam Community Market /priceoverview/
-// response. Prices are localized strings (e.g. "$1.50", "1,50€").
+// response. Prices are localized strings (e.g. "$1.50", "1,50€");
+// the *Cents fields hold them parsed with ParseMarketPrice, or 0 when
+// Steam left the price out.
 type MarketPriceOverview struct {
 	Success     bool   `json:"success"`
 	LowestPrice string `json:"lowest_price"`
 	MedianPrice string `json:"median_price"`
 	Volume      string `json:"volume"`
+
+	LowestPriceCents int `json:"-"`
+	MedianPriceCents int `json:"-"`
 }
 
 // MarketSellResult captures the /sellitem/ response. A successful
@@ -40,11 +45,11 @@ type MarketSellResult struct {
 // Market-side error taxonomy. Callers typically log-and-continue on
 // these rather than aborting the whole cycle.
 var (
-	ErrMarketItemServerDown       = errors.New("market: game's item server may be down")
-	ErrMarketPendingConfirmation  = errors.New("market: listing pending confirmation for this item")
-	ErrMarketItemNotInInventory   = errors.New("market: item no longer in inventory")
-	ErrMarketListingProblem       = errors.New("market: generic listing problem; retry")
-	ErrMarketWalletTooMuchMoney   = errors.New("market: wallet holds too much money")
+	ErrMarketItemServerDown        = errors.New("market: game's item server may be down")
+	ErrMarketPendingConfirmation   = errors.New("market: listing pending confirmation for this item")
+	ErrMarketItemNotInInventory    = errors.New("market: item no longer in inventory")
+	ErrMarketListingProblem        = errors.New("market: generic listing problem; retry")
+	ErrMarketWalletTooMuchMoney    = errors.New("market: wallet holds too much money")
 	ErrMarketPreviousActionPending = errors.New("market: previous action still pending")
 )
 
@@ -56,7 +61,7 @@ func (c *Community) GetMarketPriceOverview(ctx context.Context, appID, currency
 	q.Set("currency", strconv.Itoa(currency))
 	q.Set("market_hash_name", marketHashName)
 
-	reqURL := "https://steamcommunity.com/market/priceoverview/?" + q.Encode()
+	reqURL := c.baseURL + "/market/priceoverview/?" + q.Encode()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
@@ -81,12 +86,49 @@ func (c *Community) GetMarketPriceOverview(ctx context.Context, appID, currency
 	if err := json.Unmarshal(body, out); err != nil {
 		return nil, fmt.Errorf("decode: %w", err)
 	}
+	if out.LowestPrice != "" {
+		if out.LowestPriceCents, err = ParseMarketPrice(out.LowestPrice); err != nil {
+			return nil, fmt.Errorf("lowest price: %w", err)
+		}
+	}
+	if out.MedianPrice != "" {
+		if out.MedianPriceCents, err = ParseMarketPrice(out.MedianPrice); err != nil {
+			return nil, fmt.Errorf("median price: %w", err)
+		}
+	}
 	return out, nil
 }
 
+// SellMarketItemOption configures SellMarketItem.
+type SellMarketItemOption func(*sellMarketItemConfig)
+
+type sellMarketItemConfig struct {
+	buyerPrice       bool
+	publisherPercent float64
+}
+
+// NoPublisherFee is the publisher fee percent for games that don't take
+// one, for SellAtBuyerPrice and BulkSellItem.
+const NoPublisherFee = -1
+
+// SellAtBuyerPrice makes SellMarketItem read priceCents as what the
+// buyer pays and list for the seller amount it splits into under the
+// wallet's Steam fee and the game's publisher fee, publisherPercent.
+// 0 means the wallet's default publisher fee, which most games take;
+// pass the app's own where it differs, or NoPublisherFee. Where no seller
+// amount adds up to exactly that price, the listing comes out a hundredth
+// or so cheaper, as on the site.
+func SellAtBuyerPrice(publisherPercent float64) SellMarketItemOption {
+	return func(cfg *sellMarketItemConfig) {
+		cfg.buyerPrice = true
+		cfg.publisherPercent = publisherPercent
+	}
+}
+
 // SellMarketItem lists an inventory item on the Steam Community Market.
 // priceCents is what the seller receives (i.e. Steam's cut is added on
-// top for the buyer). amount is almost always 1 for TF2 items.
+// top for the buyer), unless SellAtBuyerPrice is given. amount is almost
+// always 1 for TF2 items.
 //
 // On known failure patterns we return a typed error so the caller can
 // log-and-skip without string matching. On success the listing is
@@ -94,10 +136,32 @@ func (c *Community) GetMarketPriceOverview(ctx context.Context, appID, currency
 // whose CreatorID matches the listing. Since Steam doesn't return the
 // listing ID in this response, matching by timestamp/position is the
 // practical approach.
-func (c *Community) SellMarketItem(ctx context.Context, appID int, contextID uint64, assetID uint64, amount, priceCents int) (*MarketSellResult, error) {
+func (c *Community) SellMarketItem(ctx context.Context, appID int, contextID uint64, assetID uint64, amount, priceCents int, opts ...SellMarketItemOption) (*MarketSellResult, error) {
 	if err := c.ensureInit(); err != nil {
 		return nil, err
 	}
+	var cfg sellMarketItemConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.buyerPrice {
+		wallet, err := c.cachedWallet(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get wallet fees: %w", err)
+		}
+		fees := wallet.Fees()
+		switch {
+		case cfg.publisherPercent == NoPublisherFee:
+			fees.PublisherPercent = 0
+		case cfg.publisherPercent > 0:
+			fees.PublisherPercent = cfg.publisherPercent
+		}
+		price := fees.SellerReceives(priceCents)
+		if price.SellerReceives < 1 {
+			return nil, fmt.Errorf("buyer price %d is below the market minimum", priceCents)
+		}
+		priceCents = price.SellerReceives
+	}
 
 	form := url.Values{}
 	form.Set("amount", strconv.Itoa(amount))
@@ -108,13 +172,13 @@ func (c *Community) SellMarketItem(ctx context.Context, appID int, contextID uin
 	form.Set("sessionid", c.sessionID)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
-		"https://steamcommunity.com/market/sellitem/",
+		c.baseURL+"/market/sellitem/",
 		strings.NewReader(form.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Referer", "https://steamcommunity.com/market/")
+	req.Header.Set("Referer", c.baseURL+"/market/")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -127,7 +191,11 @@ func (c *Community) SellMarketItem(ctx context.Context, appID int, contextID uin
 		return nil, fmt.Errorf("read body: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return nil, errRateLimited
+	default:
 		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
 	}
 
@@ -184,7 +252,7 @@ type PendingListing struct {
 // approach. Returns deduplicated listing IDs in document order.
 func (c *Community) GetMyMarketListingIDs(ctx context.Context) ([]string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
-		"https://steamcommunity.com/market/", nil)
+		c.baseURL+"/market/", nil)
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
@@ -227,7 +295,7 @@ func (c *Community) GetMyMarketListingIDs(ctx context.Context) ([]string, error)
 // document order; empty slice when the section is empty.
 func (c *Community) GetMyPendingMarketListings(ctx context.Context) ([]PendingListing, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
-		"https://steamcommunity.com/market/", nil)
+		c.baseURL+"/market/", nil)
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
@@ -384,12 +452,12 @@ func (c *Community) GetMarketListings(ctx context.Context, start, count int) (*M
 	q.Set("count", strconv.Itoa(count))
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
-		"https://steamcommunity.com/market/mylistings/render/?"+q.Encode(), nil)
+		c.baseURL+"/market/mylistings/render/?"+q.Encode(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
 	req.Header.Set("Accept", "application/json, text/plain, */*")
-	req.Header.Set("Referer", "https://steamcommunity.com/market/")
+	req.Header.Set("Referer", c.baseURL+"/market/")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -588,14 +656,14 @@ func (c *Community) CancelMarketListing(ctx context.Context, listingID string) e
 	form := url.Values{}
 	form.Set("sessionid", c.sessionID)
 
-	reqURL := "https://steamcommunity.com/market/removelisting/" + url.PathEscape(listingID)
+	reqURL := c.baseURL + "/market/removelisting/" + url.PathEscape(listingID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL,
 		strings.NewReader(form.Encode()))
 	if err != nil {
 		return fmt.Errorf("new request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Referer", "https://steamcommunity.com/market")
+	req.Header.Set("Referer", c.baseURL+"/market")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/k64z/steamstacks/steamtest"
 )
 
 func TestGetMarketPriceOverview(t *testing.T) {
@@ -39,6 +41,9 @@ func TestGetMarketPriceOverview(t *testing.T) {
 	if !o.Success || o.MedianPrice != "$1.75" || o.LowestPrice != "$1.50" || o.Volume != "42" {
 		t.Errorf("unexpected overview: %+v", o)
 	}
+	if o.LowestPriceCents != 150 || o.MedianPriceCents != 175 {
+		t.Errorf("cents = %d/%d, want 150/175", o.LowestPriceCents, o.MedianPriceCents)
+	}
 }
 
 func TestSellMarketItemSuccess(t *testing.T) {
@@ -105,6 +110,50 @@ func TestSellMarketItemTypedErrors(t *testing.T) {
 	}
 }
 
+func TestSellAtBuyerPrice(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	bob := srv.AddAccount(steamtest.Account{})
+	ctx := context.Background()
+	bobc := newSteamtestCommunity(t, srv, bob)
+
+	for _, buyerPrice := range []int{115, 22} {
+		item := srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Earbuds", Marketable: true})
+		if _, err := bobc.SellMarketItem(ctx, 440, 2, mustParseUint(t, item.AssetID), 1, buyerPrice, SellAtBuyerPrice(0)); err != nil {
+			t.Fatalf("SellMarketItem(%d): %v", buyerPrice, err)
+		}
+	}
+	listings := srv.Listings(bob.SteamID)
+	if len(listings) != 2 || listings[0].Price != 100 || listings[1].Price != 19 {
+		t.Errorf("listings = %+v, want seller prices 100 and 19", listings)
+	}
+
+	// A game taking 15% instead of 10%: 120 is 100 + 5 + 15, where the
+	// default split would list at 105.
+	item := srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Earbuds", Marketable: true})
+	if _, err := bobc.SellMarketItem(ctx, 440, 2, mustParseUint(t, item.AssetID), 1, 120, SellAtBuyerPrice(0.15)); err != nil {
+		t.Fatalf("SellMarketItem(120, 15%%): %v", err)
+	}
+	if listings := srv.Listings(bob.SteamID); len(listings) != 3 || listings[2].Price != 100 {
+		t.Errorf("listings = %+v, want seller price 100 at a 15%% publisher fee", listings)
+	}
+
+	// No publisher fee: 105 is 100 + 5.
+	item = srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Earbuds", Marketable: true})
+	if _, err := bobc.SellMarketItem(ctx, 440, 2, mustParseUint(t, item.AssetID), 1, 105, SellAtBuyerPrice(NoPublisherFee)); err != nil {
+		t.Fatalf("SellMarketItem(105, no publisher fee): %v", err)
+	}
+	if listings := srv.Listings(bob.SteamID); len(listings) != 4 || listings[3].Price != 100 {
+		t.Errorf("listings = %+v, want seller price 100 without a publisher fee", listings)
+	}
+
+	item = srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Earbuds", Marketable: true})
+	if _, err := bobc.SellMarketItem(ctx, 440, 2, mustParseUint(t, item.AssetID), 1, 2, SellAtBuyerPrice(0)); err == nil {
+		t.Error("buyer price below minimum: want error")
+	}
+}
+
 func TestGetMyMarketListingIDs(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
@@ -0,0 +1,417 @@
+package steamcommunity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/k64z/steamstacks/market"
+	"github.com/k64z/steamstacks/steamapi"
+)
+
+// Buy-side additions to the market error taxonomy.
+var (
+	ErrMarketInsufficientFunds = errors.New("market: not enough funds in wallet")
+	ErrMarketBuyOrderExists    = errors.New("market: buy order already active for this item")
+	ErrMarketListingGone       = errors.New("market: listing sold, removed or repriced")
+	ErrMarketCurrencyMismatch  = errors.New("market: price currency doesn't match wallet")
+)
+
+// MarketWalletInfo is the g_rgWalletInfo blob the market page embeds.
+// Amounts are in the wallet currency's smallest unit.
+type MarketWalletInfo struct {
+	Currency       int    `json:"wallet_currency"` // Steam currency code
+	Country        string `json:"wallet_country"`
+	Balance        int    `json:"wallet_balance,string"`
+	DelayedBalance int    `json:"wallet_delayed_balance,string"`
+	MaxBalance     int    `json:"wallet_max_balance,string"`
+
+	FeePercent          float64 `json:"wallet_fee_percent,string"`
+	FeeMinimum          int     `json:"wallet_fee_minimum,string"`
+	FeeBase             int     `json:"wallet_fee_base,string"`
+	PublisherFeePercent float64 `json:"wallet_publisher_fee_percent_default,string"`
+}
+
+// Fees returns the wallet's market fees, or market.DefaultFees if the
+// page left them out.
+func (w *MarketWalletInfo) Fees() market.Fees {
+	if w.FeePercent == 0 && w.FeeMinimum == 0 && w.PublisherFeePercent == 0 {
+		return market.DefaultFees
+	}
+	return market.Fees{
+		SteamPercent:     w.FeePercent,
+		SteamMinimum:     w.FeeMinimum,
+		SteamBase:        w.FeeBase,
+		PublisherPercent: w.PublisherFeePercent,
+	}
+}
+
+// CreateBuyOrderOptions describes a buy order. PriceCents is per unit;
+// Steam reserves PriceCents*Quantity from the wallet.
+type CreateBuyOrderOptions struct {
+	AppID          int
+	MarketHashName string
+	PriceCents     int
+	Quantity       int // defaults to 1
+}
+
+// BuyOrderResult is the /createbuyorder/ response.
+type BuyOrderResult struct {
+	BuyOrderID string `json:"buy_orderid"`
+}
+
+// BuyOrderStatus is the /getbuyorderstatus/ response. Purchases lists
+// the listings bought so far against the order.
+type BuyOrderStatus struct {
+	Active            bool
+	Purchased         int
+	Quantity          int
+	QuantityRemaining int
+	Purchases         []BuyOrderPurchase
+}
+
+// BuyOrderPurchase is one fill of a buy order. Prices are in the wallet
+// currency; PriceTotal = PriceSubtotal + PriceFee.
+type BuyOrderPurchase struct {
+	ListingID     string `json:"listingid"`
+	AppID         int    `json:"appid"`
+	ContextID     string `json:"contextid"`
+	AssetID       string `json:"assetid"`
+	PriceSubtotal int    `json:"price_subtotal"`
+	PriceFee      int    `json:"price_fee"`
+	PriceTotal    int    `json:"price_total"`
+}
+
+// BuyListingOptions prices a listing purchase. Steam rejects the buy
+// unless the amounts match the listing exactly: Subtotal is what the
+// seller receives, Fee the Steam and publisher fees, Total their sum.
+type BuyListingOptions struct {
+	Subtotal int
+	Fee      int
+	Total    int
+	Quantity int // defaults to 1
+}
+
+// BuyListingResult is the /buylisting/ response: the wallet after the
+// purchase.
+type BuyListingResult struct {
+	Wallet MarketWalletInfo `json:"wallet_info"`
+}
+
+var walletInfoRE = regexp.MustCompile(`g_rgWalletInfo\s*=\s*(\{.*?\});`)
+
+// GetMarketWalletInfo scrapes the wallet from the market home page. The
+// currency and fees are cached for the methods that must quote them.
+func (c *Community) GetMarketWalletInfo(ctx context.Context) (*MarketWalletInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.baseURL+"/market/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+	}
+
+	m := walletInfoRE.FindSubmatch(body)
+	if m == nil {
+		return nil, errors.New("wallet info not found on market page")
+	}
+	info := &MarketWalletInfo{}
+	if err := json.Unmarshal(m[1], info); err != nil {
+		return nil, fmt.Errorf("decode wallet info: %w", err)
+	}
+	if info.Currency == 0 {
+		return nil, errors.New("account has no wallet")
+	}
+
+	c.walletMu.Lock()
+	c.wallet = info
+	c.walletMu.Unlock()
+	return info, nil
+}
+
+// cachedWallet returns the wallet info, loading it on first use. Only
+// the currency and fees are meant to be read off it; the balance is
+// stale.
+func (c *Community) cachedWallet(ctx context.Context) (*MarketWalletInfo, error) {
+	c.walletMu.Lock()
+	info := c.wallet
+	c.walletMu.Unlock()
+	if info != nil {
+		return info, nil
+	}
+	return c.GetMarketWalletInfo(ctx)
+}
+
+// marketCurrency returns the wallet currency.
+func (c *Community) marketCurrency(ctx context.Context) (int, error) {
+	info, err := c.cachedWallet(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get wallet currency: %w", err)
+	}
+	return info.Currency, nil
+}
+
+// CreateBuyOrder places a buy order in the wallet currency. Steam allows
+// one active order per item; a second returns ErrMarketBuyOrderExists.
+func (c *Community) CreateBuyOrder(ctx context.Context, opts CreateBuyOrderOptions) (*BuyOrderResult, error) {
+	if err := c.ensureInit(); err != nil {
+		return nil, err
+	}
+	if opts.Quantity <= 0 {
+		opts.Quantity = 1
+	}
+	if opts.PriceCents <= 0 {
+		return nil, errors.New("price must be positive")
+	}
+	currency, err := c.marketCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("sessionid", c.sessionID)
+	form.Set("currency", strconv.Itoa(currency))
+	form.Set("appid", strconv.Itoa(opts.AppID))
+	form.Set("market_hash_name", opts.MarketHashName)
+	form.Set("price_total", strconv.Itoa(opts.PriceCents*opts.Quantity))
+	form.Set("quantity", strconv.Itoa(opts.Quantity))
+	form.Set("billing_state", "")
+	form.Set("save_my_address", "0")
+
+	referer := c.baseURL + "/market/listings/" + strconv.Itoa(opts.AppID) + "/" + url.PathEscape(opts.MarketHashName)
+	body, err := c.postMarketForm(ctx, "/market/createbuyorder/", referer, form)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		BuyOrderResult
+		Success int    `json:"success"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if out.Success != 1 || out.BuyOrderID == "" {
+		return nil, marketBuyError("create buy order", out.Message)
+	}
+	return &out.BuyOrderResult, nil
+}
+
+// CancelBuyOrder cancels an active buy order. Units already bought are
+// kept.
+func (c *Community) CancelBuyOrder(ctx context.Context, buyOrderID string) error {
+	if err := c.ensureInit(); err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("sessionid", c.sessionID)
+	form.Set("buy_orderid", buyOrderID)
+
+	body, err := c.postMarketForm(ctx, "/market/cancelbuyorder/", c.baseURL+"/market/", form)
+	if err != nil {
+		return err
+	}
+
+	var out struct {
+		Success int `json:"success"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	if out.Success != 1 {
+		return fmt.Errorf("cancel buy order failed: success=%d", out.Success)
+	}
+	return nil
+}
+
+// GetBuyOrderStatus reports how much of a buy order has been filled.
+func (c *Community) GetBuyOrderStatus(ctx context.Context, buyOrderID string) (*BuyOrderStatus, error) {
+	if err := c.ensureInit(); err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("sessionid", c.sessionID)
+	q.Set("buy_orderid", buyOrderID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.baseURL+"/market/getbuyorderstatus/?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+	}
+
+	var out struct {
+		Success           int                `json:"success"`
+		Active            int                `json:"active"`
+		Purchased         int                `json:"purchased"`
+		Quantity          int                `json:"quantity,string"`
+		QuantityRemaining int                `json:"quantity_remaining,string"`
+		Purchases         []BuyOrderPurchase `json:"purchases"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if out.Success != 1 {
+		return nil, fmt.Errorf("get buy order status failed: success=%d", out.Success)
+	}
+	return &BuyOrderStatus{
+		Active:            out.Active == 1,
+		Purchased:         out.Purchased,
+		Quantity:          out.Quantity,
+		QuantityRemaining: out.QuantityRemaining,
+		Purchases:         out.Purchases,
+	}, nil
+}
+
+// BuyListing buys a listing outright in the wallet currency. Listings
+// that sold or changed price in the meantime return ErrMarketListingGone.
+func (c *Community) BuyListing(ctx context.Context, listingID string, opts BuyListingOptions) (*BuyListingResult, error) {
+	if err := c.ensureInit(); err != nil {
+		return nil, err
+	}
+	if opts.Quantity <= 0 {
+		opts.Quantity = 1
+	}
+	if opts.Total != opts.Subtotal+opts.Fee {
+		return nil, fmt.Errorf("total %d is not subtotal %d plus fee %d", opts.Total, opts.Subtotal, opts.Fee)
+	}
+	currency, err := c.marketCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("sessionid", c.sessionID)
+	form.Set("currency", strconv.Itoa(currency))
+	form.Set("subtotal", strconv.Itoa(opts.Subtotal))
+	form.Set("fee", strconv.Itoa(opts.Fee))
+	form.Set("total", strconv.Itoa(opts.Total))
+	form.Set("quantity", strconv.Itoa(opts.Quantity))
+	form.Set("billing_state", "")
+	form.Set("save_my_address", "0")
+
+	// Steam answers a failed purchase with a 502 and a JSON message, so
+	// decode the body before looking at the status.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.baseURL+"/market/buylisting/"+url.PathEscape(listingID),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", c.baseURL+"/market/")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	var out struct {
+		BuyListingResult
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+		}
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if out.Message != "" {
+		return nil, marketBuyError("buy listing", out.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+	}
+	return &out.BuyListingResult, nil
+}
+
+// postMarketForm POSTs a form to a market endpoint and returns the body
+// of a 200 response.
+func (c *Community) postMarketForm(ctx context.Context, path, referer string, form url.Values) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", referer)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// marketBuyError maps the messages of the buy endpoints onto the market
+// error taxonomy.
+func marketBuyError(op, message string) error {
+	switch {
+	case strings.Contains(message, "enough funds"), strings.Contains(message, "enough money"):
+		return ErrMarketInsufficientFunds
+	case strings.Contains(message, "You already have an active buy order"):
+		return ErrMarketBuyOrderExists
+	case strings.Contains(message, "listing may have been removed"), strings.Contains(message, "price of this item has changed"):
+		return ErrMarketListingGone
+	case strings.Contains(message, "currency"):
+		return ErrMarketCurrencyMismatch
+	case strings.Contains(message, "until your previous action completes"):
+		return ErrMarketPreviousActionPending
+	case strings.Contains(message, "The game's item server may be down"):
+		return ErrMarketItemServerDown
+	}
+	if message == "" {
+		return fmt.Errorf("market %s failed", op)
+	}
+	return fmt.Errorf("market %s failed: %s", op, message)
+}
@@ -0,0 +1,120 @@
+package steamcommunity
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/k64z/steamstacks/steamtest"
+)
+
+func TestMarketBuyOrders(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(steamtest.Account{WalletBalance: 1000})
+	bob := srv.AddAccount(steamtest.Account{})
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+	bobc := newSteamtestCommunity(t, srv, bob)
+
+	info, err := alicec.GetMarketWalletInfo(ctx)
+	if err != nil {
+		t.Fatalf("GetMarketWalletInfo: %v", err)
+	}
+	if info.Currency != 1 || info.Balance != 1000 {
+		t.Errorf("wallet = %+v, want currency 1 balance 1000", info)
+	}
+
+	order, err := alicec.CreateBuyOrder(ctx, CreateBuyOrderOptions{AppID: 440, MarketHashName: "Earbuds", PriceCents: 130, Quantity: 2})
+	if err != nil {
+		t.Fatalf("CreateBuyOrder: %v", err)
+	}
+	if _, err := alicec.CreateBuyOrder(ctx, CreateBuyOrderOptions{AppID: 440, MarketHashName: "Earbuds", PriceCents: 120}); !errors.Is(err, ErrMarketBuyOrderExists) {
+		t.Errorf("second buy order: err = %v, want ErrMarketBuyOrderExists", err)
+	}
+	if _, err := alicec.CreateBuyOrder(ctx, CreateBuyOrderOptions{AppID: 440, MarketHashName: "Bill's Hat", PriceCents: 5000}); !errors.Is(err, ErrMarketInsufficientFunds) {
+		t.Errorf("unaffordable buy order: err = %v, want ErrMarketInsufficientFunds", err)
+	}
+
+	// Bob lists one for 100 (115 to the buyer): it fills the order at once.
+	earbuds := srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Earbuds", Marketable: true})
+	if _, err := bobc.SellMarketItem(ctx, 440, 2, mustParseUint(t, earbuds.AssetID), 1, 100); err != nil {
+		t.Fatalf("SellMarketItem: %v", err)
+	}
+
+	status, err := alicec.GetBuyOrderStatus(ctx, order.BuyOrderID)
+	if err != nil {
+		t.Fatalf("GetBuyOrderStatus: %v", err)
+	}
+	if !status.Active || status.Quantity != 2 || status.QuantityRemaining != 1 || len(status.Purchases) != 1 {
+		t.Fatalf("status = %+v, want active with 1 of 2 bought", status)
+	}
+	if p := status.Purchases[0]; p.PriceSubtotal != 100 || p.PriceTotal != 115 || p.PriceFee != 15 {
+		t.Errorf("purchase = %+v, want 100 + 15 fee", p)
+	}
+	if got := srv.Wallet(alice.SteamID); got != 885 {
+		t.Errorf("alice wallet = %d, want 885", got)
+	}
+	if got := srv.Wallet(bob.SteamID); got != 100 {
+		t.Errorf("bob wallet = %d, want 100", got)
+	}
+	if inv := srv.Inventory(alice.SteamID, 440, "2"); len(inv) != 1 || inv[0].MarketHashName != "Earbuds" {
+		t.Errorf("alice inventory = %+v, want the earbuds", inv)
+	}
+
+	if err := alicec.CancelBuyOrder(ctx, order.BuyOrderID); err != nil {
+		t.Fatalf("CancelBuyOrder: %v", err)
+	}
+	if status, err := alicec.GetBuyOrderStatus(ctx, order.BuyOrderID); err != nil || status.Active {
+		t.Errorf("after cancel: status = %+v, err = %v", status, err)
+	}
+}
+
+func TestBuyListing(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(steamtest.Account{WalletBalance: 200})
+	bob := srv.AddAccount(steamtest.Account{})
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+	bobc := newSteamtestCommunity(t, srv, bob)
+
+	for _, price := range []int{100, 500} {
+		item := srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Earbuds", Marketable: true})
+		if _, err := bobc.SellMarketItem(ctx, 440, 2, mustParseUint(t, item.AssetID), 1, price); err != nil {
+			t.Fatalf("SellMarketItem: %v", err)
+		}
+	}
+	listings := srv.Listings(bob.SteamID)
+	cheap, dear := listings[0], listings[1]
+
+	if _, err := alicec.BuyListing(ctx, cheap.ID, BuyListingOptions{Subtotal: 90, Fee: 13, Total: 103}); !errors.Is(err, ErrMarketListingGone) {
+		t.Errorf("stale price: err = %v, want ErrMarketListingGone", err)
+	}
+	if _, err := alicec.BuyListing(ctx, dear.ID, BuyListingOptions{Subtotal: 500, Fee: 75, Total: 575}); !errors.Is(err, ErrMarketInsufficientFunds) {
+		t.Errorf("unaffordable listing: err = %v, want ErrMarketInsufficientFunds", err)
+	}
+
+	res, err := alicec.BuyListing(ctx, cheap.ID, BuyListingOptions{Subtotal: 100, Fee: 15, Total: 115})
+	if err != nil {
+		t.Fatalf("BuyListing: %v", err)
+	}
+	if res.Wallet.Balance != 85 || res.Wallet.Currency != 1 {
+		t.Errorf("wallet after purchase = %+v, want 85 in currency 1", res.Wallet)
+	}
+	if _, err := alicec.BuyListing(ctx, cheap.ID, BuyListingOptions{Subtotal: 100, Fee: 15, Total: 115}); !errors.Is(err, ErrMarketListingGone) {
+		t.Errorf("buying twice: err = %v, want ErrMarketListingGone", err)
+	}
+}
+
+func mustParseUint(t *testing.T, s string) uint64 {
+	t.Helper()
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return n
+}
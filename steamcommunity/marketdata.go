@@ -0,0 +1,286 @@
+package steamcommunity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k64z/steamstacks/steamapi"
+)
+
+// MarketOrderBook is the /itemordershistogram answer with the ladders
+// made numeric. Levels are best first: buy orders from the highest
+// price down, sell listings from the lowest up. Prices are in cents of
+// the requested currency, fees included.
+type MarketOrderBook struct {
+	HighestBuyOrder int // 0 when nobody is buying
+	LowestSellOrder int // 0 when nothing is listed
+	BuyOrders       []MarketOrderLevel
+	SellOrders      []MarketOrderLevel
+}
+
+// MarketOrderLevel is one price on an order book ladder. Cumulative is
+// the quantity at this price or better; Steam truncates the ladder, so
+// the last level's Cumulative may include orders further out.
+type MarketOrderLevel struct {
+	PriceCents int
+	Quantity   int
+	Cumulative int
+}
+
+// MarketPricePoint is one sample of an item's price history: the median
+// sale price over the period starting at Time and the number sold.
+type MarketPricePoint struct {
+	Time        time.Time
+	MedianCents int
+	Volume      int
+}
+
+var itemNameIDRE = regexp.MustCompile(`Market_LoadOrderSpread\(\s*(\d+)\s*\)`)
+
+// GetItemNameID returns the item_nameid the order book endpoint keys
+// items by. Steam only exposes it on the item's listing page, so it is
+// scraped once per item and cached on c.
+func (c *Community) GetItemNameID(ctx context.Context, appID int, marketHashName string) (string, error) {
+	key := strconv.Itoa(appID) + "/" + marketHashName
+	c.nameIDMu.Lock()
+	id, ok := c.nameIDs[key]
+	c.nameIDMu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	reqURL := c.baseURL + "/market/listings/" + strconv.Itoa(appID) + "/" + url.PathEscape(marketHashName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read body: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return "", errRateLimited
+	default:
+		return "", steamapi.HTTPStatusError(resp.StatusCode, body)
+	}
+
+	m := itemNameIDRE.FindSubmatch(body)
+	if m == nil {
+		return "", fmt.Errorf("item_nameid not found for %d/%s", appID, marketHashName)
+	}
+	id = string(m[1])
+
+	c.nameIDMu.Lock()
+	if c.nameIDs == nil {
+		c.nameIDs = make(map[string]string)
+	}
+	c.nameIDs[key] = id
+	c.nameIDMu.Unlock()
+	return id, nil
+}
+
+// GetItemOrdersHistogram fetches the order book for an item. currency is
+// a Steam currency code (1 = USD, 5 = GBP, ...).
+func (c *Community) GetItemOrdersHistogram(ctx context.Context, appID, currency int, marketHashName string) (*MarketOrderBook, error) {
+	nameID, err := c.GetItemNameID(ctx, appID, marketHashName)
+	if err != nil {
+		return nil, fmt.Errorf("get item_nameid: %w", err)
+	}
+
+	q := url.Values{}
+	q.Set("country", "US")
+	q.Set("language", "english")
+	q.Set("currency", strconv.Itoa(currency))
+	q.Set("item_nameid", nameID)
+	q.Set("two_factor", "0")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.baseURL+"/market/itemordershistogram?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Referer", c.baseURL+"/market/listings/"+strconv.Itoa(appID)+"/"+url.PathEscape(marketHashName))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return nil, errRateLimited
+	default:
+		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+	}
+
+	var r struct {
+		Success         int               `json:"success"`
+		HighestBuyOrder string            `json:"highest_buy_order"`
+		LowestSellOrder string            `json:"lowest_sell_order"`
+		BuyOrderGraph   []json.RawMessage `json:"buy_order_graph"`
+		SellOrderGraph  []json.RawMessage `json:"sell_order_graph"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if r.Success != 1 {
+		return nil, fmt.Errorf("order histogram failed: success=%d", r.Success)
+	}
+
+	book := &MarketOrderBook{}
+	// Both are cents as strings, or null for an empty side.
+	book.HighestBuyOrder, _ = strconv.Atoi(r.HighestBuyOrder)
+	book.LowestSellOrder, _ = strconv.Atoi(r.LowestSellOrder)
+	if book.BuyOrders, err = parseOrderGraph(r.BuyOrderGraph); err != nil {
+		return nil, fmt.Errorf("buy order graph: %w", err)
+	}
+	if book.SellOrders, err = parseOrderGraph(r.SellOrderGraph); err != nil {
+		return nil, fmt.Errorf("sell order graph: %w", err)
+	}
+	return book, nil
+}
+
+// parseOrderGraph converts the [price, cumulative quantity, label]
+// triples of an order graph into ladder levels. Graph prices are
+// currency units as floats.
+func parseOrderGraph(graph []json.RawMessage) ([]MarketOrderLevel, error) {
+	levels := make([]MarketOrderLevel, 0, len(graph))
+	prev := 0
+	for _, raw := range graph {
+		var entry []any
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, err
+		}
+		if len(entry) < 2 {
+			return nil, fmt.Errorf("short entry %s", raw)
+		}
+		price, ok1 := entry[0].(float64)
+		cumulative, ok2 := entry[1].(float64)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("malformed entry %s", raw)
+		}
+		levels = append(levels, MarketOrderLevel{
+			PriceCents: int(math.Round(price * 100)),
+			Quantity:   int(cumulative) - prev,
+			Cumulative: int(cumulative),
+		})
+		prev = int(cumulative)
+	}
+	return levels, nil
+}
+
+// GetPriceHistory fetches an item's sale history in the wallet currency,
+// oldest first: hourly samples for the last month, daily before that.
+// Steam only serves it to logged-in sessions.
+func (c *Community) GetPriceHistory(ctx context.Context, appID int, marketHashName string) ([]MarketPricePoint, error) {
+	if err := c.ensureInit(); err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("appid", strconv.Itoa(appID))
+	q.Set("market_hash_name", marketHashName)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.baseURL+"/market/pricehistory/?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return nil, errRateLimited
+	case http.StatusBadRequest:
+		// What an anonymous session gets, with a body of "[]".
+		return nil, errors.New("price history requires a logged-in session")
+	default:
+		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+	}
+
+	var r struct {
+		Success bool                `json:"success"`
+		Prices  [][]json.RawMessage `json:"prices"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if !r.Success {
+		return nil, errors.New("price history returned success=false")
+	}
+	return parsePriceHistory(r.Prices)
+}
+
+// parsePriceHistory reads the [date, median, volume] samples of
+// /pricehistory/. Dates look like "Jul 02 2014 01: +0" and are UTC; the
+// median is a float in currency units, the volume a string.
+func parsePriceHistory(prices [][]json.RawMessage) ([]MarketPricePoint, error) {
+	points := make([]MarketPricePoint, 0, len(prices))
+	for _, p := range prices {
+		if len(p) < 3 {
+			return nil, fmt.Errorf("short sample %v", p)
+		}
+		var date, volume string
+		var median float64
+		if err := json.Unmarshal(p[0], &date); err != nil {
+			return nil, fmt.Errorf("sample date: %w", err)
+		}
+		if err := json.Unmarshal(p[1], &median); err != nil {
+			return nil, fmt.Errorf("sample median: %w", err)
+		}
+		if err := json.Unmarshal(p[2], &volume); err != nil {
+			return nil, fmt.Errorf("sample volume: %w", err)
+		}
+
+		date, _, _ = strings.Cut(date, ": ")
+		t, err := time.Parse("Jan 02 2006 15", date)
+		if err != nil {
+			return nil, fmt.Errorf("sample date: %w", err)
+		}
+		n, err := strconv.Atoi(volume)
+		if err != nil {
+			return nil, fmt.Errorf("sample volume: %w", err)
+		}
+		points = append(points, MarketPricePoint{
+			Time:        t,
+			MedianCents: int(math.Round(median * 100)),
+			Volume:      n,
+		})
+	}
+	return points, nil
+}
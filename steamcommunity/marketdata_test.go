@@ -0,0 +1,94 @@
+package steamcommunity
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/k64z/steamstacks/steamtest"
+)
+
+func TestGetItemOrdersHistogram(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(steamtest.Account{WalletBalance: 10000})
+	bob := srv.AddAccount(steamtest.Account{})
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+	bobc := newSteamtestCommunity(t, srv, bob)
+
+	// Listings at 100, 100 and 200 reach buyers at 115 and 230.
+	for _, price := range []int{100, 200, 100} {
+		item := srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Earbuds", Marketable: true})
+		if _, err := bobc.SellMarketItem(ctx, 440, 2, mustParseUint(t, item.AssetID), 1, price); err != nil {
+			t.Fatalf("SellMarketItem: %v", err)
+		}
+	}
+	if _, err := alicec.CreateBuyOrder(ctx, CreateBuyOrderOptions{AppID: 440, MarketHashName: "Earbuds", PriceCents: 90, Quantity: 3}); err != nil {
+		t.Fatalf("CreateBuyOrder: %v", err)
+	}
+
+	book, err := alicec.GetItemOrdersHistogram(ctx, 440, 1, "Earbuds")
+	if err != nil {
+		t.Fatalf("GetItemOrdersHistogram: %v", err)
+	}
+	if book.HighestBuyOrder != 90 || book.LowestSellOrder != 115 {
+		t.Errorf("best = buy %d sell %d, want 90 and 115", book.HighestBuyOrder, book.LowestSellOrder)
+	}
+	wantSells := []MarketOrderLevel{{PriceCents: 115, Quantity: 2, Cumulative: 2}, {PriceCents: 230, Quantity: 1, Cumulative: 3}}
+	if !slices.Equal(book.SellOrders, wantSells) {
+		t.Errorf("SellOrders = %+v, want %+v", book.SellOrders, wantSells)
+	}
+	wantBuys := []MarketOrderLevel{{PriceCents: 90, Quantity: 3, Cumulative: 3}}
+	if !slices.Equal(book.BuyOrders, wantBuys) {
+		t.Errorf("BuyOrders = %+v, want %+v", book.BuyOrders, wantBuys)
+	}
+
+	nameID := alicec.nameIDs["440/Earbuds"]
+	if nameID == "" {
+		t.Fatal("item_nameid not cached")
+	}
+	if id, err := alicec.GetItemNameID(ctx, 440, "Earbuds"); err != nil || id != nameID {
+		t.Errorf("GetItemNameID = %q, %v, want cached %q", id, err, nameID)
+	}
+}
+
+func TestGetPriceHistory(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(steamtest.Account{})
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+
+	day := time.Date(2024, time.July, 2, 1, 0, 0, 0, time.UTC)
+	want := []MarketPricePoint{
+		{Time: day, MedianCents: 417, Volume: 40},
+		{Time: day.Add(time.Hour), MedianCents: 1299, Volume: 3},
+	}
+	var points []steamtest.PricePoint
+	for _, p := range want {
+		points = append(points, steamtest.PricePoint{Time: p.Time, Median: p.MedianCents, Volume: p.Volume})
+	}
+	srv.SetPriceHistory(440, "Earbuds", points)
+
+	got, err := alicec.GetPriceHistory(ctx, 440, "Earbuds")
+	if err != nil {
+		t.Fatalf("GetPriceHistory: %v", err)
+	}
+	if !slices.EqualFunc(got, want, func(a, b MarketPricePoint) bool {
+		return a.Time.Equal(b.Time) && a.MedianCents == b.MedianCents && a.Volume == b.Volume
+	}) {
+		t.Errorf("history = %+v, want %+v", got, want)
+	}
+
+	anon, err := New(WithHosts(srv.Hosts()))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := anon.GetPriceHistory(ctx, 440, "Earbuds"); err == nil {
+		t.Error("anonymous GetPriceHistory: want error")
+	}
+}
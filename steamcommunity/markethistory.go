@@ -0,0 +1,398 @@
+package steamcommunity
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// MarketEventType is the event_type of a market history event.
+type MarketEventType int
+
+const (
+	MarketEventListed    MarketEventType = 1
+	MarketEventCancelled MarketEventType = 2
+	MarketEventSold      MarketEventType = 3
+	MarketEventPurchased MarketEventType = 4
+)
+
+func (t MarketEventType) String() string {
+	switch t {
+	case MarketEventListed:
+		return "listed"
+	case MarketEventCancelled:
+		return "cancelled"
+	case MarketEventSold:
+		return "sold"
+	case MarketEventPurchased:
+		return "purchased"
+	}
+	return fmt.Sprintf("MarketEventType(%d)", int(t))
+}
+
+func (t MarketEventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// MarketHistoryEvent is one row of the caller's market history. Amounts
+// are in cents of CurrencyID's currency: Total is what the buyer pays,
+// SellerReceives + SteamFee + PublisherFee. For listed and cancelled
+// events they are the listing's asking price; for sales and purchases,
+// what the buyer paid, in the buyer's currency. Sales also carry what the
+// caller was credited in their own currency, which differs when the buyer
+// paid in another.
+type MarketHistoryEvent struct {
+	// ID identifies the event across syncs: Steam has no event ID, so
+	// it is built from the listing, purchase and event type.
+	ID         string          `json:"id"`
+	Type       MarketEventType `json:"type"`
+	Time       time.Time       `json:"time"`
+	ListingID  string          `json:"listing_id"`
+	PurchaseID string          `json:"purchase_id,omitempty"`
+	// Counterparty is the buyer of a sale or the seller of a purchase.
+	// Steam doesn't always say who; it is 0 then.
+	Counterparty steamid.SteamID `json:"counterparty,omitempty,string"`
+
+	Asset MarketListingAsset `json:"asset"`
+	// NewAssetID is the item's asset ID in the buyer's inventory, for
+	// purchases.
+	NewAssetID string `json:"new_asset_id,omitempty"`
+
+	// CurrencyID is Steam's market currency ID, 2000 plus the wallet
+	// currency code that MarketWalletInfo.Currency holds (2001 for USD).
+	CurrencyID     int `json:"currency_id"`
+	Total          int `json:"total"`
+	SellerReceives int `json:"seller_receives"`
+	SteamFee       int `json:"steam_fee"`
+	PublisherFee   int `json:"publisher_fee"`
+
+	// ReceivedCurrencyID and ReceivedAmount are what the caller was
+	// credited for a sale, in their wallet currency.
+	ReceivedCurrencyID int `json:"received_currency_id,omitempty"`
+	ReceivedAmount     int `json:"received_amount,omitempty"`
+}
+
+// MarketHistoryPage is a single page of market history, newest first.
+type MarketHistoryPage struct {
+	Events   []MarketHistoryEvent `json:"events"`
+	Start    int                  `json:"start"`
+	PageSize int                  `json:"pagesize"`
+	Total    int                  `json:"total"`
+}
+
+// marketHistoryResponse mirrors /market/myhistory/render/ with
+// norender=1. Listings and purchases are keyed by listing ID and
+// "<listingid>_<purchaseid>"; assets like the listings endpoint's.
+type marketHistoryResponse struct {
+	Success    bool                                       `json:"success"`
+	PageSize   int                                        `json:"pagesize"`
+	TotalCount int                                        `json:"total_count"`
+	Assets     map[string]map[string]map[string]assetInfo `json:"assets"`
+	Events     []struct {
+		ListingID         string     `json:"listingid"`
+		PurchaseID        string     `json:"purchaseid"`
+		EventType         int        `json:"event_type"`
+		TimeEvent         int64      `json:"time_event"`
+		TimeEventFraction int64      `json:"time_event_fraction"`
+		SteamIDActor      steamIDStr `json:"steamid_actor"`
+	} `json:"events"`
+	Listings map[string]struct {
+		Price        int                 `json:"price"`
+		Fee          int                 `json:"fee"`
+		SteamFee     int                 `json:"steam_fee"`
+		PublisherFee int                 `json:"publisher_fee"`
+		CurrencyID   flexInt             `json:"currencyid"`
+		Asset        marketHistoryAssets `json:"asset"`
+	} `json:"listings"`
+	Purchases map[string]struct {
+		SteamIDPurchaser   steamIDStr          `json:"steamid_purchaser"`
+		PaidAmount         int                 `json:"paid_amount"`
+		PaidFee            int                 `json:"paid_fee"`
+		SteamFee           int                 `json:"steam_fee"`
+		PublisherFee       int                 `json:"publisher_fee"`
+		CurrencyID         flexInt             `json:"currencyid"`
+		ReceivedAmount     int                 `json:"received_amount"`
+		ReceivedCurrencyID flexInt             `json:"received_currencyid"`
+		Asset              marketHistoryAssets `json:"asset"`
+	} `json:"purchases"`
+}
+
+type marketHistoryAssets struct {
+	AppID     int    `json:"appid"`
+	ContextID string `json:"contextid"`
+	ID        string `json:"id"`
+	NewID     string `json:"new_id"`
+}
+
+// flexInt decodes a number Steam sends either bare or as a string.
+type flexInt int
+
+func (n *flexInt) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		s = string(b)
+	}
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("decode number %s: %w", b, err)
+	}
+	*n = flexInt(v)
+	return nil
+}
+
+// steamIDStr decodes a SteamID64 sent as a string.
+type steamIDStr steamid.SteamID
+
+func (id *steamIDStr) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		s = string(b)
+	}
+	if s == "" || s == "null" {
+		*id = 0
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("decode steamid %s: %w", b, err)
+	}
+	*id = steamIDStr(v)
+	return nil
+}
+
+// GetMarketHistory fetches a single page of the caller's market history,
+// newest first. Steam caps count at 500; larger values are clamped.
+// count <= 0 defaults to 100. Paginate like GetMarketListings, or use
+// SyncMarketHistory.
+func (c *Community) GetMarketHistory(ctx context.Context, start, count int) (*MarketHistoryPage, error) {
+	if count <= 0 {
+		count = 100
+	}
+	if count > 500 {
+		count = 500
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	q := url.Values{}
+	q.Set("query", "")
+	q.Set("start", strconv.Itoa(start))
+	q.Set("count", strconv.Itoa(count))
+	q.Set("norender", "1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.baseURL+"/market/myhistory/render/?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	req.Header.Set("Referer", c.baseURL+"/market/")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return nil, errRateLimited
+	default:
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var r marketHistoryResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if !r.Success {
+		return nil, errors.New("history endpoint returned success=false")
+	}
+	return &MarketHistoryPage{
+		Events:   r.events(c.SteamID),
+		Start:    start,
+		PageSize: r.PageSize,
+		Total:    r.TotalCount,
+	}, nil
+}
+
+// events joins each event with its listing or purchase and asset.
+func (r *marketHistoryResponse) events(self steamid.SteamID) []MarketHistoryEvent {
+	out := make([]MarketHistoryEvent, 0, len(r.Events))
+	for _, e := range r.Events {
+		evt := MarketHistoryEvent{
+			Type:      MarketEventType(e.EventType),
+			Time:      time.Unix(e.TimeEvent, 0),
+			ListingID: e.ListingID,
+		}
+		if e.PurchaseID != "" && e.PurchaseID != "0" {
+			evt.PurchaseID = e.PurchaseID
+		}
+		evt.ID = e.ListingID + "_" + e.PurchaseID + "_" + strconv.Itoa(e.EventType)
+
+		var asset marketHistoryAssets
+		switch evt.Type {
+		case MarketEventSold, MarketEventPurchased:
+			p, ok := r.Purchases[e.ListingID+"_"+e.PurchaseID]
+			if !ok {
+				break
+			}
+			asset = p.Asset
+			evt.NewAssetID = p.Asset.NewID
+			evt.CurrencyID = int(p.CurrencyID)
+			evt.SellerReceives = p.PaidAmount
+			evt.Total = p.PaidAmount + p.PaidFee
+			evt.SteamFee = p.SteamFee
+			evt.PublisherFee = p.PublisherFee
+			if evt.Type == MarketEventSold && p.ReceivedCurrencyID != 0 {
+				// The paid amounts are the buyer's; Steam converts
+				// them into the seller's currency on its own terms.
+				evt.ReceivedCurrencyID = int(p.ReceivedCurrencyID)
+				evt.ReceivedAmount = p.ReceivedAmount
+			}
+			if steamid.SteamID(e.SteamIDActor) != self {
+				evt.Counterparty = steamid.SteamID(e.SteamIDActor)
+			}
+			if evt.Type == MarketEventSold && evt.Counterparty == 0 {
+				evt.Counterparty = steamid.SteamID(p.SteamIDPurchaser)
+			}
+		default:
+			l, ok := r.Listings[e.ListingID]
+			if !ok {
+				break
+			}
+			asset = l.Asset
+			evt.CurrencyID = int(l.CurrencyID)
+			evt.SellerReceives = l.Price
+			evt.Total = l.Price + l.Fee
+			evt.SteamFee = l.SteamFee
+			evt.PublisherFee = l.PublisherFee
+		}
+
+		evt.Asset = MarketListingAsset{
+			AssetID:   asset.ID,
+			AppID:     asset.AppID,
+			ContextID: asset.ContextID,
+		}
+		if ai, ok := lookupAsset(r.Assets, strconv.Itoa(asset.AppID), asset.ContextID, asset.ID); ok {
+			evt.Asset.ClassID = ai.ClassID
+			evt.Asset.InstanceID = ai.InstanceID
+			evt.Asset.Amount = ai.Amount
+			evt.Asset.MarketHashName = ai.MarketHashName
+			evt.Asset.Name = ai.Name
+			evt.Asset.IconURL = ai.IconURL
+		}
+		out = append(out, evt)
+	}
+	return out
+}
+
+// SyncMarketHistory returns the events newer than the one with ID
+// sinceID, oldest first, paging back until it reaches it. An empty
+// sinceID fetches the whole history. Callers persist the ID of the last
+// event returned and pass it to the next sync.
+//
+// If sinceID is not found, everything is returned; the history only
+// shifts, so that means it has aged out or was never there.
+func (c *Community) SyncMarketHistory(ctx context.Context, sinceID string) ([]MarketHistoryEvent, error) {
+	const pageSize = 500
+
+	var events []MarketHistoryEvent
+	seen := make(map[string]bool)
+	for start := 0; ; {
+		page, err := c.GetMarketHistory(ctx, start, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("page at %d: %w", start, err)
+		}
+		for _, evt := range page.Events {
+			if sinceID != "" && evt.ID == sinceID {
+				slices.Reverse(events)
+				return events, nil
+			}
+			// New events push the pages along while we read them,
+			// repeating rows at page boundaries.
+			if seen[evt.ID] {
+				continue
+			}
+			seen[evt.ID] = true
+			events = append(events, evt)
+		}
+		start += len(page.Events)
+		if len(page.Events) == 0 || start >= page.Total {
+			break
+		}
+	}
+	slices.Reverse(events)
+	return events, nil
+}
+
+// marketHistoryCSVHeader is the column order of WriteMarketHistoryCSV.
+var marketHistoryCSVHeader = []string{
+	"id", "type", "time", "listing_id", "purchase_id", "counterparty",
+	"app_id", "context_id", "asset_id", "new_asset_id", "class_id", "instance_id", "market_hash_name",
+	"currency_id", "total", "seller_receives", "steam_fee", "publisher_fee",
+	"received_currency_id", "received_amount",
+}
+
+// WriteMarketHistoryCSV writes events as CSV with a header row. Times
+// are RFC 3339 in UTC and amounts are integer cents.
+func WriteMarketHistoryCSV(w io.Writer, events []MarketHistoryEvent) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(marketHistoryCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range events {
+		var counterparty string
+		if e.Counterparty != 0 {
+			counterparty = strconv.FormatUint(e.Counterparty.ToSteamID64(), 10)
+		}
+		var receivedCurrencyID, receivedAmount string
+		if e.ReceivedCurrencyID != 0 {
+			receivedCurrencyID = strconv.Itoa(e.ReceivedCurrencyID)
+			receivedAmount = strconv.Itoa(e.ReceivedAmount)
+		}
+		err := cw.Write([]string{
+			e.ID, e.Type.String(), e.Time.UTC().Format(time.RFC3339), e.ListingID, e.PurchaseID, counterparty,
+			strconv.Itoa(e.Asset.AppID), e.Asset.ContextID, e.Asset.AssetID, e.NewAssetID,
+			e.Asset.ClassID, e.Asset.InstanceID, e.Asset.MarketHashName,
+			strconv.Itoa(e.CurrencyID), strconv.Itoa(e.Total), strconv.Itoa(e.SellerReceives),
+			strconv.Itoa(e.SteamFee), strconv.Itoa(e.PublisherFee),
+			receivedCurrencyID, receivedAmount,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMarketHistoryJSON writes events as a JSON array.
+func WriteMarketHistoryJSON(w io.Writer, events []MarketHistoryEvent) error {
+	if events == nil {
+		events = []MarketHistoryEvent{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(events)
+}
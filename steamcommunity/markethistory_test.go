@@ -0,0 +1,132 @@
+package steamcommunity
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/k64z/steamstacks/steamtest"
+)
+
+func TestMarketHistorySync(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(steamtest.Account{WalletBalance: 1000})
+	bob := srv.AddAccount(steamtest.Account{})
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+	bobc := newSteamtestCommunity(t, srv, bob)
+
+	for _, price := range []int{100, 200} {
+		item := srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Earbuds", Marketable: true})
+		if _, err := bobc.SellMarketItem(ctx, 440, 2, mustParseUint(t, item.AssetID), 1, price); err != nil {
+			t.Fatalf("SellMarketItem: %v", err)
+		}
+	}
+	listings := srv.Listings(bob.SteamID)
+	if err := bobc.CancelMarketListing(ctx, listings[1].ID); err != nil {
+		t.Fatalf("CancelMarketListing: %v", err)
+	}
+
+	first, err := bobc.SyncMarketHistory(ctx, "")
+	if err != nil {
+		t.Fatalf("SyncMarketHistory: %v", err)
+	}
+	if len(first) != 3 || first[0].Type != MarketEventListed || first[2].Type != MarketEventCancelled {
+		t.Fatalf("first sync = %+v, want listed, listed, cancelled", first)
+	}
+	if e := first[0]; e.ListingID != listings[0].ID || e.SellerReceives != 100 || e.Total != 115 || e.Asset.MarketHashName != "Earbuds" {
+		t.Errorf("listed event = %+v", e)
+	}
+
+	if _, err := alicec.BuyListing(ctx, listings[0].ID, BuyListingOptions{Subtotal: 100, Fee: 15, Total: 115}); err != nil {
+		t.Fatalf("BuyListing: %v", err)
+	}
+
+	next, err := bobc.SyncMarketHistory(ctx, first[len(first)-1].ID)
+	if err != nil {
+		t.Fatalf("SyncMarketHistory: %v", err)
+	}
+	if len(next) != 1 {
+		t.Fatalf("incremental sync = %+v, want one sale", next)
+	}
+	sold := next[0]
+	if sold.Type != MarketEventSold || sold.Counterparty != alice.SteamID || sold.PurchaseID == "" {
+		t.Errorf("sold event = %+v, want a sale to alice", sold)
+	}
+	if sold.Total != 115 || sold.SellerReceives != 100 || sold.SteamFee != 5 || sold.PublisherFee != 10 || sold.CurrencyID != 2001 {
+		t.Errorf("sold amounts = %+v, want 115 = 100 + 5 + 10 in 2001", sold)
+	}
+	if sold.ReceivedAmount != 100 || sold.ReceivedCurrencyID != 2001 {
+		t.Errorf("received = %d in %d, want 100 in 2001", sold.ReceivedAmount, sold.ReceivedCurrencyID)
+	}
+
+	bought, err := alicec.SyncMarketHistory(ctx, "")
+	if err != nil {
+		t.Fatalf("SyncMarketHistory: %v", err)
+	}
+	if len(bought) != 1 || bought[0].Type != MarketEventPurchased || bought[0].Counterparty != bob.SteamID || bought[0].NewAssetID == "" {
+		t.Errorf("alice history = %+v, want one purchase from bob", bought)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarketHistoryCSV(&buf, append(first, next...)); err != nil {
+		t.Fatalf("WriteMarketHistoryCSV: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	if len(rows) != 5 || rows[4][0] != sold.ID || rows[4][1] != "sold" || rows[4][14] != "115" || rows[4][19] != "100" || rows[1][19] != "" {
+		t.Errorf("CSV = %q", rows)
+	}
+
+	buf.Reset()
+	if err := WriteMarketHistoryJSON(&buf, next); err != nil {
+		t.Fatalf("WriteMarketHistoryJSON: %v", err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if len(decoded) != 1 || decoded[0]["type"] != "sold" || decoded[0]["id"] != sold.ID {
+		t.Errorf("JSON = %s", buf.Bytes())
+	}
+}
+
+func TestMarketHistoryCrossCurrencySale(t *testing.T) {
+	// A sale paid in EUR to a USD seller: the paid amounts stay the
+	// buyer's, received_amount is what the seller got.
+	body := `{
+		"success": true,
+		"events": [{"listingid": "10", "purchaseid": "20", "event_type": 3, "time_event": 1700000000, "steamid_actor": "76561198000000002"}],
+		"purchases": {"10_20": {
+			"steamid_purchaser": "76561198000000002",
+			"paid_amount": 92, "paid_fee": 12, "steam_fee": 4, "publisher_fee": 8,
+			"currencyid": "2003",
+			"received_amount": 100, "received_currencyid": "2001",
+			"asset": {"appid": 440, "contextid": "2", "id": "30", "new_id": "31"}
+		}}
+	}`
+	var r marketHistoryResponse
+	if err := json.Unmarshal([]byte(body), &r); err != nil {
+		t.Fatal(err)
+	}
+	events := r.events(76561198000000001)
+	if len(events) != 1 {
+		t.Fatalf("events = %+v, want one", events)
+	}
+	e := events[0]
+	if e.CurrencyID != 2003 || e.SellerReceives != 92 || e.Total != 104 || e.SteamFee != 4 || e.PublisherFee != 8 {
+		t.Errorf("paid amounts = %+v, want 104 = 92 + 4 + 8 in 2003", e)
+	}
+	if e.ReceivedAmount != 100 || e.ReceivedCurrencyID != 2001 {
+		t.Errorf("received = %d in %d, want 100 in 2001", e.ReceivedAmount, e.ReceivedCurrencyID)
+	}
+	if e.Counterparty != 76561198000000002 {
+		t.Errorf("Counterparty = %d, want the buyer", e.Counterparty)
+	}
+}
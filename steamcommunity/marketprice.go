@@ -0,0 +1,74 @@
+package steamcommunity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseMarketPrice converts a price string as the market renders it into
+// hundredths of the currency unit, the unit Steam uses internally for
+// every currency (so "¥ 123" is 12300). It understands the formats of all
+// wallet currencies: a symbol or code on either side ("$1.23", "1,23€",
+// "R$ 1,23", "12,34 pуб.", "CHF 1.23"), comma or dot decimals, thousands
+// grouped by dots, commas, spaces or apostrophes ("1.234,56€",
+// "₩ 1,234", "Rp 12 345"), and the "5,--€" of whole euro amounts.
+//
+// A trailing group of exactly three digits is read as thousands, so
+// "1.234" is 1234 units, not 1.234.
+func ParseMarketPrice(s string) (int, error) {
+	first := strings.IndexFunc(s, isDigit)
+	if first < 0 {
+		return 0, fmt.Errorf("no digits in price %q", s)
+	}
+	last := strings.LastIndexFunc(s, isDigit)
+	num := s[first : last+1]
+	// Whole amounts: "5,--€", "5,-€".
+	if rest := s[last+1:]; strings.HasPrefix(rest, ",-") || strings.HasPrefix(rest, ".-") {
+		num += ",00"
+	}
+
+	var digits strings.Builder
+	decimals := -1 // digits after the last separator, or -1 if none
+	for _, r := range num {
+		switch {
+		case isDigit(r):
+			digits.WriteRune(r)
+			if decimals >= 0 {
+				decimals++
+			}
+		case isPriceSeparator(r):
+			decimals = 0
+		default:
+			return 0, fmt.Errorf("unexpected %q in price %q", r, s)
+		}
+	}
+
+	units, err := strconv.Atoi(digits.String())
+	if err != nil {
+		return 0, fmt.Errorf("parse price %q: %w", s, err)
+	}
+	switch decimals {
+	case 1:
+		return units * 10, nil
+	case 2:
+		return units, nil
+	default:
+		// No separator, or only thousands separators.
+		return units * 100, nil
+	}
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// isPriceSeparator reports whether r separates decimals or digit groups in
+// a rendered price. Steam groups with no-break spaces in several locales.
+func isPriceSeparator(r rune) bool {
+	switch r {
+	case '.', ',', ' ', '\'', '\u00a0', '\u202f':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package steamcommunity
+
+import "testing"
+
+func TestParseMarketPrice(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int
+	}{
+		{"$1.23", 123},
+		{"$1,234.56", 123456},
+		{"$0.03 USD", 3},
+		{"1,23€", 123},
+		{"1.234,56€", 123456},
+		{"5,--€", 500},
+		{"5,-€", 500},
+		{"£0.99", 99},
+		{"12,34 pуб.", 1234},
+		{"1 234,56 pуб.", 123456},
+		{"R$ 1,23", 123},
+		{"CDN$ 4.50", 450},
+		{"CHF 1'234.50", 123450},
+		{"CHF 3.5", 350},
+		{"¥ 123", 12300},
+		{"¥ 8.50", 850},
+		{"₩ 1,234", 123400},
+		{"Rp 12 345", 1234500},
+		{"12 345,67 zł", 1234567},
+		{"₸ 1 234", 123400},
+		{"₹ 1,234.50", 123450},
+		{"P1,234.00", 123400},
+		{"1.234 ₫", 123400},
+	}
+	for _, tc := range cases {
+		got, err := ParseMarketPrice(tc.in)
+		if err != nil {
+			t.Errorf("ParseMarketPrice(%q): %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseMarketPrice(%q) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+
+	for _, in := range []string{"", "--", "$", "1.2x3"} {
+		if _, err := ParseMarketPrice(in); err == nil {
+			t.Errorf("ParseMarketPrice(%q): want error", in)
+		}
+	}
+}
@@ -114,7 +114,7 @@ func (c *Community) ProfileData() (*ProfileData, error) {
 	if err := c.ensureInit(); err != nil {
 		return nil, err
 	}
-	u := fmt.Sprintf("https://steamcommunity.com/profiles/%d/edit/info", c.SteamID)
+	u := fmt.Sprintf("%s/profiles/%d/edit/info", c.baseURL, c.SteamID)
 	resp, err := c.httpClient.Get(u)
 	if err != nil {
 		return nil, fmt.Errorf("get: %w", err)
@@ -159,7 +159,7 @@ func (c *Community) SetPrivacySettings(ctx context.Context, settings *PrivacySet
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		fmt.Sprintf("https://steamcommunity.com/profiles/%d/ajaxsetprivacy/", c.SteamID),
+		fmt.Sprintf("%s/profiles/%d/ajaxsetprivacy/", c.baseURL, c.SteamID),
 		strings.NewReader(formData.Encode()),
 	)
 	if err != nil {
@@ -237,7 +237,7 @@ func (c *Community) EditProfile(ctx context.Context, p EditProfileRequest) error
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		fmt.Sprintf("https://steamcommunity.com/profiles/%d/edit/info", c.SteamID),
+		fmt.Sprintf("%s/profiles/%d/edit/info", c.baseURL, c.SteamID),
 		buf,
 	)
 	if err != nil {
@@ -313,7 +313,7 @@ func (c *Community) UploadAvatar(ctx context.Context, avatar io.Reader) error {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		"https://steamcommunity.com/actions/FileUploader/",
+		c.baseURL+"/actions/FileUploader/",
 		buf,
 	)
 	if err != nil {
@@ -354,7 +354,7 @@ func (c *Community) ClearAliasHistory(ctx context.Context) error {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		fmt.Sprintf("https://steamcommunity.com/profiles/%d/ajaxclearaliashistory/", c.SteamID),
+		fmt.Sprintf("%s/profiles/%d/ajaxclearaliashistory/", c.baseURL, c.SteamID),
 		strings.NewReader(formData.Encode()),
 	)
 	if err != nil {
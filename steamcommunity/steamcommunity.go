@@ -4,20 +4,36 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamhost"
 	"github.com/k64z/steamstacks/steamid"
 )
 
 type Community struct {
 	httpClient *http.Client
+	api        *steamapi.API // Web API on the same client, for server time
+	baseURL    string
 	sessionID  string
 	SteamID    steamid.SteamID
+
+	timeMu     sync.Mutex
+	timeOffset int64     // Steam time minus local time, in seconds
+	timeSynced time.Time // zero until the offset is first fetched
+
+	walletMu sync.Mutex
+	wallet   *MarketWalletInfo // nil until first loaded
+
+	nameIDMu sync.Mutex
+	nameIDs  map[string]string // "appid/market_hash_name" -> item_nameid
 }
 
 type config struct {
 	httpClient *http.Client
+	hosts      steamhost.Hosts
 }
 
 type Option func(options *config) error
@@ -32,6 +48,15 @@ func WithHTTPClient(httpClient *http.Client) Option {
 	}
 }
 
+// WithHosts maps the Steam web hosts. Requests and cookie lookups use the
+// community host.
+func WithHosts(hosts steamhost.Hosts) Option {
+	return func(options *config) error {
+		options.hosts = hosts
+		return nil
+	}
+}
+
 func New(opts ...Option) (*Community, error) {
 	var cfg config
 	for _, opt := range opts {
@@ -41,7 +66,9 @@ func New(opts ...Option) (*Community, error) {
 		}
 	}
 
-	c := &Community{}
+	c := &Community{
+		baseURL: cfg.hosts.Resolve().Community,
+	}
 
 	if cfg.httpClient != nil {
 		c.httpClient = cfg.httpClient
@@ -49,6 +76,12 @@ func New(opts ...Option) (*Community, error) {
 		c.httpClient = http.DefaultClient
 	}
 
+	var err error
+	c.api, err = steamapi.New(steamapi.WithHTTPClient(c.httpClient), steamapi.WithHosts(cfg.hosts))
+	if err != nil {
+		return nil, fmt.Errorf("init SteamAPI: %w", err)
+	}
+
 	return c, nil
 }
 
@@ -58,12 +91,15 @@ func (c *Community) ensureInit() error {
 	if c.sessionID != "" {
 		return nil
 	}
+	if c.httpClient.Jar == nil {
+		return errors.New("not logged in: HTTP client has no cookie jar")
+	}
 	var err error
-	c.sessionID, err = extractSessionID(c.httpClient.Jar)
+	c.sessionID, err = extractSessionID(c.httpClient.Jar, c.baseURL)
 	if err != nil {
 		return fmt.Errorf("extract sessionID: %w", err)
 	}
-	c.SteamID, err = extractSteamID(c.httpClient.Jar)
+	c.SteamID, err = extractSteamID(c.httpClient.Jar, c.baseURL)
 	if err != nil {
 		c.sessionID = "" // reset so next call retries
 		return fmt.Errorf("extract steamID: %w", err)
@@ -71,9 +107,8 @@ func (c *Community) ensureInit() error {
 	return nil
 }
 
-func extractSessionID(jar http.CookieJar) (string, error) {
-	u, _ := url.Parse("https://steamcommunity.com")
-	cookies := jar.Cookies(u)
+func extractSessionID(jar http.CookieJar, baseURL string) (string, error) {
+	cookies := jar.Cookies(steamhost.CookieURL(baseURL))
 
 	for _, cookie := range cookies {
 		if cookie.Name == "sessionid" {
@@ -84,9 +119,8 @@ func extractSessionID(jar http.CookieJar) (string, error) {
 	return "", errors.New("sessionID is missing")
 }
 
-func extractSteamID(jar http.CookieJar) (steamid.SteamID, error) {
-	u, _ := url.Parse("https://steamcommunity.com")
-	cookies := jar.Cookies(u)
+func extractSteamID(jar http.CookieJar, baseURL string) (steamid.SteamID, error) {
+	cookies := jar.Cookies(steamhost.CookieURL(baseURL))
 
 	for _, cookie := range cookies {
 		if cookie.Name == "steamLoginSecure" {
@@ -0,0 +1,49 @@
+package steamcommunity
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/k64z/steamstacks/steamhost"
+)
+
+func TestWithHosts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/textfilter/ajaxgetfriendslist" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"success": 1, "friendslist": {"friends": []}}`))
+	}))
+	defer srv.Close()
+
+	// Cookies live only on the mock host; nothing is set for steamcommunity.com.
+	jar, _ := cookiejar.New(nil)
+	u, _ := url.Parse(srv.URL)
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "sessionid", Value: "test-session-id"},
+		{Name: "steamLoginSecure", Value: "76561198000000000%7C%7Ctoken"},
+	})
+
+	c, err := New(
+		WithHTTPClient(&http.Client{Jar: jar}),
+		WithHosts(steamhost.Single(srv.URL)),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if _, err := c.GetFriendsList(context.Background()); err != nil {
+		t.Fatalf("GetFriendsList: %v", err)
+	}
+	if c.sessionID != "test-session-id" {
+		t.Errorf("sessionID = %q, want %q", c.sessionID, "test-session-id")
+	}
+	if got := c.SteamID.ToSteamID64(); got != 76561198000000000 {
+		t.Errorf("SteamID = %d, want 76561198000000000", got)
+	}
+}
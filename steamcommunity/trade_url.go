@@ -29,7 +29,7 @@ func (c *Community) GetTradeOfferURL(ctx context.Context) (string, error) {
 	if err := c.ensureInit(); err != nil {
 		return "", err
 	}
-	u := fmt.Sprintf("https://steamcommunity.com/profiles/%d/tradeoffers/privacy", c.SteamID)
+	u := fmt.Sprintf("%s/profiles/%d/tradeoffers/privacy", c.baseURL, c.SteamID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return "", fmt.Errorf("build request: %w", err)
@@ -21,6 +21,10 @@ type SendTradeOfferOptions struct {
 	Message        string              // Optional: message to include (max 128 chars)
 	ItemsToGive    []steamapi.TradeAsset // Items to give
 	ItemsToReceive []steamapi.TradeAsset // Items to receive
+
+	// RefuseTradeHold checks the trade hold first and returns a
+	// *TradeHoldError instead of sending if one would apply.
+	RefuseTradeHold bool
 }
 
 // SendTradeOfferResponse contains the response from SendTradeOffer
@@ -31,8 +35,10 @@ type SendTradeOfferResponse struct {
 	EmailDomain          string `json:"email_domain"`
 }
 
-// AcceptTradeOfferResponse contains the response from AcceptTradeOffer
+// AcceptTradeOfferResponse contains the response from AcceptTradeOffer.
+// TradeID is empty while the acceptance awaits confirmation.
 type AcceptTradeOfferResponse struct {
+	TradeID              string `json:"tradeid"`
 	NeedsConfirmation    bool   `json:"needs_mobile_confirmation"`
 	NeedsEmailConfirm    bool   `json:"needs_email_confirmation"`
 	EmailDomain          string `json:"email_domain"`
@@ -59,44 +65,65 @@ type tradeOfferAsset struct {
 	AssetID   string `json:"assetid"`
 }
 
+// toOfferAssets converts trade assets to the json_tradeoffer form. An
+// empty amount means 1; anything else must parse to a positive number.
+func toOfferAssets(items []steamapi.TradeAsset) ([]tradeOfferAsset, error) {
+	var assets []tradeOfferAsset
+	for _, item := range items {
+		amount, err := parseAssetAmount(item)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, tradeOfferAsset{
+			AppID:     item.AppID,
+			ContextID: item.ContextID,
+			Amount:    amount,
+			AssetID:   item.AssetID,
+		})
+	}
+	return assets, nil
+}
+
 // SendTradeOffer sends a new trade offer to a partner
 func (c *Community) SendTradeOffer(ctx context.Context, opts SendTradeOfferOptions) (*SendTradeOfferResponse, error) {
+	return c.sendTradeOffer(ctx, opts, "")
+}
+
+// CounterTradeOffer answers a received offer with a counter-offer holding
+// the items in opts. opts.Partner must be the original offer's sender.
+// Steam marks the original offer Countered.
+func (c *Community) CounterTradeOffer(ctx context.Context, offerID string, opts SendTradeOfferOptions) (*SendTradeOfferResponse, error) {
+	if offerID == "" {
+		return nil, fmt.Errorf("offer ID is required")
+	}
+	return c.sendTradeOffer(ctx, opts, offerID)
+}
+
+// sendTradeOffer sends a new offer, or a counter-offer to counteredID.
+func (c *Community) sendTradeOffer(ctx context.Context, opts SendTradeOfferOptions, counteredID string) (*SendTradeOfferResponse, error) {
 	if err := c.ensureInit(); err != nil {
 		return nil, err
 	}
 	partnerAccountID := opts.Partner.AccountID()
 
-	// Build the json_tradeoffer structure
-	var myAssets []tradeOfferAsset
-	for _, item := range opts.ItemsToGive {
-		amount := 1
-		if item.Amount != "" {
-			if parsed, err := strconv.Atoi(item.Amount); err == nil {
-				amount = parsed
-			}
+	if opts.RefuseTradeHold {
+		details, err := c.GetUserDetails(ctx, opts.Partner, opts.Token)
+		if err != nil {
+			return nil, fmt.Errorf("get trade hold: %w", err)
+		}
+		if err := details.tradeHold(len(opts.ItemsToGive) > 0, len(opts.ItemsToReceive) > 0); err != nil {
+			return nil, err
 		}
-		myAssets = append(myAssets, tradeOfferAsset{
-			AppID:     item.AppID,
-			ContextID: item.ContextID,
-			Amount:    amount,
-			AssetID:   item.AssetID,
-		})
 	}
 
-	var theirAssets []tradeOfferAsset
-	for _, item := range opts.ItemsToReceive {
-		amount := 1
-		if item.Amount != "" {
-			if parsed, err := strconv.Atoi(item.Amount); err == nil {
-				amount = parsed
-			}
-		}
-		theirAssets = append(theirAssets, tradeOfferAsset{
-			AppID:     item.AppID,
-			ContextID: item.ContextID,
-			Amount:    amount,
-			AssetID:   item.AssetID,
-		})
+	// Build the json_tradeoffer structure
+	myAssets, err := toOfferAssets(opts.ItemsToGive)
+	if err != nil {
+		return nil, err
+	}
+	theirAssets, err := toOfferAssets(opts.ItemsToReceive)
+	if err != nil {
+		return nil, err
 	}
 
 	tradeJSON := tradeOfferJSON{
@@ -137,14 +164,20 @@ func (c *Community) SendTradeOffer(ctx context.Context, opts SendTradeOfferOptio
 	formData.Set("json_tradeoffer", string(tradeJSONBytes))
 	formData.Set("captcha", "")
 	formData.Set("trade_offer_create_params", createParams)
+	if counteredID != "" {
+		formData.Set("tradeofferid_countered", counteredID)
+	}
 
 	// Build referer URL
-	refererURL := fmt.Sprintf("https://steamcommunity.com/tradeoffer/new/?partner=%d", partnerAccountID)
+	refererURL := fmt.Sprintf("%s/tradeoffer/new/?partner=%d", c.baseURL, partnerAccountID)
 	if opts.Token != "" {
 		refererURL += "&token=" + opts.Token
 	}
+	if counteredID != "" {
+		refererURL = fmt.Sprintf("%s/tradeoffer/%s/", c.baseURL, counteredID)
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://steamcommunity.com/tradeoffer/new/send", strings.NewReader(formData.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/tradeoffer/new/send", strings.NewReader(formData.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -162,8 +195,8 @@ func (c *Community) SendTradeOffer(ctx context.Context, opts SendTradeOfferOptio
 		return nil, fmt.Errorf("read body: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+	if err := checkTradeOfferResponse(resp.StatusCode, body); err != nil {
+		return nil, err
 	}
 
 	var result struct {
@@ -171,17 +204,12 @@ func (c *Community) SendTradeOffer(ctx context.Context, opts SendTradeOfferOptio
 		NeedsMobileConfirmation  bool   `json:"needs_mobile_confirmation"`
 		NeedsEmailConfirmation   bool   `json:"needs_email_confirmation"`
 		EmailDomain              string `json:"email_domain"`
-		StrError                 string `json:"strError"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
-	if result.StrError != "" {
-		return nil, fmt.Errorf("steam error: %s", result.StrError)
-	}
-
 	return &SendTradeOfferResponse{
 		TradeOfferID:      result.TradeOfferID,
 		NeedsConfirmation: result.NeedsMobileConfirmation,
@@ -190,13 +218,43 @@ func (c *Community) SendTradeOffer(ctx context.Context, opts SendTradeOfferOptio
 	}, nil
 }
 
+// AcceptTradeOfferOption configures AcceptTradeOffer.
+type AcceptTradeOfferOption func(*acceptTradeOfferConfig)
+
+type acceptTradeOfferConfig struct {
+	refuseTradeHold bool
+}
+
+// RefuseTradeHold makes AcceptTradeOffer check the offer's trade hold
+// first and return a *TradeHoldError instead of accepting if there is
+// one. The offer's items aren't known at that point, so a hold on either
+// side counts.
+func RefuseTradeHold() AcceptTradeOfferOption {
+	return func(cfg *acceptTradeOfferConfig) {
+		cfg.refuseTradeHold = true
+	}
+}
+
 // AcceptTradeOffer accepts a received trade offer
-func (c *Community) AcceptTradeOffer(ctx context.Context, offerID string, partnerSteamID steamid.SteamID) (*AcceptTradeOfferResponse, error) {
+func (c *Community) AcceptTradeOffer(ctx context.Context, offerID string, partnerSteamID steamid.SteamID, opts ...AcceptTradeOfferOption) (*AcceptTradeOfferResponse, error) {
 	if err := c.ensureInit(); err != nil {
 		return nil, err
 	}
-	acceptURL := fmt.Sprintf("https://steamcommunity.com/tradeoffer/%s/accept", offerID)
-	refererURL := fmt.Sprintf("https://steamcommunity.com/tradeoffer/%s/", offerID)
+	var cfg acceptTradeOfferConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.refuseTradeHold {
+		details, err := c.GetOfferUserDetails(ctx, offerID)
+		if err != nil {
+			return nil, fmt.Errorf("get trade hold: %w", err)
+		}
+		if err := details.tradeHold(true, true); err != nil {
+			return nil, err
+		}
+	}
+	acceptURL := fmt.Sprintf("%s/tradeoffer/%s/accept", c.baseURL, offerID)
+	refererURL := fmt.Sprintf("%s/tradeoffer/%s/", c.baseURL, offerID)
 
 	formData := url.Values{}
 	formData.Set("sessionid", c.sessionID)
@@ -223,26 +281,23 @@ func (c *Community) AcceptTradeOffer(ctx context.Context, offerID string, partne
 		return nil, fmt.Errorf("read body: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+	if err := checkTradeOfferResponse(resp.StatusCode, body); err != nil {
+		return nil, err
 	}
 
 	var result struct {
+		TradeID                 string `json:"tradeid"`
 		NeedsMobileConfirmation bool   `json:"needs_mobile_confirmation"`
 		NeedsEmailConfirmation  bool   `json:"needs_email_confirmation"`
 		EmailDomain             string `json:"email_domain"`
-		StrError                string `json:"strError"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
-	if result.StrError != "" {
-		return nil, fmt.Errorf("steam error: %s", result.StrError)
-	}
-
 	return &AcceptTradeOfferResponse{
+		TradeID:           result.TradeID,
 		NeedsConfirmation: result.NeedsMobileConfirmation,
 		NeedsEmailConfirm: result.NeedsEmailConfirmation,
 		EmailDomain:       result.EmailDomain,
@@ -263,8 +318,8 @@ func (c *Community) cancelOrDeclineOffer(ctx context.Context, offerID, action st
 	if err := c.ensureInit(); err != nil {
 		return err
 	}
-	actionURL := fmt.Sprintf("https://steamcommunity.com/tradeoffer/%s/%s", offerID, action)
-	refererURL := fmt.Sprintf("https://steamcommunity.com/tradeoffer/%s/", offerID)
+	actionURL := fmt.Sprintf("%s/tradeoffer/%s/%s", c.baseURL, offerID, action)
+	refererURL := fmt.Sprintf("%s/tradeoffer/%s/", c.baseURL, offerID)
 
 	formData := url.Values{}
 	formData.Set("sessionid", c.sessionID)
@@ -287,22 +342,17 @@ func (c *Community) cancelOrDeclineOffer(ctx context.Context, offerID, action st
 		return fmt.Errorf("read body: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return steamapi.HTTPStatusError(resp.StatusCode, body)
+	if err := checkTradeOfferResponse(resp.StatusCode, body); err != nil {
+		return err
 	}
 
 	var result struct {
 		TradeOfferID string `json:"tradeofferid"`
-		StrError     string `json:"strError"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
 		return fmt.Errorf("decode response: %w", err)
 	}
 
-	if result.StrError != "" {
-		return fmt.Errorf("steam error: %s", result.StrError)
-	}
-
 	return nil
 }
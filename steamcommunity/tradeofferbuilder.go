@@ -0,0 +1,225 @@
+package steamcommunity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// MaxTradeOfferMessageLength is the longest message, in characters, Steam
+// accepts on a trade offer.
+const MaxTradeOfferMessageLength = 128
+
+// Errors from building a trade offer. Item errors are wrapped with the
+// asset ID they concern.
+var (
+	ErrInvalidTradeURL         = errors.New("trade offer: invalid trade URL")
+	ErrTradeMessageTooLong     = errors.New("trade offer: message too long")
+	ErrTradeItemNotTradable    = errors.New("trade offer: item not tradable")
+	ErrTradeItemDuplicate      = errors.New("trade offer: item added twice")
+	ErrTradeItemAmount         = errors.New("trade offer: amount out of range")
+	ErrTradeItemNotInInventory = errors.New("trade offer: item not in inventory")
+	ErrTradeOfferEmpty         = errors.New("trade offer: no items")
+)
+
+// ParseTradeURL extracts the partner and access token from a trade offer
+// URL such as https://steamcommunity.com/tradeoffer/new/?partner=12345&token=AbCd.
+// The token is empty for friends-only URLs without one.
+func ParseTradeURL(tradeURL string) (partner steamid.SteamID, token string, err error) {
+	u, err := url.Parse(tradeURL)
+	if err != nil {
+		return 0, "", fmt.Errorf("%w: %v", ErrInvalidTradeURL, err)
+	}
+	q := u.Query()
+	accountID, err := strconv.ParseUint(q.Get("partner"), 10, 32)
+	if err != nil || accountID == 0 {
+		return 0, "", fmt.Errorf("%w: bad partner %q", ErrInvalidTradeURL, q.Get("partner"))
+	}
+	partner = steamid.SteamID(0).SetUniverse(1).SetType(1).SetInstance(1).SetAccountID(uint32(accountID))
+	return partner, q.Get("token"), nil
+}
+
+// TradeOffer composes a trade offer item by item and sends it. Items are
+// checked as they are added, and again against the live inventories when
+// the offer is sent.
+type TradeOffer struct {
+	c       *Community
+	partner steamid.SteamID
+	token   string
+	message string
+	give    []offerItem
+	receive []offerItem
+}
+
+type offerItem struct {
+	appID     int
+	contextID string
+	item      InventoryItem
+	amount    int
+}
+
+// NewTradeOffer starts a trade offer to the owner of tradeURL.
+func (c *Community) NewTradeOffer(tradeURL string) (*TradeOffer, error) {
+	partner, token, err := ParseTradeURL(tradeURL)
+	if err != nil {
+		return nil, err
+	}
+	return &TradeOffer{c: c, partner: partner, token: token}, nil
+}
+
+// Partner returns the SteamID the offer is for.
+func (o *TradeOffer) Partner() steamid.SteamID {
+	return o.partner
+}
+
+// SetMessage sets the message shown with the offer.
+func (o *TradeOffer) SetMessage(message string) error {
+	if utf8.RuneCountInString(message) > MaxTradeOfferMessageLength {
+		return fmt.Errorf("%w: %d characters, max %d", ErrTradeMessageTooLong,
+			utf8.RuneCountInString(message), MaxTradeOfferMessageLength)
+	}
+	o.message = message
+	return nil
+}
+
+// AddMyItem adds amount of one of our items, as listed by GetOwnInventory,
+// to the items we give.
+func (o *TradeOffer) AddMyItem(appID int, contextID string, item InventoryItem, amount int) error {
+	return o.add(&o.give, appID, contextID, item, amount)
+}
+
+// AddTheirItem adds amount of one of the partner's items, as listed by
+// GetInventory, to the items we receive.
+func (o *TradeOffer) AddTheirItem(appID int, contextID string, item InventoryItem, amount int) error {
+	return o.add(&o.receive, appID, contextID, item, amount)
+}
+
+func (o *TradeOffer) add(side *[]offerItem, appID int, contextID string, item InventoryItem, amount int) error {
+	if !item.Tradable {
+		return fmt.Errorf("asset %s: %w", item.AssetID, ErrTradeItemNotTradable)
+	}
+	if err := checkAmount(item, amount); err != nil {
+		return err
+	}
+	for _, it := range *side {
+		if it.appID == appID && it.contextID == contextID && it.item.AssetID == item.AssetID {
+			return fmt.Errorf("asset %s: %w", item.AssetID, ErrTradeItemDuplicate)
+		}
+	}
+	*side = append(*side, offerItem{appID: appID, contextID: contextID, item: item, amount: amount})
+	return nil
+}
+
+// Validate checks every item against the current inventories of both
+// sides: each must still be there, tradable, and held in at least the
+// offered amount.
+func (o *TradeOffer) Validate(ctx context.Context) error {
+	if len(o.give)+len(o.receive) == 0 {
+		return ErrTradeOfferEmpty
+	}
+	if err := o.c.ensureInit(); err != nil {
+		return err
+	}
+	ours := func(appID int, contextID string) ([]InventoryItem, error) {
+		return o.c.GetInventory(ctx, o.c.SteamID, appID, contextID)
+	}
+	// The partner's inventory may be private; the trade token gets past
+	// that like it does on the new offer page.
+	theirs := func(appID int, contextID string) ([]InventoryItem, error) {
+		return o.c.GetPartnerInventory(ctx, o.partner, o.token, appID, contextID)
+	}
+	if err := validateSide(ours, o.give); err != nil {
+		return fmt.Errorf("our items: %w", err)
+	}
+	if err := validateSide(theirs, o.receive); err != nil {
+		return fmt.Errorf("their items: %w", err)
+	}
+	return nil
+}
+
+func validateSide(fetch func(appID int, contextID string) ([]InventoryItem, error), items []offerItem) error {
+	type inventoryKey struct {
+		appID     int
+		contextID string
+	}
+	inventories := make(map[inventoryKey]map[string]InventoryItem)
+
+	for _, it := range items {
+		key := inventoryKey{it.appID, it.contextID}
+		inv, ok := inventories[key]
+		if !ok {
+			fetched, err := fetch(it.appID, it.contextID)
+			if err != nil {
+				return fmt.Errorf("get inventory %d/%s: %w", it.appID, it.contextID, err)
+			}
+			inv = make(map[string]InventoryItem, len(fetched))
+			for _, f := range fetched {
+				inv[f.AssetID] = f
+			}
+			inventories[key] = inv
+		}
+
+		current, ok := inv[it.item.AssetID]
+		if !ok {
+			return fmt.Errorf("asset %s: %w", it.item.AssetID, ErrTradeItemNotInInventory)
+		}
+		if !current.Tradable {
+			return fmt.Errorf("asset %s: %w", it.item.AssetID, ErrTradeItemNotTradable)
+		}
+		if err := checkAmount(current, it.amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Send validates the offer and sends it. Steam's refusals come back as a
+// *TradeOfferError.
+func (o *TradeOffer) Send(ctx context.Context) (*SendTradeOfferResponse, error) {
+	if err := o.Validate(ctx); err != nil {
+		return nil, err
+	}
+	return o.c.SendTradeOffer(ctx, SendTradeOfferOptions{
+		Partner:        o.partner,
+		Token:          o.token,
+		Message:        o.message,
+		ItemsToGive:    tradeAssets(o.give),
+		ItemsToReceive: tradeAssets(o.receive),
+	})
+}
+
+// checkAmount reports whether amount of item can be offered.
+func checkAmount(item InventoryItem, amount int) error {
+	held := 1
+	if item.Amount != "" {
+		var err error
+		if held, err = strconv.Atoi(item.Amount); err != nil {
+			return fmt.Errorf("asset %s: bad inventory amount %q", item.AssetID, item.Amount)
+		}
+	}
+	if amount < 1 || amount > held {
+		return fmt.Errorf("asset %s: %w: %d of %d", item.AssetID, ErrTradeItemAmount, amount, held)
+	}
+	return nil
+}
+
+func tradeAssets(items []offerItem) []steamapi.TradeAsset {
+	out := make([]steamapi.TradeAsset, len(items))
+	for i, it := range items {
+		out[i] = steamapi.TradeAsset{
+			AppID:      it.appID,
+			ContextID:  it.contextID,
+			AssetID:    it.item.AssetID,
+			ClassID:    it.item.ClassID,
+			InstanceID: it.item.InstanceID,
+			Amount:     strconv.Itoa(it.amount),
+		}
+	}
+	return out
+}
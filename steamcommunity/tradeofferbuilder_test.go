@@ -0,0 +1,171 @@
+package steamcommunity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/k64z/steamstacks/steamtest"
+)
+
+func TestParseTradeURL(t *testing.T) {
+	partner, token, err := ParseTradeURL("https://steamcommunity.com/tradeoffer/new/?partner=39782337&token=AbCdEfGh")
+	if err != nil {
+		t.Fatalf("ParseTradeURL: %v", err)
+	}
+	if got, want := partner.ToSteamID64(), uint64(76561198000048065); got != want {
+		t.Errorf("partner = %d, want %d", got, want)
+	}
+	if token != "AbCdEfGh" {
+		t.Errorf("token = %q, want AbCdEfGh", token)
+	}
+
+	for _, bad := range []string{
+		"https://steamcommunity.com/tradeoffer/new/",
+		"https://steamcommunity.com/tradeoffer/new/?partner=abc",
+		"https://steamcommunity.com/tradeoffer/new/?partner=0",
+	} {
+		if _, _, err := ParseTradeURL(bad); !errors.Is(err, ErrInvalidTradeURL) {
+			t.Errorf("ParseTradeURL(%q) = %v, want ErrInvalidTradeURL", bad, err)
+		}
+	}
+}
+
+func TestCheckTradeOfferResponse(t *testing.T) {
+	body := []byte(`{"strError":"There was an error sending your trade offer.  Please try again later. (26)"}`)
+	err := checkTradeOfferResponse(http.StatusInternalServerError, body)
+
+	var tradeErr *TradeOfferError
+	if !errors.As(err, &tradeErr) {
+		t.Fatalf("err = %v, want *TradeOfferError", err)
+	}
+	if tradeErr.EResult != 26 {
+		t.Errorf("EResult = %d, want 26", tradeErr.EResult)
+	}
+	if !errors.Is(err, ErrTradeOfferItemsUnavailable) {
+		t.Errorf("errors.Is(err, ErrTradeOfferItemsUnavailable) = false")
+	}
+
+	err = checkTradeOfferResponse(http.StatusInternalServerError, []byte(`{"strError":"You cannot trade with this user."}`))
+	if !errors.As(err, &tradeErr) || tradeErr.EResult != 0 {
+		t.Errorf("err = %v, want *TradeOfferError without EResult", err)
+	}
+
+	if err := checkTradeOfferResponse(http.StatusBadGateway, []byte("bad gateway")); err == nil || errors.As(err, &tradeErr) {
+		t.Errorf("err = %v, want plain HTTP error", err)
+	}
+	if err := checkTradeOfferResponse(http.StatusOK, []byte(`{"tradeofferid":"1"}`)); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+}
+
+func TestTradeOfferBuilder(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(steamtest.Account{})
+	// Bob's inventory is private: only his trade token gets at it.
+	bob := srv.AddAccount(steamtest.Account{InventoryPrivate: true, TradeToken: "tok"})
+	srv.AddItem(alice.SteamID, steamtest.Item{AppID: 440, Name: "Team Captain", Tradable: true})
+	srv.AddItem(alice.SteamID, steamtest.Item{AppID: 440, Name: "Gift-Stuffed Stocking", Amount: 5, Tradable: true})
+	srv.AddItem(alice.SteamID, steamtest.Item{AppID: 440, Name: "Untradable Hat"})
+	srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Mann Co. Supply Crate Key", Tradable: true})
+
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+	bobc := newSteamtestCommunity(t, srv, bob)
+
+	mine, err := alicec.GetOwnInventory(ctx, 440, "2")
+	if err != nil {
+		t.Fatalf("GetOwnInventory: %v", err)
+	}
+	theirs, err := alicec.GetPartnerInventory(ctx, bob.SteamID, "tok", 440, "2")
+	if err != nil {
+		t.Fatalf("GetPartnerInventory: %v", err)
+	}
+	hat, stocking, untradable, key := mine[0], mine[1], mine[2], theirs[0]
+
+	offer, err := alicec.NewTradeOffer(fmt.Sprintf("%s/tradeoffer/new/?partner=%d&token=tok", srv.URL(), bob.SteamID.AccountID()))
+	if err != nil {
+		t.Fatalf("NewTradeOffer: %v", err)
+	}
+	if offer.Partner() != bob.SteamID {
+		t.Errorf("Partner = %d, want %d", offer.Partner(), bob.SteamID)
+	}
+
+	if _, err := offer.Send(ctx); !errors.Is(err, ErrTradeOfferEmpty) {
+		t.Errorf("empty Send = %v, want ErrTradeOfferEmpty", err)
+	}
+	if err := offer.SetMessage(strings.Repeat("é", MaxTradeOfferMessageLength+1)); !errors.Is(err, ErrTradeMessageTooLong) {
+		t.Errorf("SetMessage = %v, want ErrTradeMessageTooLong", err)
+	}
+	if err := offer.SetMessage(strings.Repeat("é", MaxTradeOfferMessageLength)); err != nil {
+		t.Errorf("SetMessage at the limit: %v", err)
+	}
+	if err := offer.AddMyItem(440, "2", untradable, 1); !errors.Is(err, ErrTradeItemNotTradable) {
+		t.Errorf("AddMyItem(untradable) = %v, want ErrTradeItemNotTradable", err)
+	}
+	if err := offer.AddMyItem(440, "2", stocking, 6); !errors.Is(err, ErrTradeItemAmount) {
+		t.Errorf("AddMyItem(6 of 5) = %v, want ErrTradeItemAmount", err)
+	}
+	if err := offer.AddMyItem(440, "2", stocking, 3); err != nil {
+		t.Fatalf("AddMyItem(stocking): %v", err)
+	}
+	if err := offer.AddMyItem(440, "2", stocking, 1); !errors.Is(err, ErrTradeItemDuplicate) {
+		t.Errorf("AddMyItem twice = %v, want ErrTradeItemDuplicate", err)
+	}
+	if err := offer.AddMyItem(440, "2", hat, 1); err != nil {
+		t.Fatalf("AddMyItem(hat): %v", err)
+	}
+	if err := offer.AddTheirItem(440, "2", key, 1); err != nil {
+		t.Fatalf("AddTheirItem: %v", err)
+	}
+
+	// The key leaves Bob's inventory before the offer goes out.
+	other := srv.AddAccount(steamtest.Account{})
+	gift, err := bobc.SendTradeOffer(ctx, SendTradeOfferOptions{
+		Partner:     other.SteamID,
+		ItemsToGive: tradeAssets([]offerItem{{appID: 440, contextID: "2", item: key, amount: 1}}),
+	})
+	if err != nil {
+		t.Fatalf("SendTradeOffer(gift): %v", err)
+	}
+	otherc := newSteamtestCommunity(t, srv, other)
+	if _, err := otherc.AcceptTradeOffer(ctx, gift.TradeOfferID, bob.SteamID); err != nil {
+		t.Fatalf("AcceptTradeOffer(gift): %v", err)
+	}
+	if _, err := offer.Send(ctx); !errors.Is(err, ErrTradeItemNotInInventory) {
+		t.Fatalf("Send = %v, want ErrTradeItemNotInInventory", err)
+	}
+
+	// Accepting the gift again fails with Steam's EResult.
+	_, err = otherc.AcceptTradeOffer(ctx, gift.TradeOfferID, bob.SteamID)
+	if !errors.Is(err, ErrTradeOfferInvalidState) {
+		t.Errorf("second accept = %v, want ErrTradeOfferInvalidState", err)
+	}
+
+	offer.receive = nil
+	sent, err := offer.Send(ctx)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	got, ok := srv.TradeOffer(sent.TradeOfferID)
+	if !ok {
+		t.Fatal("offer not on the server")
+	}
+	if len(got.ItemsToGive) != 2 || got.ItemsToGive[0].Amount != "3" {
+		t.Errorf("ItemsToGive = %+v, want 3 stockings and the hat", got.ItemsToGive)
+	}
+}
+
+func newSteamtestCommunity(t *testing.T, srv *steamtest.Server, acct steamtest.Account) *Community {
+	t.Helper()
+	c, err := New(WithHTTPClient(srv.Client(acct.SteamID)), WithHosts(srv.Hosts()))
+	if err != nil {
+		t.Fatalf("create community: %v", err)
+	}
+	return c
+}
@@ -0,0 +1,113 @@
+package steamcommunity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/k64z/steamstacks/eresult"
+	"github.com/k64z/steamstacks/steamapi"
+)
+
+// Trade offer failures Steam reports through a well-known EResult. A
+// *TradeOfferError unwraps to one of these, so callers can use errors.Is.
+var (
+	ErrTradeOfferFailed             = errors.New("trade offer: failed")
+	ErrTradeOfferInvalidParam       = errors.New("trade offer: invalid parameter")
+	ErrTradeOfferInvalidState       = errors.New("trade offer: no longer valid")
+	ErrTradeOfferAccessDenied       = errors.New("trade offer: access denied")
+	ErrTradeOfferTimeout            = errors.New("trade offer: timed out")
+	ErrTradeOfferServiceUnavailable = errors.New("trade offer: service unavailable")
+	ErrTradeOfferLimitExceeded      = errors.New("trade offer: too many offers")
+	ErrTradeOfferItemsUnavailable   = errors.New("trade offer: items no longer available")
+)
+
+var tradeOfferErrors = map[eresult.EResult]error{
+	eresult.Fail:               ErrTradeOfferFailed,
+	eresult.InvalidParam:       ErrTradeOfferInvalidParam,
+	eresult.InvalidState:       ErrTradeOfferInvalidState,
+	eresult.AccessDenied:       ErrTradeOfferAccessDenied,
+	eresult.Timeout:            ErrTradeOfferTimeout,
+	eresult.ServiceUnavailable: ErrTradeOfferServiceUnavailable,
+	eresult.LimitExceeded:      ErrTradeOfferLimitExceeded,
+	eresult.Revoked:            ErrTradeOfferItemsUnavailable,
+}
+
+// TradeOfferError is Steam's strError from a trade offer request. EResult
+// is the code Steam appends in parentheses, or 0 if there was none.
+type TradeOfferError struct {
+	EResult eresult.EResult
+	Message string
+}
+
+func (e *TradeOfferError) Error() string {
+	return "steam error: " + e.Message
+}
+
+// Unwrap returns the ErrTradeOffer* sentinel for the EResult, if any.
+func (e *TradeOfferError) Unwrap() error {
+	return tradeOfferErrors[e.EResult]
+}
+
+// ErrTradeHold is what a *TradeHoldError unwraps to.
+var ErrTradeHold = errors.New("trade offer: trade hold would apply")
+
+// TradeHoldError is returned instead of sending or accepting an offer when
+// RefuseTradeHold is in effect and Steam would hold the trade. The days
+// are as in TradeUserDetails.
+type TradeHoldError struct {
+	MyDays    int
+	TheirDays int
+}
+
+func (e *TradeHoldError) Error() string {
+	return fmt.Sprintf("trade offer: trade hold would apply (ours %d days, theirs %d days)", e.MyDays, e.TheirDays)
+}
+
+func (e *TradeHoldError) Unwrap() error {
+	return ErrTradeHold
+}
+
+// strErrorEResultRE matches the EResult at the end of a strError, e.g.
+// "There was an error accepting this trade offer. Please try again later. (28)".
+var strErrorEResultRE = regexp.MustCompile(`[(\[](\d+)[)\]]\s*$`)
+
+func newTradeOfferError(strError string) *TradeOfferError {
+	e := &TradeOfferError{Message: strError}
+	if m := strErrorEResultRE.FindStringSubmatch(strError); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		e.EResult = eresult.EResult(code)
+	}
+	return e
+}
+
+// checkTradeOfferResponse turns a failed trade offer response into an
+// error. Steam answers failures with HTTP 500 and a JSON strError, so the
+// body is checked for one before falling back to the status.
+func checkTradeOfferResponse(status int, body []byte) error {
+	var result struct {
+		StrError string `json:"strError"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && result.StrError != "" {
+		return newTradeOfferError(result.StrError)
+	}
+	if status != http.StatusOK {
+		return steamapi.HTTPStatusError(status, body)
+	}
+	return nil
+}
+
+// parseAssetAmount parses a TradeAsset amount; empty means 1.
+func parseAssetAmount(a steamapi.TradeAsset) (int, error) {
+	if a.Amount == "" {
+		return 1, nil
+	}
+	amount, err := strconv.Atoi(a.Amount)
+	if err != nil || amount < 1 {
+		return 0, fmt.Errorf("asset %s: invalid amount %q", a.AssetID, a.Amount)
+	}
+	return amount, nil
+}
@@ -0,0 +1,298 @@
+package steamcommunity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// TradeUserDetails is what the new trade offer page says about both sides
+// of a prospective trade.
+type TradeUserDetails struct {
+	MyEscrowDays     int // trade hold on the items we give
+	TheirEscrowDays  int // trade hold on the items they give
+	MyPersonaName    string
+	TheirPersonaName string
+	TheirProbation   bool // partner is on trade probation
+}
+
+var (
+	reMyEscrow         = regexp.MustCompile(`var g_daysMyEscrow\s*=\s*(\d+);`)
+	reTheirEscrow      = regexp.MustCompile(`var g_daysTheirEscrow\s*=\s*(\d+);`)
+	reTheirProbation   = regexp.MustCompile(`var g_bTradePartnerProbation\s*=\s*(true|false);`)
+	reMyPersonaName    = regexp.MustCompile(`var g_strYourPersonaName\s*=\s*("(?:[^"\\]|\\.)*");`)
+	reTheirPersonaName = regexp.MustCompile(`g_strTradePartnerPersonaName\s*=\s*("(?:[^"\\]|\\.)*");`)
+	reTradeErrorMsg    = regexp.MustCompile(`(?s)<div id="error_msg">(.*?)</div>`)
+)
+
+// GetUserDetails loads the new trade offer page for partner and reads the
+// trade hold durations and persona names off it. token is the partner's
+// trade token, empty for friends. If Steam refuses to open the page, e.g.
+// because the token is stale, the reason comes back as a *TradeOfferError.
+func (c *Community) GetUserDetails(ctx context.Context, partner steamid.SteamID, token string) (*TradeUserDetails, error) {
+	if err := c.ensureInit(); err != nil {
+		return nil, err
+	}
+	return c.getTradeUserDetails(ctx, newOfferURL(c.baseURL, partner, token))
+}
+
+// GetOfferUserDetails is GetUserDetails for an existing offer, read off
+// its page. It is what to check before accepting a received offer.
+func (c *Community) GetOfferUserDetails(ctx context.Context, offerID string) (*TradeUserDetails, error) {
+	if err := c.ensureInit(); err != nil {
+		return nil, err
+	}
+	return c.getTradeUserDetails(ctx, fmt.Sprintf("%s/tradeoffer/%s/", c.baseURL, offerID))
+}
+
+func (c *Community) getTradeUserDetails(ctx context.Context, pageURL string) (*TradeUserDetails, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+	}
+
+	return parseTradeUserDetails(string(body))
+}
+
+// tradeHold returns the hold that trading the given sides would incur, as
+// a *TradeHoldError, or nil if there is none. Each side's hold applies
+// only if that side gives items.
+func (d *TradeUserDetails) tradeHold(giving, receiving bool) error {
+	if (giving && d.MyEscrowDays > 0) || (receiving && d.TheirEscrowDays > 0) {
+		return &TradeHoldError{MyDays: d.MyEscrowDays, TheirDays: d.TheirEscrowDays}
+	}
+	return nil
+}
+
+func parseTradeUserDetails(page string) (*TradeUserDetails, error) {
+	if m := reTradeErrorMsg.FindStringSubmatch(page); m != nil {
+		return nil, newTradeOfferError(normalizeWhitespace(html.UnescapeString(m[1])))
+	}
+
+	myEscrow := reMyEscrow.FindStringSubmatch(page)
+	theirEscrow := reTheirEscrow.FindStringSubmatch(page)
+	if myEscrow == nil || theirEscrow == nil {
+		return nil, errors.New("escrow durations not found on trade offer page")
+	}
+
+	details := &TradeUserDetails{}
+	details.MyEscrowDays, _ = strconv.Atoi(myEscrow[1])
+	details.TheirEscrowDays, _ = strconv.Atoi(theirEscrow[1])
+	if m := reTheirProbation.FindStringSubmatch(page); m != nil {
+		details.TheirProbation = m[1] == "true"
+	}
+	if m := reMyPersonaName.FindStringSubmatch(page); m != nil {
+		_ = json.Unmarshal([]byte(m[1]), &details.MyPersonaName)
+	}
+	if m := reTheirPersonaName.FindStringSubmatch(page); m != nil {
+		_ = json.Unmarshal([]byte(m[1]), &details.TheirPersonaName)
+	}
+	return details, nil
+}
+
+// partnerInventoryResponse is the legacy inventory format served by
+// /tradeoffer/new/partnerinventory/. rgInventory is an empty array rather
+// than an object when there are no items, and more_start is false on the
+// last page.
+type partnerInventoryResponse struct {
+	Success        bool                                   `json:"success"`
+	Error          string                                 `json:"error"`
+	RgInventory    json.RawMessage                        `json:"rgInventory"`
+	RgDescriptions map[string]partnerInventoryDescription `json:"rgDescriptions"`
+	More           bool                                   `json:"more"`
+	MoreStart      json.RawMessage                        `json:"more_start"`
+}
+
+type partnerInventoryAsset struct {
+	ID         string `json:"id"`
+	ClassID    string `json:"classid"`
+	InstanceID string `json:"instanceid"`
+	Amount     string `json:"amount"`
+	Pos        int    `json:"pos"`
+}
+
+type partnerInventoryDescription struct {
+	Name           string            `json:"name"`
+	MarketHashName string            `json:"market_hash_name"`
+	Type           string            `json:"type"`
+	Tradable       int               `json:"tradable"`
+	Marketable     int               `json:"marketable"`
+	Commodity      int               `json:"commodity"`
+	IconURL        string            `json:"icon_url"`
+	IconURLLarge   string            `json:"icon_url_large"`
+	Descriptions   []DescriptionLine `json:"descriptions"`
+	Tags           []struct {
+		InternalName string `json:"internal_name"`
+		Name         string `json:"name"`
+		Category     string `json:"category"`
+		CategoryName string `json:"category_name"`
+		Color        string `json:"color"`
+	} `json:"tags"`
+	Actions       []InventoryAction `json:"actions"`
+	FraudWarnings []string          `json:"fraudwarnings"`
+}
+
+// GetPartnerInventory fetches the partner's inventory the way the new trade
+// offer page does. Unlike GetInventory it works for private inventories,
+// as long as token is the partner's current trade token (empty for
+// friends).
+func (c *Community) GetPartnerInventory(ctx context.Context, partner steamid.SteamID, token string, appID int, contextID string) ([]InventoryItem, error) {
+	if err := c.ensureInit(); err != nil {
+		return nil, err
+	}
+
+	var items []InventoryItem
+	start := ""
+	for {
+		q := url.Values{}
+		q.Set("sessionid", c.sessionID)
+		q.Set("partner", strconv.FormatUint(partner.ToSteamID64(), 10))
+		q.Set("appid", strconv.Itoa(appID))
+		q.Set("contextid", contextID)
+		q.Set("l", "english")
+		if start != "" {
+			q.Set("start", start)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			c.baseURL+"/tradeoffer/new/partnerinventory/?"+q.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Referer", newOfferURL(c.baseURL, partner, token))
+		req.Header.Set("X-Requested-With", "XMLHttpRequest")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("do request: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read body: %w", err)
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusTooManyRequests:
+			return nil, errRateLimited
+		default:
+			return nil, steamapi.HTTPStatusError(resp.StatusCode, body)
+		}
+
+		page, next, err := parsePartnerInventory(body)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if next == "" {
+			return items, nil
+		}
+		start = next
+	}
+}
+
+// parsePartnerInventory decodes one partnerinventory page into items in
+// inventory order, and returns the start of the next page, if any.
+func parsePartnerInventory(data []byte) ([]InventoryItem, string, error) {
+	var resp partnerInventoryResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, "", fmt.Errorf("decode response: %w", err)
+	}
+	if !resp.Success {
+		if resp.Error != "" {
+			return nil, "", newTradeOfferError(resp.Error)
+		}
+		return nil, "", errors.New("request failed")
+	}
+
+	var assets map[string]partnerInventoryAsset
+	if raw := strings.TrimSpace(string(resp.RgInventory)); raw != "" && raw != "[]" {
+		if err := json.Unmarshal(resp.RgInventory, &assets); err != nil {
+			return nil, "", fmt.Errorf("decode rgInventory: %w", err)
+		}
+	}
+
+	ordered := make([]partnerInventoryAsset, 0, len(assets))
+	for _, a := range assets {
+		ordered = append(ordered, a)
+	}
+	// Map order is random; pos is the slot in the inventory.
+	slices.SortFunc(ordered, func(a, b partnerInventoryAsset) int { return a.Pos - b.Pos })
+
+	items := make([]InventoryItem, 0, len(ordered))
+	for _, a := range ordered {
+		desc := resp.RgDescriptions[descriptionKey(a.ClassID, a.InstanceID)]
+		item := InventoryItem{
+			AssetID:        a.ID,
+			ClassID:        a.ClassID,
+			InstanceID:     a.InstanceID,
+			Amount:         a.Amount,
+			Name:           desc.Name,
+			MarketHashName: desc.MarketHashName,
+			Type:           desc.Type,
+			Tradable:       desc.Tradable == 1,
+			Marketable:     desc.Marketable == 1,
+			Commodity:      desc.Commodity == 1,
+			IconURL:        desc.IconURL,
+			IconURLLarge:   desc.IconURLLarge,
+			Descriptions:   desc.Descriptions,
+			Actions:        desc.Actions,
+			FraudWarnings:  desc.FraudWarnings,
+		}
+		for _, t := range desc.Tags {
+			item.Tags = append(item.Tags, InventoryTag{
+				Category:              t.Category,
+				InternalName:          t.InternalName,
+				LocalizedCategoryName: t.CategoryName,
+				LocalizedTagName:      t.Name,
+				Color:                 t.Color,
+			})
+		}
+		items = append(items, item)
+	}
+
+	next := ""
+	if resp.More {
+		if n, err := strconv.Atoi(string(resp.MoreStart)); err == nil {
+			next = strconv.Itoa(n)
+		}
+	}
+	return items, next, nil
+}
+
+// newOfferURL is the new trade offer page for partner.
+func newOfferURL(baseURL string, partner steamid.SteamID, token string) string {
+	u := fmt.Sprintf("%s/tradeoffer/new/?partner=%d", baseURL, partner.AccountID())
+	if token != "" {
+		u += "&token=" + url.QueryEscape(token)
+	}
+	return u
+}
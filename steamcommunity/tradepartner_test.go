@@ -0,0 +1,95 @@
+package steamcommunity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamtest"
+)
+
+func TestParseTradeUserDetails(t *testing.T) {
+	page := `<script>
+	var g_daysMyEscrow = 0;
+	var g_daysTheirEscrow = 7;
+	var g_bTradePartnerProbation = true;
+	var g_strYourPersonaName = "me";
+	g_strTradePartnerPersonaName = "thém \"quoted\"";
+</script>`
+	details, err := parseTradeUserDetails(page)
+	if err != nil {
+		t.Fatalf("parseTradeUserDetails: %v", err)
+	}
+	want := TradeUserDetails{
+		TheirEscrowDays:  7,
+		MyPersonaName:    "me",
+		TheirPersonaName: `thém "quoted"`,
+		TheirProbation:   true,
+	}
+	if *details != want {
+		t.Errorf("details = %+v, want %+v", *details, want)
+	}
+
+	if details.tradeHold(true, false) != nil {
+		t.Error("giving only: want no hold")
+	}
+	var holdErr *TradeHoldError
+	if err := details.tradeHold(false, true); !errors.As(err, &holdErr) || holdErr.TheirDays != 7 || !errors.Is(err, ErrTradeHold) {
+		t.Errorf("receiving: err = %v, want TradeHoldError with 7 days", err)
+	}
+
+	_, err = parseTradeUserDetails(`<div id="error_msg">
+		This Trade URL is no longer valid for sending a trade offer to bob.	</div>`)
+	var tradeErr *TradeOfferError
+	if !errors.As(err, &tradeErr) || tradeErr.Message != "This Trade URL is no longer valid for sending a trade offer to bob." {
+		t.Errorf("err = %v, want TradeOfferError with the page message", err)
+	}
+}
+
+func TestRefuseTradeHold(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(steamtest.Account{})
+	bob := srv.AddAccount(steamtest.Account{EscrowDays: 7})
+	hat := srv.AddItem(alice.SteamID, steamtest.Item{AppID: 440, Name: "Team Captain", Tradable: true})
+	key := srv.AddItem(bob.SteamID, steamtest.Item{AppID: 440, Name: "Mann Co. Supply Crate Key", Tradable: true})
+
+	ctx := context.Background()
+	alicec := newSteamtestCommunity(t, srv, alice)
+	bobc := newSteamtestCommunity(t, srv, bob)
+
+	giveHat := []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: hat.AssetID}}
+	getKey := []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: key.AssetID}}
+
+	// Bob's hold only matters when Bob gives items.
+	_, err := alicec.SendTradeOffer(ctx, SendTradeOfferOptions{
+		Partner:         bob.SteamID,
+		ItemsToGive:     giveHat,
+		ItemsToReceive:  getKey,
+		RefuseTradeHold: true,
+	})
+	var holdErr *TradeHoldError
+	if !errors.As(err, &holdErr) || holdErr.TheirDays != 7 {
+		t.Fatalf("SendTradeOffer for the key = %v, want TradeHoldError", err)
+	}
+	gift, err := alicec.SendTradeOffer(ctx, SendTradeOfferOptions{
+		Partner:         bob.SteamID,
+		ItemsToGive:     giveHat,
+		RefuseTradeHold: true,
+	})
+	if err != nil {
+		t.Fatalf("SendTradeOffer gift: %v", err)
+	}
+
+	if _, err := bobc.AcceptTradeOffer(ctx, gift.TradeOfferID, alice.SteamID, RefuseTradeHold()); !errors.Is(err, ErrTradeHold) {
+		t.Fatalf("AcceptTradeOffer = %v, want ErrTradeHold", err)
+	}
+	if offer, _ := srv.TradeOffer(gift.TradeOfferID); offer.State != steamapi.ETradeOfferStateActive {
+		t.Errorf("State = %v after refused accept, want Active", offer.State)
+	}
+	if _, err := bobc.AcceptTradeOffer(ctx, gift.TradeOfferID, alice.SteamID); err != nil {
+		t.Fatalf("AcceptTradeOffer without option: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+// Package steamhost maps the Steam web hosts to base URLs, so every client
+// in the module can be pointed at a local mock server or a regional proxy
+// with a single option.
+package steamhost
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Default base URLs of the Steam web hosts.
+const (
+	DefaultAPI       = "https://api.steampowered.com"
+	DefaultCommunity = "https://steamcommunity.com"
+	DefaultStore     = "https://store.steampowered.com"
+	DefaultCheckout  = "https://checkout.steampowered.com"
+	DefaultLogin     = "https://login.steampowered.com"
+	DefaultHelp      = "https://help.steampowered.com"
+)
+
+// Hosts holds a base URL (scheme, host and optional path prefix, no trailing
+// slash) for each Steam web host. Empty fields fall back to the defaults.
+type Hosts struct {
+	API       string
+	Community string
+	Store     string
+	Checkout  string
+	Login     string
+	Help      string
+}
+
+// Default returns the production Steam hosts.
+func Default() Hosts {
+	return Hosts{
+		API:       DefaultAPI,
+		Community: DefaultCommunity,
+		Store:     DefaultStore,
+		Checkout:  DefaultCheckout,
+		Login:     DefaultLogin,
+		Help:      DefaultHelp,
+	}
+}
+
+// Single maps every host to baseURL, as when one mock server answers for
+// all of them.
+func Single(baseURL string) Hosts {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return Hosts{
+		API:       baseURL,
+		Community: baseURL,
+		Store:     baseURL,
+		Checkout:  baseURL,
+		Login:     baseURL,
+		Help:      baseURL,
+	}
+}
+
+// Resolve returns h with empty fields set to their defaults and trailing
+// slashes removed.
+func (h Hosts) Resolve() Hosts {
+	d := Default()
+	pick := func(v, def string) string {
+		if v == "" {
+			return def
+		}
+		return strings.TrimRight(v, "/")
+	}
+	return Hosts{
+		API:       pick(h.API, d.API),
+		Community: pick(h.Community, d.Community),
+		Store:     pick(h.Store, d.Store),
+		Checkout:  pick(h.Checkout, d.Checkout),
+		Login:     pick(h.Login, d.Login),
+		Help:      pick(h.Help, d.Help),
+	}
+}
+
+// Rewrite maps an absolute URL on one of the default Steam hosts, such as a
+// transfer URL returned by Steam, onto h. Other URLs are returned unchanged.
+func (h Hosts) Rewrite(rawURL string) string {
+	r := h.Resolve()
+	for _, m := range []struct{ from, to string }{
+		{DefaultAPI, r.API},
+		{DefaultCommunity, r.Community},
+		{DefaultStore, r.Store},
+		{DefaultCheckout, r.Checkout},
+		{DefaultLogin, r.Login},
+		{DefaultHelp, r.Help},
+	} {
+		if rawURL == m.from || strings.HasPrefix(rawURL, m.from+"/") || strings.HasPrefix(rawURL, m.from+"?") {
+			return m.to + strings.TrimPrefix(rawURL, m.from)
+		}
+	}
+	return rawURL
+}
+
+// CookieURL returns the URL under which cookies for baseURL live in a
+// cookie jar (its scheme and host, without the path prefix).
+func CookieURL(baseURL string) *url.URL {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return &url.URL{}
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}
+}
@@ -0,0 +1,42 @@
+package steamhost
+
+import "testing"
+
+func TestResolve(t *testing.T) {
+	h := Hosts{Community: "http://127.0.0.1:8080/"}.Resolve()
+	if h.Community != "http://127.0.0.1:8080" {
+		t.Errorf("Community = %q, want trailing slash trimmed", h.Community)
+	}
+	if h.API != DefaultAPI || h.Store != DefaultStore || h.Login != DefaultLogin {
+		t.Errorf("unset hosts not defaulted: %+v", h)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	h := Single("http://127.0.0.1:8080")
+	tests := []struct {
+		in, want string
+	}{
+		{"https://steamcommunity.com/login/settoken", "http://127.0.0.1:8080/login/settoken"},
+		{"https://help.steampowered.com/login/settoken", "http://127.0.0.1:8080/login/settoken"},
+		{"https://store.steampowered.com", "http://127.0.0.1:8080"},
+		{"https://steamcommunity.com.evil.example/x", "https://steamcommunity.com.evil.example/x"},
+		{"https://example.com/", "https://example.com/"},
+	}
+	for _, tt := range tests {
+		if got := h.Rewrite(tt.in); got != tt.want {
+			t.Errorf("Rewrite(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if got := (Hosts{}).Rewrite("https://steamcommunity.com/x"); got != "https://steamcommunity.com/x" {
+		t.Errorf("zero Hosts rewrote %q", got)
+	}
+}
+
+func TestCookieURL(t *testing.T) {
+	u := CookieURL("https://proxy.example/community")
+	if u.String() != "https://proxy.example/" {
+		t.Errorf("CookieURL = %q", u)
+	}
+}
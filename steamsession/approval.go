@@ -0,0 +1,182 @@
+package steamsession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamtotp"
+	"google.golang.org/protobuf/proto"
+)
+
+// PendingLogin describes a login attempt on this account that is waiting
+// for mobile confirmation (EAuthSessionGuardTypeDeviceConfirmation).
+type PendingLogin struct {
+	ClientID uint64
+	Version  int32
+
+	IP                 string
+	City               string
+	State              string
+	Country            string
+	DeviceFriendlyName string
+	PlatformType       PlatformType
+
+	// RequestorLocationMismatch is set when the login comes from a
+	// different location than the approving device.
+	RequestorLocationMismatch bool
+	HighUsageLogin            bool
+}
+
+// ClientID returns the client ID of the auth session started by
+// StartWithCredentials or StartWithQR. Another, already authenticated
+// Session can pass it to ApproveLogin.
+func (s *Session) ClientID() uint64 {
+	return s.clientID
+}
+
+// GetPendingLogins lists the login attempts awaiting mobile confirmation on
+// this session's account.
+func (s *Session) GetPendingLogins(ctx context.Context) ([]PendingLogin, error) {
+	if err := s.useAccessToken(); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.steamAPI.GetAuthSessionsForAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get auth sessions: %w", err)
+	}
+
+	logins := make([]PendingLogin, 0, len(resp.ClientIds))
+	for _, clientID := range resp.ClientIds {
+		login, err := s.GetPendingLogin(ctx, clientID)
+		if err != nil {
+			return nil, err
+		}
+		logins = append(logins, *login)
+	}
+	return logins, nil
+}
+
+// GetPendingLogin describes a single pending login attempt.
+func (s *Session) GetPendingLogin(ctx context.Context, clientID uint64) (*PendingLogin, error) {
+	if err := s.useAccessToken(); err != nil {
+		return nil, err
+	}
+
+	info, err := s.steamAPI.GetAuthSessionInfo(ctx, &protocol.CAuthentication_GetAuthSessionInfo_Request{
+		ClientId: &clientID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get auth session info: %w", err)
+	}
+
+	return &PendingLogin{
+		ClientID:                  clientID,
+		Version:                   info.GetVersion(),
+		IP:                        info.GetIp(),
+		City:                      info.GetCity(),
+		State:                     info.GetState(),
+		Country:                   info.GetCountry(),
+		DeviceFriendlyName:        info.GetDeviceFriendlyName(),
+		PlatformType:              PlatformType(info.GetPlatformType()),
+		RequestorLocationMismatch: info.GetRequestorLocationMismatch(),
+		HighUsageLogin:            info.GetHighUsageLogin(),
+	}, nil
+}
+
+// ApproveLogin approves a pending login as the account's mobile
+// authenticator would. The session must be logged in with a MobileApp token,
+// and sharedSecret is the shared_secret from the account's maFile.
+func (s *Session) ApproveLogin(ctx context.Context, clientID uint64, sharedSecret string) error {
+	login, err := s.GetPendingLogin(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	return s.updateWithMobileConfirmation(ctx, login.ClientID, login.Version, sharedSecret, true)
+}
+
+// DenyLogin rejects a pending login. See ApproveLogin.
+func (s *Session) DenyLogin(ctx context.Context, clientID uint64, sharedSecret string) error {
+	login, err := s.GetPendingLogin(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	return s.updateWithMobileConfirmation(ctx, login.ClientID, login.Version, sharedSecret, false)
+}
+
+// ApproveQRLogin approves the login behind a QR challenge URL such as
+// "https://s.team/q/1/2960817468519466396", as scanning it in the mobile
+// app would. See ApproveLogin.
+func (s *Session) ApproveQRLogin(ctx context.Context, challengeURL, sharedSecret string) error {
+	version, clientID, err := parseChallengeURL(challengeURL)
+	if err != nil {
+		return err
+	}
+	return s.updateWithMobileConfirmation(ctx, clientID, version, sharedSecret, true)
+}
+
+func (s *Session) updateWithMobileConfirmation(ctx context.Context, clientID uint64, version int32, sharedSecret string, confirm bool) error {
+	if err := s.useAccessToken(); err != nil {
+		return err
+	}
+
+	steamID := s.SteamID.ToSteamID64()
+	signature, err := steamtotp.GenerateAuthSessionSignature(sharedSecret, version, clientID, steamID)
+	if err != nil {
+		return fmt.Errorf("sign auth session: %w", err)
+	}
+
+	req := &protocol.CAuthentication_UpdateAuthSessionWithMobileConfirmation_Request{
+		Version:     &version,
+		ClientId:    &clientID,
+		Steamid:     &steamID,
+		Signature:   signature,
+		Confirm:     proto.Bool(confirm),
+		Persistence: protocol.ESessionPersistence_k_ESessionPersistence_Persistent.Enum(),
+	}
+
+	if err := s.steamAPI.UpdateAuthSessionWithMobileConfirmation(ctx, req); err != nil {
+		return fmt.Errorf("update auth session with mobile confirmation: %w", err)
+	}
+	return nil
+}
+
+// useAccessToken makes the session's access token available to Web API
+// calls that aren't covered by the cookie jar.
+func (s *Session) useAccessToken() error {
+	if s.AccessToken == "" {
+		return errors.New("access token is required")
+	}
+	s.steamAPI.SetAccessToken(s.AccessToken)
+	return nil
+}
+
+// parseChallengeURL extracts the version and client ID from a QR challenge
+// URL of the form https://s.team/q/{version}/{client_id}.
+func parseChallengeURL(challengeURL string) (int32, uint64, error) {
+	u, err := url.Parse(challengeURL)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse challenge URL: %w", err)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "q" {
+		return 0, 0, fmt.Errorf("unexpected challenge URL: %s", challengeURL)
+	}
+
+	version, err := strconv.ParseInt(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse challenge version: %w", err)
+	}
+	clientID, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse challenge client ID: %w", err)
+	}
+
+	return int32(version), clientID, nil
+}
@@ -0,0 +1,187 @@
+package steamsession
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamid"
+	"github.com/k64z/steamstacks/steamtotp"
+	"google.golang.org/protobuf/proto"
+)
+
+const testSharedSecret = "SGVsbG9Xb3JsZFRlc3RTZWNyZXQh"
+
+// newAPITestSession returns a logged-in MobileApp session whose Web API
+// requests are served by mux.
+func newAPITestSession(t *testing.T, mux *http.ServeMux) *Session {
+	t.Helper()
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+	tsURL, _ := url.Parse(ts.URL)
+
+	s, err := New(
+		WithHTTPClient(&http.Client{Transport: &hostRewriter{base: ts.Client().Transport, target: tsURL}}),
+		WithPlatformType(PlatformTypeMobileApp),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SteamID = steamid.FromSteamID64(76561198012345678)
+	s.AccessToken = "mobile-access"
+	return s
+}
+
+// decodeAPIRequest decodes the protobuf request body and checks the access token.
+func decodeAPIRequest(t *testing.T, r *http.Request, msg proto.Message) {
+	t.Helper()
+	if got := r.URL.Query().Get("access_token"); got != "mobile-access" {
+		t.Errorf("%s: access_token = %q", r.URL.Path, got)
+	}
+	r.ParseMultipartForm(1 << 20)
+	raw, _ := base64.StdEncoding.DecodeString(r.FormValue("input_protobuf_encoded"))
+	if err := proto.Unmarshal(raw, msg); err != nil {
+		t.Errorf("%s: unmarshal request: %v", r.URL.Path, err)
+	}
+}
+
+func TestApproveLogin(t *testing.T) {
+	var got *protocol.CAuthentication_UpdateAuthSessionWithMobileConfirmation_Request
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/GetAuthSessionsForAccount/v1", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, &protocol.CAuthentication_GetAuthSessionsForAccount_Response{ClientIds: []uint64{42}})
+	})
+	mux.HandleFunc("/IAuthenticationService/GetAuthSessionInfo/v1", func(w http.ResponseWriter, r *http.Request) {
+		var req protocol.CAuthentication_GetAuthSessionInfo_Request
+		decodeAPIRequest(t, r, &req)
+		writeProto(w, &protocol.CAuthentication_GetAuthSessionInfo_Response{
+			Ip:                 proto.String("203.0.113.7"),
+			Country:            proto.String("NL"),
+			DeviceFriendlyName: proto.String("worker-1"),
+			PlatformType:       protocol.EAuthTokenPlatformType_k_EAuthTokenPlatformType_SteamClient.Enum(),
+			Version:            proto.Int32(1),
+		})
+	})
+	mux.HandleFunc("/IAuthenticationService/UpdateAuthSessionWithMobileConfirmation/v1", func(w http.ResponseWriter, r *http.Request) {
+		got = &protocol.CAuthentication_UpdateAuthSessionWithMobileConfirmation_Request{}
+		decodeAPIRequest(t, r, got)
+		writeProto(w, &protocol.CAuthentication_UpdateAuthSessionWithMobileConfirmation_Response{})
+	})
+
+	s := newAPITestSession(t, mux)
+	ctx := context.Background()
+
+	logins, err := s.GetPendingLogins(ctx)
+	if err != nil {
+		t.Fatalf("GetPendingLogins: %v", err)
+	}
+	if len(logins) != 1 || logins[0].ClientID != 42 || logins[0].DeviceFriendlyName != "worker-1" ||
+		logins[0].PlatformType != PlatformTypeSteamClient {
+		t.Fatalf("logins = %+v", logins)
+	}
+
+	if err := s.ApproveLogin(ctx, 42, testSharedSecret); err != nil {
+		t.Fatalf("ApproveLogin: %v", err)
+	}
+
+	want, _ := steamtotp.GenerateAuthSessionSignature(testSharedSecret, 1, 42, 76561198012345678)
+	if !bytes.Equal(got.GetSignature(), want) {
+		t.Errorf("signature = %x, want %x", got.GetSignature(), want)
+	}
+	if !got.GetConfirm() || got.GetClientId() != 42 || got.GetSteamid() != 76561198012345678 || got.GetVersion() != 1 {
+		t.Errorf("request = %v", got)
+	}
+
+	if err := s.DenyLogin(ctx, 42, testSharedSecret); err != nil {
+		t.Fatalf("DenyLogin: %v", err)
+	}
+	if got.GetConfirm() {
+		t.Error("DenyLogin sent confirm=true")
+	}
+}
+
+func TestApproveQRLogin(t *testing.T) {
+	var got protocol.CAuthentication_UpdateAuthSessionWithMobileConfirmation_Request
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/UpdateAuthSessionWithMobileConfirmation/v1", func(w http.ResponseWriter, r *http.Request) {
+		decodeAPIRequest(t, r, &got)
+		writeProto(w, &protocol.CAuthentication_UpdateAuthSessionWithMobileConfirmation_Response{})
+	})
+
+	s := newAPITestSession(t, mux)
+	if err := s.ApproveQRLogin(context.Background(), "https://s.team/q/1/2960817468519466396", testSharedSecret); err != nil {
+		t.Fatalf("ApproveQRLogin: %v", err)
+	}
+	if got.GetClientId() != 2960817468519466396 || got.GetVersion() != 1 {
+		t.Errorf("request = %v", &got)
+	}
+
+	if err := s.ApproveQRLogin(context.Background(), "https://s.team/p/foo", testSharedSecret); err == nil {
+		t.Error("expected error for malformed challenge URL")
+	}
+}
+
+func TestEnumerateAndRevokeTokens(t *testing.T) {
+	var revoked protocol.CAuthentication_RefreshToken_Revoke_Request
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/EnumerateTokens/v1", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, &protocol.CAuthentication_RefreshToken_Enumerate_Response{
+			RequestingToken: proto.Uint64(1),
+			RefreshTokens: []*protocol.CAuthentication_RefreshToken_Enumerate_Response_RefreshTokenDescription{
+				{TokenId: proto.Uint64(1), TokenDescription: proto.String("this phone")},
+				{
+					TokenId:          proto.Uint64(2),
+					TokenDescription: proto.String("old laptop"),
+					LastSeen: &protocol.CAuthentication_RefreshToken_Enumerate_Response_TokenUsageEvent{
+						Time: proto.Uint32(1700000000),
+						Ip:   &protocol.CMsgIPAddress{Ip: &protocol.CMsgIPAddress_V4{V4: 0xCB007107}},
+						City: proto.String("Amsterdam"),
+					},
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/IAuthenticationService/RevokeRefreshToken/v1", func(w http.ResponseWriter, r *http.Request) {
+		decodeAPIRequest(t, r, &revoked)
+		writeProto(w, &protocol.CAuthentication_RefreshToken_Revoke_Response{})
+	})
+
+	s := newAPITestSession(t, mux)
+	ctx := context.Background()
+
+	tokens, err := s.EnumerateTokens(ctx)
+	if err != nil {
+		t.Fatalf("EnumerateTokens: %v", err)
+	}
+	if len(tokens) != 2 || !tokens[0].Current || tokens[1].Current {
+		t.Fatalf("tokens = %+v", tokens)
+	}
+	if last := tokens[1].LastSeen; last == nil || last.IP.String() != "203.0.113.7" || last.City != "Amsterdam" {
+		t.Errorf("LastSeen = %+v", last)
+	}
+
+	if err := s.RevokeRefreshToken(ctx, 2, testSharedSecret); err != nil {
+		t.Fatalf("RevokeRefreshToken: %v", err)
+	}
+	want, _ := steamtotp.GenerateTokenRevokeSignature(testSharedSecret, 2)
+	if revoked.GetTokenId() != 2 || !bytes.Equal(revoked.GetSignature(), want) ||
+		revoked.GetRevokeAction() != protocol.EAuthTokenRevokeAction_k_EAuthTokenRevokePermanent {
+		t.Errorf("revoke request = %v", &revoked)
+	}
+}
+
+func TestApprovalRequiresAccessToken(t *testing.T) {
+	s, _ := New(WithHTTPClient(&http.Client{}))
+	if err := s.ApproveQRLogin(context.Background(), "https://s.team/q/1/1", testSharedSecret); err == nil {
+		t.Error("expected error without an access token")
+	}
+}
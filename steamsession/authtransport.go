@@ -7,6 +7,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/k64z/steamstacks/steamhost"
 )
 
 const tokenRefreshMargin = 5 * time.Minute
@@ -31,7 +33,7 @@ type refreshBypassKey struct{}
 //     access token, then updates cookies via setWebCookies.
 //     No bypass context needed since the API call goes to api.steampowered.com.
 //
-// Only triggers for steamcommunity.com to avoid interfering with
+// Only triggers for the community host to avoid interfering with
 // Steam Web API calls (which authenticate via protobuf body, not cookies)
 // and to prevent recursive refresh loops.
 type authTransport struct {
@@ -43,7 +45,7 @@ type authTransport struct {
 }
 
 func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if req.URL.Host != "steamcommunity.com" {
+	if req.URL.Host != steamhost.CookieURL(t.session.hosts.Resolve().Community).Host {
 		return t.base.RoundTrip(req)
 	}
 
@@ -8,11 +8,17 @@ import (
 	"math/big"
 )
 
-func encryptPassword(password, mod string, exp int64) (string, error) {
-	var n big.Int
-	n.SetString(mod, 16)
+// EncryptPassword encrypts password with the RSA key from
+// GetPasswordRSAPublicKey, given as a hex modulus and exponent, and returns
+// it base64-encoded as BeginAuthSessionViaCredentials expects. steamclient
+// uses it for CM logins too.
+func EncryptPassword(password, mod string, exp int64) (string, error) {
+	n, ok := new(big.Int).SetString(mod, 16)
+	if !ok {
+		return "", fmt.Errorf("invalid RSA modulus %q", mod)
+	}
 
-	pubkey := rsa.PublicKey{N: &n, E: int(exp)}
+	pubkey := rsa.PublicKey{N: n, E: int(exp)}
 	encPwd, err := rsa.EncryptPKCS1v15(rand.Reader, &pubkey, []byte(password))
 	if err != nil {
 		return "", fmt.Errorf("rsa encrypt: %w", err)
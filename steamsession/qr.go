@@ -0,0 +1,127 @@
+package steamsession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/k64z/steamstacks/protocol"
+)
+
+// AuthStatus describes a change observed while polling an auth session.
+type AuthStatus struct {
+	// ChallengeURL is the current QR challenge URL. Steam rotates it
+	// periodically; when ChallengeURLChanged is set, the previously
+	// displayed QR code no longer works and must be re-rendered.
+	ChallengeURL        string
+	ChallengeURLChanged bool
+
+	// HadRemoteInteraction is set once the QR code has been scanned on a
+	// phone, before the login is approved there.
+	HadRemoteInteraction bool
+}
+
+// WithAuthStatusHandler registers a callback that PollAuthSessionStatus calls
+// whenever Steam reports a new challenge URL or a remote interaction.
+func WithAuthStatusHandler(fn func(AuthStatus)) Option {
+	return func(options *config) error {
+		options.onAuthStatus = fn
+		return nil
+	}
+}
+
+// LoginWithQR starts a QR auth session, hands the challenge URL to
+// onChallenge, and polls until a phone approves it. onChallenge is called
+// again with the new URL each time Steam rotates the challenge.
+func (s *Session) LoginWithQR(ctx context.Context, onChallenge func(challengeURL string)) error {
+	challengeURL, err := s.StartWithQR(ctx)
+	if err != nil {
+		return fmt.Errorf("start with QR: %w", err)
+	}
+	onChallenge(challengeURL)
+
+	userHandler := s.onAuthStatus
+	s.onAuthStatus = func(status AuthStatus) {
+		if status.ChallengeURLChanged {
+			onChallenge(status.ChallengeURL)
+		}
+		if userHandler != nil {
+			userHandler(status)
+		}
+	}
+	defer func() { s.onAuthStatus = userHandler }()
+
+	err = s.PollAuthSessionStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("poll auth session status: %w", err)
+	}
+
+	return nil
+}
+
+// StartWithQR begins an auth session to be approved from the Steam mobile
+// app and returns the challenge URL to render as a QR code.
+func (s *Session) StartWithQR(ctx context.Context) (string, error) {
+	req := &protocol.CAuthentication_BeginAuthSessionViaQR_Request{
+		DeviceFriendlyName: &s.userAgent,
+		PlatformType:       &s.platformType,
+		WebsiteId:          &s.websiteID,
+		DeviceDetails: &protocol.CAuthentication_DeviceDetails{
+			DeviceFriendlyName: &s.userAgent,
+			PlatformType:       &s.platformType,
+		},
+	}
+
+	authSession, err := s.steamAPI.BeginAuthSessionViaQR(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("begin session: %w", err)
+	}
+
+	if authSession.ChallengeUrl == nil {
+		return "", errors.New("challenge URL is nil")
+	}
+
+	s.clientID = authSession.GetClientId()
+	s.requestID = authSession.RequestId
+	s.pollingInterval = time.Duration(authSession.GetInterval() * float32(time.Second))
+	s.challengeURL = authSession.GetChallengeUrl()
+	s.hadRemoteInteraction = false
+
+	return s.challengeURL, nil
+}
+
+// ChallengeURL returns the current QR challenge URL, which changes when
+// Steam rotates it during polling.
+func (s *Session) ChallengeURL() string {
+	return s.challengeURL
+}
+
+// applyPollStatus records the session changes carried by a poll response and
+// reports them through the AuthStatus handler.
+func (s *Session) applyPollStatus(resp *protocol.CAuthentication_PollAuthSessionStatus_Response) {
+	status := AuthStatus{
+		ChallengeURL:         s.challengeURL,
+		HadRemoteInteraction: s.hadRemoteInteraction,
+	}
+	changed := false
+
+	if resp.NewClientId != nil {
+		s.clientID = resp.GetNewClientId()
+	}
+	if url := resp.GetNewChallengeUrl(); url != "" && url != s.challengeURL {
+		s.challengeURL = url
+		status.ChallengeURL = url
+		status.ChallengeURLChanged = true
+		changed = true
+	}
+	if resp.GetHadRemoteInteraction() && !s.hadRemoteInteraction {
+		s.hadRemoteInteraction = true
+		status.HadRemoteInteraction = true
+		changed = true
+	}
+
+	if changed && s.onAuthStatus != nil {
+		s.onAuthStatus(status)
+	}
+}
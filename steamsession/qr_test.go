@@ -0,0 +1,163 @@
+package steamsession
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/k64z/steamstacks/eresult"
+	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamapi"
+	"google.golang.org/protobuf/proto"
+)
+
+func writeProto(w http.ResponseWriter, msg proto.Message) {
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Eresult", "1")
+	w.Write(body)
+}
+
+func TestLoginWithQR(t *testing.T) {
+	claims, _ := json.Marshal(map[string]any{"sub": "76561198012345678", "exp": time.Now().Add(time.Hour).Unix()})
+	refreshToken := "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+
+	var polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/BeginAuthSessionViaQR/v1", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, &protocol.CAuthentication_BeginAuthSessionViaQR_Response{
+			ClientId:     proto.Uint64(1),
+			ChallengeUrl: proto.String("https://s.team/q/1/first"),
+			RequestId:    []byte("request"),
+			Interval:     proto.Float32(0.01),
+		})
+	})
+	mux.HandleFunc("/IAuthenticationService/PollAuthSessionStatus/v1", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseMultipartForm(1 << 20)
+		raw, _ := base64.StdEncoding.DecodeString(r.FormValue("input_protobuf_encoded"))
+		var req protocol.CAuthentication_PollAuthSessionStatus_Request
+		proto.Unmarshal(raw, &req)
+
+		switch polls.Add(1) {
+		case 1:
+			writeProto(w, &protocol.CAuthentication_PollAuthSessionStatus_Response{})
+		case 2:
+			writeProto(w, &protocol.CAuthentication_PollAuthSessionStatus_Response{
+				NewClientId:     proto.Uint64(2),
+				NewChallengeUrl: proto.String("https://s.team/q/1/second"),
+			})
+		case 3:
+			if req.GetClientId() != 2 {
+				t.Errorf("poll ClientId = %d, want rotated id 2", req.GetClientId())
+			}
+			writeProto(w, &protocol.CAuthentication_PollAuthSessionStatus_Response{
+				HadRemoteInteraction: proto.Bool(true),
+			})
+		default:
+			writeProto(w, &protocol.CAuthentication_PollAuthSessionStatus_Response{
+				HadRemoteInteraction: proto.Bool(true),
+				AccessToken:          proto.String("access"),
+				RefreshToken:         proto.String(refreshToken),
+			})
+		}
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	defer ts.Close()
+	tsURL, _ := url.Parse(ts.URL)
+
+	var statuses []AuthStatus
+	s, err := New(
+		WithHTTPClient(&http.Client{Transport: &hostRewriter{base: ts.Client().Transport, target: tsURL}}),
+		WithAuthStatusHandler(func(status AuthStatus) { statuses = append(statuses, status) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var challenges []string
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.LoginWithQR(ctx, func(u string) { challenges = append(challenges, u) }); err != nil {
+		t.Fatalf("LoginWithQR: %v", err)
+	}
+
+	if want := []string{"https://s.team/q/1/first", "https://s.team/q/1/second"}; fmt.Sprint(challenges) != fmt.Sprint(want) {
+		t.Errorf("challenges = %v, want %v", challenges, want)
+	}
+	if s.ChallengeURL() != "https://s.team/q/1/second" {
+		t.Errorf("ChallengeURL = %q", s.ChallengeURL())
+	}
+
+	if len(statuses) != 2 {
+		t.Fatalf("got %d status callbacks, want 2: %+v", len(statuses), statuses)
+	}
+	if !statuses[0].ChallengeURLChanged || statuses[0].HadRemoteInteraction {
+		t.Errorf("first status = %+v, want challenge change only", statuses[0])
+	}
+	if statuses[1].ChallengeURLChanged || !statuses[1].HadRemoteInteraction {
+		t.Errorf("second status = %+v, want remote interaction only", statuses[1])
+	}
+
+	if s.RefreshToken != refreshToken || s.AccessToken != "access" {
+		t.Errorf("tokens not stored: access=%q refresh=%q", s.AccessToken, s.RefreshToken)
+	}
+	if s.SteamID.ToSteamID64() != 76561198012345678 {
+		t.Errorf("SteamID = %d, want it taken from the token", s.SteamID.ToSteamID64())
+	}
+}
+
+func TestLoginWithQRStopsWhenDenied(t *testing.T) {
+	var polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/BeginAuthSessionViaQR/v1", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, &protocol.CAuthentication_BeginAuthSessionViaQR_Response{
+			ClientId:     proto.Uint64(1),
+			ChallengeUrl: proto.String("https://s.team/q/1/first"),
+			RequestId:    []byte("request"),
+			Interval:     proto.Float32(0.01),
+		})
+	})
+	mux.HandleFunc("/IAuthenticationService/PollAuthSessionStatus/v1", func(w http.ResponseWriter, r *http.Request) {
+		if polls.Add(1) == 1 {
+			writeProto(w, &protocol.CAuthentication_PollAuthSessionStatus_Response{})
+			return
+		}
+		// Denied on the phone.
+		w.Header().Set("X-Eresult", strconv.Itoa(int(eresult.FileNotFound)))
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	defer ts.Close()
+	tsURL, _ := url.Parse(ts.URL)
+
+	s, err := New(WithHTTPClient(&http.Client{Transport: &hostRewriter{base: ts.Client().Transport, target: tsURL}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = s.LoginWithQR(ctx, func(string) {})
+
+	var eresultErr *steamapi.EResultError
+	if !errors.As(err, &eresultErr) || eresultErr.EResult != eresult.FileNotFound {
+		t.Fatalf("LoginWithQR = %v, want FileNotFound EResultError", err)
+	}
+	if n := polls.Load(); n != 2 {
+		t.Errorf("polled %d times, want 2", n)
+	}
+}
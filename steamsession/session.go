@@ -11,12 +11,16 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/k64z/steamstacks/eresult"
 	"github.com/k64z/steamstacks/protocol"
 	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamhost"
 	"github.com/k64z/steamstacks/steamid"
+	"github.com/k64z/steamstacks/steamtotp"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -46,12 +50,20 @@ type Session struct {
 
 	pollingInterval time.Duration // INFO: returned by 'BeginAuthSession...', usually 5 seconds
 
-	loginURL string // base URL for login.steampowered.com (overridable for tests)
+	challengeURL         string // NOTE: QR login only, rotated by Steam while polling
+	hadRemoteInteraction bool
+	onAuthStatus         func(AuthStatus)
+
+	hosts steamhost.Hosts // zero fields fall back to the Steam defaults
+
+	timeOffset *int64 // Steam server time minus local time in seconds, once queried
 }
 
 type config struct {
 	httpClient   *http.Client
 	platformType PlatformType
+	hosts        steamhost.Hosts
+	onAuthStatus func(AuthStatus)
 }
 
 type Option func(options *config) error
@@ -75,6 +87,15 @@ func WithPlatformType(pt PlatformType) Option {
 	}
 }
 
+// WithHosts maps the Steam web hosts. Login, the Web API, transfer URLs
+// returned by Steam and the web cookies all follow the mapping.
+func WithHosts(hosts steamhost.Hosts) Option {
+	return func(options *config) error {
+		options.hosts = hosts
+		return nil
+	}
+}
+
 func New(opts ...Option) (*Session, error) {
 	var cfg config
 	for _, opt := range opts {
@@ -88,7 +109,8 @@ func New(opts ...Option) (*Session, error) {
 		platformType: protocol.EAuthTokenPlatformType_k_EAuthTokenPlatformType_WebBrowser,
 		persistence:  protocol.ESessionPersistence_k_ESessionPersistence_Persistent,
 		language:     DefaultLanguageCode,
-		loginURL:     "https://login.steampowered.com",
+		hosts:        cfg.hosts.Resolve(),
+		onAuthStatus: cfg.onAuthStatus,
 	}
 
 	switch cfg.platformType {
@@ -113,7 +135,7 @@ func New(opts ...Option) (*Session, error) {
 	}
 
 	var err error
-	s.steamAPI, err = steamapi.New(steamapi.WithHTTPClient(s.httpClient))
+	s.steamAPI, err = steamapi.New(steamapi.WithHTTPClient(s.httpClient), steamapi.WithHosts(s.hosts))
 	if err != nil {
 		return nil, fmt.Errorf("init SteamAPI: %w", err)
 	}
@@ -147,6 +169,107 @@ func (s *Session) LoginWithDeviceCode(ctx context.Context, username, password, c
 	return nil
 }
 
+// maxAuthCodeAttempts bounds how many 30-second TOTP windows
+// LoginWithSharedSecret tries before giving up.
+const maxAuthCodeAttempts = 3
+
+// LoginWithSharedSecret is LoginWithDeviceCode for accounts whose
+// authenticator secrets are at hand. The code is generated from sharedSecret
+// against Steam's clock, and if Steam rejects it as expired the next
+// 30-second window's code is submitted instead.
+func (s *Session) LoginWithSharedSecret(ctx context.Context, username, password, sharedSecret string) error {
+	guardTypes, err := s.StartWithCredentials(ctx, username, password)
+	if err != nil {
+		return fmt.Errorf("start with credentials: %w", err)
+	}
+
+	switch guardType := BestGuardType(guardTypes); guardType {
+	case EAuthSessionGuardTypeNone:
+	case EAuthSessionGuardTypeDeviceCode:
+		if err := s.submitGeneratedCode(ctx, sharedSecret); err != nil {
+			return fmt.Errorf("submit steam guard code: %w", err)
+		}
+	default:
+		return fmt.Errorf("device code authentication is not allowed (allowed: %v)", guardTypes)
+	}
+
+	err = s.PollAuthSessionStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("poll auth session status: %w", err)
+	}
+
+	return nil
+}
+
+// BestGuardType picks the guard type a headless login should use: None when
+// no confirmation is needed, then DeviceCode (which can be generated from the
+// shared secret), then the remaining types in Steam's order of preference.
+func BestGuardType(guardTypes []EAuthSessionGuardType) EAuthSessionGuardType {
+	for _, preferred := range []EAuthSessionGuardType{
+		EAuthSessionGuardTypeNone,
+		EAuthSessionGuardTypeDeviceCode,
+	} {
+		if slices.Contains(guardTypes, preferred) {
+			return preferred
+		}
+	}
+	if len(guardTypes) > 0 {
+		return guardTypes[0]
+	}
+	return EAuthSessionGuardTypeUnknown
+}
+
+// submitGeneratedCode submits a TOTP code generated from sharedSecret,
+// retrying with the next window's code when Steam rejects it.
+func (s *Session) submitGeneratedCode(ctx context.Context, sharedSecret string) error {
+	offset, err := s.steamTimeOffset(ctx)
+	if err != nil {
+		return fmt.Errorf("get steam time: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		code, err := steamtotp.GenerateAuthCode(sharedSecret, offset)
+		if err != nil {
+			return fmt.Errorf("generate auth code: %w", err)
+		}
+
+		err = s.SubmitSteamGuardCode(ctx, code, EAuthSessionGuardTypeDeviceCode)
+		if err == nil {
+			return nil
+		}
+
+		var eresultErr *steamapi.EResultError
+		if !errors.As(err, &eresultErr) || attempt == maxAuthCodeAttempts ||
+			(eresultErr.EResult != eresult.InvalidLoginAuthCode && eresultErr.EResult != eresult.TwoFactorCodeMismatch) {
+			return err
+		}
+
+		// Wait for the next 30-second window on Steam's clock.
+		serverNow := time.Now().Add(time.Duration(offset) * time.Second)
+		wait := serverNow.Truncate(30 * time.Second).Add(30 * time.Second).Sub(serverNow)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// steamTimeOffset returns the difference between Steam's clock and the local
+// one in seconds, querying it once per session.
+func (s *Session) steamTimeOffset(ctx context.Context) (int64, error) {
+	if s.timeOffset != nil {
+		return *s.timeOffset, nil
+	}
+
+	_, offset, err := s.steamAPI.GetSteamTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.timeOffset = &offset
+	return offset, nil
+}
+
 // StartWithCredentials begins an auth session and returns the allowed guard types.
 func (s *Session) StartWithCredentials(ctx context.Context, username, password string) ([]EAuthSessionGuardType, error) {
 	if strings.TrimSpace(username) == "" {
@@ -161,7 +284,7 @@ func (s *Session) StartWithCredentials(ctx context.Context, username, password s
 		return nil, fmt.Errorf("get RSA public key: %w", err)
 	}
 
-	encryptedPassword, err := encryptPassword(password, rsaKey.Mod, rsaKey.Exp)
+	encryptedPassword, err := EncryptPassword(password, rsaKey.Mod, rsaKey.Exp)
 	if err != nil {
 		return nil, fmt.Errorf("encrypt password: %w", err)
 	}
@@ -221,41 +344,52 @@ func (s *Session) PollAuthSessionStatus(ctx context.Context) error {
 
 	for {
 		resp, err := s.steamAPI.PollAuthSessionStatus(ctx, req)
-		if err != nil {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(s.pollingInterval):
-			}
-		} else {
-			if resp.AccessToken == nil {
-				return errors.New("access token is nil")
+		var eresultErr *steamapi.EResultError
+		if errors.As(err, &eresultErr) {
+			switch eresultErr.EResult {
+			case eresult.Expired, eresult.FileNotFound, eresult.AccessDenied:
+				// The session expired or was denied; polling on won't
+				// change that.
+				return fmt.Errorf("poll auth session status: %w", err)
 			}
-			if resp.RefreshToken == nil {
-				return errors.New("refresh token is nil")
+		}
+		if err == nil {
+			// ClientId points at s.clientID, so a rotated id is picked up
+			// by the next poll.
+			s.applyPollStatus(resp)
+
+			// Until the session is approved, Steam answers with no tokens.
+			if resp.GetRefreshToken() != "" {
+				if resp.AccessToken == nil {
+					return errors.New("access token is nil")
+				}
+				s.AccessToken = *resp.AccessToken
+				s.RefreshToken = *resp.RefreshToken
+
+				// QR sessions don't learn the SteamID up front.
+				if s.SteamID == 0 {
+					if sid, err := jwtSteamID(s.RefreshToken); err == nil {
+						s.SteamID = sid
+					}
+				}
+				return nil
 			}
-			s.AccessToken = *resp.AccessToken
-			s.RefreshToken = *resp.RefreshToken
-			return nil
 		}
-	}
-}
 
-type PersistentSession struct {
-	RefreshToken string          `json:"refresh_token"`
-	AccessToken  string          `json:"access_token,omitempty"`
-	SteamID      steamid.SteamID `json:"steam_id"`
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.pollingInterval):
+		}
+	}
 }
 
+// SaveToFile writes the session to filePath as plain JSON with mode 0600.
+// For many accounts or encryption at rest, use SaveTo with a SessionStore.
 func (s *Session) SaveToFile(filePath string) error {
-	if s.RefreshToken == "" {
-		return errors.New("no refresh token to save")
-	}
-
-	data := PersistentSession{
-		RefreshToken: s.RefreshToken,
-		AccessToken:  s.AccessToken,
-		SteamID:      s.SteamID,
+	data, err := s.Snapshot()
+	if err != nil {
+		return err
 	}
 
 	jsonData, err := json.Marshal(data)
@@ -275,6 +409,7 @@ func (s *Session) SaveToFile(filePath string) error {
 	return nil
 }
 
+// LoadFromFile restores a session written by SaveToFile.
 func (s *Session) LoadFromFile(filePath string) error {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -289,11 +424,7 @@ func (s *Session) LoadFromFile(filePath string) error {
 		return fmt.Errorf("unmarshal session data: %w", err)
 	}
 
-	s.RefreshToken = persistentSession.RefreshToken
-	s.AccessToken = persistentSession.AccessToken
-	s.SteamID = persistentSession.SteamID
-
-	return nil
+	return s.Restore(&persistentSession)
 }
 
 func (s *Session) IsValidToken(ctx context.Context) bool {
@@ -307,19 +438,30 @@ func (s *Session) IsValidToken(ctx context.Context) bool {
 	return time.Now().Before(exp)
 }
 
-func jwtExpiry(token string) (time.Time, error) {
+type jwtClaims struct {
+	Sub string `json:"sub"`
+	Exp int64  `json:"exp"`
+}
+
+func parseJWTClaims(token string) (*jwtClaims, error) {
 	parts := strings.SplitN(token, ".", 3)
 	if len(parts) != 3 {
-		return time.Time{}, errors.New("invalid JWT format")
+		return nil, errors.New("invalid JWT format")
 	}
 	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return time.Time{}, err
-	}
-	var claims struct {
-		Exp int64 `json:"exp"`
+		return nil, err
 	}
+	var claims jwtClaims
 	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func jwtExpiry(token string) (time.Time, error) {
+	claims, err := parseJWTClaims(token)
+	if err != nil {
 		return time.Time{}, err
 	}
 	if claims.Exp == 0 {
@@ -328,6 +470,19 @@ func jwtExpiry(token string) (time.Time, error) {
 	return time.Unix(claims.Exp, 0), nil
 }
 
+// jwtSteamID returns the SteamID a token was issued for (its sub claim).
+func jwtSteamID(token string) (steamid.SteamID, error) {
+	claims, err := parseJWTClaims(token)
+	if err != nil {
+		return 0, err
+	}
+	sid, err := strconv.ParseUint(claims.Sub, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse sub claim: %w", err)
+	}
+	return steamid.FromSteamID64(sid), nil
+}
+
 // HTTPClient returns the session's underlying HTTP client.
 // After authentication and GetWebCookies, its cookie jar holds all session
 // state needed to construct steamapi.API or steamcommunity.Community instances.
@@ -0,0 +1,112 @@
+package steamsession
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamtotp"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestBestGuardType(t *testing.T) {
+	tests := []struct {
+		name  string
+		types []EAuthSessionGuardType
+		want  EAuthSessionGuardType
+	}{
+		{"none wins", []EAuthSessionGuardType{EAuthSessionGuardTypeDeviceCode, EAuthSessionGuardTypeNone}, EAuthSessionGuardTypeNone},
+		{"device code over confirmation", []EAuthSessionGuardType{EAuthSessionGuardTypeDeviceConfirmation, EAuthSessionGuardTypeDeviceCode}, EAuthSessionGuardTypeDeviceCode},
+		{"falls back to first", []EAuthSessionGuardType{EAuthSessionGuardTypeEmailCode, EAuthSessionGuardTypeEmailConfirmation}, EAuthSessionGuardTypeEmailCode},
+		{"empty", nil, EAuthSessionGuardTypeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BestGuardType(tt.types); got != tt.want {
+				t.Errorf("BestGuardType(%v) = %v, want %v", tt.types, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginWithSharedSecretRetriesNextWindow(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var codes []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IAuthenticationService/GetPasswordRSAPublicKey/v1", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, &protocol.CAuthentication_GetPasswordRSAPublicKey_Response{
+			PublickeyMod: proto.String(key.N.Text(16)),
+			PublickeyExp: proto.String(strconv.FormatInt(int64(key.E), 16)),
+			Timestamp:    proto.Uint64(1),
+		})
+	})
+	mux.HandleFunc("/IAuthenticationService/BeginAuthSessionViaCredentials/v1", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, &protocol.CAuthentication_BeginAuthSessionViaCredentials_Response{
+			ClientId:  proto.Uint64(7),
+			RequestId: []byte("request"),
+			Interval:  proto.Float32(0.01),
+			Steamid:   proto.Uint64(76561198012345678),
+			WeakToken: proto.String("weak"),
+			AllowedConfirmations: []*protocol.CAuthentication_AllowedConfirmation{
+				{ConfirmationType: protocol.EAuthSessionGuardType_k_EAuthSessionGuardType_DeviceConfirmation.Enum()},
+				{ConfirmationType: protocol.EAuthSessionGuardType_k_EAuthSessionGuardType_DeviceCode.Enum()},
+			},
+		})
+	})
+	mux.HandleFunc("/IAuthenticationService/UpdateAuthSessionWithSteamGuardCode/v1", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseMultipartForm(1 << 20)
+		raw, _ := base64.StdEncoding.DecodeString(r.FormValue("input_protobuf_encoded"))
+		var req protocol.CAuthentication_UpdateAuthSessionWithSteamGuardCode_Request
+		proto.Unmarshal(raw, &req)
+		codes = append(codes, req.GetCode())
+
+		if len(codes) == 1 {
+			w.Header().Set("X-Eresult", "65") // InvalidLoginAuthCode
+			return
+		}
+		writeProto(w, &protocol.CAuthentication_UpdateAuthSessionWithSteamGuardCode_Response{})
+	})
+	mux.HandleFunc("/IAuthenticationService/PollAuthSessionStatus/v1", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, &protocol.CAuthentication_PollAuthSessionStatus_Response{
+			AccessToken:  proto.String("access"),
+			RefreshToken: proto.String("refresh"),
+		})
+	})
+
+	s := newAPITestSession(t, mux)
+	s.AccessToken = ""
+
+	// Put Steam's clock in the last second of a TOTP window so the retry
+	// doesn't have to wait long for the next one.
+	offset := 29 - time.Now().Unix()%30
+	s.timeOffset = &offset
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.LoginWithSharedSecret(ctx, "user", "hunter2", testSharedSecret); err != nil {
+		t.Fatalf("LoginWithSharedSecret: %v", err)
+	}
+
+	if len(codes) != 2 {
+		t.Fatalf("submitted %d codes, want 2", len(codes))
+	}
+	if codes[0] == codes[1] {
+		t.Errorf("retry reused the rejected code %q", codes[0])
+	}
+	if want, _ := steamtotp.GenerateAuthCode(testSharedSecret, offset); codes[1] != want {
+		t.Errorf("retry code = %q, want current window's %q", codes[1], want)
+	}
+	if s.RefreshToken != "refresh" {
+		t.Errorf("RefreshToken = %q", s.RefreshToken)
+	}
+}
@@ -0,0 +1,375 @@
+package steamsession
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamhost"
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// ErrSessionNotFound is returned by SessionStore.Load when no session is
+// stored under the key.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore persists sessions by key, typically the account name.
+type SessionStore interface {
+	Save(ctx context.Context, key string, data *PersistentSession) error
+	Load(ctx context.Context, key string) (*PersistentSession, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// PersistentSession is everything needed to resume a session in a new
+// process without logging in or calling FinalizeLogin again.
+type PersistentSession struct {
+	RefreshToken string          `json:"refresh_token"`
+	AccessToken  string          `json:"access_token,omitempty"`
+	SteamID      steamid.SteamID `json:"steam_id"`
+
+	PlatformType       PlatformType `json:"platform_type,omitempty"`
+	SessionID          string       `json:"session_id,omitempty"`
+	AccessTokenExpiry  time.Time    `json:"access_token_expiry,omitzero"`
+	RefreshTokenExpiry time.Time    `json:"refresh_token_expiry,omitzero"`
+
+	// Cookies holds the web cookies per origin, e.g. "https://steamcommunity.com".
+	Cookies map[string][]PersistentCookie `json:"cookies,omitempty"`
+}
+
+type PersistentCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// webCookieOrigins are the origins whose cookies are persisted. A cookie jar
+// can't be enumerated, so only these are captured. Snapshots are keyed by
+// the default origins; the session's host mapping is applied when reading
+// and writing the jar.
+var webCookieOrigins = []string{
+	steamhost.DefaultCommunity,
+	steamhost.DefaultStore,
+	steamhost.DefaultHelp,
+	steamhost.DefaultCheckout,
+	steamhost.DefaultLogin,
+}
+
+// Snapshot captures the session's tokens, platform type and web cookies.
+func (s *Session) Snapshot() (*PersistentSession, error) {
+	if s.RefreshToken == "" {
+		return nil, errors.New("no refresh token to save")
+	}
+
+	data := &PersistentSession{
+		RefreshToken: s.RefreshToken,
+		AccessToken:  s.AccessToken,
+		SteamID:      s.SteamID,
+		PlatformType: PlatformType(s.platformType),
+		SessionID:    s.sessionID,
+	}
+	if exp, err := jwtExpiry(s.RefreshToken); err == nil {
+		data.RefreshTokenExpiry = exp
+	}
+	if exp, err := jwtExpiry(s.AccessToken); err == nil {
+		data.AccessTokenExpiry = exp
+	}
+
+	if s.httpClient.Jar != nil {
+		for _, origin := range webCookieOrigins {
+			u := steamhost.CookieURL(s.hosts.Rewrite(origin))
+			for _, c := range s.httpClient.Jar.Cookies(u) {
+				if data.Cookies == nil {
+					data.Cookies = make(map[string][]PersistentCookie)
+				}
+				data.Cookies[origin] = append(data.Cookies[origin], PersistentCookie{Name: c.Name, Value: c.Value})
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// Restore loads a snapshot into the session. When the snapshot carries web
+// cookies and a live access token, the session is ready for web requests
+// without GetWebCookies, and the auth transport is installed as it would be
+// after login.
+func (s *Session) Restore(data *PersistentSession) error {
+	if data == nil || data.RefreshToken == "" {
+		return errors.New("no refresh token in session data")
+	}
+
+	s.RefreshToken = data.RefreshToken
+	s.AccessToken = data.AccessToken
+	s.SteamID = data.SteamID
+	if data.PlatformType != 0 {
+		s.platformType = protocol.EAuthTokenPlatformType(data.PlatformType)
+		s.SetHeaders()
+	}
+	if data.SessionID != "" {
+		s.sessionID = data.SessionID
+	}
+
+	for origin, cookies := range data.Cookies {
+		u := steamhost.CookieURL(s.hosts.Rewrite(origin))
+		if u.Host == "" {
+			return fmt.Errorf("invalid cookie origin %q", origin)
+		}
+		jarCookies := make([]*http.Cookie, len(cookies))
+		for i, c := range cookies {
+			jarCookies[i] = &http.Cookie{Name: c.Name, Value: c.Value, Path: "/", Secure: u.Scheme == "https", HttpOnly: true}
+		}
+		s.httpClient.Jar.SetCookies(u, jarCookies)
+	}
+
+	// SteamClient tokens can't be refreshed over the Web API; see GetWebCookies.
+	if len(data.Cookies) > 0 && s.platformType != PlatformTypeSteamClient {
+		if exp, err := jwtExpiry(s.AccessToken); err == nil && time.Now().Before(exp) {
+			s.installAuthTransport(exp)
+		}
+	}
+
+	return nil
+}
+
+// SaveTo persists the session in store under key.
+func (s *Session) SaveTo(ctx context.Context, store SessionStore, key string) error {
+	data, err := s.Snapshot()
+	if err != nil {
+		return err
+	}
+	return store.Save(ctx, key, data)
+}
+
+// LoadFrom restores the session stored in store under key.
+func (s *Session) LoadFrom(ctx context.Context, store SessionStore, key string) error {
+	data, err := store.Load(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.Restore(data)
+}
+
+// FileStore keeps each session as a plain JSON file named after its key.
+// Files are written atomically with mode 0600.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (f *FileStore) Save(_ context.Context, key string, data *PersistentSession) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal session data: %w", err)
+	}
+	return writeSessionFile(f.dir, key, ".json", jsonData)
+}
+
+func (f *FileStore) Load(_ context.Context, key string) (*PersistentSession, error) {
+	jsonData, err := readSessionFile(f.dir, key, ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	var data PersistentSession
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return nil, fmt.Errorf("unmarshal session data: %w", err)
+	}
+	return &data, nil
+}
+
+func (f *FileStore) Delete(_ context.Context, key string) error {
+	return deleteSessionFile(f.dir, key, ".json")
+}
+
+// defaultKDFIterations follows the OWASP recommendation for PBKDF2-SHA256.
+const defaultKDFIterations = 600_000
+
+// EncryptedFileStore is a FileStore that encrypts sessions at rest with
+// AES-256-GCM. Each file gets its own random salt, from which the key is
+// derived from the passphrase with PBKDF2-SHA256.
+type EncryptedFileStore struct {
+	dir        string
+	passphrase string
+	iterations int
+}
+
+func NewEncryptedFileStore(dir, passphrase string) (*EncryptedFileStore, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+	return &EncryptedFileStore{dir: dir, passphrase: passphrase, iterations: defaultKDFIterations}, nil
+}
+
+// encryptedSession is the on-disk envelope of an EncryptedFileStore file.
+type encryptedSession struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (e *EncryptedFileStore) Save(_ context.Context, key string, data *PersistentSession) error {
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal session data: %w", err)
+	}
+
+	env := encryptedSession{
+		Version:    1,
+		Iterations: e.iterations,
+		Salt:       make([]byte, 16),
+	}
+	if _, err := rand.Read(env.Salt); err != nil {
+		return fmt.Errorf("generate salt: %w", err)
+	}
+
+	aead, err := e.aead(env.Salt, env.Iterations)
+	if err != nil {
+		return err
+	}
+	env.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+	env.Ciphertext = aead.Seal(nil, env.Nonce, plaintext, []byte(key))
+
+	envData, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("marshal envelope: %w", err)
+	}
+	return writeSessionFile(e.dir, key, ".json.enc", envData)
+}
+
+func (e *EncryptedFileStore) Load(_ context.Context, key string) (*PersistentSession, error) {
+	envData, err := readSessionFile(e.dir, key, ".json.enc")
+	if err != nil {
+		return nil, err
+	}
+
+	var env encryptedSession
+	if err := json.Unmarshal(envData, &env); err != nil {
+		return nil, fmt.Errorf("unmarshal envelope: %w", err)
+	}
+	if env.Version != 1 {
+		return nil, fmt.Errorf("unsupported session file version %d", env.Version)
+	}
+
+	aead, err := e.aead(env.Salt, env.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+
+	// The key is bound as additional data, so a file renamed to another
+	// account's key fails to decrypt.
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, []byte(key))
+	if err != nil {
+		return nil, errors.New("decrypt session: wrong passphrase or corrupted file")
+	}
+
+	var data PersistentSession
+	if err := json.Unmarshal(plaintext, &data); err != nil {
+		return nil, fmt.Errorf("unmarshal session data: %w", err)
+	}
+	return &data, nil
+}
+
+func (e *EncryptedFileStore) Delete(_ context.Context, key string) error {
+	return deleteSessionFile(e.dir, key, ".json.enc")
+}
+
+func (e *EncryptedFileStore) aead(salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, e.passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// sessionFilePath maps a store key to a file in dir, rejecting keys that
+// would escape it.
+func sessionFilePath(dir, key, ext string) (string, error) {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+		return "", fmt.Errorf("invalid session key %q", key)
+	}
+	return filepath.Join(dir, key+ext), nil
+}
+
+func writeSessionFile(dir, key, ext string, data []byte) error {
+	path, err := sessionFilePath(dir, key, ext)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	// Write to a temp file (created 0600) and rename, so a crash never
+	// leaves a truncated session behind.
+	tmp, err := os.CreateTemp(dir, "."+key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write session file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write session file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename session file: %w", err)
+	}
+	return nil
+}
+
+func readSessionFile(dir, key, ext string) ([]byte, error) {
+	path, err := sessionFilePath(dir, key, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("read session file: %w", err)
+	}
+	return data, nil
+}
+
+func deleteSessionFile(dir, key, ext string) error {
+	path, err := sessionFilePath(dir, key, ext)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete session file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package steamsession
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/k64z/steamstacks/steamid"
+)
+
+func testPersistentSession() *PersistentSession {
+	return &PersistentSession{
+		RefreshToken: "eyRefresh",
+		AccessToken:  "eyAccess",
+		SteamID:      steamid.FromSteamID64(76561198012345678),
+		PlatformType: PlatformTypeMobileApp,
+		SessionID:    "abcdef0123456789abcdef01",
+		Cookies: map[string][]PersistentCookie{
+			"https://steamcommunity.com": {{Name: "sessionid", Value: "abcdef0123456789abcdef01"}},
+		},
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+
+	if _, err := store.Load(ctx, "alice"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("Load missing = %v, want ErrSessionNotFound", err)
+	}
+
+	want := testPersistentSession()
+	if err := store.Save(ctx, "alice", want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "alice.json"))
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+
+	got, err := store.Load(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.RefreshToken != want.RefreshToken || got.PlatformType != want.PlatformType ||
+		got.Cookies["https://steamcommunity.com"][0] != want.Cookies["https://steamcommunity.com"][0] {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+
+	if err := store.Delete(ctx, "alice"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Load(ctx, "alice"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Load after Delete = %v, want ErrSessionNotFound", err)
+	}
+
+	for _, key := range []string{"", "..", "../escape", `a\b`} {
+		if err := store.Save(ctx, key, want); err == nil {
+			t.Errorf("Save(%q) should fail", key)
+		}
+	}
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := NewEncryptedFileStore(dir, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.iterations = 1000 // keep the test fast
+
+	want := testPersistentSession()
+	if err := store.Save(ctx, "alice", want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, "alice.json.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte(want.RefreshToken)) {
+		t.Error("refresh token stored in plaintext")
+	}
+
+	got, err := store.Load(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.RefreshToken != want.RefreshToken || got.SteamID != want.SteamID {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+
+	wrong, _ := NewEncryptedFileStore(dir, "wrong")
+	if _, err := wrong.Load(ctx, "alice"); err == nil {
+		t.Error("Load with wrong passphrase should fail")
+	}
+
+	// A file moved to another key must not decrypt.
+	os.WriteFile(filepath.Join(dir, "bob.json.enc"), raw, 0600)
+	if _, err := store.Load(ctx, "bob"); err == nil {
+		t.Error("Load of a renamed file should fail")
+	}
+
+	if _, err := NewEncryptedFileStore(dir, ""); err == nil {
+		t.Error("empty passphrase should be rejected")
+	}
+}
+
+func TestSnapshotRestoreResumesWebSession(t *testing.T) {
+	accessToken := fakeJWT(time.Now().Add(time.Hour))
+
+	src, _ := New(WithHTTPClient(&http.Client{}), WithPlatformType(PlatformTypeMobileApp))
+	src.RefreshToken = fakeJWT(time.Now().Add(200 * 24 * time.Hour))
+	src.AccessToken = accessToken
+	src.SteamID = steamid.FromSteamID64(76561198012345678)
+	src.sessionID = "abcdef0123456789abcdef01"
+	src.setWebCookies()
+
+	ctx := context.Background()
+	store := NewFileStore(t.TempDir())
+	if err := src.SaveTo(ctx, store, "alice"); err != nil {
+		t.Fatalf("SaveTo: %v", err)
+	}
+
+	dst, _ := New(WithHTTPClient(&http.Client{}))
+	if err := dst.LoadFrom(ctx, store, "alice"); err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+
+	if dst.platformType != src.platformType || dst.websiteID != WebsiteIDMobile {
+		t.Errorf("platform = %v/%q, want MobileApp", dst.platformType, dst.websiteID)
+	}
+	if dst.sessionID != src.sessionID {
+		t.Errorf("sessionID = %q, want %q", dst.sessionID, src.sessionID)
+	}
+	token, err := dst.accessTokenFromJar()
+	if err != nil || token != accessToken {
+		t.Errorf("accessTokenFromJar = %q, %v; want restored token", token, err)
+	}
+	store2, _ := url.Parse("https://store.steampowered.com")
+	if len(dst.httpClient.Jar.Cookies(store2)) != 2 {
+		t.Errorf("store cookies not restored: %v", dst.httpClient.Jar.Cookies(store2))
+	}
+	if _, ok := dst.httpClient.Transport.(*authTransport); !ok {
+		t.Error("auth transport not installed for a live MobileApp session")
+	}
+
+	data, _ := store.Load(ctx, "alice")
+	if data.AccessTokenExpiry.IsZero() || data.RefreshTokenExpiry.IsZero() {
+		t.Error("token expiry not persisted")
+	}
+}
+
+func TestLoadFromFileLegacyFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	os.WriteFile(path, []byte(`{"refresh_token":"eyRefresh","access_token":"eyAccess","steam_id":76561198012345678}`), 0600)
+
+	s, _ := New(WithHTTPClient(&http.Client{}))
+	if err := s.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	if s.RefreshToken != "eyRefresh" || s.SteamID.ToSteamID64() != 76561198012345678 {
+		t.Errorf("session = %q/%d", s.RefreshToken, s.SteamID.ToSteamID64())
+	}
+}
@@ -0,0 +1,365 @@
+package steamsession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/k64z/steamstacks/steamid"
+)
+
+var (
+	// ErrTokenRevoked is wrapped by TokenGenerator errors when Steam rejects
+	// the refresh token as revoked or expired. Only a new login helps.
+	ErrTokenRevoked = errors.New("refresh token revoked")
+
+	// ErrTokenExpired is returned by TokenManager.Run when the refresh token
+	// expired before it could be renewed.
+	ErrTokenExpired = errors.New("refresh token expired")
+)
+
+// TokenGenerator exchanges a refresh token for a new access token and,
+// when Steam agrees to rotate it, a new refresh token. Session implements it
+// over the Web API (MobileApp tokens) and steamclient.Client over the CM
+// connection (SteamClient tokens).
+type TokenGenerator interface {
+	RenewRefreshToken(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, err error)
+}
+
+type TokenEventType int
+
+const (
+	// TokenRenewed: Steam issued a new refresh token, which has been persisted.
+	TokenRenewed TokenEventType = iota + 1
+	// TokenRenewalFailed: a renewal attempt failed and will be retried.
+	TokenRenewalFailed
+	// TokenRevoked: Steam rejected the refresh token. The manager stops.
+	TokenRevoked
+	// TokenExpired: the refresh token expired. The manager stops.
+	TokenExpired
+)
+
+func (t TokenEventType) String() string {
+	switch t {
+	case TokenRenewed:
+		return "TokenRenewed"
+	case TokenRenewalFailed:
+		return "TokenRenewalFailed"
+	case TokenRevoked:
+		return "TokenRevoked"
+	case TokenExpired:
+		return "TokenExpired"
+	}
+	return fmt.Sprintf("TokenEventType(%d)", int(t))
+}
+
+// TokenEvent reports what the TokenManager did.
+type TokenEvent struct {
+	Type         TokenEventType
+	RefreshToken string    // the current refresh token after the event
+	Expiry       time.Time // its expiry
+	Err          error     // set for TokenRenewalFailed, TokenRevoked and TokenExpired
+}
+
+// TokenManager renews a refresh token in the background before it expires.
+type TokenManager struct {
+	gen     TokenGenerator
+	steamID steamid.SteamID
+	session *Session // set when managing a Session's tokens
+
+	renewBefore   time.Duration
+	checkInterval time.Duration
+	retryInterval time.Duration
+	store         SessionStore
+	storeKey      string
+	onEvent       func(TokenEvent)
+
+	mu           sync.Mutex
+	refreshToken string
+	accessToken  string
+	unsaved      *tokenRotation // rotated tokens persist has yet to save
+}
+
+// tokenRotation is a refresh token Steam issued along with its access token.
+type tokenRotation struct {
+	accessToken  string
+	refreshToken string
+}
+
+type tokenManagerConfig struct {
+	renewBefore   time.Duration
+	checkInterval time.Duration
+	retryInterval time.Duration
+	store         SessionStore
+	storeKey      string
+	onEvent       func(TokenEvent)
+}
+
+type TokenManagerOption func(*tokenManagerConfig)
+
+// WithRenewBefore sets how long before expiry renewal starts. Default 30 days.
+func WithRenewBefore(d time.Duration) TokenManagerOption {
+	return func(c *tokenManagerConfig) {
+		c.renewBefore = d
+	}
+}
+
+// WithCheckInterval sets how often the expiry is re-checked, and how long to
+// wait after Steam declines to rotate the token. Default 6 hours.
+func WithCheckInterval(d time.Duration) TokenManagerOption {
+	return func(c *tokenManagerConfig) {
+		c.checkInterval = d
+	}
+}
+
+// WithRetryInterval sets the delay after a failed renewal. Default 5 minutes.
+func WithRetryInterval(d time.Duration) TokenManagerOption {
+	return func(c *tokenManagerConfig) {
+		c.retryInterval = d
+	}
+}
+
+// WithTokenStore persists renewed tokens in store under key.
+func WithTokenStore(store SessionStore, key string) TokenManagerOption {
+	return func(c *tokenManagerConfig) {
+		c.store = store
+		c.storeKey = key
+	}
+}
+
+// WithTokenEventHandler registers a callback for renewals, failures,
+// revocations and expiry. It runs on the manager's goroutine.
+func WithTokenEventHandler(fn func(TokenEvent)) TokenManagerOption {
+	return func(c *tokenManagerConfig) {
+		c.onEvent = fn
+	}
+}
+
+// NewTokenManager manages refreshToken using gen. Use it directly with a
+// steamclient.Client; for a Session, Session.NewTokenManager keeps the
+// session's own tokens in sync as well.
+func NewTokenManager(gen TokenGenerator, refreshToken string, steamID steamid.SteamID, opts ...TokenManagerOption) (*TokenManager, error) {
+	if gen == nil {
+		return nil, errors.New("token generator should be non-nil")
+	}
+	if refreshToken == "" {
+		return nil, errors.New("refresh token is required")
+	}
+	if _, err := jwtExpiry(refreshToken); err != nil {
+		return nil, fmt.Errorf("parse refresh token expiry: %w", err)
+	}
+
+	cfg := tokenManagerConfig{
+		renewBefore:   30 * 24 * time.Hour,
+		checkInterval: 6 * time.Hour,
+		retryInterval: 5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &TokenManager{
+		gen:           gen,
+		steamID:       steamID,
+		renewBefore:   cfg.renewBefore,
+		checkInterval: cfg.checkInterval,
+		retryInterval: cfg.retryInterval,
+		store:         cfg.store,
+		storeKey:      cfg.storeKey,
+		onEvent:       cfg.onEvent,
+		refreshToken:  refreshToken,
+	}, nil
+}
+
+// NewTokenManager returns a TokenManager for the session's refresh token that
+// renews it over the Web API and updates the session on rotation. Pass
+// steamclient.Client as gen via NewTokenManager for SteamClient tokens.
+//
+// The session's RefreshToken and AccessToken fields are written from the
+// goroutine calling Run or RenewNow, without synchronisation: don't use the
+// session on other goroutines while Run is going. The manager's own
+// RefreshToken and AccessToken methods are safe to call at any time.
+func (s *Session) NewTokenManager(opts ...TokenManagerOption) (*TokenManager, error) {
+	m, err := NewTokenManager(s, s.RefreshToken, s.SteamID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.session = s
+	return m, nil
+}
+
+// RefreshToken returns the current, possibly rotated, refresh token.
+func (m *TokenManager) RefreshToken() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.refreshToken
+}
+
+// AccessToken returns the access token issued by the last renewal, if any.
+func (m *TokenManager) AccessToken() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.accessToken
+}
+
+// Expiry returns when the current refresh token expires.
+func (m *TokenManager) Expiry() time.Time {
+	exp, _ := jwtExpiry(m.RefreshToken())
+	return exp
+}
+
+// Run checks the refresh token until ctx is done, renewing it once it is
+// within the renew-before window. A rotated token that could not be
+// persisted is saved again on every check until that succeeds. Failed
+// attempts are reported as TokenRenewalFailed and retried after the retry
+// interval. It returns ctx.Err(), or ErrTokenRevoked or ErrTokenExpired
+// when the token can no longer be used.
+func (m *TokenManager) Run(ctx context.Context) error {
+	for {
+		wait := m.checkInterval
+		err := m.saveRotation(ctx)
+		if err == nil {
+			if until := time.Until(m.Expiry().Add(-m.renewBefore)); until > 0 {
+				wait = min(wait, until)
+			} else {
+				err = m.renew(ctx)
+			}
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrTokenRevoked), errors.Is(err, ErrTokenExpired):
+			return err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		default:
+			wait = m.retryInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RenewNow asks Steam to rotate the refresh token immediately. It reports
+// whether a new token was issued; if one was but could not be persisted,
+// it returns true along with the error, and Run keeps retrying the save.
+func (m *TokenManager) RenewNow(ctx context.Context) (bool, error) {
+	old := m.RefreshToken()
+	err := m.renew(ctx)
+	return m.RefreshToken() != old, err
+}
+
+// renew performs one renewal attempt. Every failure is reported as an
+// event and returned; only ErrTokenRevoked and ErrTokenExpired mean the
+// token is dead.
+func (m *TokenManager) renew(ctx context.Context) error {
+	refreshToken := m.RefreshToken()
+	exp := m.Expiry()
+
+	if !time.Now().Before(exp) {
+		m.emit(TokenEvent{Type: TokenExpired, RefreshToken: refreshToken, Expiry: exp, Err: ErrTokenExpired})
+		return ErrTokenExpired
+	}
+
+	accessToken, newRefreshToken, err := m.gen.RenewRefreshToken(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, ErrTokenRevoked) {
+			m.emit(TokenEvent{Type: TokenRevoked, RefreshToken: refreshToken, Expiry: exp, Err: err})
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		m.emit(TokenEvent{Type: TokenRenewalFailed, RefreshToken: refreshToken, Expiry: exp, Err: err})
+		return fmt.Errorf("renew refresh token: %w", err)
+	}
+
+	m.mu.Lock()
+	m.accessToken = accessToken
+	rotated := newRefreshToken != "" && newRefreshToken != refreshToken
+	if rotated {
+		m.refreshToken = newRefreshToken
+		m.unsaved = &tokenRotation{accessToken: accessToken, refreshToken: newRefreshToken}
+	}
+	m.mu.Unlock()
+
+	// Steam only rotates tokens it considers close enough to expiry;
+	// otherwise there is nothing to save.
+	return m.saveRotation(ctx)
+}
+
+// saveRotation persists the last rotated tokens if they have not been yet,
+// reporting TokenRenewed once they are. Until it succeeds the store still
+// holds the old refresh token, which Steam no longer accepts, so callers
+// retry it.
+func (m *TokenManager) saveRotation(ctx context.Context) error {
+	m.mu.Lock()
+	rot := m.unsaved
+	m.mu.Unlock()
+	if rot == nil {
+		return nil
+	}
+
+	exp, _ := jwtExpiry(rot.refreshToken)
+	if err := m.persist(ctx, rot.accessToken, rot.refreshToken); err != nil {
+		m.emit(TokenEvent{Type: TokenRenewalFailed, RefreshToken: rot.refreshToken, Expiry: exp, Err: err})
+		return fmt.Errorf("persist rotated token: %w", err)
+	}
+
+	m.mu.Lock()
+	if m.unsaved == rot {
+		m.unsaved = nil
+	}
+	m.mu.Unlock()
+	m.emit(TokenEvent{Type: TokenRenewed, RefreshToken: rot.refreshToken, Expiry: exp})
+	return nil
+}
+
+// persist updates the managed session, if any, and the store with the
+// rotated tokens.
+func (m *TokenManager) persist(ctx context.Context, accessToken, refreshToken string) error {
+	if m.session != nil {
+		m.session.RefreshToken = refreshToken
+		m.session.AccessToken = accessToken
+	}
+
+	if m.store == nil {
+		return nil
+	}
+
+	if m.session != nil {
+		if err := m.session.SaveTo(ctx, m.store, m.storeKey); err != nil {
+			return fmt.Errorf("save session: %w", err)
+		}
+		return nil
+	}
+
+	// Keep whatever else is stored (cookies, platform type) and only
+	// replace the tokens.
+	data, err := m.store.Load(ctx, m.storeKey)
+	if errors.Is(err, ErrSessionNotFound) {
+		data, err = &PersistentSession{SteamID: m.steamID}, nil
+	}
+	if err != nil {
+		return fmt.Errorf("load session: %w", err)
+	}
+	data.RefreshToken = refreshToken
+	data.AccessToken = accessToken
+	data.RefreshTokenExpiry, _ = jwtExpiry(refreshToken)
+	data.AccessTokenExpiry, _ = jwtExpiry(accessToken)
+	if err := m.store.Save(ctx, m.storeKey, data); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return nil
+}
+
+func (m *TokenManager) emit(evt TokenEvent) {
+	if m.onEvent != nil {
+		m.onEvent(evt)
+	}
+}
@@ -0,0 +1,245 @@
+package steamsession
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// fakeGenerator answers RenewRefreshToken from a queue of results.
+type fakeGenerator struct {
+	mu      sync.Mutex
+	results []fakeGenResult
+	calls   int
+}
+
+type fakeGenResult struct {
+	refresh string
+	err     error
+}
+
+func (g *fakeGenerator) RenewRefreshToken(_ context.Context, _ string) (string, string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls++
+	r := g.results[0]
+	if len(g.results) > 1 {
+		g.results = g.results[1:]
+	}
+	return "access", r.refresh, r.err
+}
+
+func TestTokenManagerRenewsAndPersists(t *testing.T) {
+	oldToken := fakeJWT(time.Now().Add(24 * time.Hour))
+	newToken := fakeJWT(time.Now().Add(200 * 24 * time.Hour))
+
+	ctx := context.Background()
+	store := NewFileStore(t.TempDir())
+	existing := testPersistentSession()
+	existing.RefreshToken = oldToken
+	store.Save(ctx, "alice", existing)
+
+	gen := &fakeGenerator{results: []fakeGenResult{
+		{err: errors.New("network down")},
+		{refresh: newToken},
+	}}
+
+	var events []TokenEvent
+	renewed := make(chan struct{})
+	m, err := NewTokenManager(gen, oldToken, steamid.FromSteamID64(76561198012345678),
+		WithRetryInterval(time.Millisecond),
+		WithTokenStore(store, "alice"),
+		WithTokenEventHandler(func(evt TokenEvent) {
+			events = append(events, evt)
+			if evt.Type == TokenRenewed {
+				close(renewed)
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- m.Run(runCtx) }()
+
+	select {
+	case <-renewed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("token was not renewed")
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v, want context.Canceled", err)
+	}
+
+	if len(events) != 2 || events[0].Type != TokenRenewalFailed || events[1].Type != TokenRenewed {
+		t.Fatalf("events = %+v, want RenewalFailed then Renewed", events)
+	}
+	if m.RefreshToken() != newToken || m.AccessToken() != "access" {
+		t.Error("manager did not switch to the rotated token")
+	}
+
+	data, err := store.Load(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.RefreshToken != newToken {
+		t.Error("rotated token not persisted")
+	}
+	if len(data.Cookies) == 0 || data.PlatformType != PlatformTypeMobileApp {
+		t.Error("persisting the token dropped the rest of the stored session")
+	}
+}
+
+// flakyStore fails the first failSaves calls to Save.
+type flakyStore struct {
+	SessionStore
+	mu        sync.Mutex
+	failSaves int
+}
+
+func (s *flakyStore) Save(ctx context.Context, key string, data *PersistentSession) error {
+	s.mu.Lock()
+	fail := s.failSaves > 0
+	if fail {
+		s.failSaves--
+	}
+	s.mu.Unlock()
+	if fail {
+		return errors.New("disk full")
+	}
+	return s.SessionStore.Save(ctx, key, data)
+}
+
+func TestTokenManagerRetriesFailedSave(t *testing.T) {
+	oldToken := fakeJWT(time.Now().Add(24 * time.Hour))
+	newToken := fakeJWT(time.Now().Add(200 * 24 * time.Hour))
+
+	ctx := context.Background()
+	store := &flakyStore{SessionStore: NewFileStore(t.TempDir()), failSaves: 1}
+	gen := &fakeGenerator{results: []fakeGenResult{{refresh: newToken}}}
+
+	var events []TokenEvent
+	renewed := make(chan struct{})
+	m, err := NewTokenManager(gen, oldToken, steamid.FromSteamID64(76561198012345678),
+		WithRetryInterval(time.Millisecond),
+		WithTokenStore(store, "alice"),
+		WithTokenEventHandler(func(evt TokenEvent) {
+			events = append(events, evt)
+			if evt.Type == TokenRenewed {
+				close(renewed)
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- m.Run(runCtx) }()
+
+	select {
+	case <-renewed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("rotated token was never persisted")
+	}
+	cancel()
+	<-done
+
+	if len(events) != 2 || events[0].Type != TokenRenewalFailed || events[1].Type != TokenRenewed {
+		t.Fatalf("events = %+v, want RenewalFailed then Renewed", events)
+	}
+	if gen.calls != 1 {
+		t.Errorf("renewed %d times, want 1: only the save should be retried", gen.calls)
+	}
+	data, err := store.Load(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.RefreshToken != newToken {
+		t.Error("rotated token not persisted")
+	}
+}
+
+func TestTokenManagerRenewNowReportsFailures(t *testing.T) {
+	newToken := fakeJWT(time.Now().Add(200 * 24 * time.Hour))
+	store := &flakyStore{SessionStore: NewFileStore(t.TempDir()), failSaves: 1}
+	gen := &fakeGenerator{results: []fakeGenResult{
+		{err: errors.New("network down")},
+		{refresh: newToken},
+	}}
+	m, _ := NewTokenManager(gen, fakeJWT(time.Now().Add(24*time.Hour)), 0, WithTokenStore(store, "alice"))
+	ctx := context.Background()
+
+	if rotated, err := m.RenewNow(ctx); rotated || err == nil {
+		t.Errorf("RenewNow with the generator failing = %v, %v; want false and an error", rotated, err)
+	}
+	if rotated, err := m.RenewNow(ctx); !rotated || err == nil {
+		t.Errorf("RenewNow with the save failing = %v, %v; want true and an error", rotated, err)
+	}
+}
+
+func TestTokenManagerWaitsOutsideWindow(t *testing.T) {
+	gen := &fakeGenerator{results: []fakeGenResult{{refresh: "unused"}}}
+	m, _ := NewTokenManager(gen, fakeJWT(time.Now().Add(100*24*time.Hour)), 0, WithCheckInterval(10*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m.Run(ctx)
+
+	if gen.calls != 0 {
+		t.Errorf("renewed %d times for a token far from expiry", gen.calls)
+	}
+}
+
+func TestTokenManagerStopsOnRevocation(t *testing.T) {
+	gen := &fakeGenerator{results: []fakeGenResult{{err: ErrTokenRevoked}}}
+
+	var got TokenEventType
+	m, _ := NewTokenManager(gen, fakeJWT(time.Now().Add(time.Hour)), 0,
+		WithTokenEventHandler(func(evt TokenEvent) { got = evt.Type }))
+
+	if err := m.Run(context.Background()); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Run = %v, want ErrTokenRevoked", err)
+	}
+	if got != TokenRevoked {
+		t.Errorf("event = %v, want TokenRevoked", got)
+	}
+}
+
+func TestTokenManagerStopsWhenExpired(t *testing.T) {
+	gen := &fakeGenerator{results: []fakeGenResult{{refresh: "unused"}}}
+	m, _ := NewTokenManager(gen, fakeJWT(time.Now().Add(-time.Hour)), 0)
+
+	if err := m.Run(context.Background()); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Run = %v, want ErrTokenExpired", err)
+	}
+	if gen.calls != 0 {
+		t.Error("tried to renew an expired token")
+	}
+}
+
+func TestSessionTokenManagerUpdatesSession(t *testing.T) {
+	newToken := fakeJWT(time.Now().Add(200 * 24 * time.Hour))
+
+	s, _ := New(WithHTTPClient(&http.Client{}))
+	s.RefreshToken = fakeJWT(time.Now().Add(24 * time.Hour))
+	m, err := s.NewTokenManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.gen = &fakeGenerator{results: []fakeGenResult{{refresh: newToken}}}
+
+	if rotated, err := m.RenewNow(context.Background()); err != nil || !rotated {
+		t.Fatalf("RenewNow = %v, %v", rotated, err)
+	}
+	if s.RefreshToken != newToken || s.AccessToken != "access" {
+		t.Error("session tokens not updated")
+	}
+}
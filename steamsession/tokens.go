@@ -0,0 +1,113 @@
+package steamsession
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamtotp"
+)
+
+// RefreshTokenInfo describes a refresh token issued for the account.
+type RefreshTokenInfo struct {
+	TokenID      uint64
+	Description  string // usually the device name
+	TimeUpdated  time.Time
+	PlatformType PlatformType
+	LoggedIn     bool
+	FirstSeen    *TokenUsage
+	LastSeen     *TokenUsage
+
+	// Current is set for the token this session is using.
+	Current bool
+}
+
+// TokenUsage records where and when a refresh token was used.
+type TokenUsage struct {
+	Time    time.Time
+	IP      net.IP
+	Country string
+	State   string
+	City    string
+}
+
+// EnumerateTokens lists the refresh tokens issued for this session's account.
+func (s *Session) EnumerateTokens(ctx context.Context) ([]RefreshTokenInfo, error) {
+	if err := s.useAccessToken(); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.steamAPI.EnumerateTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("enumerate tokens: %w", err)
+	}
+
+	tokens := make([]RefreshTokenInfo, len(resp.RefreshTokens))
+	for i, t := range resp.RefreshTokens {
+		tokens[i] = RefreshTokenInfo{
+			TokenID:      t.GetTokenId(),
+			Description:  t.GetTokenDescription(),
+			TimeUpdated:  time.Unix(int64(t.GetTimeUpdated()), 0),
+			PlatformType: PlatformType(t.GetPlatformType()),
+			LoggedIn:     t.GetLoggedIn(),
+			FirstSeen:    tokenUsage(t.GetFirstSeen()),
+			LastSeen:     tokenUsage(t.GetLastSeen()),
+			Current:      resp.RequestingToken != nil && t.GetTokenId() == resp.GetRequestingToken(),
+		}
+	}
+	return tokens, nil
+}
+
+// RevokeRefreshToken permanently revokes a refresh token by its ID, as
+// returned by EnumerateTokens. Revoking another device's token requires the
+// account's shared secret; pass "" to revoke without a signature.
+func (s *Session) RevokeRefreshToken(ctx context.Context, tokenID uint64, sharedSecret string) error {
+	if err := s.useAccessToken(); err != nil {
+		return err
+	}
+
+	steamID := s.SteamID.ToSteamID64()
+	req := &protocol.CAuthentication_RefreshToken_Revoke_Request{
+		TokenId:      &tokenID,
+		Steamid:      &steamID,
+		RevokeAction: protocol.EAuthTokenRevokeAction_k_EAuthTokenRevokePermanent.Enum(),
+	}
+
+	if sharedSecret != "" {
+		signature, err := steamtotp.GenerateTokenRevokeSignature(sharedSecret, tokenID)
+		if err != nil {
+			return fmt.Errorf("sign token revocation: %w", err)
+		}
+		req.Signature = signature
+	}
+
+	if err := s.steamAPI.RevokeRefreshToken(ctx, req); err != nil {
+		return fmt.Errorf("revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+func tokenUsage(evt *protocol.CAuthentication_RefreshToken_Enumerate_Response_TokenUsageEvent) *TokenUsage {
+	if evt == nil {
+		return nil
+	}
+
+	usage := &TokenUsage{
+		Time:    time.Unix(int64(evt.GetTime()), 0),
+		Country: evt.GetCountry(),
+		State:   evt.GetState(),
+		City:    evt.GetCity(),
+	}
+	if ip := evt.GetIp(); ip != nil {
+		if v6 := ip.GetV6(); len(v6) == net.IPv6len {
+			usage.IP = net.IP(v6)
+		} else {
+			usage.IP = make(net.IP, net.IPv4len)
+			binary.BigEndian.PutUint32(usage.IP, ip.GetV4())
+		}
+	}
+	return usage
+}
@@ -16,7 +16,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/k64z/steamstacks/eresult"
 	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamhost"
 	"github.com/k64z/steamstacks/steamid"
 )
 
@@ -95,17 +98,18 @@ func (s *Session) FinalizeLogin(ctx context.Context) error {
 
 	w.WriteField("nonce", s.RefreshToken)
 	w.WriteField("sessionid", s.sessionID)
-	w.WriteField("redir", "https://steamcommunity.com/login/home/?goto=")
+	hosts := s.hosts.Resolve()
+	w.WriteField("redir", hosts.Community+"/login/home/?goto=")
 	w.Close()
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.loginURL+"/jwt/finalizelogin", buf)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, hosts.Login+"/jwt/finalizelogin", buf)
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", w.FormDataContentType())
-	httpReq.Header.Set("Origin", "https://steamcommunity.com")
-	httpReq.Header.Set("Referer", "https://steamcommunity.com")
+	httpReq.Header.Set("Origin", hosts.Community)
+	httpReq.Header.Set("Referer", hosts.Community)
 
 	resp, err := s.httpClient.Do(httpReq)
 	if err != nil {
@@ -146,7 +150,8 @@ func (s *Session) FinalizeLogin(ctx context.Context) error {
 }
 
 func (s *Session) submitTransferInfo(ctx context.Context, transferInfo TransferInfo) error {
-	u, err := url.Parse(transferInfo.URL)
+	transferURL := s.hosts.Rewrite(transferInfo.URL)
+	u, err := url.Parse(transferURL)
 	if err != nil {
 		return fmt.Errorf("parseURL: %w", err)
 	}
@@ -156,7 +161,7 @@ func (s *Session) submitTransferInfo(ctx context.Context, transferInfo TransferI
 			Name:     "sessionid",
 			Value:    s.sessionID,
 			SameSite: http.SameSiteNoneMode,
-			Secure:   true,
+			Secure:   u.Scheme == "https",
 			HttpOnly: true,
 			Path:     "/",
 		},
@@ -170,7 +175,7 @@ func (s *Session) submitTransferInfo(ctx context.Context, transferInfo TransferI
 	w.WriteField("steamID", strconv.FormatUint(s.SteamID.ToSteamID64(), 10))
 	w.Close()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, transferInfo.URL, buf)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, transferURL, buf)
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
@@ -191,28 +196,30 @@ func (s *Session) submitTransferInfo(ctx context.Context, transferInfo TransferI
 }
 
 // setWebCookies populates the cookie jar with sessionid and steamLoginSecure
-// for steamcommunity.com and store.steampowered.com using the current AccessToken.
+// for the community and store hosts using the current AccessToken. Cookies
+// are only marked Secure on https hosts, so that a plain-http mock server
+// gets them back.
 func (s *Session) setWebCookies() {
-	cookies := []*http.Cookie{
-		{
-			Name:     "sessionid",
-			Value:    s.sessionID,
-			Path:     "/",
-			Secure:   true,
-			HttpOnly: true,
-		},
-		{
-			Name:     "steamLoginSecure",
-			Value:    fmt.Sprintf("%d%%7C%%7C%s", s.SteamID.ToSteamID64(), s.AccessToken),
-			Path:     "/",
-			Secure:   true,
-			HttpOnly: true,
-		},
-	}
-
-	for _, domain := range []string{"https://steamcommunity.com", "https://store.steampowered.com"} {
-		u, _ := url.Parse(domain)
-		s.httpClient.Jar.SetCookies(u, cookies)
+	hosts := s.hosts.Resolve()
+	for _, origin := range []string{hosts.Community, hosts.Store} {
+		u := steamhost.CookieURL(origin)
+		secure := u.Scheme == "https"
+		s.httpClient.Jar.SetCookies(u, []*http.Cookie{
+			{
+				Name:     "sessionid",
+				Value:    s.sessionID,
+				Path:     "/",
+				Secure:   secure,
+				HttpOnly: true,
+			},
+			{
+				Name:     "steamLoginSecure",
+				Value:    fmt.Sprintf("%d%%7C%%7C%s", s.SteamID.ToSteamID64(), s.AccessToken),
+				Path:     "/",
+				Secure:   secure,
+				HttpOnly: true,
+			},
+		})
 	}
 }
 
@@ -240,10 +247,9 @@ func (s *Session) installAuthTransport(tokenExpiry time.Time) {
 }
 
 // accessTokenFromJar extracts the access token from the steamLoginSecure
-// cookie in the cookie jar for steamcommunity.com.
+// cookie in the cookie jar for the community host.
 func (s *Session) accessTokenFromJar() (string, error) {
-	u, _ := url.Parse("https://steamcommunity.com")
-	for _, c := range s.httpClient.Jar.Cookies(u) {
+	for _, c := range s.httpClient.Jar.Cookies(steamhost.CookieURL(s.hosts.Resolve().Community)) {
 		if c.Name == "steamLoginSecure" {
 			parts := strings.Split(c.Value, "%7C%7C")
 			if len(parts) >= 2 {
@@ -262,23 +268,56 @@ func (s *Session) accessTokenFromJar() (string, error) {
 // (AccountLogonDenied) unless sent over an authenticated CM session.
 // For WebBrowser, authTransport uses FinalizeLogin instead.
 func (s *Session) refreshAccessToken(ctx context.Context) error {
+	accessToken, _, err := s.GenerateAccessTokenForApp(ctx, s.RefreshToken)
+	if err != nil {
+		return err
+	}
+	s.AccessToken = accessToken
+	return nil
+}
+
+// GenerateAccessTokenForApp exchanges refreshToken for a new access token via
+// the Web API. Like refreshAccessToken, this only works for MobileApp tokens.
+// A revoked or expired refresh token yields an error wrapping
+// ErrTokenRevoked.
+func (s *Session) GenerateAccessTokenForApp(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, err error) {
+	return s.generateAccessToken(ctx, refreshToken, false)
+}
+
+// RenewRefreshToken is GenerateAccessTokenForApp with renewal allowed: Steam
+// may also issue a new refresh token, returned as newRefreshToken (empty
+// when it declines). For SteamClient tokens use steamclient.Client, which
+// satisfies TokenGenerator as well.
+func (s *Session) RenewRefreshToken(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, err error) {
+	return s.generateAccessToken(ctx, refreshToken, true)
+}
+
+func (s *Session) generateAccessToken(ctx context.Context, refreshToken string, renew bool) (accessToken, newRefreshToken string, err error) {
 	sid := s.SteamID.ToSteamID64()
-	resp, err := s.steamAPI.GenerateAccessTokenForApp(ctx, &protocol.CAuthentication_AccessToken_GenerateForApp_Request{
-		RefreshToken: &s.RefreshToken,
+	req := &protocol.CAuthentication_AccessToken_GenerateForApp_Request{
+		RefreshToken: &refreshToken,
 		Steamid:      &sid,
-	})
+	}
+	if renew {
+		req.RenewalType = protocol.ETokenRenewalType_k_ETokenRenewalType_Allow.Enum()
+	}
+
+	resp, err := s.steamAPI.GenerateAccessTokenForApp(ctx, req)
 	if err != nil {
-		return err
+		var eresultErr *steamapi.EResultError
+		if errors.As(err, &eresultErr) && (eresultErr.EResult == eresult.Revoked || eresultErr.EResult == eresult.Expired) {
+			return "", "", fmt.Errorf("%w: %w", ErrTokenRevoked, err)
+		}
+		return "", "", err
 	}
 	if resp.AccessToken == nil {
-		return errors.New("access token is nil")
+		return "", "", errors.New("access token is nil")
 	}
-	s.AccessToken = *resp.AccessToken
-	return nil
+	return resp.GetAccessToken(), resp.GetRefreshToken(), nil
 }
 
 // ExpireAuthTransportToken forces the authTransport to treat the current
-// access token as expired. The next request to steamcommunity.com will
+// access token as expired. The next request to the community host will
 // trigger a proactive token refresh.
 // This is a no-op if authTransport is not installed.
 func (s *Session) ExpireAuthTransportToken() {
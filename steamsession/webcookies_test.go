@@ -16,6 +16,7 @@ import (
 
 	"github.com/k64z/steamstacks/protocol"
 	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamhost"
 	"github.com/k64z/steamstacks/steamid"
 	"google.golang.org/protobuf/proto"
 )
@@ -99,6 +100,49 @@ func TestGetWebCookiesDirect(t *testing.T) {
 	}
 }
 
+func TestGetWebCookiesFollowsHostMapping(t *testing.T) {
+	token := fakeJWT(time.Now().Add(24 * time.Hour))
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Session{
+		httpClient:   &http.Client{Jar: jar},
+		platformType: protocol.EAuthTokenPlatformType_k_EAuthTokenPlatformType_MobileApp,
+		SteamID:      steamid.FromSteamID64(76561198012345678),
+		RefreshToken: "eyFakeRefresh456",
+		AccessToken:  token,
+		hosts: steamhost.Hosts{
+			Community: "http://127.0.0.1:8001",
+			Store:     "http://127.0.0.1:8002/store",
+		},
+	}
+
+	if err := s.GetWebCookies(context.Background()); err != nil {
+		t.Fatalf("GetWebCookies returned error: %v", err)
+	}
+
+	for _, raw := range []string{"http://127.0.0.1:8001", "http://127.0.0.1:8002"} {
+		u, _ := url.Parse(raw)
+		cookieMap := make(map[string]string)
+		for _, c := range jar.Cookies(u) {
+			cookieMap[c.Name] = c.Value
+		}
+		assertCookie(t, cookieMap, "steamLoginSecure", "76561198012345678%7C%7C"+token)
+	}
+
+	u, _ := url.Parse("https://steamcommunity.com")
+	if cookies := jar.Cookies(u); len(cookies) != 0 {
+		t.Errorf("cookies set on steamcommunity.com: %v", cookies)
+	}
+
+	got, err := s.accessTokenFromJar()
+	if err != nil || got != token {
+		t.Errorf("accessTokenFromJar = %q, %v; want the access token", got, err)
+	}
+}
+
 func TestGetWebCookiesInstallsAuthTransport(t *testing.T) {
 	t.Run("MobileApp gets authTransport", func(t *testing.T) {
 		token := fakeJWT(time.Now().Add(24 * time.Hour))
@@ -215,7 +259,7 @@ func TestAuthTransportViaGetWebCookies(t *testing.T) {
 		SteamID:      steamid.FromSteamID64(76561198012345678),
 		RefreshToken: "eyFakeRefresh456",
 		AccessToken:  accessToken, // set during login (PollAuthSessionStatus)
-		loginURL:     ts.URL,
+		hosts:        steamhost.Hosts{Login: ts.URL},
 	}
 
 	// GetWebCookies: FinalizeLogin → install authTransport using existing AccessToken
@@ -303,7 +347,7 @@ func TestAuthTransportProactiveRefresh(t *testing.T) {
 		RefreshToken: "eyFakeRefresh456",
 		AccessToken:  expiredToken,
 		sessionID:    "testsession123",
-		loginURL:     ts.URL,
+		hosts:        steamhost.Hosts{Login: ts.URL},
 	}
 
 	// Pre-populate cookies so the jar has something for patchRequestCookies.
@@ -408,7 +452,7 @@ func TestAuthTransportReactiveRefresh(t *testing.T) {
 		RefreshToken: "eyFakeRefresh456",
 		AccessToken:  validToken,
 		sessionID:    "testsession123",
-		loginURL:     ts.URL,
+		hosts:        steamhost.Hosts{Login: ts.URL},
 	}
 
 	s.setWebCookies()
@@ -28,7 +28,7 @@ type BuyItemResult struct {
 // appID is the game's app ID (e.g., 440 for TF2).
 // itemID is the item definition ID.
 func (s *Store) BuyItem(ctx context.Context, appID, itemID int) (*BuyItemResult, error) {
-	buyURL := fmt.Sprintf("https://store.steampowered.com/buyitem/%d/%d", appID, itemID)
+	buyURL := fmt.Sprintf("%s/buyitem/%d/%d", s.storeURL, appID, itemID)
 
 	txnData, err := s.initBuyItem(ctx, buyURL)
 	if err != nil {
@@ -122,7 +122,7 @@ func (s *Store) approveTransaction(ctx context.Context, txn *buyItemTxnData) err
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		"https://checkout.steampowered.com/checkout/approvetxnsubmit",
+		s.checkoutURL+"/checkout/approvetxnsubmit",
 		strings.NewReader(formData.Encode()),
 	)
 	if err != nil {
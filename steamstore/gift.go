@@ -34,7 +34,7 @@ func (s *Store) SendGift(ctx context.Context, req *SendGiftRequest) error {
 	httpReq, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		"https://store.steampowered.com/gifts/sendgift",
+		s.storeURL+"/gifts/sendgift",
 		strings.NewReader(formData.Encode()),
 	)
 	if err != nil {
@@ -21,7 +21,7 @@ func (s *Store) SetDisplayLanguages(ctx context.Context, primaryLang, secondaryL
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		"https://store.steampowered.com/account/setlanguagepreferences",
+		s.storeURL+"/account/setlanguagepreferences",
 		strings.NewReader(formData.Encode()),
 	)
 	if err != nil {
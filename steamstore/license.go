@@ -26,7 +26,7 @@ func (s *Store) AddFreeLicense(ctx context.Context, subID int) (*AddFreeLicenseR
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		"https://store.steampowered.com/checkout/addfreelicense",
+		s.storeURL+"/checkout/addfreelicense",
 		strings.NewReader(formData.Encode()),
 	)
 	if err != nil {
@@ -70,7 +70,7 @@ func (s *Store) RemoveLicense(ctx context.Context, subID int) error {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		"https://store.steampowered.com/account/removelicense",
+		s.storeURL+"/account/removelicense",
 		strings.NewReader(formData.Encode()),
 	)
 	if err != nil {
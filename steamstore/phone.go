@@ -11,7 +11,7 @@ import (
 	"strings"
 )
 
-const phoneAjaxURL = "https://store.steampowered.com/phone/add_ajaxop"
+const phoneAjaxPath = "/phone/add_ajaxop"
 
 // PhoneAjaxResponse is the response from the phone add_ajaxop endpoint.
 // Note: Steam returns inconsistent types (e.g. state is string on success, false on error).
@@ -50,7 +50,7 @@ func (s *Store) phoneAjaxOp(ctx context.Context, op, input string, confirmed boo
 		"token":       {"0"},
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, phoneAjaxURL, strings.NewReader(formData.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.storeURL+phoneAjaxPath, strings.NewReader(formData.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
@@ -138,7 +138,7 @@ func (s *Store) HasPhone(ctx context.Context) (*PhoneInfo, error) {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
-		"https://store.steampowered.com/account/",
+		s.storeURL+"/account/",
 		nil,
 	)
 	if err != nil {
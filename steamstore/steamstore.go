@@ -4,20 +4,23 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
 	"strings"
 
+	"github.com/k64z/steamstacks/steamhost"
 	"github.com/k64z/steamstacks/steamid"
 )
 
 type Store struct {
-	httpClient *http.Client
-	sessionID  string
-	SteamID    steamid.SteamID
+	httpClient  *http.Client
+	storeURL    string
+	checkoutURL string
+	sessionID   string
+	SteamID     steamid.SteamID
 }
 
 type config struct {
 	httpClient *http.Client
+	hosts      steamhost.Hosts
 }
 
 type Option func(options *config) error
@@ -32,6 +35,15 @@ func WithHTTPClient(httpClient *http.Client) Option {
 	}
 }
 
+// WithHosts maps the Steam web hosts. Requests use the store and checkout
+// hosts, and session cookies are read from the store host.
+func WithHosts(hosts steamhost.Hosts) Option {
+	return func(options *config) error {
+		options.hosts = hosts
+		return nil
+	}
+}
+
 func New(opts ...Option) (*Store, error) {
 	var cfg config
 	for _, opt := range opts {
@@ -45,26 +57,29 @@ func New(opts ...Option) (*Store, error) {
 		httpClient = http.DefaultClient
 	}
 
-	sessionID, err := extractSessionID(httpClient.Jar)
+	hosts := cfg.hosts.Resolve()
+
+	sessionID, err := extractSessionID(httpClient.Jar, hosts.Store)
 	if err != nil {
 		return nil, fmt.Errorf("extract sessionID: %w", err)
 	}
 
-	steamID, err := extractSteamID(httpClient.Jar)
+	steamID, err := extractSteamID(httpClient.Jar, hosts.Store)
 	if err != nil {
 		return nil, fmt.Errorf("extract steamID: %w", err)
 	}
 
 	return &Store{
-		httpClient: httpClient,
-		sessionID:  sessionID,
-		SteamID:    steamID,
+		httpClient:  httpClient,
+		storeURL:    hosts.Store,
+		checkoutURL: hosts.Checkout,
+		sessionID:   sessionID,
+		SteamID:     steamID,
 	}, nil
 }
 
-func extractSessionID(jar http.CookieJar) (string, error) {
-	u, _ := url.Parse("https://store.steampowered.com")
-	cookies := jar.Cookies(u)
+func extractSessionID(jar http.CookieJar, baseURL string) (string, error) {
+	cookies := jar.Cookies(steamhost.CookieURL(baseURL))
 
 	for _, cookie := range cookies {
 		if cookie.Name == "sessionid" {
@@ -75,9 +90,8 @@ func extractSessionID(jar http.CookieJar) (string, error) {
 	return "", errors.New("sessionID is missing")
 }
 
-func extractSteamID(jar http.CookieJar) (steamid.SteamID, error) {
-	u, _ := url.Parse("https://store.steampowered.com")
-	cookies := jar.Cookies(u)
+func extractSteamID(jar http.CookieJar, baseURL string) (steamid.SteamID, error) {
+	cookies := jar.Cookies(steamhost.CookieURL(baseURL))
 
 	for _, cookie := range cookies {
 		if cookie.Name == "steamLoginSecure" {
@@ -38,7 +38,7 @@ func (s *Store) GetUserData(ctx context.Context) (*UserData, error) {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
-		"https://store.steampowered.com/dynamicstore/userdata/",
+		s.storeURL+"/dynamicstore/userdata/",
 		nil,
 	)
 	if err != nil {
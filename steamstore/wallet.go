@@ -31,7 +31,7 @@ func (s *Store) GetWalletBalance(ctx context.Context) (*WalletBalance, error) {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
-		"https://store.steampowered.com/account/",
+		s.storeURL+"/account/",
 		nil,
 	)
 	if err != nil {
@@ -81,7 +81,7 @@ func (s *Store) RedeemWalletCode(ctx context.Context, code string) (*RedeemWalle
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		"https://store.steampowered.com/account/ajaxredeemwalletcode/",
+		s.storeURL+"/account/ajaxredeemwalletcode/",
 		strings.NewReader(formData.Encode()),
 	)
 	if err != nil {
@@ -150,7 +150,7 @@ func (s *Store) CreateWallet(ctx context.Context, req *CreateWalletRequest) erro
 	httpReq, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		"https://store.steampowered.com/account/createwallet/",
+		s.storeURL+"/account/createwallet/",
 		strings.NewReader(formData.Encode()),
 	)
 	if err != nil {
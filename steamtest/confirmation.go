@@ -0,0 +1,293 @@
+package steamtest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamid"
+	"github.com/k64z/steamstacks/steamtotp"
+)
+
+// Confirmation types, as in the mobileconf type field.
+const (
+	confTypeTrade         = 2
+	confTypeMarketListing = 3
+)
+
+// confirmationTimeSkew is how far the t parameter of a confirmation request
+// may be from the server clock.
+const confirmationTimeSkew = 5 * time.Minute
+
+// confirmation is a pending mobile confirmation.
+type confirmation struct {
+	id        string
+	nonce     string
+	typ       int
+	creatorID string // trade offer or listing ID
+	headline  string
+	summary   []string
+	created   time.Time
+}
+
+// Confirmation is a pending mobile confirmation as the server holds it.
+type Confirmation struct {
+	ID        string
+	Type      int
+	CreatorID string
+	Headline  string
+}
+
+// Confirmations returns the owner's pending mobile confirmations.
+func (s *Server) Confirmations(owner steamid.SteamID) []Confirmation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Confirmation
+	for _, c := range s.confs[owner] {
+		out = append(out, Confirmation{
+			ID:        c.id,
+			Type:      c.typ,
+			CreatorID: c.creatorID,
+			Headline:  c.headline,
+		})
+	}
+	return out
+}
+
+func (s *Server) addConfirmationLocked(owner steamid.SteamID, typ int, creatorID, headline string, summary []string) {
+	s.confs[owner] = append(s.confs[owner], &confirmation{
+		id:        s.newIDLocked(),
+		nonce:     strconv.FormatUint(randomUint64(), 10),
+		typ:       typ,
+		creatorID: creatorID,
+		headline:  headline,
+		summary:   summary,
+		created:   time.Now(),
+	})
+}
+
+// removeConfirmationsLocked drops the confirmations for a trade offer or
+// listing that no longer needs one.
+func (s *Server) removeConfirmationsLocked(creatorID string) {
+	for owner, confs := range s.confs {
+		s.confs[owner] = slices.DeleteFunc(confs, func(c *confirmation) bool {
+			return c.creatorID == creatorID
+		})
+	}
+}
+
+func (s *Server) registerConfirmations(mux *http.ServeMux) {
+	mux.HandleFunc("GET /mobileconf/getlist", s.handleGetConfirmations)
+	mux.HandleFunc("GET /mobileconf/ajaxop", s.handleConfirmationOp)
+	mux.HandleFunc("POST /mobileconf/multiajaxop", s.handleMultiConfirmationOp)
+	mux.HandleFunc("GET /mobileconf/detailspage/{id}", s.handleConfirmationDetails)
+}
+
+// confirmationUser authenticates a mobileconf request: the session cookie,
+// the a parameter and the k key for the given tag must all agree.
+func (s *Server) confirmationUser(r *http.Request, tag string) (steamid.SteamID, bool) {
+	user, ok := s.cookieUser(r)
+	if !ok {
+		return 0, false
+	}
+	q := r.URL.Query()
+	if q.Get("a") != strconv.FormatUint(user.ToSteamID64(), 10) || q.Get("tag") != tag {
+		return 0, false
+	}
+	t, err := strconv.ParseInt(q.Get("t"), 10, 64)
+	if err != nil || time.Since(time.Unix(t, 0)).Abs() > confirmationTimeSkew {
+		return 0, false
+	}
+
+	s.mu.Lock()
+	secret := s.accounts[user].IdentitySecret
+	s.mu.Unlock()
+	if secret == nil || q.Get("k") != steamtotp.GenerateConfirmationKey(secret, t, tag) {
+		return 0, false
+	}
+	return user, true
+}
+
+func (s *Server) handleGetConfirmations(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.confirmationUser(r, "list")
+	if !ok {
+		writeJSON(w, map[string]any{"success": false, "needauth": true})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conf := []map[string]any{}
+	for _, c := range s.confs[user] {
+		typeName := "Trade Offer"
+		if c.typ == confTypeMarketListing {
+			typeName = "Market Listing"
+		}
+		conf = append(conf, map[string]any{
+			"type":          c.typ,
+			"type_name":     typeName,
+			"id":            c.id,
+			"creator_id":    c.creatorID,
+			"nonce":         c.nonce,
+			"creation_time": c.created.Unix(),
+			"headline":      c.headline,
+			"summary":       c.summary,
+			"icon":          "",
+		})
+	}
+	writeJSON(w, map[string]any{"success": true, "conf": conf})
+}
+
+// handleConfirmationDetails renders the parts of a details page that
+// GetConfirmationDetails reads: the offer with each side's avatar and
+// items, or the listed item.
+func (s *Server) handleConfirmationDetails(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.confirmationUser(r, "details")
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.confs[user], func(c *confirmation) bool { return c.id == r.PathValue("id") })
+	if i < 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	c := s.confs[user][i]
+
+	var b strings.Builder
+	b.WriteString("<html><body><div id=\"mobileconf_details\">\n")
+	switch c.typ {
+	case confTypeTrade:
+		if o := s.offers[c.creatorID]; o != nil {
+			fmt.Fprintf(&b, "<div class=\"tradeoffer\" id=\"tradeofferid_%s\">\n", o.id)
+			writeDetailsSide(&b, "primary", o.sender, o.give)
+			writeDetailsSide(&b, "secondary", o.recipient, o.receive)
+			b.WriteString("</div>\n")
+		}
+	case confTypeMarketListing:
+		if l := s.listings[c.creatorID]; l != nil {
+			fmt.Fprintf(&b, "<div id=\"mobileconf_listing_item\" data-economy-item=\"classinfo/%d/%s/%s\"></div>\n",
+				l.item.AppID, l.item.ClassID, l.item.InstanceID)
+		}
+	}
+	b.WriteString("</div></body></html>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+func writeDetailsSide(b *strings.Builder, class string, owner steamid.SteamID, items []Item) {
+	fmt.Fprintf(b, "<div class=\"tradeoffer_items %s\">\n", class)
+	fmt.Fprintf(b, "\t<a class=\"tradeoffer_avatar playerAvatar\" data-miniprofile=\"%d\"></a>\n", owner.AccountID())
+	for _, it := range items {
+		fmt.Fprintf(b, "\t<div class=\"trade_item \" data-economy-item=\"classinfo/%d/%s/%s\"></div>\n",
+			it.AppID, it.ClassID, it.InstanceID)
+	}
+	b.WriteString("</div>\n")
+}
+
+func (s *Server) handleConfirmationOp(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.confirmationOp(w, r, q.Get("op"), []string{q.Get("cid")}, []string{q.Get("ck")})
+}
+
+func (s *Server) handleMultiConfirmationOp(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+	// The mobileconf parameters come in the body; check them as a query.
+	r.URL.RawQuery = r.PostForm.Encode()
+	s.confirmationOp(w, r, r.PostForm.Get("op"), r.PostForm["cid[]"], r.PostForm["ck[]"])
+}
+
+// confirmationOp allows or cancels the confirmations with the given IDs
+// and nonces. Like Steam, it does nothing unless all of them are found.
+func (s *Server) confirmationOp(w http.ResponseWriter, r *http.Request, op string, cids, cks []string) {
+	var allow bool
+	switch op {
+	case "allow":
+		allow = true
+		if _, ok := s.confirmationUser(r, "accept"); !ok {
+			writeJSON(w, map[string]any{"success": false, "needauth": true})
+			return
+		}
+	case "cancel":
+		if _, ok := s.confirmationUser(r, "reject"); !ok {
+			writeJSON(w, map[string]any{"success": false, "needauth": true})
+			return
+		}
+	default:
+		writeJSON(w, map[string]any{"success": false, "message": "Invalid operation"})
+		return
+	}
+	if len(cids) == 0 || len(cids) != len(cks) {
+		writeJSON(w, map[string]any{"success": false, "message": "Invalid confirmation list"})
+		return
+	}
+	user, _ := s.cookieUser(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found []*confirmation
+	for i, cid := range cids {
+		j := slices.IndexFunc(s.confs[user], func(c *confirmation) bool {
+			return c.id == cid && c.nonce == cks[i]
+		})
+		if j < 0 {
+			writeJSON(w, map[string]any{"success": false, "message": "Could not find confirmation"})
+			return
+		}
+		found = append(found, s.confs[user][j])
+	}
+	s.confs[user] = slices.DeleteFunc(s.confs[user], func(c *confirmation) bool {
+		return slices.Contains(found, c)
+	})
+
+	for _, c := range found {
+		switch c.typ {
+		case confTypeTrade:
+			s.resolveTradeConfirmationLocked(user, c.creatorID, allow)
+		case confTypeMarketListing:
+			s.resolveListingConfirmationLocked(c.creatorID, allow)
+		}
+	}
+	writeJSON(w, map[string]any{"success": true})
+}
+
+// resolveTradeConfirmationLocked applies a confirmed or cancelled trade
+// confirmation: the sender's confirms a new offer, the recipient's an
+// acceptance.
+func (s *Server) resolveTradeConfirmationLocked(user steamid.SteamID, offerID string, allow bool) {
+	o := s.offers[offerID]
+	if o == nil {
+		return
+	}
+	o.updated = time.Now()
+
+	switch {
+	case o.sender == user && o.state == steamapi.ETradeOfferStateCreatedNeedsConfirmation:
+		if allow {
+			o.state = steamapi.ETradeOfferStateActive
+		} else {
+			o.state = steamapi.ETradeOfferStateCanceledBySecondFactor
+		}
+	case o.recipient == user && o.acceptPending:
+		if allow {
+			s.completeTradeLocked(o)
+		} else {
+			o.acceptPending = false
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package steamtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/k64z/steamstacks/protocol"
+	"github.com/k64z/steamstacks/steamid"
+	"github.com/k64z/steamstacks/steamtotp"
+	"google.golang.org/protobuf/proto"
+)
+
+// EResult codes the fake answers with.
+const (
+	eresultOK                    = 1
+	eresultFail                  = 2
+	eresultInvalidPassword       = 5
+	eresultInvalidParam          = 8
+	eresultFileNotFound          = 9
+	eresultInvalidState          = 11
+	eresultAccessDenied          = 15
+	eresultTwoFactorCodeMismatch = 88
+)
+
+const (
+	accessTokenTTL  = 24 * time.Hour
+	refreshTokenTTL = 200 * 24 * time.Hour
+)
+
+// authSession is a credentials login in progress.
+type authSession struct {
+	clientID  uint64
+	requestID []byte
+	steamID   steamid.SteamID
+	approved  bool
+}
+
+func (s *Server) registerLogin(mux *http.ServeMux) {
+	mux.HandleFunc("/ITwoFactorService/QueryTime/v1/", s.handleQueryTime)
+	mux.HandleFunc("/IAuthenticationService/GetPasswordRSAPublicKey/v1", s.handleGetPasswordRSAPublicKey)
+	mux.HandleFunc("POST /IAuthenticationService/BeginAuthSessionViaCredentials/v1", s.handleBeginAuthSession)
+	mux.HandleFunc("POST /IAuthenticationService/UpdateAuthSessionWithSteamGuardCode/v1", s.handleUpdateWithGuardCode)
+	mux.HandleFunc("POST /IAuthenticationService/PollAuthSessionStatus/v1", s.handlePollAuthSession)
+	mux.HandleFunc("POST /IAuthenticationService/GenerateAccessTokenForApp/v1", s.handleGenerateAccessToken)
+	mux.HandleFunc("POST /jwt/finalizelogin", s.handleFinalizeLogin)
+	mux.HandleFunc("POST /login/settoken", s.handleSetToken)
+}
+
+func (s *Server) handleQueryTime(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"response": map[string]any{
+			"server_time": strconv.FormatInt(time.Now().Unix(), 10),
+		},
+	})
+}
+
+func (s *Server) handleGetPasswordRSAPublicKey(w http.ResponseWriter, r *http.Request) {
+	var req protocol.CAuthentication_GetPasswordRSAPublicKey_Request
+	if !readProto(w, r, &req) {
+		return
+	}
+	writeProto(w, eresultOK, &protocol.CAuthentication_GetPasswordRSAPublicKey_Response{
+		PublickeyMod: proto.String(s.key.N.Text(16)),
+		PublickeyExp: proto.String(strconv.FormatInt(int64(s.key.E), 16)),
+		Timestamp:    proto.Uint64(uint64(time.Now().Unix())),
+	})
+}
+
+func (s *Server) handleBeginAuthSession(w http.ResponseWriter, r *http.Request) {
+	var req protocol.CAuthentication_BeginAuthSessionViaCredentials_Request
+	if !readProto(w, r, &req) {
+		return
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(req.GetEncryptedPassword())
+	if err != nil {
+		writeProto(w, eresultInvalidParam, nil)
+		return
+	}
+	password, err := rsa.DecryptPKCS1v15(nil, s.key, encrypted)
+	if err != nil {
+		writeProto(w, eresultInvalidPassword, nil)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var acct *Account
+	for _, a := range s.accounts {
+		if a.AccountName == req.GetAccountName() {
+			acct = a
+			break
+		}
+	}
+	if acct == nil || acct.Password != string(password) {
+		writeProto(w, eresultInvalidPassword, nil)
+		return
+	}
+
+	guard := protocol.EAuthSessionGuardType_k_EAuthSessionGuardType_None
+	if acct.SharedSecret != "" {
+		guard = protocol.EAuthSessionGuardType_k_EAuthSessionGuardType_DeviceCode
+	}
+
+	sess := &authSession{
+		clientID:  randomUint64(),
+		requestID: make([]byte, 16),
+		steamID:   acct.SteamID,
+		approved:  acct.SharedSecret == "",
+	}
+	rand.Read(sess.requestID)
+	s.authSessions[sess.clientID] = sess
+
+	writeProto(w, eresultOK, &protocol.CAuthentication_BeginAuthSessionViaCredentials_Response{
+		ClientId:  proto.Uint64(sess.clientID),
+		RequestId: sess.requestID,
+		Interval:  proto.Float32(0.1),
+		AllowedConfirmations: []*protocol.CAuthentication_AllowedConfirmation{
+			{ConfirmationType: guard.Enum()},
+		},
+		Steamid:   proto.Uint64(acct.SteamID.ToSteamID64()),
+		WeakToken: proto.String(randomHex(16)),
+	})
+}
+
+func (s *Server) handleUpdateWithGuardCode(w http.ResponseWriter, r *http.Request) {
+	var req protocol.CAuthentication_UpdateAuthSessionWithSteamGuardCode_Request
+	if !readProto(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess := s.authSessions[req.GetClientId()]
+	if sess == nil || sess.steamID.ToSteamID64() != req.GetSteamid() {
+		writeProto(w, eresultFileNotFound, nil)
+		return
+	}
+	acct := s.accounts[sess.steamID]
+	if acct.SharedSecret == "" || !validAuthCode(acct.SharedSecret, req.GetCode()) {
+		writeProto(w, eresultTwoFactorCodeMismatch, nil)
+		return
+	}
+	sess.approved = true
+	writeProto(w, eresultOK, nil)
+}
+
+func (s *Server) handlePollAuthSession(w http.ResponseWriter, r *http.Request) {
+	var req protocol.CAuthentication_PollAuthSessionStatus_Request
+	if !readProto(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess := s.authSessions[req.GetClientId()]
+	if sess == nil || string(sess.requestID) != string(req.GetRequestId()) {
+		writeProto(w, eresultFileNotFound, nil)
+		return
+	}
+	if !sess.approved {
+		writeProto(w, eresultOK, &protocol.CAuthentication_PollAuthSessionStatus_Response{})
+		return
+	}
+
+	delete(s.authSessions, sess.clientID)
+	writeProto(w, eresultOK, &protocol.CAuthentication_PollAuthSessionStatus_Response{
+		RefreshToken: proto.String(s.issueTokenLocked(sess.steamID, refreshTokenTTL)),
+		AccessToken:  proto.String(s.issueTokenLocked(sess.steamID, accessTokenTTL)),
+		AccountName:  proto.String(s.accounts[sess.steamID].AccountName),
+	})
+}
+
+func (s *Server) handleGenerateAccessToken(w http.ResponseWriter, r *http.Request) {
+	var req protocol.CAuthentication_AccessToken_GenerateForApp_Request
+	if !readProto(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner, ok := s.tokens[req.GetRefreshToken()]
+	if !ok || owner.ToSteamID64() != req.GetSteamid() {
+		writeProto(w, eresultAccessDenied, nil)
+		return
+	}
+
+	resp := &protocol.CAuthentication_AccessToken_GenerateForApp_Response{
+		AccessToken: proto.String(s.issueTokenLocked(owner, accessTokenTTL)),
+	}
+	if req.GetRenewalType() == protocol.ETokenRenewalType_k_ETokenRenewalType_Allow {
+		resp.RefreshToken = proto.String(s.issueTokenLocked(owner, refreshTokenTTL))
+	}
+	writeProto(w, eresultOK, resp)
+}
+
+// handleFinalizeLogin exchanges a refresh token for transfer info. Real
+// Steam returns one transfer URL per web host; here they are all the same
+// server, so one is enough.
+func (s *Server) handleFinalizeLogin(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	owner, ok := s.tokens[r.FormValue("nonce")]
+	var nonce string
+	if ok {
+		nonce = s.issueTokenLocked(owner, accessTokenTTL)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, map[string]any{"error": "Invalid nonce"})
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"steamID": strconv.FormatUint(owner.ToSteamID64(), 10),
+		"redir":   r.FormValue("redir"),
+		"transfer_info": []map[string]any{{
+			"url": s.srv.URL + "/login/settoken",
+			"params": map[string]string{
+				"nonce": nonce,
+				"auth":  randomHex(16),
+			},
+		}},
+		"primary_domain": "steamcommunity.com",
+	})
+}
+
+// handleSetToken sets the steamLoginSecure cookie for a transfer nonce.
+func (s *Server) handleSetToken(w http.ResponseWriter, r *http.Request) {
+	nonce := r.FormValue("nonce")
+	owner, ok := s.tokenUser(nonce)
+	if !ok || strconv.FormatUint(owner.ToSteamID64(), 10) != r.FormValue("steamID") {
+		writeJSON(w, map[string]any{"result": eresultAccessDenied})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "steamLoginSecure",
+		Value:    loginSecureValue(owner, nonce),
+		Path:     "/",
+		HttpOnly: true,
+	})
+	writeJSON(w, map[string]any{"result": eresultOK})
+}
+
+// validAuthCode accepts the Steam Guard code for the current 30s window
+// and the ones either side of it, like Steam does.
+func validAuthCode(sharedSecret, code string) bool {
+	for _, offset := range []int64{-30, 0, 30} {
+		want, err := steamtotp.GenerateAuthCode(sharedSecret, offset)
+		if err == nil && want == code {
+			return true
+		}
+	}
+	return false
+}
+
+// readProto decodes the input_protobuf_encoded parameter of a Web API
+// request, from the query string or a multipart body.
+func readProto(w http.ResponseWriter, r *http.Request, msg proto.Message) bool {
+	data, err := base64.StdEncoding.DecodeString(r.FormValue("input_protobuf_encoded"))
+	if err == nil {
+		err = proto.Unmarshal(data, msg)
+	}
+	if err != nil {
+		writeProto(w, eresultInvalidParam, nil)
+		return false
+	}
+	return true
+}
+
+// writeProto answers a protobuf Web API call with the given EResult.
+func writeProto(w http.ResponseWriter, eresult int, msg proto.Message) {
+	var body []byte
+	if msg != nil {
+		var err error
+		if body, err = proto.Marshal(msg); err != nil {
+			http.Error(w, fmt.Sprintf("marshal: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Eresult", strconv.Itoa(eresult))
+	w.Write(body)
+}
+
+func randomUint64() uint64 {
+	var b [8]byte
+	rand.Read(b[:])
+	return binary.LittleEndian.Uint64(b[:])
+}
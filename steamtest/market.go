@@ -0,0 +1,351 @@
+package steamtest
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k64z/steamstacks/market"
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// listing is a market listing. price is what the seller receives, in cents.
+type listing struct {
+	id      string
+	owner   steamid.SteamID
+	item    Item
+	price   int
+	pending bool // awaiting mobile confirmation
+	created time.Time
+}
+
+// Listing is a market listing as the server holds it.
+type Listing struct {
+	ID      string
+	Owner   steamid.SteamID
+	Item    Item
+	Price   int  // seller receives, in cents
+	Pending bool // awaiting mobile confirmation
+}
+
+// PriceOverview is the /market/priceoverview/ answer for one item. The
+// prices are the localized strings Steam returns, e.g. "$1.50".
+type PriceOverview struct {
+	LowestPrice string
+	MedianPrice string
+	Volume      string
+}
+
+// SetPriceOverview sets the price overview served for an item.
+func (s *Server) SetPriceOverview(appID int, marketHashName string, p PriceOverview) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prices[priceKey(appID, marketHashName)] = p
+}
+
+// Listings returns the owner's market listings, pending ones included,
+// oldest first.
+func (s *Server) Listings(owner steamid.SteamID) []Listing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Listing
+	for _, l := range s.listingsLocked(owner) {
+		out = append(out, l.export())
+	}
+	return out
+}
+
+// FillListing buys an active listing: the item leaves the market and the
+// seller's wallet is credited with the listing price.
+func (s *Server) FillListing(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.listings[id]
+	if l == nil || l.pending {
+		return fmt.Errorf("steamtest: no active listing %s", id)
+	}
+	delete(s.listings, id)
+	if a := s.accounts[l.owner]; a != nil {
+		a.WalletBalance += l.price
+	}
+	return nil
+}
+
+func (s *Server) registerMarket(mux *http.ServeMux) {
+	mux.HandleFunc("GET /market/priceoverview/", s.handlePriceOverview)
+	mux.HandleFunc("POST /market/sellitem/", s.handleSellItem)
+	mux.HandleFunc("GET /market/{$}", s.handleMarketHome)
+	mux.HandleFunc("GET /market/mylistings/render/", s.handleMyListings)
+	mux.HandleFunc("POST /market/removelisting/{id}", s.handleRemoveListing)
+}
+
+func (s *Server) handlePriceOverview(w http.ResponseWriter, r *http.Request) {
+	appID, _ := strconv.Atoi(r.URL.Query().Get("appid"))
+
+	s.mu.Lock()
+	p, ok := s.prices[priceKey(appID, r.URL.Query().Get("market_hash_name"))]
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, map[string]any{"success": false})
+		return
+	}
+	writeJSON(w, map[string]any{
+		"success":      true,
+		"lowest_price": p.LowestPrice,
+		"median_price": p.MedianPrice,
+		"volume":       p.Volume,
+	})
+}
+
+func (s *Server) handleSellItem(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok || !checkSessionID(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	appID, _ := strconv.Atoi(r.FormValue("appid"))
+	price, err := strconv.Atoi(r.FormValue("price"))
+	if err != nil || price < 1 {
+		writeJSON(w, map[string]any{"success": false, "message": "There was a problem listing your item. Refresh the page and try again."})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.lastSell[user]; ok && time.Since(last) < s.accounts[user].SellCooldown {
+		writeJSON(w, map[string]any{"success": false, "message": "You cannot sell any items until your previous action completes."})
+		return
+	}
+	s.lastSell[user] = time.Now()
+
+	i := s.findItemLocked(user, appID, r.FormValue("contextid"), r.FormValue("assetid"))
+	if i < 0 || !s.inventories[user][i].Marketable {
+		writeJSON(w, map[string]any{"success": false, "message": "The item specified is no longer in your inventory or is not allowed to be traded on the Community Market."})
+		return
+	}
+	item := *s.inventories[user][i]
+	s.inventories[user] = slices.Delete(s.inventories[user], i, i+1)
+
+	needsConfirmation := s.accounts[user].IdentitySecret != nil
+	l := &listing{
+		id:      s.newIDLocked(),
+		owner:   user,
+		item:    item,
+		price:   price,
+		pending: needsConfirmation,
+		created: time.Now(),
+	}
+	s.listings[l.id] = l
+	s.addMarketEventLocked(user, marketEvent{
+		typ:       marketEventListed,
+		listingID: l.id,
+		actor:     user,
+		item:      item,
+		price:     price,
+		currency:  s.accounts[user].WalletCurrency,
+	})
+	if needsConfirmation {
+		s.addConfirmationLocked(user, confTypeMarketListing, l.id,
+			"Sell - "+item.Name, []string{formatCents(buyerPrice(price))})
+	} else {
+		s.matchBuyOrdersLocked(l)
+	}
+
+	writeJSON(w, map[string]any{
+		"success":                   true,
+		"requires_confirmation":     boolInt(needsConfirmation),
+		"needs_mobile_confirmation": needsConfirmation,
+		"needs_email_confirmation":  false,
+	})
+}
+
+// handleMarketHome renders the parts of /market/ that GetMyMarketListingIDs
+// and GetMyPendingMarketListings scrape: one row per listing, with the
+// cancel link Steam uses for pending rows.
+func (s *Server) handleMarketHome(w http.ResponseWriter, r *http.Request) {
+	user, _ := s.cookieUser(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("<html><body>\n")
+	s.writeWalletInfoScript(&b, user)
+	for _, l := range s.listingsLocked(user) {
+		fmt.Fprintf(&b, "<div class=\"market_listing_row market_recent_listing_row listing_%s\" id=\"mylisting_%s\">\n", l.id, l.id)
+		if l.pending {
+			fmt.Fprintf(&b, "<a href=\"javascript:CancelMarketListingConfirmation('mylisting', '%s', %d, '%s', '%s')\">Cancel</a>\n",
+				l.id, l.item.AppID, l.item.ContextID, l.item.AssetID)
+		} else {
+			fmt.Fprintf(&b, "<a href=\"javascript:RemoveMarketListing('mylisting', '%s', %d, '%s', '%s')\">Remove</a>\n",
+				l.id, l.item.AppID, l.item.ContextID, l.item.AssetID)
+		}
+		b.WriteString("</div>\n")
+	}
+	b.WriteString("</body></html>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// handleMyListings serves the active listings page by page, like
+// /market/mylistings/render/.
+func (s *Server) handleMyListings(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok {
+		writeJSON(w, map[string]any{"success": false})
+		return
+	}
+	start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+	count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+	if count <= 0 || count > 100 {
+		count = 100
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var active []*listing
+	for _, l := range s.listingsLocked(user) {
+		if !l.pending {
+			active = append(active, l)
+		}
+	}
+	total := len(active)
+	active = active[min(start, total):min(start+count, total)]
+
+	var b strings.Builder
+	assets := map[string]map[string]map[string]any{}
+	for _, l := range active {
+		it := l.item
+		fmt.Fprintf(&b, `<div class="market_listing_row market_recent_listing_row listing_%s" id="mylisting_%s">
+	<span class="market_listing_price"><span><span title="This is the price the buyer pays.">%s</span><br><span title="This is how much you will receive.">(%s)</span></span></span>
+	<div class="market_listing_listed_date_combined">Listed: %s</div>
+	<a class="market_listing_item_name_link" href="#">%s</a>
+	<a href="javascript:RemoveMarketListing('mylisting', '%s', %d, '%s', '%s')">Remove</a>
+</div>
+`, l.id, l.id, formatCents(buyerPrice(l.price)), formatCents(l.price), l.created.Format("2 Jan"),
+			html.EscapeString(it.MarketHashName), l.id, it.AppID, it.ContextID, it.AssetID)
+
+		app := strconv.Itoa(it.AppID)
+		if assets[app] == nil {
+			assets[app] = map[string]map[string]any{}
+		}
+		if assets[app][it.ContextID] == nil {
+			assets[app][it.ContextID] = map[string]any{}
+		}
+		assets[app][it.ContextID][it.AssetID] = map[string]any{
+			"appid":            it.AppID,
+			"contextid":        it.ContextID,
+			"id":               it.AssetID,
+			"classid":          it.ClassID,
+			"instanceid":       it.InstanceID,
+			"amount":           strconv.Itoa(it.Amount),
+			"market_hash_name": it.MarketHashName,
+			"name":             it.Name,
+		}
+	}
+
+	writeJSON(w, map[string]any{
+		"success":      true,
+		"start":        start,
+		"pagesize":     count,
+		"total_count":  total,
+		"results_html": b.String(),
+		"assets":       assets,
+	})
+}
+
+func (s *Server) handleRemoveListing(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok || !checkSessionID(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.listings[r.PathValue("id")]
+	if l == nil || l.owner != user {
+		http.Error(w, "listing not found", http.StatusBadRequest)
+		return
+	}
+	s.cancelListingLocked(l)
+	writeJSON(w, []any{})
+}
+
+// resolveListingConfirmationLocked activates a confirmed listing or
+// cancels a rejected one.
+func (s *Server) resolveListingConfirmationLocked(id string, allow bool) {
+	l := s.listings[id]
+	if l == nil || !l.pending {
+		return
+	}
+	if allow {
+		l.pending = false
+		s.matchBuyOrdersLocked(l)
+		return
+	}
+	s.cancelListingLocked(l)
+}
+
+// cancelListingLocked takes a listing off the market and returns its item
+// to the owner's inventory.
+func (s *Server) cancelListingLocked(l *listing) {
+	delete(s.listings, l.id)
+	s.addMarketEventLocked(l.owner, marketEvent{
+		typ:       marketEventCancelled,
+		listingID: l.id,
+		actor:     l.owner,
+		item:      l.item,
+		price:     l.price,
+		currency:  s.accounts[l.owner].WalletCurrency,
+	})
+	s.removeConfirmationsLocked(l.id)
+	it := l.item
+	s.inventories[l.owner] = append(s.inventories[l.owner], &it)
+}
+
+func (s *Server) listingsLocked(owner steamid.SteamID) []*listing {
+	var out []*listing
+	for _, l := range s.listings {
+		if l.owner == owner {
+			out = append(out, l)
+		}
+	}
+	slices.SortFunc(out, func(a, b *listing) int { return strings.Compare(a.id, b.id) })
+	return out
+}
+
+func (l *listing) export() Listing {
+	return Listing{ID: l.id, Owner: l.owner, Item: l.item, Price: l.price, Pending: l.pending}
+}
+
+func priceKey(appID int, marketHashName string) string {
+	return strconv.Itoa(appID) + "/" + marketHashName
+}
+
+// buyerPrice adds the default Steam (5%) and publisher (10%) fees to what
+// the seller receives.
+func buyerPrice(cents int) int {
+	return market.DefaultFees.BuyerPays(cents).BuyerPays
+}
+
+// marketFees returns the Steam and publisher fees buyerPrice adds.
+func marketFees(cents int) (steamFee, publisherFee int) {
+	p := market.DefaultFees.BuyerPays(cents)
+	return p.SteamFee, p.PublisherFee
+}
+
+func formatCents(cents int) string {
+	return fmt.Sprintf("$%d.%02d", cents/100, cents%100)
+}
@@ -0,0 +1,299 @@
+package steamtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// buyOrder is a standing order to buy an item at up to price cents a unit,
+// fees included.
+type buyOrder struct {
+	id             string
+	owner          steamid.SteamID
+	appID          int
+	marketHashName string
+	price          int
+	quantity       int
+	purchases      []buyOrderPurchase
+	canceled       bool
+}
+
+type buyOrderPurchase struct {
+	listingID string
+	item      Item // as received by the buyer
+	price     int  // what the seller received
+	total     int  // what the buyer paid
+}
+
+func (o *buyOrder) active() bool {
+	return !o.canceled && len(o.purchases) < o.quantity
+}
+
+func (s *Server) registerMarketBuy(mux *http.ServeMux) {
+	mux.HandleFunc("POST /market/createbuyorder/", s.handleCreateBuyOrder)
+	mux.HandleFunc("POST /market/cancelbuyorder/", s.handleCancelBuyOrder)
+	mux.HandleFunc("GET /market/getbuyorderstatus/", s.handleBuyOrderStatus)
+	mux.HandleFunc("POST /market/buylisting/{id}", s.handleBuyListing)
+}
+
+// walletInfoLocked is the account's g_rgWalletInfo.
+func (s *Server) walletInfoLocked(user steamid.SteamID) map[string]any {
+	a := s.accounts[user]
+	return map[string]any{
+		"wallet_currency":        a.WalletCurrency,
+		"wallet_country":         "US",
+		"wallet_balance":         strconv.Itoa(a.WalletBalance),
+		"wallet_delayed_balance": "0",
+		"wallet_max_balance":     "200000",
+		"wallet_fee":             1,
+		"wallet_fee_minimum":     "1",
+		"wallet_fee_percent":     "0.05",
+		"wallet_fee_base":        "0",
+		"success":                eresultOK,
+
+		"wallet_publisher_fee_percent_default": "0.10",
+	}
+}
+
+// writeWalletInfoScript renders the script tag the market page sets
+// g_rgWalletInfo in.
+func (s *Server) writeWalletInfoScript(b *strings.Builder, user steamid.SteamID) {
+	if s.accounts[user] == nil {
+		return
+	}
+	info, _ := json.Marshal(s.walletInfoLocked(user))
+	fmt.Fprintf(b, "<script type=\"text/javascript\">\n\tvar g_rgWalletInfo = %s;\n</script>\n", info)
+}
+
+func (s *Server) handleCreateBuyOrder(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok || !checkSessionID(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	appID, _ := strconv.Atoi(r.FormValue("appid"))
+	currency, _ := strconv.Atoi(r.FormValue("currency"))
+	total, _ := strconv.Atoi(r.FormValue("price_total"))
+	quantity, _ := strconv.Atoi(r.FormValue("quantity"))
+	name := r.FormValue("market_hash_name")
+	if quantity < 1 || total < quantity || total%quantity != 0 || name == "" {
+		writeJSON(w, map[string]any{"success": eresultInvalidParam, "message": "Sorry! We had trouble hearing back from the Steam servers about your order. Double check whether or not your order has actually been created or filled. If not, then please try again later."})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acct := s.accounts[user]
+	if currency != acct.WalletCurrency {
+		writeJSON(w, map[string]any{"success": eresultInvalidParam, "message": "The currency of your order does not match the currency of your Steam Wallet."})
+		return
+	}
+	if total > acct.WalletBalance {
+		writeJSON(w, map[string]any{"success": eresultFail, "message": "You do not have enough funds in your Steam Wallet to place this order."})
+		return
+	}
+	for _, o := range s.buyOrders {
+		if o.owner == user && o.appID == appID && o.marketHashName == name && o.active() {
+			writeJSON(w, map[string]any{"success": eresultFail, "message": "You already have an active buy order for this item. You will need to either cancel that order, or wait for it to be fulfilled before you can place a new order."})
+			return
+		}
+	}
+
+	o := &buyOrder{
+		id:             s.newIDLocked(),
+		owner:          user,
+		appID:          appID,
+		marketHashName: name,
+		price:          total / quantity,
+		quantity:       quantity,
+	}
+	s.buyOrders[o.id] = o
+
+	// Fill what the listings already on the market can, cheapest first.
+	var candidates []*listing
+	for _, l := range s.listings {
+		if s.listingFillsLocked(l, o) {
+			candidates = append(candidates, l)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b *listing) int { return a.price - b.price })
+	for _, l := range candidates {
+		if !o.active() || acct.WalletBalance < buyerPrice(l.price) {
+			break
+		}
+		s.fillBuyOrderLocked(o, l)
+	}
+
+	writeJSON(w, map[string]any{"success": eresultOK, "buy_orderid": o.id})
+}
+
+func (s *Server) handleCancelBuyOrder(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok || !checkSessionID(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.buyOrders[r.FormValue("buy_orderid")]
+	if o == nil || o.owner != user || !o.active() {
+		writeJSON(w, map[string]any{"success": eresultFail})
+		return
+	}
+	o.canceled = true
+	writeJSON(w, map[string]any{"success": eresultOK})
+}
+
+func (s *Server) handleBuyOrderStatus(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok || r.URL.Query().Get("sessionid") == "" {
+		writeJSON(w, map[string]any{"success": eresultFail})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.buyOrders[r.URL.Query().Get("buy_orderid")]
+	if o == nil || o.owner != user {
+		writeJSON(w, map[string]any{"success": eresultFail})
+		return
+	}
+	purchases := []map[string]any{}
+	for _, p := range o.purchases {
+		purchases = append(purchases, map[string]any{
+			"listingid":      p.listingID,
+			"appid":          p.item.AppID,
+			"contextid":      p.item.ContextID,
+			"assetid":        p.item.AssetID,
+			"price_subtotal": p.price,
+			"price_fee":      p.total - p.price,
+			"price_total":    p.total,
+		})
+	}
+	writeJSON(w, map[string]any{
+		"success":            eresultOK,
+		"active":             boolInt(o.active()),
+		"purchased":          len(o.purchases),
+		"quantity":           strconv.Itoa(o.quantity),
+		"quantity_remaining": strconv.Itoa(o.quantity - len(o.purchases)),
+		"purchases":          purchases,
+	})
+}
+
+// handleBuyListing buys a listing. Like Steam, it fails with a 502 and a
+// message when the amounts don't match the listing's current price.
+func (s *Server) handleBuyListing(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok || !checkSessionID(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	currency, _ := strconv.Atoi(r.FormValue("currency"))
+	subtotal, _ := strconv.Atoi(r.FormValue("subtotal"))
+	fee, _ := strconv.Atoi(r.FormValue("fee"))
+	total, _ := strconv.Atoi(r.FormValue("total"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fail := func(message string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]any{"message": message})
+	}
+
+	acct := s.accounts[user]
+	l := s.listings[r.PathValue("id")]
+	switch {
+	case currency != acct.WalletCurrency:
+		fail("The currency of your purchase does not match the currency of your Steam Wallet.")
+		return
+	case l == nil || l.pending || l.owner == user || subtotal != l.price || total != buyerPrice(l.price) || subtotal+fee != total:
+		fail("There was a problem purchasing your item. The listing may have been removed. Refresh the page and try again.")
+		return
+	case total > acct.WalletBalance:
+		fail("You do not have enough funds in your Steam Wallet to purchase this item.")
+		return
+	}
+
+	s.sellListingLocked(l, user)
+	writeJSON(w, map[string]any{"wallet_info": s.walletInfoLocked(user)})
+}
+
+// listingFillsLocked reports whether an active listing can fill the buy
+// order.
+func (s *Server) listingFillsLocked(l *listing, o *buyOrder) bool {
+	return !l.pending && l.owner != o.owner && l.item.AppID == o.appID &&
+		l.item.MarketHashName == o.marketHashName && buyerPrice(l.price) <= o.price
+}
+
+// matchBuyOrdersLocked fills the best-paying buy order a newly active
+// listing satisfies, if any.
+func (s *Server) matchBuyOrdersLocked(l *listing) {
+	var best *buyOrder
+	for _, o := range s.buyOrders {
+		if !o.active() || !s.listingFillsLocked(l, o) || s.accounts[o.owner].WalletBalance < buyerPrice(l.price) {
+			continue
+		}
+		if best == nil || o.price > best.price || o.price == best.price && o.id < best.id {
+			best = o
+		}
+	}
+	if best != nil {
+		s.fillBuyOrderLocked(best, l)
+	}
+}
+
+func (s *Server) fillBuyOrderLocked(o *buyOrder, l *listing) {
+	item := s.sellListingLocked(l, o.owner)
+	o.purchases = append(o.purchases, buyOrderPurchase{
+		listingID: l.id,
+		item:      item,
+		price:     l.price,
+		total:     buyerPrice(l.price),
+	})
+}
+
+// sellListingLocked completes the sale of a listing to buyer: the buyer
+// pays the buyer price and gets the item under a new asset ID, the seller
+// is credited with the listing price.
+func (s *Server) sellListingLocked(l *listing, buyer steamid.SteamID) Item {
+	delete(s.listings, l.id)
+	s.accounts[buyer].WalletBalance -= buyerPrice(l.price)
+	if a := s.accounts[l.owner]; a != nil {
+		a.WalletBalance += l.price
+	}
+	item := l.item
+	item.AssetID = s.newIDLocked()
+	it := item
+	s.inventories[buyer] = append(s.inventories[buyer], &it)
+
+	sale := marketEvent{
+		listingID:  l.id,
+		purchaseID: s.newIDLocked(),
+		purchaser:  buyer,
+		item:       l.item,
+		newAssetID: item.AssetID,
+		price:      l.price,
+		currency:   s.accounts[buyer].WalletCurrency,
+	}
+	if a := s.accounts[l.owner]; a != nil {
+		sale.received = a.WalletCurrency
+	}
+	sold, purchased := sale, sale
+	sold.typ, sold.actor = marketEventSold, buyer
+	purchased.typ, purchased.actor = marketEventPurchased, l.owner
+	s.addMarketEventLocked(l.owner, sold)
+	s.addMarketEventLocked(buyer, purchased)
+	return item
+}
@@ -0,0 +1,156 @@
+package steamtest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// PricePoint is one sample of an item's /market/pricehistory/.
+type PricePoint struct {
+	Time   time.Time // truncated to the hour, UTC
+	Median int       // in cents
+	Volume int
+}
+
+// SetPriceHistory sets the price history served for an item.
+func (s *Server) SetPriceHistory(appID int, marketHashName string, points []PricePoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.priceHistory[priceKey(appID, marketHashName)] = slices.Clone(points)
+}
+
+func (s *Server) registerMarketData(mux *http.ServeMux) {
+	mux.HandleFunc("GET /market/listings/{appid}/{name}", s.handleListingPage)
+	mux.HandleFunc("GET /market/itemordershistogram", s.handleOrdersHistogram)
+	mux.HandleFunc("GET /market/pricehistory/", s.handlePriceHistory)
+}
+
+// handleListingPage renders the script call of an item's listing page
+// that carries its item_nameid. IDs are handed out on first view.
+func (s *Server) handleListingPage(w http.ResponseWriter, r *http.Request) {
+	appID, _ := strconv.Atoi(r.PathValue("appid"))
+	key := priceKey(appID, r.PathValue("name"))
+
+	s.mu.Lock()
+	id, ok := s.itemNameIDs[key]
+	if !ok {
+		id = s.newIDLocked()
+		s.itemNameIDs[key] = id
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<html><body>\n<script type=\"text/javascript\">\n\t$J(function() {\n\t\tMarket_LoadOrderSpread( %s );\t// initial load\n\t});\n</script>\n</body></html>\n", id)
+}
+
+// handleOrdersHistogram builds the order book from the active listings,
+// at the price buyers pay, and the open buy orders.
+func (s *Server) handleOrdersHistogram(w http.ResponseWriter, r *http.Request) {
+	nameID := r.URL.Query().Get("item_nameid")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var key string
+	for k, id := range s.itemNameIDs {
+		if id == nameID {
+			key = k
+		}
+	}
+	if key == "" {
+		writeJSON(w, map[string]any{"success": eresultFail})
+		return
+	}
+
+	sells := map[int]int{}
+	for _, l := range s.listings {
+		if !l.pending && priceKey(l.item.AppID, l.item.MarketHashName) == key {
+			sells[buyerPrice(l.price)]++
+		}
+	}
+	buys := map[int]int{}
+	for _, o := range s.buyOrders {
+		if o.active() && priceKey(o.appID, o.marketHashName) == key {
+			buys[o.price] += o.quantity - len(o.purchases)
+		}
+	}
+
+	resp := map[string]any{
+		"success":           eresultOK,
+		"highest_buy_order": nil,
+		"lowest_sell_order": nil,
+		"buy_order_graph":   orderGraph(buys, true),
+		"sell_order_graph":  orderGraph(sells, false),
+		"price_prefix":      "$",
+		"price_suffix":      "",
+	}
+	if len(buys) > 0 {
+		resp["highest_buy_order"] = strconv.Itoa(slices.Max(keys(buys)))
+	}
+	if len(sells) > 0 {
+		resp["lowest_sell_order"] = strconv.Itoa(slices.Min(keys(sells)))
+	}
+	writeJSON(w, resp)
+}
+
+// orderGraph renders quantities by price as Steam's graph: best price
+// first, cumulative quantities, prices as floats.
+func orderGraph(byPrice map[int]int, descending bool) [][]any {
+	prices := keys(byPrice)
+	slices.Sort(prices)
+	if descending {
+		slices.Reverse(prices)
+	}
+	graph := [][]any{}
+	total := 0
+	for _, p := range prices {
+		total += byPrice[p]
+		graph = append(graph, []any{float64(p) / 100, total, fmt.Sprintf("%d at %s", total, formatCents(p))})
+	}
+	return graph
+}
+
+func keys(m map[int]int) []int {
+	out := make([]int, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+func (s *Server) handlePriceHistory(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.cookieUser(r); !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("[]"))
+		return
+	}
+	q := r.URL.Query()
+	appID, _ := strconv.Atoi(q.Get("appid"))
+
+	s.mu.Lock()
+	points := s.priceHistory[priceKey(appID, q.Get("market_hash_name"))]
+	s.mu.Unlock()
+
+	if points == nil {
+		writeJSON(w, map[string]any{"success": false})
+		return
+	}
+	prices := [][]any{}
+	for _, p := range points {
+		prices = append(prices, []any{
+			p.Time.UTC().Format("Jan 02 2006 15") + ": +0",
+			float64(p.Median) / 100,
+			strconv.Itoa(p.Volume),
+		})
+	}
+	writeJSON(w, map[string]any{
+		"success":      true,
+		"price_prefix": "$",
+		"price_suffix": "",
+		"prices":       prices,
+	})
+}
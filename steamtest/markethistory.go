@@ -0,0 +1,156 @@
+package steamtest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// Market history event types, as in the event_type field.
+const (
+	marketEventListed    = 1
+	marketEventCancelled = 2
+	marketEventSold      = 3
+	marketEventPurchased = 4
+)
+
+// marketEvent is a row of an account's market history. The listing is
+// copied in, since sold and cancelled listings are gone from the market.
+type marketEvent struct {
+	typ        int
+	at         time.Time
+	seq        int64 // breaks ties between events in the same second
+	listingID  string
+	purchaseID string // "0" for listed and cancelled events
+	actor      steamid.SteamID
+	purchaser  steamid.SteamID
+	item       Item
+	newAssetID string
+	price      int // what the seller receives
+	currency   int // the buyer's for sales; the fake doesn't convert prices
+	received   int // the seller's currency, for sales
+}
+
+func (s *Server) addMarketEventLocked(owner steamid.SteamID, e marketEvent) {
+	s.nextEventSeq++
+	e.seq = s.nextEventSeq
+	e.at = time.Now()
+	if e.purchaseID == "" {
+		e.purchaseID = "0"
+	}
+	s.marketEvents[owner] = append(s.marketEvents[owner], &e)
+}
+
+func (s *Server) registerMarketHistory(mux *http.ServeMux) {
+	mux.HandleFunc("GET /market/myhistory/render/", s.handleMyHistory)
+}
+
+// handleMyHistory serves the history newest first, with the norender=1
+// shape: events plus the listings, purchases and assets they refer to.
+func (s *Server) handleMyHistory(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok {
+		writeJSON(w, map[string]any{"success": false})
+		return
+	}
+	start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+	count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+	if count <= 0 || count > 500 {
+		count = 100
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := s.marketEvents[user]
+	total := len(all)
+	lo, hi := max(0, total-start-count), max(0, total-start)
+
+	events := []map[string]any{}
+	listings := map[string]any{}
+	purchases := map[string]any{}
+	assets := map[string]map[string]map[string]any{}
+	for i := hi - 1; i >= lo; i-- {
+		e := all[i]
+		events = append(events, map[string]any{
+			"listingid":           e.listingID,
+			"purchaseid":          e.purchaseID,
+			"event_type":          e.typ,
+			"time_event":          e.at.Unix(),
+			"time_event_fraction": e.seq,
+			"steamid_actor":       strconv.FormatUint(e.actor.ToSteamID64(), 10),
+			"date_event":          e.at.Format("2 Jan"),
+		})
+
+		it := e.item
+		steamFee, publisherFee := marketFees(e.price)
+		asset := map[string]any{
+			"currency":  0,
+			"appid":     it.AppID,
+			"contextid": it.ContextID,
+			"id":        it.AssetID,
+			"amount":    strconv.Itoa(it.Amount),
+		}
+		if e.typ == marketEventSold || e.typ == marketEventPurchased {
+			asset["new_id"] = e.newAssetID
+			asset["new_contextid"] = it.ContextID
+			purchases[e.listingID+"_"+e.purchaseID] = map[string]any{
+				"listingid":         e.listingID,
+				"purchaseid":        e.purchaseID,
+				"time_sold":         e.at.Unix(),
+				"steamid_purchaser": strconv.FormatUint(e.purchaser.ToSteamID64(), 10),
+				"paid_amount":       e.price,
+				"paid_fee":          steamFee + publisherFee,
+				"steam_fee":         steamFee,
+				"publisher_fee":     publisherFee,
+				"currencyid":        strconv.Itoa(2000 + e.currency),
+				"received_amount":   e.price,
+				"asset":             asset,
+
+				"received_currencyid":   strconv.Itoa(2000 + e.received),
+				"publisher_fee_percent": "0.100000001490116119",
+			}
+		} else {
+			listings[e.listingID] = map[string]any{
+				"listingid":     e.listingID,
+				"price":         e.price,
+				"fee":           steamFee + publisherFee,
+				"steam_fee":     steamFee,
+				"publisher_fee": publisherFee,
+				"currencyid":    2000 + e.currency,
+				"asset":         asset,
+			}
+		}
+
+		app := strconv.Itoa(it.AppID)
+		if assets[app] == nil {
+			assets[app] = map[string]map[string]any{}
+		}
+		if assets[app][it.ContextID] == nil {
+			assets[app][it.ContextID] = map[string]any{}
+		}
+		assets[app][it.ContextID][it.AssetID] = map[string]any{
+			"appid":            it.AppID,
+			"contextid":        it.ContextID,
+			"id":               it.AssetID,
+			"classid":          it.ClassID,
+			"instanceid":       it.InstanceID,
+			"amount":           strconv.Itoa(it.Amount),
+			"market_hash_name": it.MarketHashName,
+			"name":             it.Name,
+		}
+	}
+
+	writeJSON(w, map[string]any{
+		"success":     true,
+		"pagesize":    count,
+		"total_count": total,
+		"start":       start,
+		"events":      events,
+		"listings":    listings,
+		"purchases":   purchases,
+		"assets":      assets,
+	})
+}
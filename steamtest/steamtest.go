@@ -0,0 +1,293 @@
+// Package steamtest runs an in-process fake of the Steam web hosts for
+// integration tests: the community site, the Web API, the store and the
+// login endpoints, all served by one httptest server.
+//
+// The server keeps state per account (inventories, trade offers, mobile
+// confirmations, market listings and the wallet), so flows that span
+// several hosts run end to end offline:
+//
+//	srv := steamtest.NewServer()
+//	defer srv.Close()
+//
+//	alice := srv.AddAccount(steamtest.Account{IdentitySecret: secret})
+//	community, _ := steamcommunity.New(
+//		steamcommunity.WithHTTPClient(srv.Client(alice.SteamID)),
+//		steamcommunity.WithHosts(srv.Hosts()),
+//	)
+package steamtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k64z/steamstacks/steamhost"
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// firstSteamID is the SteamID given to the first account added without one.
+const firstSteamID = 76561198000000001
+
+// Account is a Steam account known to the server.
+type Account struct {
+	SteamID     steamid.SteamID // assigned when zero
+	AccountName string          // defaults to "user<accountid>"
+	Password    string          // defaults to "password"
+
+	// SharedSecret enables Steam Guard: logins must then submit a code
+	// generated from it. Empty means no guard.
+	SharedSecret string
+
+	// IdentitySecret enables the mobile authenticator. Sent offers, accepted
+	// offers that give items and market listings then wait for a mobile
+	// confirmation keyed with it. Nil means they take effect immediately.
+	IdentitySecret []byte
+
+	// TradeToken is the token in the account's trade URL. When set, others
+	// must present it to load the inventory through partnerinventory.
+	TradeToken string
+
+	// InventoryPrivate hides the inventory from /inventory/ for everyone but
+	// its owner. partnerinventory still serves it.
+	InventoryPrivate bool
+
+	// EscrowDays is the trade hold, in days, on items the account gives.
+	EscrowDays int
+
+	WalletBalance  int // in cents
+	WalletCurrency int // Steam currency code, defaults to 1 (USD)
+
+	// SellCooldown makes the market refuse a listing that comes sooner
+	// than this after the account's previous one, the way Steam answers
+	// listings made too fast.
+	SellCooldown time.Duration
+}
+
+// Server is the fake Steam web server. Use Hosts to point the clients of
+// this module at it and Client for an HTTP client that is already logged in.
+type Server struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu           sync.Mutex
+	accounts     map[steamid.SteamID]*Account
+	tokens       map[string]steamid.SteamID // access and refresh tokens
+	authSessions map[uint64]*authSession
+	inventories  map[steamid.SteamID][]*Item
+	offers       map[string]*offer
+	trades       map[string]*trade
+	confs        map[steamid.SteamID][]*confirmation
+	listings     map[string]*listing
+	buyOrders    map[string]*buyOrder
+	marketEvents map[steamid.SteamID][]*marketEvent // oldest first
+	lastSell     map[steamid.SteamID]time.Time
+	prices       map[string]PriceOverview
+	priceHistory map[string][]PricePoint
+	itemNameIDs  map[string]string // appid/market_hash_name -> item_nameid
+	walletCodes  map[string]int    // unredeemed code -> cents
+	nextSteamID  uint64
+	nextID       uint64 // shared counter for asset, offer, trade and listing IDs
+	nextEventSeq int64
+}
+
+// NewServer starts a server listening on loopback. Like httptest.NewServer,
+// it panics if it cannot listen. Call Close when done.
+func NewServer() *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("steamtest: generate key: %v", err))
+	}
+
+	s := &Server{
+		key:          key,
+		accounts:     make(map[steamid.SteamID]*Account),
+		tokens:       make(map[string]steamid.SteamID),
+		authSessions: make(map[uint64]*authSession),
+		inventories:  make(map[steamid.SteamID][]*Item),
+		offers:       make(map[string]*offer),
+		trades:       make(map[string]*trade),
+		confs:        make(map[steamid.SteamID][]*confirmation),
+		listings:     make(map[string]*listing),
+		buyOrders:    make(map[string]*buyOrder),
+		marketEvents: make(map[steamid.SteamID][]*marketEvent),
+		lastSell:     make(map[steamid.SteamID]time.Time),
+		prices:       make(map[string]PriceOverview),
+		priceHistory: make(map[string][]PricePoint),
+		itemNameIDs:  make(map[string]string),
+		walletCodes:  make(map[string]int),
+		nextSteamID:  firstSteamID,
+		nextID:       1000000000,
+	}
+
+	mux := http.NewServeMux()
+	s.registerLogin(mux)
+	s.registerTrade(mux)
+	s.registerConfirmations(mux)
+	s.registerMarket(mux)
+	s.registerMarketBuy(mux)
+	s.registerMarketData(mux)
+	s.registerMarketHistory(mux)
+	s.registerStore(mux)
+	s.srv = httptest.NewServer(mux)
+
+	return s
+}
+
+// URL returns the base URL of the server.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Hosts maps every Steam host to the server, for the WithHosts options of
+// steamapi, steamcommunity, steamstore and steamsession.
+func (s *Server) Hosts() steamhost.Hosts {
+	return steamhost.Single(s.srv.URL)
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// AddAccount registers an account and returns it with defaults filled in.
+func (s *Server) AddAccount(a Account) Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a.SteamID == 0 {
+		a.SteamID = steamid.FromSteamID64(s.nextSteamID)
+		s.nextSteamID++
+	}
+	if a.AccountName == "" {
+		a.AccountName = fmt.Sprintf("user%d", a.SteamID.AccountID())
+	}
+	if a.Password == "" {
+		a.Password = "password"
+	}
+	if a.WalletCurrency == 0 {
+		a.WalletCurrency = 1
+	}
+
+	acct := a
+	s.accounts[a.SteamID] = &acct
+	return a
+}
+
+// Client returns an HTTP client whose cookie jar holds a web session for
+// the account, as if it had logged in. It panics if the account is unknown.
+func (s *Server) Client(owner steamid.SteamID) *http.Client {
+	s.mu.Lock()
+	if s.accounts[owner] == nil {
+		s.mu.Unlock()
+		panic(fmt.Sprintf("steamtest: unknown account %d", owner.ToSteamID64()))
+	}
+	token := s.issueTokenLocked(owner, time.Hour)
+	s.mu.Unlock()
+
+	jar, _ := cookiejar.New(nil)
+	u, _ := url.Parse(s.srv.URL)
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "sessionid", Value: randomHex(12), Path: "/"},
+		{Name: "steamLoginSecure", Value: loginSecureValue(owner, token), Path: "/"},
+	})
+	return &http.Client{Jar: jar}
+}
+
+// Wallet returns the account's wallet balance in cents.
+func (s *Server) Wallet(owner steamid.SteamID) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.accounts[owner]; a != nil {
+		return a.WalletBalance
+	}
+	return 0
+}
+
+// SetWallet sets the account's wallet balance in cents.
+func (s *Server) SetWallet(owner steamid.SteamID, cents int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.accounts[owner]; a != nil {
+		a.WalletBalance = cents
+	}
+}
+
+// newIDLocked returns a fresh numeric ID.
+func (s *Server) newIDLocked() string {
+	s.nextID++
+	return strconv.FormatUint(s.nextID, 10)
+}
+
+// issueTokenLocked mints a JWT-shaped token for owner. Only its sub and exp
+// claims mean anything; the signature is not checked by anyone.
+func (s *Server) issueTokenLocked(owner steamid.SteamID, ttl time.Duration) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"EdDSA"}`))
+	claims, _ := json.Marshal(map[string]any{
+		"iss": "steamtest",
+		"sub": strconv.FormatUint(owner.ToSteamID64(), 10),
+		"exp": time.Now().Add(ttl).Unix(),
+		"jti": randomHex(8),
+	})
+	token := header + "." + base64.RawURLEncoding.EncodeToString(claims) + "." + randomHex(16)
+	s.tokens[token] = owner
+	return token
+}
+
+// cookieUser authenticates a community or store request by its
+// steamLoginSecure cookie.
+func (s *Server) cookieUser(r *http.Request) (steamid.SteamID, bool) {
+	c, err := r.Cookie("steamLoginSecure")
+	if err != nil {
+		return 0, false
+	}
+	parts := strings.SplitN(c.Value, "%7C%7C", 2)
+	if len(parts) != 2 {
+		return 0, false
+	}
+	sid, ok := s.tokenUser(parts[1])
+	if !ok || strconv.FormatUint(sid.ToSteamID64(), 10) != parts[0] {
+		return 0, false
+	}
+	return sid, true
+}
+
+// tokenUser resolves an access token.
+func (s *Server) tokenUser(token string) (steamid.SteamID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sid, ok := s.tokens[token]
+	return sid, ok
+}
+
+// checkSessionID reports whether a form POST carries the sessionid cookie
+// value, as Steam requires for every state-changing community request.
+func checkSessionID(r *http.Request) bool {
+	c, err := r.Cookie("sessionid")
+	return err == nil && c.Value != "" && r.FormValue("sessionid") == c.Value
+}
+
+func loginSecureValue(owner steamid.SteamID, token string) string {
+	return fmt.Sprintf("%d%%7C%%7C%s", owner.ToSteamID64(), token)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,387 @@
+package steamtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamcommunity"
+	"github.com/k64z/steamstacks/steamsession"
+	"github.com/k64z/steamstacks/steamstore"
+	"github.com/k64z/steamstacks/steamtotp"
+)
+
+var testIdentitySecret = []byte("identity-secret-for-tests")
+
+func newCommunity(t *testing.T, srv *Server, client *http.Client) *steamcommunity.Community {
+	t.Helper()
+	c, err := steamcommunity.New(
+		steamcommunity.WithHTTPClient(client),
+		steamcommunity.WithHosts(srv.Hosts()),
+	)
+	if err != nil {
+		t.Fatalf("steamcommunity.New: %v", err)
+	}
+	return c
+}
+
+func TestLoginWithSharedSecret(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	sharedSecret := "c2hhcmVkLXNlY3JldC1mb3ItdGVzdHM="
+	alice := srv.AddAccount(Account{AccountName: "alice", Password: "hunter2", SharedSecret: sharedSecret})
+
+	ctx := context.Background()
+	sess, err := steamsession.New(
+		steamsession.WithHTTPClient(&http.Client{}),
+		steamsession.WithHosts(srv.Hosts()),
+	)
+	if err != nil {
+		t.Fatalf("steamsession.New: %v", err)
+	}
+
+	if err := sess.LoginWithSharedSecret(ctx, "alice", "wrong", sharedSecret); err == nil {
+		t.Fatal("login with wrong password: want error")
+	}
+	if err := sess.LoginWithSharedSecret(ctx, "alice", "hunter2", sharedSecret); err != nil {
+		t.Fatalf("LoginWithSharedSecret: %v", err)
+	}
+	if sess.SteamID != alice.SteamID {
+		t.Errorf("SteamID = %d, want %d", sess.SteamID, alice.SteamID)
+	}
+	if err := sess.GetWebCookies(ctx); err != nil {
+		t.Fatalf("GetWebCookies: %v", err)
+	}
+
+	// The web session works against the community endpoints, which answer
+	// success=false without a valid steamLoginSecure cookie.
+	community := newCommunity(t, srv, sess.HTTPClient())
+	if _, err := community.GetMarketListings(ctx, 0, 10); err != nil {
+		t.Fatalf("GetMarketListings: %v", err)
+	}
+}
+
+func TestTradeOfferFlow(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(Account{IdentitySecret: testIdentitySecret})
+	bob := srv.AddAccount(Account{})
+	hat := srv.AddItem(alice.SteamID, Item{AppID: 440, Name: "Team Captain", Tradable: true})
+	key := srv.AddItem(bob.SteamID, Item{AppID: 440, Name: "Mann Co. Supply Crate Key", Tradable: true})
+
+	ctx := context.Background()
+	aliceClient := srv.Client(alice.SteamID)
+	aliceCommunity := newCommunity(t, srv, aliceClient)
+	bobCommunity := newCommunity(t, srv, srv.Client(bob.SteamID))
+
+	sent, err := aliceCommunity.SendTradeOffer(ctx, steamcommunity.SendTradeOfferOptions{
+		Partner:        bob.SteamID,
+		Message:        "hat for key",
+		ItemsToGive:    []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: hat.AssetID}},
+		ItemsToReceive: []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: key.AssetID}},
+	})
+	if err != nil {
+		t.Fatalf("SendTradeOffer: %v", err)
+	}
+	if !sent.NeedsConfirmation {
+		t.Fatal("NeedsConfirmation = false, want true")
+	}
+
+	// Bob can't accept until Alice confirms.
+	if _, err := bobCommunity.AcceptTradeOffer(ctx, sent.TradeOfferID, alice.SteamID); err == nil {
+		t.Fatal("accept before confirmation: want error")
+	}
+
+	if err := aliceCommunity.AcceptConfirmationByCreatorID(ctx, testIdentitySecret, sent.TradeOfferID); err != nil {
+		t.Fatalf("AcceptConfirmationByCreatorID: %v", err)
+	}
+	accepted, err := bobCommunity.AcceptTradeOffer(ctx, sent.TradeOfferID, alice.SteamID)
+	if err != nil {
+		t.Fatalf("AcceptTradeOffer: %v", err)
+	}
+
+	api, err := steamapi.New(steamapi.WithHTTPClient(aliceClient), steamapi.WithHosts(srv.Hosts()))
+	if err != nil {
+		t.Fatalf("steamapi.New: %v", err)
+	}
+	offer, err := api.GetTradeOffer(ctx, sent.TradeOfferID)
+	if err != nil {
+		t.Fatalf("GetTradeOffer: %v", err)
+	}
+	if offer.State != steamapi.ETradeOfferStateAccepted {
+		t.Errorf("State = %v, want Accepted", offer.State)
+	}
+	if offer.TradeID == "" || offer.TradeID != accepted.TradeID {
+		t.Errorf("TradeID = %q, want %q", offer.TradeID, accepted.TradeID)
+	}
+
+	status, err := api.GetTradeStatus(ctx, offer.TradeID)
+	if err != nil {
+		t.Fatalf("GetTradeStatus: %v", err)
+	}
+	newKeyID := status.Trade.ReceivedAssetIDs()[key.AssetID]
+	if status.Trade.SteamIDOther != bob.SteamID.ToSteamID64() || newKeyID == "" {
+		t.Errorf("trade = %+v, want the key received from bob", status.Trade)
+	}
+	if got := status.Descriptions[status.Trade.AssetsReceived[0].DescriptionKey()].Name; got != key.Name {
+		t.Errorf("received item name = %q, want %q", got, key.Name)
+	}
+
+	aliceInv := srv.Inventory(alice.SteamID, 440, "2")
+	if len(aliceInv) != 1 || aliceInv[0].Name != key.Name {
+		t.Errorf("alice inventory = %+v, want the key", aliceInv)
+	}
+	if aliceInv[0].AssetID == key.AssetID || aliceInv[0].AssetID != newKeyID {
+		t.Errorf("key asset ID = %s, want %s from the receipt", aliceInv[0].AssetID, newKeyID)
+	}
+
+	history, err := api.GetTradeHistory(ctx, steamapi.GetTradeHistoryOptions{IncludeTotal: true})
+	if err != nil {
+		t.Fatalf("GetTradeHistory: %v", err)
+	}
+	if history.TotalTrades != 1 || len(history.Trades) != 1 || history.Trades[0].TradeID != offer.TradeID {
+		t.Errorf("history = %+v, want the one trade", history)
+	}
+	if bobInv := srv.Inventory(bob.SteamID, 440, "2"); len(bobInv) != 1 || bobInv[0].Name != hat.Name {
+		t.Errorf("bob inventory = %+v, want the hat", bobInv)
+	}
+}
+
+func TestCounterOfferAndPartnerInventory(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(Account{AccountName: "alice", EscrowDays: 15})
+	bob := srv.AddAccount(Account{AccountName: "bob", TradeToken: "bobtoken", InventoryPrivate: true})
+	hat := srv.AddItem(alice.SteamID, Item{AppID: 440, Name: "Team Captain", Tradable: true})
+	key := srv.AddItem(bob.SteamID, Item{AppID: 440, Name: "Mann Co. Supply Crate Key", Tradable: true})
+	srv.AddItem(bob.SteamID, Item{AppID: 440, Name: "Refined Metal", Tradable: true})
+
+	ctx := context.Background()
+	aliceCommunity := newCommunity(t, srv, srv.Client(alice.SteamID))
+	bobCommunity := newCommunity(t, srv, srv.Client(bob.SteamID))
+
+	if _, err := aliceCommunity.GetInventory(ctx, bob.SteamID, 440, "2"); err == nil {
+		t.Fatal("GetInventory of private inventory: want error")
+	}
+	if _, err := aliceCommunity.GetPartnerInventory(ctx, bob.SteamID, "stale", 440, "2"); err == nil {
+		t.Fatal("GetPartnerInventory with wrong token: want error")
+	}
+	inv, err := aliceCommunity.GetPartnerInventory(ctx, bob.SteamID, "bobtoken", 440, "2")
+	if err != nil {
+		t.Fatalf("GetPartnerInventory: %v", err)
+	}
+	if len(inv) != 2 || inv[0].AssetID != key.AssetID || inv[0].Name != key.Name {
+		t.Errorf("partner inventory = %+v, want the key first", inv)
+	}
+
+	details, err := aliceCommunity.GetUserDetails(ctx, bob.SteamID, "bobtoken")
+	if err != nil {
+		t.Fatalf("GetUserDetails: %v", err)
+	}
+	if details.MyEscrowDays != 15 || details.TheirEscrowDays != 0 || details.TheirPersonaName != "bob" {
+		t.Errorf("details = %+v, want 15/0 days with bob", details)
+	}
+	var tradeErr *steamcommunity.TradeOfferError
+	if _, err := aliceCommunity.GetUserDetails(ctx, bob.SteamID, "stale"); !errors.As(err, &tradeErr) {
+		t.Errorf("GetUserDetails with wrong token: err = %v, want *TradeOfferError", err)
+	}
+
+	sent, err := aliceCommunity.SendTradeOffer(ctx, steamcommunity.SendTradeOfferOptions{
+		Partner:        bob.SteamID,
+		Token:          "bobtoken",
+		ItemsToGive:    []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: hat.AssetID}},
+		ItemsToReceive: []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: inv[0].AssetID}, {AppID: 440, ContextID: "2", AssetID: inv[1].AssetID}},
+	})
+	if err != nil {
+		t.Fatalf("SendTradeOffer: %v", err)
+	}
+
+	// Alice can't counter her own offer.
+	if _, err := aliceCommunity.CounterTradeOffer(ctx, sent.TradeOfferID, steamcommunity.SendTradeOfferOptions{Partner: bob.SteamID}); err == nil {
+		t.Fatal("counter own offer: want error")
+	}
+	counter, err := bobCommunity.CounterTradeOffer(ctx, sent.TradeOfferID, steamcommunity.SendTradeOfferOptions{
+		Partner:        alice.SteamID,
+		ItemsToGive:    []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: key.AssetID}},
+		ItemsToReceive: []steamapi.TradeAsset{{AppID: 440, ContextID: "2", AssetID: hat.AssetID}},
+	})
+	if err != nil {
+		t.Fatalf("CounterTradeOffer: %v", err)
+	}
+
+	api, err := steamapi.New(steamapi.WithHTTPClient(srv.Client(alice.SteamID)), steamapi.WithHosts(srv.Hosts()))
+	if err != nil {
+		t.Fatalf("steamapi.New: %v", err)
+	}
+	original, err := api.GetTradeOffer(ctx, sent.TradeOfferID)
+	if err != nil {
+		t.Fatalf("GetTradeOffer: %v", err)
+	}
+	if original.State != steamapi.ETradeOfferStateCountered {
+		t.Errorf("original State = %v, want Countered", original.State)
+	}
+	if _, err := aliceCommunity.AcceptTradeOffer(ctx, counter.TradeOfferID, bob.SteamID); err != nil {
+		t.Fatalf("AcceptTradeOffer: %v", err)
+	}
+}
+
+func TestConfirmationKeyChecked(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(Account{IdentitySecret: testIdentitySecret})
+	community := newCommunity(t, srv, srv.Client(alice.SteamID))
+
+	if _, err := community.GetConfirmations(context.Background(), []byte("wrong secret")); err == nil {
+		t.Fatal("GetConfirmations with wrong identity secret: want error")
+	}
+	if _, err := community.GetConfirmations(context.Background(), testIdentitySecret); err != nil {
+		t.Fatalf("GetConfirmations: %v", err)
+	}
+}
+
+func TestMarketListingFlow(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(Account{IdentitySecret: testIdentitySecret})
+	hat := srv.AddItem(alice.SteamID, Item{AppID: 440, Name: "Team Captain", Tradable: true, Marketable: true})
+	srv.SetPriceOverview(440, hat.MarketHashName, PriceOverview{LowestPrice: "$1.50", MedianPrice: "$1.45", Volume: "12"})
+
+	ctx := context.Background()
+	community := newCommunity(t, srv, srv.Client(alice.SteamID))
+
+	overview, err := community.GetMarketPriceOverview(ctx, 440, 1, hat.MarketHashName)
+	if err != nil {
+		t.Fatalf("GetMarketPriceOverview: %v", err)
+	}
+	if overview.LowestPrice != "$1.50" {
+		t.Errorf("LowestPrice = %q, want $1.50", overview.LowestPrice)
+	}
+
+	if _, err := community.SellMarketItem(ctx, 440, 2, mustUint(t, hat.AssetID), 1, 130); err != nil {
+		t.Fatalf("SellMarketItem: %v", err)
+	}
+	if _, err := community.SellMarketItem(ctx, 440, 2, mustUint(t, hat.AssetID), 1, 130); err != steamcommunity.ErrMarketItemNotInInventory {
+		t.Fatalf("second SellMarketItem: err = %v, want ErrMarketItemNotInInventory", err)
+	}
+
+	pending, err := community.GetMyPendingMarketListings(ctx)
+	if err != nil {
+		t.Fatalf("GetMyPendingMarketListings: %v", err)
+	}
+	if len(pending) != 1 || pending[0].AssetID != hat.AssetID {
+		t.Fatalf("pending = %+v, want the hat", pending)
+	}
+
+	if err := community.AcceptConfirmationByCreatorID(ctx, testIdentitySecret, pending[0].ListingID); err != nil {
+		t.Fatalf("AcceptConfirmationByCreatorID: %v", err)
+	}
+	page, err := community.GetMarketListings(ctx, 0, 100)
+	if err != nil {
+		t.Fatalf("GetMarketListings: %v", err)
+	}
+	if page.Total != 1 || page.Listings[0].ID != pending[0].ListingID {
+		t.Fatalf("listings = %+v, want the confirmed listing", page.Listings)
+	}
+	if a := page.Listings[0].Asset; a == nil || a.MarketHashName != hat.MarketHashName {
+		t.Errorf("listing asset = %+v, want %q", a, hat.MarketHashName)
+	}
+
+	if err := srv.FillListing(pending[0].ListingID); err != nil {
+		t.Fatalf("FillListing: %v", err)
+	}
+	if got := srv.Wallet(alice.SteamID); got != 130 {
+		t.Errorf("wallet = %d, want 130", got)
+	}
+}
+
+func TestCancelListingReturnsItem(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(Account{})
+	hat := srv.AddItem(alice.SteamID, Item{AppID: 440, Name: "Team Captain", Marketable: true})
+
+	ctx := context.Background()
+	community := newCommunity(t, srv, srv.Client(alice.SteamID))
+
+	if _, err := community.SellMarketItem(ctx, 440, 2, mustUint(t, hat.AssetID), 1, 100); err != nil {
+		t.Fatalf("SellMarketItem: %v", err)
+	}
+	ids, err := community.GetMyMarketListingIDs(ctx)
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("GetMyMarketListingIDs = %v, %v; want one listing", ids, err)
+	}
+	if err := community.CancelMarketListing(ctx, ids[0]); err != nil {
+		t.Fatalf("CancelMarketListing: %v", err)
+	}
+	if inv := srv.Inventory(alice.SteamID, 440, "2"); len(inv) != 1 {
+		t.Errorf("inventory = %+v, want the item back", inv)
+	}
+}
+
+func TestWallet(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	alice := srv.AddAccount(Account{WalletBalance: 250})
+	srv.AddWalletCode("ABCDE-FGHIJ-KLMNO", 1000)
+
+	ctx := context.Background()
+	store, err := steamstore.New(
+		steamstore.WithHTTPClient(srv.Client(alice.SteamID)),
+		steamstore.WithHosts(srv.Hosts()),
+	)
+	if err != nil {
+		t.Fatalf("steamstore.New: %v", err)
+	}
+
+	balance, err := store.GetWalletBalance(ctx)
+	if err != nil {
+		t.Fatalf("GetWalletBalance: %v", err)
+	}
+	if balance.Balance != "$2.50" {
+		t.Errorf("Balance = %q, want $2.50", balance.Balance)
+	}
+
+	res, err := store.RedeemWalletCode(ctx, "ABCDE-FGHIJ-KLMNO")
+	if err != nil {
+		t.Fatalf("RedeemWalletCode: %v", err)
+	}
+	if res.Amount != "$12.50" {
+		t.Errorf("new balance = %q, want $12.50", res.Amount)
+	}
+	if _, err := store.RedeemWalletCode(ctx, "ABCDE-FGHIJ-KLMNO"); err == nil {
+		t.Error("redeeming a used code: want error")
+	}
+}
+
+func TestValidAuthCode(t *testing.T) {
+	secret := "c2hhcmVkLXNlY3JldC1mb3ItdGVzdHM="
+	code, err := steamtotp.GenerateAuthCode(secret, 0)
+	if err != nil {
+		t.Fatalf("GenerateAuthCode: %v", err)
+	}
+	if !validAuthCode(secret, code) {
+		t.Errorf("validAuthCode(%q) = false, want true", code)
+	}
+	if validAuthCode(secret, "XXXXX") {
+		t.Error("validAuthCode(XXXXX) = true, want false")
+	}
+}
+
+func mustUint(t *testing.T, s string) uint64 {
+	t.Helper()
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return n
+}
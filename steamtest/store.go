@@ -0,0 +1,75 @@
+package steamtest
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// EPurchaseResultDetail codes for a wallet code redemption.
+const (
+	purchaseResultOK                = 0
+	purchaseResultBadActivationCode = 14
+)
+
+// AddWalletCode registers a wallet code worth cents. Each code can be
+// redeemed once.
+func (s *Server) AddWalletCode(code string, cents int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.walletCodes[code] = cents
+}
+
+func (s *Server) registerStore(mux *http.ServeMux) {
+	mux.HandleFunc("GET /account/{$}", s.handleAccountPage)
+	mux.HandleFunc("POST /account/ajaxredeemwalletcode/", s.handleRedeemWalletCode)
+}
+
+// handleAccountPage renders the wallet balance the way the store header
+// does.
+func (s *Server) handleAccountPage(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login/", http.StatusFound)
+		return
+	}
+
+	s.mu.Lock()
+	balance := s.accounts[user].WalletBalance
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<html><body>\n<a class=\"global_action_link\" id=\"header_wallet_balance\" href=\"%s/account/store_transactions/\">%s</a>\n</body></html>\n",
+		s.srv.URL, formatCents(balance))
+}
+
+func (s *Server) handleRedeemWalletCode(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok || !checkSessionID(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := r.FormValue("wallet_code")
+	cents, ok := s.walletCodes[code]
+	if !ok {
+		writeJSON(w, map[string]any{
+			"success":                 eresultFail,
+			"purchase_result_details": purchaseResultBadActivationCode,
+			"detail":                  purchaseResultBadActivationCode,
+		})
+		return
+	}
+	delete(s.walletCodes, code)
+
+	acct := s.accounts[user]
+	acct.WalletBalance += cents
+	writeJSON(w, map[string]any{
+		"success":                   eresultOK,
+		"purchase_result_details":   purchaseResultOK,
+		"formattedNewWalletBalance": formatCents(acct.WalletBalance),
+		"detail":                    0,
+	})
+}
@@ -0,0 +1,814 @@
+package steamtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// offerLifetime is how long a new trade offer stays open.
+const offerLifetime = 14 * 24 * time.Hour
+
+// Item is an inventory item.
+type Item struct {
+	AppID      int
+	ContextID  string // defaults to "2"
+	AssetID    string // assigned by AddItem when empty
+	ClassID    string // defaults to a fresh ID
+	InstanceID string // defaults to "0"
+	Amount     int    // defaults to 1
+
+	Name           string
+	MarketHashName string // defaults to Name
+	Type           string
+	Tradable       bool
+	Marketable     bool
+}
+
+// offer is a trade offer between two accounts. give holds the sender's
+// items and receive the recipient's, as they were when the offer was made.
+type offer struct {
+	id        string
+	sender    steamid.SteamID
+	recipient steamid.SteamID
+	message   string
+	give      []Item
+	receive   []Item
+	state     steamapi.ETradeOfferState
+	created   time.Time
+	updated   time.Time
+	expires   time.Time
+	confirm   steamapi.EConfirmationMethod
+	tradeID   string
+
+	acceptPending bool // accepted by the recipient, awaiting their confirmation
+}
+
+// AddItem puts an item into the owner's inventory and returns it with
+// defaults filled in.
+func (s *Server) AddItem(owner steamid.SteamID, item Item) Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if item.ContextID == "" {
+		item.ContextID = "2"
+	}
+	if item.AssetID == "" {
+		item.AssetID = s.newIDLocked()
+	}
+	if item.ClassID == "" {
+		item.ClassID = s.newIDLocked()
+	}
+	if item.InstanceID == "" {
+		item.InstanceID = "0"
+	}
+	if item.Amount == 0 {
+		item.Amount = 1
+	}
+	if item.MarketHashName == "" {
+		item.MarketHashName = item.Name
+	}
+
+	it := item
+	s.inventories[owner] = append(s.inventories[owner], &it)
+	return item
+}
+
+// Inventory returns the items the owner holds in the given app and context.
+func (s *Server) Inventory(owner steamid.SteamID, appID int, contextID string) []Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Item
+	for _, it := range s.inventories[owner] {
+		if it.AppID == appID && it.ContextID == contextID {
+			out = append(out, *it)
+		}
+	}
+	return out
+}
+
+// TradeOffer returns a trade offer as its sender sees it.
+func (s *Server) TradeOffer(id string) (steamapi.TradeOffer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.offers[id]
+	if o == nil {
+		return steamapi.TradeOffer{}, false
+	}
+	return o.view(o.sender), true
+}
+
+// SetTradeOfferState forces an offer into state, e.g. to simulate expiry.
+func (s *Server) SetTradeOfferState(id string, state steamapi.ETradeOfferState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if o := s.offers[id]; o != nil {
+		o.state = state
+		o.updated = time.Now()
+	}
+}
+
+func (s *Server) registerTrade(mux *http.ServeMux) {
+	mux.HandleFunc("GET /inventory/{steamid}/{appid}/{contextid}", s.handleInventory)
+	mux.HandleFunc("GET /tradeoffer/new/{$}", s.handleNewOfferPage)
+	mux.HandleFunc("GET /tradeoffer/new/partnerinventory/", s.handlePartnerInventory)
+	mux.HandleFunc("POST /tradeoffer/new/send", s.handleSendOffer)
+	mux.HandleFunc("GET /tradeoffer/{id}/{$}", s.handleOfferPage)
+	mux.HandleFunc("POST /tradeoffer/{id}/accept", s.handleAcceptOffer)
+	mux.HandleFunc("POST /tradeoffer/{id}/cancel", s.handleCancelOffer)
+	mux.HandleFunc("POST /tradeoffer/{id}/decline", s.handleDeclineOffer)
+	mux.HandleFunc("GET /IEconService/GetTradeOffer/v1/", s.handleGetTradeOffer)
+	mux.HandleFunc("GET /IEconService/GetTradeOffers/v1/", s.handleGetTradeOffers)
+	mux.HandleFunc("GET /IEconService/GetTradeStatus/v1/", s.handleGetTradeStatus)
+	mux.HandleFunc("GET /IEconService/GetTradeHistory/v1/", s.handleGetTradeHistory)
+}
+
+func (s *Server) handleInventory(w http.ResponseWriter, r *http.Request) {
+	sid64, err := strconv.ParseUint(r.PathValue("steamid"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad steamid", http.StatusBadRequest)
+		return
+	}
+	owner := steamid.FromSteamID64(sid64)
+	s.mu.Lock()
+	private := s.accounts[owner] != nil && s.accounts[owner].InventoryPrivate
+	s.mu.Unlock()
+	if viewer, _ := s.cookieUser(r); private && viewer != owner {
+		// Steam answers a private inventory with 403 and "null".
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("null"))
+		return
+	}
+
+	appID, _ := strconv.Atoi(r.PathValue("appid"))
+	contextID := r.PathValue("contextid")
+	count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+	if count <= 0 || count > 5000 {
+		count = 5000
+	}
+	start := r.URL.Query().Get("start_assetid")
+
+	items := s.Inventory(owner, appID, contextID)
+	if start != "" {
+		i := slices.IndexFunc(items, func(it Item) bool { return it.AssetID == start })
+		items = items[i+1:]
+	}
+
+	resp := map[string]any{
+		"success":               1,
+		"total_inventory_count": len(items),
+	}
+	if len(items) > count {
+		resp["more_items"] = 1
+		resp["last_assetid"] = items[count-1].AssetID
+		items = items[:count]
+	}
+
+	assets := make([]map[string]any, 0, len(items))
+	var descs []map[string]any
+	seen := make(map[string]bool)
+	for _, it := range items {
+		assets = append(assets, map[string]any{
+			"appid":      it.AppID,
+			"contextid":  it.ContextID,
+			"assetid":    it.AssetID,
+			"classid":    it.ClassID,
+			"instanceid": it.InstanceID,
+			"amount":     strconv.Itoa(it.Amount),
+		})
+		key := it.ClassID + "_" + it.InstanceID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		descs = append(descs, map[string]any{
+			"appid":            it.AppID,
+			"classid":          it.ClassID,
+			"instanceid":       it.InstanceID,
+			"name":             it.Name,
+			"market_hash_name": it.MarketHashName,
+			"type":             it.Type,
+			"tradable":         boolInt(it.Tradable),
+			"marketable":       boolInt(it.Marketable),
+		})
+	}
+	resp["assets"] = assets
+	resp["descriptions"] = descs
+	writeJSON(w, resp)
+}
+
+// handleNewOfferPage renders the trade offer page script variables that
+// GetUserDetails reads, or Steam's error box when the partner is unknown
+// or the token is wrong.
+func (s *Server) handleNewOfferPage(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login/", http.StatusFound)
+		return
+	}
+
+	s.mu.Lock()
+	me := *s.accounts[user]
+	partner, err := s.partnerLocked(r.URL.Query().Get("partner"), r.URL.Query().Get("token"))
+	s.mu.Unlock()
+
+	if err != "" {
+		writeOfferPageError(w, err)
+		return
+	}
+	writeOfferPage(w, me, partner)
+}
+
+// handleOfferPage renders an existing offer's page for either side of it.
+func (s *Server) handleOfferPage(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login/", http.StatusFound)
+		return
+	}
+
+	s.mu.Lock()
+	o := s.offers[r.PathValue("id")]
+	if o == nil || (o.sender != user && o.recipient != user) {
+		s.mu.Unlock()
+		writeOfferPageError(w, "The trade offer does not exist, or the trade offer belongs to another user.")
+		return
+	}
+	other := o.sender
+	if user == o.sender {
+		other = o.recipient
+	}
+	me, partner := *s.accounts[user], *s.accounts[other]
+	s.mu.Unlock()
+
+	writeOfferPage(w, me, partner)
+}
+
+// writeOfferPage writes the trade offer page script variables between me
+// and partner.
+func writeOfferPage(w http.ResponseWriter, me, partner Account) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<html><head><script type="text/javascript">
+	var g_daysMyEscrow = %d;
+	var g_daysTheirEscrow = %d;
+	var g_bTradePartnerProbation = false;
+	var g_strYourPersonaName = %s;
+	g_strTradePartnerPersonaName = %s;
+</script></head><body></body></html>
+`, me.EscrowDays, partner.EscrowDays, jsString(me.AccountName), jsString(partner.AccountName))
+}
+
+// writeOfferPageError writes a trade offer page showing Steam's error box.
+func writeOfferPageError(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<html><body><div id=\"error_msg\">\n\t\t%s\t</div></body></html>\n", msg)
+}
+
+// handlePartnerInventory serves an inventory in the legacy rgInventory
+// form of /tradeoffer/new/partnerinventory/, which ignores inventory
+// privacy but wants the trade token.
+func (s *Server) handlePartnerInventory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	user, ok := s.cookieUser(r)
+	c, err := r.Cookie("sessionid")
+	if !ok || err != nil || q.Get("sessionid") != c.Value {
+		writeJSON(w, map[string]any{"success": false})
+		return
+	}
+
+	sid64, _ := strconv.ParseUint(q.Get("partner"), 10, 64)
+	partnerID := steamid.FromSteamID64(sid64)
+	s.mu.Lock()
+	_, msg := s.partnerLocked(strconv.FormatUint(uint64(partnerID.AccountID()), 10), tokenFromReferer(r))
+	s.mu.Unlock()
+	if msg != "" || partnerID == user {
+		writeJSON(w, map[string]any{"success": false, "error": msg})
+		return
+	}
+
+	appID, _ := strconv.Atoi(q.Get("appid"))
+	items := s.Inventory(partnerID, appID, q.Get("contextid"))
+	start, _ := strconv.Atoi(q.Get("start"))
+	items = items[min(start, len(items)):]
+
+	const pageSize = 2000
+	more := len(items) > pageSize
+	if more {
+		items = items[:pageSize]
+	}
+
+	inventory := make(map[string]any, len(items))
+	descs := make(map[string]any)
+	for i, it := range items {
+		inventory[it.AssetID] = map[string]any{
+			"id":         it.AssetID,
+			"classid":    it.ClassID,
+			"instanceid": it.InstanceID,
+			"amount":     strconv.Itoa(it.Amount),
+			"pos":        start + i + 1,
+		}
+		descs[it.ClassID+"_"+it.InstanceID] = map[string]any{
+			"appid":            strconv.Itoa(it.AppID),
+			"classid":          it.ClassID,
+			"instanceid":       it.InstanceID,
+			"name":             it.Name,
+			"market_hash_name": it.MarketHashName,
+			"type":             it.Type,
+			"tradable":         boolInt(it.Tradable),
+			"marketable":       boolInt(it.Marketable),
+		}
+	}
+
+	resp := map[string]any{
+		"success":        true,
+		"rgInventory":    inventory,
+		"rgCurrency":     []any{},
+		"rgDescriptions": descs,
+		"more":           more,
+		"more_start":     false,
+	}
+	if more {
+		resp["more_start"] = start + pageSize
+	}
+	writeJSON(w, resp)
+}
+
+// partnerLocked resolves the partner account ID of a new offer page and
+// checks the trade token. On failure it returns the message Steam shows.
+func (s *Server) partnerLocked(accountID, token string) (Account, string) {
+	id, err := strconv.ParseUint(accountID, 10, 32)
+	if err != nil {
+		return Account{}, "This Trade URL is no longer valid for sending a trade offer to this user."
+	}
+	sid := steamid.SteamID(firstSteamID).SetAccountID(uint32(id))
+	partner := s.accounts[sid]
+	if partner == nil {
+		return Account{}, "This Trade URL is no longer valid for sending a trade offer to this user."
+	}
+	if partner.TradeToken != "" && partner.TradeToken != token {
+		return Account{}, "This Trade URL is no longer valid for sending a trade offer to " + partner.AccountName + "."
+	}
+	return *partner, ""
+}
+
+// tokenFromReferer returns the trade token of the new offer page a
+// partnerinventory request was made from.
+func tokenFromReferer(r *http.Request) string {
+	u, err := url.Parse(r.Referer())
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("token")
+}
+
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// tradeError answers a community trade request the way Steam does: HTTP
+// 500 with strError ending in the EResult.
+func tradeError(w http.ResponseWriter, eresult int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]any{
+		"strError": fmt.Sprintf("There was an error sending your trade offer.  Please try again later. (%d)", eresult),
+	})
+}
+
+func (s *Server) handleSendOffer(w http.ResponseWriter, r *http.Request) {
+	sender, ok := s.cookieUser(r)
+	if !ok || !checkSessionID(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	partner64, err := strconv.ParseUint(r.FormValue("partner"), 10, 64)
+	if err != nil {
+		tradeError(w, eresultInvalidParam)
+		return
+	}
+	partner := steamid.FromSteamID64(partner64)
+
+	var offerJSON struct {
+		Me   struct{ Assets []offerAsset } `json:"me"`
+		Them struct{ Assets []offerAsset } `json:"them"`
+	}
+	if err := json.Unmarshal([]byte(r.FormValue("json_tradeoffer")), &offerJSON); err != nil {
+		tradeError(w, eresultInvalidParam)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accounts[partner] == nil || partner == sender {
+		tradeError(w, eresultInvalidParam)
+		return
+	}
+	give, ok := s.lookupAssetsLocked(sender, offerJSON.Me.Assets)
+	if !ok {
+		tradeError(w, eresultInvalidParam)
+		return
+	}
+	receive, ok := s.lookupAssetsLocked(partner, offerJSON.Them.Assets)
+	if !ok || len(give)+len(receive) == 0 {
+		tradeError(w, eresultInvalidParam)
+		return
+	}
+
+	var countered *offer
+	if id := r.FormValue("tradeofferid_countered"); id != "" {
+		countered = s.offers[id]
+		if countered == nil || countered.recipient != sender || countered.sender != partner ||
+			countered.state != steamapi.ETradeOfferStateActive {
+			tradeError(w, eresultInvalidState)
+			return
+		}
+	}
+
+	now := time.Now()
+	o := &offer{
+		id:        s.newIDLocked(),
+		sender:    sender,
+		recipient: partner,
+		message:   r.FormValue("tradeoffermessage"),
+		give:      give,
+		receive:   receive,
+		state:     steamapi.ETradeOfferStateActive,
+		created:   now,
+		updated:   now,
+		expires:   now.Add(offerLifetime),
+	}
+	s.offers[o.id] = o
+	if countered != nil {
+		countered.state = steamapi.ETradeOfferStateCountered
+		countered.updated = now
+		s.removeConfirmationsLocked(countered.id)
+	}
+
+	needsConfirmation := len(give) > 0 && s.accounts[sender].IdentitySecret != nil
+	if needsConfirmation {
+		o.state = steamapi.ETradeOfferStateCreatedNeedsConfirmation
+		o.confirm = steamapi.EConfirmationMethodMobileApp
+		s.addConfirmationLocked(sender, confTypeTrade, o.id,
+			"Trade with "+s.accounts[partner].AccountName, offerSummary(o.give, o.receive))
+	}
+
+	writeJSON(w, map[string]any{
+		"tradeofferid":              o.id,
+		"needs_mobile_confirmation": needsConfirmation,
+		"needs_email_confirmation":  false,
+	})
+}
+
+func (s *Server) handleAcceptOffer(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.cookieUser(r)
+	if !ok || !checkSessionID(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.offers[r.PathValue("id")]
+	if o == nil || o.recipient != user || o.state != steamapi.ETradeOfferStateActive || o.acceptPending {
+		tradeError(w, eresultInvalidState)
+		return
+	}
+
+	if len(o.receive) > 0 && s.accounts[user].IdentitySecret != nil {
+		o.acceptPending = true
+		s.addConfirmationLocked(user, confTypeTrade, o.id,
+			"Trade with "+s.accounts[o.sender].AccountName, offerSummary(o.receive, o.give))
+		writeJSON(w, map[string]any{"needs_mobile_confirmation": true})
+		return
+	}
+
+	if !s.completeTradeLocked(o) {
+		tradeError(w, eresultInvalidState)
+		return
+	}
+	writeJSON(w, map[string]any{"tradeid": o.tradeID})
+}
+
+func (s *Server) handleCancelOffer(w http.ResponseWriter, r *http.Request) {
+	s.closeOffer(w, r, true)
+}
+
+func (s *Server) handleDeclineOffer(w http.ResponseWriter, r *http.Request) {
+	s.closeOffer(w, r, false)
+}
+
+// closeOffer cancels an offer for its sender or declines it for its recipient.
+func (s *Server) closeOffer(w http.ResponseWriter, r *http.Request, cancel bool) {
+	user, ok := s.cookieUser(r)
+	if !ok || !checkSessionID(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.offers[r.PathValue("id")]
+	switch {
+	case o == nil:
+		tradeError(w, eresultInvalidParam)
+		return
+	case cancel && o.sender == user && (o.state == steamapi.ETradeOfferStateActive || o.state == steamapi.ETradeOfferStateCreatedNeedsConfirmation):
+		o.state = steamapi.ETradeOfferStateCanceled
+	case !cancel && o.recipient == user && o.state == steamapi.ETradeOfferStateActive:
+		o.state = steamapi.ETradeOfferStateDeclined
+	default:
+		tradeError(w, eresultInvalidState)
+		return
+	}
+	o.updated = time.Now()
+	s.removeConfirmationsLocked(o.id)
+
+	writeJSON(w, map[string]any{"tradeofferid": o.id})
+}
+
+// completeTradeLocked swaps the items of an accepted offer. Every item is
+// given a new asset ID in its new inventory, as on Steam; stacks traded in
+// part are split. If any item has left its owner's inventory the offer
+// becomes InvalidItems instead.
+func (s *Server) completeTradeLocked(o *offer) bool {
+	o.updated = time.Now()
+	o.acceptPending = false
+
+	for _, side := range []struct {
+		from  steamid.SteamID
+		items []Item
+	}{{o.sender, o.give}, {o.recipient, o.receive}} {
+		for _, it := range side.items {
+			i := s.findItemLocked(side.from, it.AppID, it.ContextID, it.AssetID)
+			if i < 0 || s.inventories[side.from][i].Amount < it.Amount {
+				o.state = steamapi.ETradeOfferStateInvalidItems
+				return false
+			}
+		}
+	}
+
+	move := func(from, to steamid.SteamID, items []Item) []tradedItem {
+		var traded []tradedItem
+		for _, it := range items {
+			i := s.findItemLocked(from, it.AppID, it.ContextID, it.AssetID)
+			held := s.inventories[from][i]
+			moved := *held
+			moved.Amount = it.Amount
+			moved.AssetID = s.newIDLocked()
+			if held.Amount -= it.Amount; held.Amount == 0 {
+				s.inventories[from] = slices.Delete(s.inventories[from], i, i+1)
+			}
+			s.inventories[to] = append(s.inventories[to], &moved)
+			traded = append(traded, tradedItem{Item: it, newAssetID: moved.AssetID})
+		}
+		return traded
+	}
+	t := &trade{
+		id:        s.newIDLocked(),
+		sender:    o.sender,
+		recipient: o.recipient,
+		time:      o.updated,
+	}
+	t.give = move(o.sender, o.recipient, o.give)
+	t.receive = move(o.recipient, o.sender, o.receive)
+	s.trades[t.id] = t
+
+	o.state = steamapi.ETradeOfferStateAccepted
+	o.tradeID = t.id
+	return true
+}
+
+// offerAsset is an asset in the json_tradeoffer form field.
+type offerAsset struct {
+	AppID     int    `json:"appid"`
+	ContextID string `json:"contextid"`
+	Amount    int    `json:"amount"`
+	AssetID   string `json:"assetid"`
+}
+
+// lookupAssetsLocked resolves offered assets against owner's inventory.
+// It fails if an asset is missing, untradable or listed twice.
+func (s *Server) lookupAssetsLocked(owner steamid.SteamID, assets []offerAsset) ([]Item, bool) {
+	items := make([]Item, 0, len(assets))
+	seen := make(map[string]bool, len(assets))
+	for _, a := range assets {
+		i := s.findItemLocked(owner, a.AppID, a.ContextID, a.AssetID)
+		if i < 0 || seen[a.AssetID] {
+			return nil, false
+		}
+		it := s.inventories[owner][i]
+		if !it.Tradable || a.Amount < 1 || a.Amount > it.Amount {
+			return nil, false
+		}
+		seen[a.AssetID] = true
+		offered := *it
+		offered.Amount = a.Amount
+		items = append(items, offered)
+	}
+	return items, true
+}
+
+func (s *Server) findItemLocked(owner steamid.SteamID, appID int, contextID, assetID string) int {
+	return slices.IndexFunc(s.inventories[owner], func(it *Item) bool {
+		return it.AppID == appID && it.ContextID == contextID && it.AssetID == assetID
+	})
+}
+
+func (s *Server) handleGetTradeOffer(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.apiUser(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.offers[r.URL.Query().Get("tradeofferid")]
+	if o == nil || (o.sender != user && o.recipient != user) {
+		writeJSON(w, map[string]any{"response": map[string]any{}})
+		return
+	}
+
+	resp := map[string]any{"offer": o.view(user)}
+	if r.URL.Query().Get("get_descriptions") == "1" {
+		resp["descriptions"] = describe([]*offer{o})
+	}
+	writeJSON(w, map[string]any{"response": resp})
+}
+
+func (s *Server) handleGetTradeOffers(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.apiUser(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	activeOnly := q.Get("active_only") == "1"
+	historicalOnly := q.Get("historical_only") == "1"
+	cutoff, _ := strconv.ParseInt(q.Get("time_historical_cutoff"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var all []*offer
+	for _, o := range s.offers {
+		all = append(all, o)
+	}
+	slices.SortFunc(all, func(a, b *offer) int { return a.created.Compare(b.created) })
+
+	sent := []steamapi.TradeOffer{}
+	received := []steamapi.TradeOffer{}
+	var included []*offer
+	for _, o := range all {
+		active := o.isActive()
+		switch {
+		case activeOnly && !active && o.updated.Unix() < cutoff:
+			continue
+		case historicalOnly && active:
+			continue
+		}
+		switch {
+		case o.sender == user && q.Get("get_sent_offers") == "1":
+			sent = append(sent, o.view(user))
+		case o.recipient == user && q.Get("get_received_offers") == "1" &&
+			o.state != steamapi.ETradeOfferStateCreatedNeedsConfirmation:
+			// Steam hides offers from the recipient until the sender confirms them.
+			received = append(received, o.view(user))
+		default:
+			continue
+		}
+		included = append(included, o)
+	}
+
+	resp := map[string]any{
+		"trade_offers_sent":     sent,
+		"trade_offers_received": received,
+		"next_cursor":           0,
+	}
+	if q.Get("get_descriptions") == "1" {
+		resp["descriptions"] = describe(included)
+	}
+	writeJSON(w, map[string]any{"response": resp})
+}
+
+// apiUser authenticates a Web API request by its access_token parameter.
+func (s *Server) apiUser(w http.ResponseWriter, r *http.Request) (steamid.SteamID, bool) {
+	sid, ok := s.tokenUser(r.URL.Query().Get("access_token"))
+	if !ok {
+		http.Error(w, "<html><body>Access is denied.</body></html>", http.StatusForbidden)
+	}
+	return sid, ok
+}
+
+// view renders the offer as viewer sees it through IEconService.
+func (o *offer) view(viewer steamid.SteamID) steamapi.TradeOffer {
+	out := steamapi.TradeOffer{
+		ID:                 o.id,
+		Message:            o.message,
+		ExpirationTime:     o.expires.Unix(),
+		State:              o.state,
+		IsOurOffer:         viewer == o.sender,
+		TimeCreated:        o.created.Unix(),
+		TimeUpdated:        o.updated.Unix(),
+		ConfirmationMethod: o.confirm,
+		TradeID:            o.tradeID,
+	}
+	give, receive := o.give, o.receive
+	if viewer == o.sender {
+		out.PartnerAccountID = o.recipient.AccountID()
+	} else {
+		out.PartnerAccountID = o.sender.AccountID()
+		give, receive = receive, give
+	}
+	out.ItemsToGive = tradeAssets(give)
+	out.ItemsToReceive = tradeAssets(receive)
+	return out
+}
+
+func (o *offer) isActive() bool {
+	switch o.state {
+	case steamapi.ETradeOfferStateActive,
+		steamapi.ETradeOfferStateCreatedNeedsConfirmation,
+		steamapi.ETradeOfferStateInEscrow:
+		return true
+	}
+	return false
+}
+
+func tradeAssets(items []Item) []steamapi.TradeAsset {
+	out := make([]steamapi.TradeAsset, len(items))
+	for i, it := range items {
+		out[i] = steamapi.TradeAsset{
+			AppID:      it.AppID,
+			ContextID:  it.ContextID,
+			AssetID:    it.AssetID,
+			ClassID:    it.ClassID,
+			InstanceID: it.InstanceID,
+			Amount:     strconv.Itoa(it.Amount),
+		}
+	}
+	return out
+}
+
+// describe returns the distinct item descriptions of the offers' items.
+func describe(offers []*offer) []steamapi.AssetDescription {
+	var items []Item
+	for _, o := range offers {
+		items = slices.Concat(items, o.give, o.receive)
+	}
+	return describeItems(items)
+}
+
+// describeItems returns the distinct descriptions of items.
+func describeItems(items []Item) []steamapi.AssetDescription {
+	out := []steamapi.AssetDescription{}
+	seen := make(map[string]bool)
+	for _, it := range items {
+		key := steamapi.AssetDescriptionKey(it.AppID, it.ClassID, it.InstanceID)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, steamapi.AssetDescription{
+			AppID:          it.AppID,
+			ClassID:        it.ClassID,
+			InstanceID:     it.InstanceID,
+			Name:           it.Name,
+			MarketHashName: it.MarketHashName,
+			Type:           it.Type,
+			Tradable:       it.Tradable,
+			Marketable:     it.Marketable,
+		})
+	}
+	return out
+}
+
+// offerSummary is the confirmation summary from the confirming side.
+func offerSummary(give, receive []Item) []string {
+	return []string{
+		fmt.Sprintf("You will give %d item(s)", len(give)),
+		fmt.Sprintf("You will receive %d item(s)", len(receive)),
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
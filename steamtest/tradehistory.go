@@ -0,0 +1,163 @@
+package steamtest
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/k64z/steamstacks/steamapi"
+	"github.com/k64z/steamstacks/steamid"
+)
+
+// trade is the receipt of a completed offer. give holds what the offer's
+// sender gave and receive what they got, each with its new asset ID.
+type trade struct {
+	id        string
+	sender    steamid.SteamID
+	recipient steamid.SteamID
+	time      time.Time
+	give      []tradedItem
+	receive   []tradedItem
+}
+
+// tradedItem is an item as it was before the trade, and the asset ID it
+// was given in its new inventory.
+type tradedItem struct {
+	Item
+	newAssetID string
+}
+
+func (s *Server) handleGetTradeStatus(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.apiUser(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.trades[r.URL.Query().Get("tradeid")]
+	if t == nil || (t.sender != user && t.recipient != user) {
+		writeJSON(w, map[string]any{"response": map[string]any{}})
+		return
+	}
+
+	resp := map[string]any{"trades": []steamapi.Trade{t.view(user)}}
+	if r.URL.Query().Get("get_descriptions") == "1" {
+		resp["descriptions"] = describeItems(t.items())
+	}
+	writeJSON(w, map[string]any{"response": resp})
+}
+
+// handleGetTradeHistory lists the user's trades newest first, paged by
+// start_after_time and start_after_tradeid. navigating_back is not
+// supported.
+func (s *Server) handleGetTradeHistory(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.apiUser(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	maxTrades, _ := strconv.Atoi(q.Get("max_trades"))
+	if maxTrades <= 0 {
+		maxTrades = 100
+	}
+	afterTime, _ := strconv.ParseInt(q.Get("start_after_time"), 10, 64)
+	afterID, _ := strconv.ParseUint(q.Get("start_after_tradeid"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var all []*trade
+	for _, t := range s.trades {
+		if t.sender == user || t.recipient == user {
+			all = append(all, t)
+		}
+	}
+	// Newest first; IDs break ties within a second.
+	slices.SortFunc(all, func(a, b *trade) int {
+		return cmp.Or(cmp.Compare(b.time.Unix(), a.time.Unix()), cmp.Compare(b.numericID(), a.numericID()))
+	})
+	total := len(all)
+
+	if afterTime > 0 {
+		i := slices.IndexFunc(all, func(t *trade) bool {
+			ts := t.time.Unix()
+			return ts < afterTime || (ts == afterTime && t.numericID() < afterID)
+		})
+		if i < 0 {
+			i = len(all)
+		}
+		all = all[i:]
+	}
+	more := len(all) > maxTrades
+	if more {
+		all = all[:maxTrades]
+	}
+
+	trades := make([]steamapi.Trade, len(all))
+	var items []Item
+	for i, t := range all {
+		trades[i] = t.view(user)
+		items = append(items, t.items()...)
+	}
+	resp := map[string]any{"trades": trades, "more": more}
+	if q.Get("include_total") == "1" {
+		resp["total_trades"] = total
+	}
+	if q.Get("get_descriptions") == "1" {
+		resp["descriptions"] = describeItems(items)
+	}
+	writeJSON(w, map[string]any{"response": resp})
+}
+
+// view renders the trade as viewer sees it through IEconService.
+func (t *trade) view(viewer steamid.SteamID) steamapi.Trade {
+	out := steamapi.Trade{
+		TradeID:        t.id,
+		TimeInit:       t.time.Unix(),
+		TimeSettlement: t.time.Unix(),
+		Status:         steamapi.ETradeStatusComplete,
+	}
+	given, received := t.give, t.receive
+	out.SteamIDOther = t.recipient.ToSteamID64()
+	if viewer != t.sender {
+		given, received = received, given
+		out.SteamIDOther = t.sender.ToSteamID64()
+	}
+	out.AssetsGiven = historyAssets(given)
+	out.AssetsReceived = historyAssets(received)
+	return out
+}
+
+func (t *trade) items() []Item {
+	var out []Item
+	for _, it := range slices.Concat(t.give, t.receive) {
+		out = append(out, it.Item)
+	}
+	return out
+}
+
+func (t *trade) numericID() uint64 {
+	id, _ := strconv.ParseUint(t.id, 10, 64)
+	return id
+}
+
+func historyAssets(items []tradedItem) []steamapi.TradeHistoryAsset {
+	out := make([]steamapi.TradeHistoryAsset, len(items))
+	for i, it := range items {
+		out[i] = steamapi.TradeHistoryAsset{
+			AppID:        it.AppID,
+			ContextID:    it.ContextID,
+			AssetID:      it.AssetID,
+			Amount:       strconv.Itoa(it.Amount),
+			ClassID:      it.ClassID,
+			InstanceID:   it.InstanceID,
+			NewAssetID:   it.newAssetID,
+			NewContextID: it.ContextID,
+		}
+	}
+	return out
+}
@@ -3,6 +3,7 @@ package steamtotp
 import (
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
@@ -77,3 +78,40 @@ func GetDeviceID(steamID64 uint64) string {
 	return fmt.Sprintf("android:%s-%s-%s-%s-%s",
 		s[0:8], s[8:12], s[12:16], s[16:20], s[20:32])
 }
+
+// GenerateAuthSessionSignature signs a pending login for
+// UpdateAuthSessionWithMobileConfirmation, as the mobile app does when the
+// user taps "Approve". The HMAC-SHA256 covers the session version, client ID
+// and SteamID (little-endian), keyed with the shared secret.
+func GenerateAuthSessionSignature(sharedSecret string, version int32, clientID, steamID64 uint64) ([]byte, error) {
+	secret, err := decodeSecret(sharedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("decode shared secret: %w", err)
+	}
+
+	var buf [18]byte
+	binary.LittleEndian.PutUint16(buf[0:2], uint16(version))
+	binary.LittleEndian.PutUint64(buf[2:10], clientID)
+	binary.LittleEndian.PutUint64(buf[10:18], steamID64)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(buf[:])
+	return mac.Sum(nil), nil
+}
+
+// GenerateTokenRevokeSignature signs a RevokeRefreshToken request for
+// another device's token. The HMAC-SHA256 covers the token ID
+// (little-endian), keyed with the shared secret.
+func GenerateTokenRevokeSignature(sharedSecret string, tokenID uint64) ([]byte, error) {
+	secret, err := decodeSecret(sharedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("decode shared secret: %w", err)
+	}
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], tokenID)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(buf[:])
+	return mac.Sum(nil), nil
+}
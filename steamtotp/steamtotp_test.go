@@ -2,6 +2,7 @@ package steamtotp
 
 import (
 	"encoding/base64"
+	"encoding/hex"
 	"testing"
 	"time"
 )
@@ -160,3 +161,29 @@ func TestGetDeviceID(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateAuthSessionSignature(t *testing.T) {
+	got, err := GenerateAuthSessionSignature("SGVsbG9Xb3JsZFRlc3RTZWNyZXQh", 1, 1234567890123, 76561198012345678)
+	if err != nil {
+		t.Fatalf("GenerateAuthSessionSignature: %v", err)
+	}
+	want := "f7c818c74e602160c361e3932f6b11fd6fea4d44f3321f257531cad8a8ed91d4"
+	if hex.EncodeToString(got) != want {
+		t.Errorf("signature = %x, want %s", got, want)
+	}
+
+	if _, err := GenerateAuthSessionSignature("!!!invalid!!!", 1, 1, 1); err == nil {
+		t.Error("expected error for invalid secret")
+	}
+}
+
+func TestGenerateTokenRevokeSignature(t *testing.T) {
+	got, err := GenerateTokenRevokeSignature("SGVsbG9Xb3JsZFRlc3RTZWNyZXQh", 9876543210)
+	if err != nil {
+		t.Fatalf("GenerateTokenRevokeSignature: %v", err)
+	}
+	want := "d2c5d0760d14eb016e775a52bc25ee7937be1baae04e0cb6de26ef7226c7a480"
+	if hex.EncodeToString(got) != want {
+		t.Errorf("signature = %x, want %s", got, want)
+	}
+}
@@ -187,10 +187,11 @@ func (c *Client) Connect(ctx context.Context) error {
 		c.mu.Unlock()
 		return fmt.Errorf("tf2: already connecting")
 	}
-	c.helloStop = make(chan struct{})
+	stop := make(chan struct{})
+	c.helloStop = stop
 	c.mu.Unlock()
 
-	return c.sendHello(ctx)
+	return c.sendHello(ctx, stop)
 }
 
 // Disconnect stops the hello loop and marks the session as disconnected.
@@ -445,7 +446,10 @@ func parseGoodbye(b []byte) *GoodbyeEvent {
 	return ev
 }
 
-func (c *Client) sendHello(ctx context.Context) error {
+// sendHello sends the first hello and starts the loop resending it until
+// stop is closed. stop is taken before sending, as the welcome may close
+// it before SendGCMessage returns.
+func (c *Client) sendHello(ctx context.Context, stop <-chan struct{}) error {
 	// Encode CMsgClientHello with protowire to avoid name conflicts with
 	// protocol.CMsgClientHello. The GC hello has a single field:
 	// field 1 (uint32): client_launcher = 0
@@ -457,18 +461,15 @@ func (c *Client) sendHello(ctx context.Context) error {
 		return fmt.Errorf("tf2: send hello: %w", err)
 	}
 
-	c.mu.Lock()
-	stop := c.helloStop
-	c.mu.Unlock()
-
 	// Start background hello loop — resends every 5 seconds until welcome or stop.
-	go c.helloLoop(stop, body)
+	// The ticker is made here rather than in the goroutine, so tests
+	// swapping newTicker don't race with a loop that has yet to start.
+	go c.helloLoop(stop, newTicker(helloInterval), body)
 
 	return nil
 }
 
-func (c *Client) helloLoop(stop <-chan struct{}, helloBody []byte) {
-	ticker := newTicker(helloInterval)
+func (c *Client) helloLoop(stop <-chan struct{}, ticker ticker, helloBody []byte) {
 	defer ticker.Stop()
 
 	for {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/k64z/steamstacks/protocol"
 	"github.com/k64z/steamstacks/steamclient"
+	"github.com/k64z/steamstacks/steamclient/cmtest"
 	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
@@ -538,6 +539,67 @@ func TestCraftResponse(t *testing.T) {
 	}
 }
 
+func TestEndToEndWithFakeCM(t *testing.T) {
+	srv := cmtest.NewServer()
+	defer srv.Close()
+
+	// The fake GC welcomes the hello, then sends the SO cache.
+	srv.HandleGC(AppID, func(s *cmtest.Session, msg *steamclient.GCMessage) {
+		if msg.MsgType != MsgClientHello {
+			return
+		}
+		s.SendGC(&steamclient.GCMessage{
+			AppID: AppID, MsgType: MsgClientWelcome, IsProto: true, Body: buildWelcomeBody(1500, "US"),
+		})
+		s.SendGC(&steamclient.GCMessage{
+			AppID: AppID, MsgType: MsgSOCacheSubscribed, IsProto: true,
+			Body: buildCacheSubscribed(buildSubscribedType(SOTypeItem, buildItemBytes(1001, 5021, 100))),
+		})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cm := steamclient.New(srv.ClientOptions(steamclient.TransportTCP)...)
+	if err := cm.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer cm.Disconnect()
+	if err := cm.Login(ctx, "user", "refresh-token", 76561197960287930); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	welcome := make(chan *WelcomeEvent, 1)
+	backpack := make(chan []*Item, 1)
+	tc := New(cm,
+		WithConnectedHandler(func(e *WelcomeEvent) { welcome <- e }),
+		WithBackpackLoadedHandler(func(items []*Item) { backpack <- items }),
+	)
+	defer tc.Disconnect()
+
+	if err := tc.Connect(ctx); err != nil {
+		t.Fatalf("tf2 Connect: %v", err)
+	}
+
+	select {
+	case e := <-welcome:
+		if e.Version != 1500 {
+			t.Errorf("WelcomeEvent.Version = %d, want 1500", e.Version)
+		}
+	case <-ctx.Done():
+		t.Fatal("OnConnected was not called")
+	}
+
+	select {
+	case items := <-backpack:
+		if len(items) != 1 || items[0].ID != 1001 {
+			t.Errorf("backpack = %v, want item 1001", items)
+		}
+	case <-ctx.Done():
+		t.Fatal("OnBackpackLoaded was not called")
+	}
+}
+
 // --- test helpers ---
 
 type mockConn struct {